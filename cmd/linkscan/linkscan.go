@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Lockwarr/codefi/pkg/helpers"
+	"github.com/Lockwarr/codefi/pkg/scraper"
+)
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitFailures = 1 // more pages failed than the thresholds allow
+	exitUsage    = 2 // invalid flags or url files, or the scan was interrupted
+)
+
+// Output formats
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+var csvHeader = []string{"page_url", "success", "internal_links_num", "external_links_num", "error", "error_category", "status_code"}
+
+// config - parsed command line
+type config struct {
+	format         string
+	concurrency    int
+	maxFailures    int
+	maxFailureRate float64
+	verbose        bool
+	opts           scraper.Options
+	files          []string
+}
+
+// row - result of a page as printed, the error is flattened to its message, category and status code
+type row struct {
+	PageURL          string                `json:"page_url"`
+	Success          bool                  `json:"success"`
+	InternalLinksNum uint                  `json:"internal_links_num"`
+	ExternalLinksNum uint                  `json:"external_links_num"`
+	Error            string                `json:"error,omitempty"`
+	ErrorCategory    scraper.ErrorCategory `json:"error_category,omitempty"`
+	StatusCode       int                   `json:"status_code,omitempty"`
+	InternalLinks    []string              `json:"internal_links,omitempty"`
+	ExternalLinks    []string              `json:"external_links,omitempty"`
+}
+
+// summary - outcome of the whole scan
+type summary struct {
+	URLCount     int `json:"url_count"`
+	SuccessCount int `json:"success_count"`
+	FailureCount int `json:"failure_count"`
+}
+
+// run - scans the url files from args and prints the results to stdout, the summary and errors go to stderr
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "linkscan:", err)
+		return exitUsage
+	}
+	if cfg.verbose {
+		log.SetOutput(stderr)
+	}
+
+	urls, err := gatherURLs(cfg.files, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "linkscan:", err)
+		return exitUsage
+	}
+	if len(urls) == 0 {
+		fmt.Fprintln(stderr, "linkscan: no urls for processing")
+		return exitUsage
+	}
+
+	client := scraper.NewScraper(scraper.WithConcurrency(cfg.concurrency))
+	stream := client.ScrapeStream(ctx, urls, cfg.opts)
+
+	rows := make([]row, 0, len(urls))
+	var ndjson *json.Encoder
+	if cfg.format == formatNDJSON {
+		ndjson = json.NewEncoder(stdout) // lines are printed as the pages finish
+	}
+	for result := range stream.Results() {
+		printed := toRow(result)
+		rows = append(rows, printed)
+		if ndjson != nil {
+			if err := ndjson.Encode(printed); err != nil {
+				fmt.Fprintln(stderr, "linkscan:", err)
+				return exitUsage
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		fmt.Fprintln(stderr, "linkscan: scan interrupted:", err)
+		return exitUsage
+	}
+
+	sortByInput(rows, urls)
+	total := summarize(rows)
+	if err := printRows(stdout, cfg.format, rows, total); err != nil {
+		fmt.Fprintln(stderr, "linkscan:", err)
+		return exitUsage
+	}
+	fmt.Fprintf(stderr, "%d pages, %d successful, %d failed\n", total.URLCount, total.SuccessCount, total.FailureCount)
+
+	if exceeded, reason := cfg.exceedsThresholds(total); exceeded {
+		fmt.Fprintln(stderr, "linkscan:", reason)
+		return exitFailures
+	}
+	return exitOK
+}
+
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	cfg := config{}
+	var (
+		headers         headerFlag
+		internalPolicy  string
+		redirectPolicy  string
+		internalDomains string
+		linkCategories  string
+	)
+
+	flags := flag.NewFlagSet("linkscan", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: linkscan [flags] [file ...]")
+		fmt.Fprintln(stderr, "Counts the internal and external links of the urls in the files, stdin is read when no file or - is passed.")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&cfg.format, "format", formatTable, "output format: table, json, ndjson or csv")
+	flags.IntVar(&cfg.concurrency, "concurrency", 0, "pages fetched at the same time, 0 for the scraper default")
+	flags.DurationVar(&cfg.opts.Timeout, "timeout", 0, "timeout of every page, e.g. 10s, 0 for the default of 30s")
+	flags.StringVar(&cfg.opts.UserAgent, "user-agent", "", "user agent sent with every request")
+	flags.Var(&headers, "header", `extra request header as "Name: value", can be repeated`)
+	flags.Int64Var(&cfg.opts.MaxBodySize, "max-body-size", 0, "largest page in bytes, 0 for the default of 10 MiB")
+	flags.StringVar(&redirectPolicy, "redirect-policy", "", "follow (default), none or same_host")
+	flags.IntVar(&cfg.opts.MaxRedirects, "max-redirects", 0, "redirects followed per page, 0 for the default of 10")
+	flags.StringVar(&internalPolicy, "internal-policy", "", "same_host (default) or same_domain")
+	flags.StringVar(&internalDomains, "internal-domains", "", "comma separated hosts which are always internal")
+	flags.StringVar(&linkCategories, "link-categories", "", "comma separated elements to count links from: anchor (default), area and link")
+	flags.BoolVar(&cfg.opts.CollectLinks, "collect-links", false, "print the links of every page, not only their count (json and ndjson)")
+	flags.IntVar(&cfg.maxFailures, "max-failures", 0, "failed pages allowed before exiting with 1, -1 for no limit")
+	flags.Float64Var(&cfg.maxFailureRate, "max-failure-rate", -1, "percentage of failed pages allowed before exiting with 1, -1 for no limit")
+	flags.BoolVar(&cfg.verbose, "verbose", false, "log the progress of the scraper to stderr")
+
+	if err := flags.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	switch cfg.format {
+	case formatTable, formatJSON, formatNDJSON, formatCSV:
+	default:
+		return config{}, fmt.Errorf("unknown format %q, expected table, json, ndjson or csv", cfg.format)
+	}
+	if cfg.concurrency < 0 {
+		return config{}, errors.New("concurrency can't be negative")
+	}
+
+	cfg.opts.Headers = headers
+	cfg.opts.RedirectPolicy = scraper.RedirectPolicy(redirectPolicy)
+	cfg.opts.InternalPolicy = scraper.InternalPolicy(internalPolicy)
+	cfg.opts.InternalDomains = splitList(internalDomains)
+	for _, category := range splitList(linkCategories) {
+		cfg.opts.LinkCategories = append(cfg.opts.LinkCategories, scraper.LinkCategory(category))
+	}
+	if err := cfg.opts.Validate(); err != nil {
+		return config{}, err
+	}
+
+	cfg.files = flags.Args()
+	return cfg, nil
+}
+
+// exceedsThresholds - whether more pages failed than the thresholds allow and why
+func (c config) exceedsThresholds(total summary) (bool, string) {
+	if c.maxFailures >= 0 && total.FailureCount > c.maxFailures {
+		return true, fmt.Sprintf("%d pages failed, at most %d are allowed", total.FailureCount, c.maxFailures)
+	}
+	if c.maxFailureRate >= 0 && total.URLCount > 0 {
+		rate := float64(total.FailureCount) / float64(total.URLCount) * 100
+		if rate > c.maxFailureRate {
+			return true, fmt.Sprintf("%.1f%% of the pages failed, at most %.1f%% are allowed", rate, c.maxFailureRate)
+		}
+	}
+	return false, ""
+}
+
+// gatherURLs - urls of every file in order, stdin for "-" or when there are no files
+func gatherURLs(files []string, stdin io.Reader) ([]*url.URL, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	urls := []*url.URL{}
+	for _, name := range files {
+		var (
+			fileURLs []*url.URL
+			err      error
+		)
+		if name == "-" {
+			fileURLs, err = helpers.GatherUrls(io.NopCloser(stdin))
+		} else {
+			fileURLs, err = gatherFile(name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		urls = append(urls, fileURLs...)
+	}
+	return urls, nil
+}
+
+func gatherFile(name string) ([]*url.URL, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return helpers.GatherUrls(file)
+}
+
+func toRow(result scraper.Result) row {
+	printed := row{
+		PageURL:          result.PageURL,
+		Success:          result.Success,
+		InternalLinksNum: result.InternalLinksNum,
+		ExternalLinksNum: result.ExternalLinksNum,
+		InternalLinks:    result.InternalLinks,
+		ExternalLinks:    result.ExternalLinks,
+	}
+	if result.Error != nil {
+		printed.Error = result.Error.Error()
+		printed.ErrorCategory, printed.StatusCode = scraper.ClassifyError(result.Error)
+	}
+	return printed
+}
+
+// sortByInput - rows in the order of their urls in the files, the pages finish in any order
+func sortByInput(rows []row, urls []*url.URL) {
+	position := make(map[string]int, len(urls))
+	for i := len(urls) - 1; i >= 0; i-- {
+		position[urls[i].String()] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return position[rows[i].PageURL] < position[rows[j].PageURL]
+	})
+}
+
+func summarize(rows []row) summary {
+	total := summary{URLCount: len(rows)}
+	for _, printed := range rows {
+		if printed.Success {
+			total.SuccessCount++
+		} else {
+			total.FailureCount++
+		}
+	}
+	return total
+}
+
+// printRows - prints the rows in the format, ndjson rows are printed while scanning
+func printRows(w io.Writer, format string, rows []row, total summary) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Summary summary `json:"summary"`
+			Results []row   `json:"results"`
+		}{Summary: total, Results: rows})
+
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, printed := range rows {
+			if err := writer.Write(csvRow(printed)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case formatTable:
+		writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "PAGE URL\tSUCCESS\tINTERNAL\tEXTERNAL\tERROR")
+		for _, printed := range rows {
+			fmt.Fprintf(writer, "%s\t%t\t%d\t%d\t%s\n", printed.PageURL, printed.Success, printed.InternalLinksNum, printed.ExternalLinksNum, tableError(printed))
+		}
+		return writer.Flush()
+	}
+	return nil
+}
+
+func csvRow(printed row) []string {
+	statusCode := ""
+	if printed.StatusCode != 0 {
+		statusCode = strconv.Itoa(printed.StatusCode)
+	}
+	return []string{
+		printed.PageURL,
+		strconv.FormatBool(printed.Success),
+		strconv.FormatUint(uint64(printed.InternalLinksNum), 10),
+		strconv.FormatUint(uint64(printed.ExternalLinksNum), 10),
+		printed.Error,
+		string(printed.ErrorCategory),
+		statusCode,
+	}
+}
+
+// tableError - category and status code of the error, the message when it has no category of its own
+func tableError(printed row) string {
+	switch {
+	case printed.Error == "":
+		return ""
+	case printed.StatusCode != 0:
+		return fmt.Sprintf("%s %d", printed.ErrorCategory, printed.StatusCode)
+	case printed.ErrorCategory == scraper.ErrorCategoryOther:
+		return printed.Error
+	}
+	return fmt.Sprintf("%s: %s", printed.ErrorCategory, printed.Error)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// headerFlag - repeatable "Name: value" flag
+type headerFlag map[string]string
+
+func (h *headerFlag) String() string {
+	return ""
+}
+
+func (h *headerFlag) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q must look like \"Name: value\"", value)
+	}
+	if *h == nil {
+		*h = headerFlag{}
+	}
+	(*h)[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type linkscanTestSuite struct {
+	suite.Suite
+	site *httptest.Server
+}
+
+func (s *linkscanTestSuite) SetupTest() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/links", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="https://external.example.com/">c</a>`)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>nothing to see here</p>`)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	s.site = httptest.NewServer(mux)
+}
+
+func (s *linkscanTestSuite) TearDownTest() {
+	s.site.Close()
+}
+
+func TestLinkscanTestSuite(t *testing.T) {
+	suite.Run(t, &linkscanTestSuite{})
+}
+
+// scan - runs the command with the urls file on stdin, {site} is replaced by the fixture site url
+func (s *linkscanTestSuite) scan(stdin string, args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(context.Background(), args, strings.NewReader(strings.ReplaceAll(stdin, "{site}", s.site.URL)), stdout, stderr)
+	return code, strings.ReplaceAll(stdout.String(), s.site.URL, "{site}"), stderr.String()
+}
+
+func (s *linkscanTestSuite) TestRun_WhenEveryPageSucceeds_ThenTableIsPrintedInInputOrder() {
+	// Act
+	code, stdout, stderr := s.scan("{site}/links\n{site}/empty\n")
+
+	// Assert
+	s.Equal(exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	s.Equal(3, len(lines))
+	s.Equal([]string{"PAGE", "URL", "SUCCESS", "INTERNAL", "EXTERNAL", "ERROR"}, strings.Fields(lines[0]))
+	s.Equal([]string{"{site}/links", "true", "2", "1"}, strings.Fields(lines[1]))
+	s.Equal([]string{"{site}/empty", "true", "0", "0"}, strings.Fields(lines[2]))
+	s.Equal("2 pages, 2 successful, 0 failed\n", stderr)
+}
+
+func (s *linkscanTestSuite) TestRun_WhenFailuresAreChecked_ThenExitCodeReflectsTheThresholds() {
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedErr  string
+	}{
+		{name: "no failures allowed by default", args: nil, expectedCode: exitFailures, expectedErr: "1 pages failed, at most 0 are allowed"},
+		{name: "within max failures", args: []string{"-max-failures", "1"}, expectedCode: exitOK},
+		{name: "no limit", args: []string{"-max-failures", "-1"}, expectedCode: exitOK},
+		{name: "above max failure rate", args: []string{"-max-failures", "-1", "-max-failure-rate", "30"}, expectedCode: exitFailures, expectedErr: "33.3% of the pages failed, at most 30.0% are allowed"},
+		{name: "within max failure rate", args: []string{"-max-failures", "-1", "-max-failure-rate", "50"}, expectedCode: exitOK},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			code, _, stderr := s.scan("{site}/links\n{site}/empty\n{site}/missing\n", append([]string{"-format", "csv"}, tc.args...)...)
+
+			// Assert
+			s.Equal(tc.expectedCode, code)
+			s.Contains(stderr, "3 pages, 2 successful, 1 failed")
+			s.Contains(stderr, tc.expectedErr)
+		})
+	}
+}
+
+func (s *linkscanTestSuite) TestRun_WhenFormatIsCSV_ThenErrorsAreFlattened() {
+	// Act
+	code, stdout, _ := s.scan("{site}/missing\n{site}/links\n", "-format", "csv", "-max-failures", "-1")
+
+	// Assert
+	s.Equal(exitOK, code)
+	s.Equal("page_url,success,internal_links_num,external_links_num,error,error_category,status_code\n"+
+		"{site}/missing,false,0,0,bad status code,status,404\n"+
+		"{site}/links,true,2,1,,,\n", stdout)
+}
+
+func (s *linkscanTestSuite) TestRun_WhenFormatIsJSON_ThenSummaryAndResultsArePrinted() {
+	// Act
+	code, stdout, _ := s.scan("{site}/links\n", "-format", "json", "-collect-links")
+
+	// Assert
+	s.Equal(exitOK, code)
+	var output struct {
+		Summary summary `json:"summary"`
+		Results []row   `json:"results"`
+	}
+	s.NoError(json.Unmarshal([]byte(stdout), &output))
+	s.Equal(summary{URLCount: 1, SuccessCount: 1}, output.Summary)
+	s.Equal([]string{"https://external.example.com/"}, output.Results[0].ExternalLinks)
+	s.Equal(2, len(output.Results[0].InternalLinks))
+}
+
+func (s *linkscanTestSuite) TestRun_WhenFilesArePassed_ThenEveryFileIsScannedAsNDJSON() {
+	// Arrange
+	dir := s.T().TempDir()
+	first, second := filepath.Join(dir, "first.txt"), filepath.Join(dir, "second.txt")
+	s.NoError(os.WriteFile(first, []byte(s.site.URL+"/links\n"), 0o644))
+	s.NoError(os.WriteFile(second, []byte(s.site.URL+"/empty\n"), 0o644))
+
+	// Act
+	code, stdout, _ := s.scan("", "-format", "ndjson", "-concurrency", "1", first, second)
+
+	// Assert
+	s.Equal(exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	s.Equal(2, len(lines))
+	s.Contains(stdout, `{"page_url":"{site}/links","success":true,"internal_links_num":2,"external_links_num":1}`)
+	s.Contains(stdout, `{"page_url":"{site}/empty","success":true,"internal_links_num":0,"external_links_num":0}`)
+}
+
+func (s *linkscanTestSuite) TestRun_WhenInputIsInvalid_ThenUsageErrorIsReturned() {
+	testCases := []struct {
+		name        string
+		stdin       string
+		args        []string
+		expectedErr string
+	}{
+		{name: "unknown format", stdin: "{site}/links\n", args: []string{"-format", "xml"}, expectedErr: `unknown format "xml"`},
+		{name: "invalid option", stdin: "{site}/links\n", args: []string{"-internal-policy", "same_planet"}, expectedErr: `unknown internal policy "same_planet"`},
+		{name: "invalid header", stdin: "{site}/links\n", args: []string{"-header", "no-colon"}, expectedErr: `must look like "Name: value"`},
+		{name: "bad url", stdin: "{site}/links\nnot a url\n", expectedErr: "-: bad url at line 2"},
+		{name: "missing file", args: []string{"missing.txt"}, expectedErr: "missing.txt:"},
+		{name: "no urls", stdin: "", expectedErr: "no urls for processing"},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			code, stdout, stderr := s.scan(tc.stdin, tc.args...)
+
+			// Assert
+			s.Equal(exitUsage, code)
+			s.Equal("", stdout)
+			s.Contains(stderr, tc.expectedErr)
+		})
+	}
+}
+
+func (s *linkscanTestSuite) TestRun_WhenContextIsCancelled_ThenScanIsInterrupted() {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	// Act
+	code := run(ctx, nil, strings.NewReader(s.site.URL+"/links\n"), stdout, stderr)
+
+	// Assert
+	s.Equal(exitUsage, code)
+	s.Contains(stderr.String(), "scan interrupted: context canceled")
+}
@@ -0,0 +1,26 @@
+// Command linkscan counts the internal and external links of the pages in url files without the REST server.
+//
+// Usage:
+//
+//	linkscan [flags] [file ...]
+//
+// The files hold a valid url on each line, stdin is read when no file or "-" is passed.
+// The exit code is 0 when the failed pages are within the thresholds, 1 when they exceed them
+// and 2 for invalid flags or url files.
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	log.SetOutput(io.Discard) // the scraper logs every batch, -verbose brings the logs back
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,390 @@
+// Package client - Go client of the links service api. Batches are submitted from a file, a reader
+// or a slice of urls, their results are returned as links.Result and error responses are returned
+// as an *APIError which matches the errors of the service with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+var ErrInvalidBaseURL = errors.New("invalid base url")
+var ErrUnexpectedResponse = errors.New("unexpected response")
+var ErrEventStreamEnded = errors.New("event stream ended before the batch completed")
+
+const apiPrefix = "/api/v1"
+
+// Client - client of the links service api, safe for concurrent use
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+	apiKey     string
+}
+
+// Option - configures a Client
+type Option func(*Client)
+
+// WithHTTPClient - http client the requests are sent with, a pooled cleanhttp client by default.
+// Synchronous batches respond once every url is scraped so the client shouldn't time out too early,
+// prefer deadlines on the context of the calls.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithRetryPolicy - how failed requests are retried, DefaultRetryPolicy by default
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithAPIKey - api key sent as a bearer token, needed when the service has api keys enabled
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// New - client of the service at baseURL, e.g. http://localhost:8080
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w %q, expected an http or https url", ErrInvalidBaseURL, baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: cleanhttp.DefaultPooledClient(),
+		retry:      DefaultRetryPolicy,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// BatchOptions - optional settings of a submitted batch
+type BatchOptions struct {
+	Scrape   links.ScrapeOptions
+	Callback *links.Callback // notified once the batch finishes
+}
+
+// Submit - processes the urls of the source and returns the results once every url is scraped
+func (c *Client) Submit(ctx context.Context, source URLSource, opts BatchOptions) ([]links.Result, error) {
+	req, err := newSubmitRequest(source, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var response links.ProcessBatchResponse
+	if err := c.call(ctx, req, &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// Start - starts processing the urls of the source in the background and returns the running batch,
+// see WaitForBatch and GetBatch for its outcome
+func (c *Client) Start(ctx context.Context, source URLSource, opts BatchOptions) (links.Batch, error) {
+	req, err := newSubmitRequest(source, opts, true)
+	if err != nil {
+		return links.Batch{}, err
+	}
+
+	var batch links.Batch
+	if err := c.call(ctx, req, &batch); err != nil {
+		return links.Batch{}, err
+	}
+	return batch, nil
+}
+
+// GetBatch - one page of the results of a batch, pass the NextCursor of the response
+// as the cursor of the query for the next page. The service returns 50 results when no limit is set.
+func (c *Client) GetBatch(ctx context.Context, batchID string, query links.ResultQuery) (links.GetBatchResponse, error) {
+	req := request{method: http.MethodGet, path: "/links/" + url.PathEscape(batchID), query: resultQueryParams(query)}
+
+	var response links.GetBatchResponse
+	if err := c.call(ctx, req, &response); err != nil {
+		return links.GetBatchResponse{}, err
+	}
+	return response, nil
+}
+
+// ListBatches - one page of batches, newest first when the query has no sort field
+func (c *Client) ListBatches(ctx context.Context, query links.BatchQuery) (links.ListBatchesResponse, error) {
+	req := request{method: http.MethodGet, path: "/links", query: batchQueryParams(query)}
+
+	var response links.ListBatchesResponse
+	if err := c.call(ctx, req, &response); err != nil {
+		return links.ListBatchesResponse{}, err
+	}
+	return response, nil
+}
+
+// WaitForBatch - follows the events of a batch until it finishes and returns the final batch,
+// finished batches are returned right away. Lost connections are resumed according to the retry policy.
+func (c *Client) WaitForBatch(ctx context.Context, batchID string) (links.Batch, error) {
+	req := request{method: http.MethodGet, path: "/links/" + url.PathEscape(batchID) + "/events", accept: "text/event-stream"}
+
+	backoff := c.retry.Backoff
+	for failures := 1; ; failures++ {
+		resp, err := c.do(ctx, req)
+		if err != nil {
+			return links.Batch{}, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return links.Batch{}, decodeResponse(resp, nil)
+		}
+
+		batch, received, err := readCompletedEvent(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return links.Batch{}, err
+		}
+		if batch != nil {
+			return *batch, nil
+		}
+		if ctx.Err() != nil {
+			return links.Batch{}, ctx.Err()
+		}
+
+		// a stream which delivered events was healthy, only consecutive empty streams use up the attempts
+		if received > 0 {
+			failures, backoff = 0, c.retry.Backoff
+		}
+		if failures >= c.retry.MaxAttempts {
+			return links.Batch{}, ErrEventStreamEnded
+		}
+		if err := sleep(ctx, backoff); err != nil {
+			return links.Batch{}, err
+		}
+		backoff = c.retry.next(backoff)
+	}
+}
+
+// call - sends the request and decodes the data of the response into data
+func (c *Client) call(ctx context.Context, req request, data interface{}) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, data)
+}
+
+// decodeResponse - error responses are returned as an *APIError, the data of other responses is decoded into data
+func decodeResponse(resp *http.Response, data interface{}) error {
+	defer resp.Body.Close()
+
+	body := links.Response{Data: data}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Errors: body.Errors}
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("%w with status %d %v", ErrUnexpectedResponse, resp.StatusCode, decodeErr)
+	}
+	return nil
+}
+
+// request - api request, the path is relative to /api/v1
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+	accept      string
+}
+
+// do - sends the request, retrying it according to the retry policy.
+// The body of the returned response has to be closed by the caller.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	backoff := c.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, req)
+		if attempt >= c.retry.MaxAttempts || ctx.Err() != nil || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := backoff
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if c.retry.MaxRetryAfter > 0 && after > c.retry.MaxRetryAfter {
+					return resp, nil
+				}
+				wait = after
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		backoff = c.retry.next(backoff)
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	target := c.baseURL + apiPrefix + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	accept := req.accept
+	if accept == "" {
+		accept = "application/json"
+	}
+	httpReq.Header.Set("Accept", accept)
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return c.httpClient.Do(httpReq)
+}
+
+// newSubmitRequest - multipart form with the url file as `urlsFile`, the scrape options as JSON and the callback
+func newSubmitRequest(source URLSource, opts BatchOptions, async bool) (request, error) {
+	if source == nil {
+		return request{}, fmt.Errorf("%w: no url source", ErrInvalidSource)
+	}
+	name, content, err := source()
+	if err != nil {
+		return request{}, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("urlsFile", name)
+	if err != nil {
+		return request{}, err
+	}
+	if _, err := file.Write(content); err != nil {
+		return request{}, err
+	}
+
+	if !isZero(opts.Scrape) {
+		rawOpts, err := json.Marshal(opts.Scrape)
+		if err != nil {
+			return request{}, err
+		}
+		if err := form.WriteField("options", string(rawOpts)); err != nil {
+			return request{}, err
+		}
+	}
+	if opts.Callback != nil {
+		if err := form.WriteField("callback_url", opts.Callback.URL); err != nil {
+			return request{}, err
+		}
+		if err := form.WriteField("callback_secret", opts.Callback.Secret); err != nil {
+			return request{}, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return request{}, err
+	}
+
+	req := request{method: http.MethodPost, path: "/links", body: body.Bytes(), contentType: form.FormDataContentType()}
+	if async {
+		req.query = url.Values{"async": {"true"}}
+	}
+	return req, nil
+}
+
+// resultQueryParams - query parameters of the batch results endpoint
+func resultQueryParams(query links.ResultQuery) url.Values {
+	params := url.Values{}
+	setLimitAndSort(params, query.Limit, query.Cursor, query.SortBy, query.Descending)
+	if query.Success != nil {
+		params.Set("success", strconv.FormatBool(*query.Success))
+	}
+	if query.Host != "" {
+		params.Set("host", query.Host)
+	}
+
+	bounds := []struct {
+		name  string
+		value *uint
+	}{
+		{"min_internal", query.MinInternalLinks},
+		{"max_internal", query.MaxInternalLinks},
+		{"min_external", query.MinExternalLinks},
+		{"max_external", query.MaxExternalLinks},
+	}
+	for _, bound := range bounds {
+		if bound.value != nil {
+			params.Set(bound.name, strconv.FormatUint(uint64(*bound.value), 10))
+		}
+	}
+	return params
+}
+
+// batchQueryParams - query parameters of the batch listing endpoint
+func batchQueryParams(query links.BatchQuery) url.Values {
+	params := url.Values{}
+	setLimitAndSort(params, query.Limit, query.Cursor, query.SortBy, query.Descending)
+	if query.Status != "" {
+		params.Set("status", string(query.Status))
+	}
+	if query.MonitorID != "" {
+		params.Set("monitor_id", query.MonitorID)
+	}
+	if query.APIKeyID != "" {
+		params.Set("api_key_id", query.APIKeyID)
+	}
+	if !query.CreatedAfter.IsZero() {
+		params.Set("created_after", query.CreatedAfter.Format(time.RFC3339))
+	}
+	if !query.CreatedBefore.IsZero() {
+		params.Set("created_before", query.CreatedBefore.Format(time.RFC3339))
+	}
+	return params
+}
+
+func setLimitAndSort(params url.Values, limit int, cursor, sortBy string, descending bool) {
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	if sortBy != "" {
+		if descending {
+			sortBy = "-" + sortBy
+		}
+		params.Set("sort", sortBy)
+	}
+}
+
+func isZero(opts links.ScrapeOptions) bool {
+	raw, _ := json.Marshal(opts)
+	return string(raw) == "{}"
+}
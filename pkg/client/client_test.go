@@ -0,0 +1,367 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/client"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/mocks"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+var fastRetries = client.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+type clientTestSuite struct {
+	suite.Suite
+	mockLinkProcessor *mocks.MockLinksProcessor
+	server            *httptest.Server
+	client            *client.Client
+}
+
+// SetupTest - the client talks to the real router of the service backed by a mocked processor
+func (s *clientTestSuite) SetupTest() {
+	s.mockLinkProcessor = new(mocks.MockLinksProcessor)
+	s.server = httptest.NewServer(handler.NewRouter(handler.NewHandler(s.mockLinkProcessor, new(mocks.MockMonitorService))))
+	s.client = s.newClient(s.server.URL)
+}
+
+func (s *clientTestSuite) AfterTest(suite string, testName string) {
+	s.server.Close()
+	s.mockLinkProcessor.AssertExpectations(s.T())
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, &clientTestSuite{})
+}
+
+func (s *clientTestSuite) newClient(baseURL string) *client.Client {
+	c, err := client.New(baseURL, client.WithRetryPolicy(fastRetries))
+	s.Require().NoError(err)
+	return c
+}
+
+func (s *clientTestSuite) TestNew_WhenBaseURLIsInvalid_ThenFail() {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://localhost", "http://"} {
+		// Act
+		_, err := client.New(baseURL)
+
+		// Assert
+		s.ErrorIs(err, client.ErrInvalidBaseURL, baseURL)
+	}
+}
+
+func (s *clientTestSuite) TestSubmit_WhenURLsAreSubmitted_ThenResultsAreReturned() {
+	// Arrange
+	failed := links.Result{ID: "r1", BatchID: "b0", PageURL: "https://example.com/missing", Error: errors.New("bad status code")}
+	succeeded := links.Result{ID: "r0", BatchID: "b0", PageURL: "https://example.com", Success: true, InternalLinksNum: 3}
+	s.mockLinkProcessor.On("ProcessBatch", mock.MatchedBy(func(req links.ProcessBatchRequest) bool {
+		return len(req.URLs) == 2 && req.URLs[1].String() == "https://example.com/missing" &&
+			req.Options.UserAgent == "links-bot" && req.Callback != nil && req.Callback.Secret == "s3cret"
+	})).Return([]links.Result{succeeded, failed}, nil).Once()
+	opts := client.BatchOptions{
+		Scrape:   links.ScrapeOptions{UserAgent: "links-bot"},
+		Callback: &links.Callback{URL: "https://hooks.example.com", Secret: "s3cret"},
+	}
+
+	// Act
+	results, err := s.client.Submit(context.Background(), client.FromURLs([]string{"https://example.com", "https://example.com/missing"}), opts)
+
+	// Assert
+	s.NoError(err)
+	s.Equal(2, len(results))
+	s.Equal(succeeded.ID, results[0].ID)
+	s.Equal(uint(3), results[0].InternalLinksNum)
+	s.NoError(results[0].Error)
+	s.EqualError(results[1].Error, "bad status code")
+}
+
+func (s *clientTestSuite) TestSubmit_WhenSourceIsAFileOrReader_ThenItsURLsAreSubmitted() {
+	// Arrange
+	s.mockLinkProcessor.On("ProcessBatch", mock.MatchedBy(func(req links.ProcessBatchRequest) bool {
+		return len(req.URLs) == 2
+	})).Return([]links.Result{{ID: "r0"}, {ID: "r1"}}, nil).Twice()
+
+	for _, source := range []client.URLSource{
+		client.FromFile("testdata/urls.txt"),
+		client.FromReader(strings.NewReader("https://example.com\nhttps://example.org\n")),
+	} {
+		// Act
+		results, err := s.client.Submit(context.Background(), source, client.BatchOptions{})
+
+		// Assert
+		s.NoError(err)
+		s.Equal(2, len(results))
+	}
+}
+
+func (s *clientTestSuite) TestSubmit_WhenFileIsMissing_ThenFailWithoutARequest() {
+	// Act
+	_, err := s.client.Submit(context.Background(), client.FromFile("testdata/missing.txt"), client.BatchOptions{})
+
+	// Assert
+	s.Error(err)
+}
+
+func (s *clientTestSuite) TestSubmit_WhenServiceRejectsTheBatch_ThenErrorsAreMapped() {
+	// Act
+	_, err := s.client.Submit(context.Background(), client.FromURLs(nil), client.BatchOptions{})
+
+	// Assert
+	var apiErr *client.APIError
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusBadRequest, apiErr.StatusCode)
+	s.ErrorIs(err, handler.ErrNoUrlsForProcessing)
+}
+
+func (s *clientTestSuite) TestStart_ThenRunningBatchIsReturned() {
+	// Arrange
+	s.mockLinkProcessor.On("StartBatch", mock.Anything).Return(links.Batch{ID: "b0", Status: links.BatchStatusRunning, URLCount: 1}, nil).Once()
+
+	// Act
+	batch, err := s.client.Start(context.Background(), client.FromURLs([]string{"https://example.com"}), client.BatchOptions{})
+
+	// Assert
+	s.NoError(err)
+	s.Equal("b0", batch.ID)
+	s.Equal(links.BatchStatusRunning, batch.Status)
+}
+
+func (s *clientTestSuite) TestGetBatch_WhenQueryIsSet_ThenItIsSentAsQueryParameters() {
+	// Arrange
+	success, minExternal := false, uint(2)
+	query := links.ResultQuery{Limit: 10, Cursor: "c0", SortBy: links.ResultSortPageURL, Descending: true, Success: &success, Host: "example.com", MinExternalLinks: &minExternal}
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{BatchID: "b0", Query: query}).
+		Return(links.GetBatchResponse{Results: []links.Result{{ID: "r0", Error: errors.New("timeout")}}, NextCursor: "c1"}, nil).Once()
+
+	// Act
+	response, err := s.client.GetBatch(context.Background(), "b0", query)
+
+	// Assert
+	s.NoError(err)
+	s.Equal("c1", response.NextCursor)
+	s.Equal(1, len(response.Results))
+	s.EqualError(response.Results[0].Error, "timeout")
+}
+
+func (s *clientTestSuite) TestGetBatch_WhenBatchIsMissing_ThenErrorMatchesTheServiceError() {
+	// Arrange
+	s.mockLinkProcessor.On("GetBatch", mock.Anything).Return(links.GetBatchResponse{}, repository.ErrBatchNotFound).Once()
+
+	// Act
+	_, err := s.client.GetBatch(context.Background(), "b0", links.ResultQuery{})
+
+	// Assert
+	s.ErrorIs(err, repository.ErrBatchNotFound)
+	s.NotErrorIs(err, repository.ErrResultNotFound)
+	s.EqualError(err, "links api responded with status 404: batch of results not found")
+}
+
+func (s *clientTestSuite) TestListBatches_WhenQueryIsSet_ThenItIsSentAsQueryParameters() {
+	// Arrange
+	after := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	query := links.BatchQuery{Limit: 5, SortBy: links.BatchSortCreatedAt, Descending: true, Status: links.BatchStatusCompleted, CreatedAfter: after}
+	s.mockLinkProcessor.On("ListBatches", links.ListBatchesRequest{Query: query}).
+		Return(links.ListBatchesResponse{Batches: []links.Batch{{ID: "b0"}}, NextCursor: "c1"}, nil).Once()
+
+	// Act
+	response, err := s.client.ListBatches(context.Background(), query)
+
+	// Assert
+	s.NoError(err)
+	s.Equal("c1", response.NextCursor)
+	s.Equal([]string{"b0"}, []string{response.Batches[0].ID})
+}
+
+func (s *clientTestSuite) TestWaitForBatch_WhenBatchCompletes_ThenFinalBatchIsReturned() {
+	// Arrange
+	events := []links.BatchEvent{
+		{Type: links.BatchEventResult, Result: &links.Result{ID: "r0"}},
+		{Type: links.BatchEventProgress, Progress: &links.BatchProgress{BatchID: "b0", URLCount: 1, Processed: 1}},
+		{Type: links.BatchEventCompleted, Batch: &links.Batch{ID: "b0", Status: links.BatchStatusCompleted, SuccessCount: 1}},
+	}
+	s.mockLinkProcessor.On("WatchBatch", "b0").Return(events, nil).Once()
+
+	// Act
+	batch, err := s.client.WaitForBatch(context.Background(), "b0")
+
+	// Assert
+	s.NoError(err)
+	s.Equal(links.BatchStatusCompleted, batch.Status)
+	s.Equal(1, batch.SuccessCount)
+}
+
+func (s *clientTestSuite) TestWaitForBatch_WhenStreamEndsEarly_ThenItIsResumed() {
+	// Arrange
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if atomic.AddInt32(&requests, 1) == 1 {
+			fmt.Fprint(w, "event: progress\ndata: {\"processed\":1}\n\n")
+			return
+		}
+		fmt.Fprint(w, "event: completed\ndata: {\"id\":\"b0\",\"status\":\"completed\"}\n\n")
+	}))
+	defer server.Close()
+
+	// Act
+	batch, err := s.newClient(server.URL).WaitForBatch(context.Background(), "b0")
+
+	// Assert
+	s.NoError(err)
+	s.Equal("b0", batch.ID)
+	s.Equal(int32(2), atomic.LoadInt32(&requests))
+}
+
+func (s *clientTestSuite) TestWaitForBatch_WhenStreamsKeepEndingEmpty_ThenFail() {
+	// Arrange
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer server.Close()
+
+	// Act
+	_, err := s.newClient(server.URL).WaitForBatch(context.Background(), "b0")
+
+	// Assert
+	s.ErrorIs(err, client.ErrEventStreamEnded)
+	s.Equal(int32(fastRetries.MaxAttempts), atomic.LoadInt32(&requests))
+}
+
+func (s *clientTestSuite) TestRetry_DifferentCases_ThenItIsHandledAsExpected() {
+	testCases := []struct {
+		name             string
+		statuses         []int // statuses of the consecutive responses, 200 afterwards
+		submit           bool
+		expectedRequests int32
+		expectedStatus   int // status of the returned error, 0 for success
+	}{
+		{name: "read is retried after 503", statuses: []int{503, 502}, expectedRequests: 3},
+		{name: "read gives up after the max attempts", statuses: []int{503, 503, 504}, expectedRequests: 3, expectedStatus: 504},
+		{name: "read isn't retried after 500", statuses: []int{500}, expectedRequests: 1, expectedStatus: 500},
+		{name: "submission is retried after 429", statuses: []int{429}, submit: true, expectedRequests: 2},
+		{name: "submission isn't retried after 502", statuses: []int{502}, submit: true, expectedRequests: 1, expectedStatus: 502},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request := atomic.AddInt32(&requests, 1)
+				if int(request) <= len(tc.statuses) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tc.statuses[request-1])
+					fmt.Fprint(w, `{"errors":["try again later"]}`)
+					return
+				}
+				fmt.Fprint(w, `{"data":{"Results":[]}}`)
+			}))
+			defer server.Close()
+			c := s.newClient(server.URL)
+
+			// Act
+			var err error
+			if tc.submit {
+				_, err = c.Submit(context.Background(), client.FromURLs([]string{"https://example.com"}), client.BatchOptions{})
+			} else {
+				_, err = c.GetBatch(context.Background(), "b0", links.ResultQuery{})
+			}
+
+			// Assert
+			s.Equal(tc.expectedRequests, atomic.LoadInt32(&requests))
+			if tc.expectedStatus == 0 {
+				s.NoError(err)
+				return
+			}
+			var apiErr *client.APIError
+			s.Require().ErrorAs(err, &apiErr)
+			s.Equal(tc.expectedStatus, apiErr.StatusCode)
+			s.Equal([]string{"try again later"}, apiErr.Errors)
+		})
+	}
+}
+
+func (s *clientTestSuite) TestRetry_WhenContextIsCancelled_ThenWaitingStops() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, err := s.newClient(server.URL).ListBatches(ctx, links.BatchQuery{})
+
+	// Assert
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *clientTestSuite) TestRetry_WhenRetryAfterExceedsMaxRetryAfter_ThenErrorIsReturned() {
+	// Arrange
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"errors":["api key quota exceeded: limit of 10 urls per day reached"]}`)
+	}))
+	defer server.Close()
+	policy := fastRetries
+	policy.MaxRetryAfter = time.Minute
+	c, err := client.New(server.URL, client.WithRetryPolicy(policy))
+	s.Require().NoError(err)
+
+	// Act
+	_, err = c.Submit(context.Background(), client.FromURLs([]string{"https://example.com"}), client.BatchOptions{})
+
+	// Assert
+	s.Equal(int32(1), atomic.LoadInt32(&requests))
+	s.ErrorIs(err, links.ErrQuotaExceeded)
+}
+
+func (s *clientTestSuite) TestWithAPIKey_WhenServiceHasAPIKeys_ThenRequestsAreAuthenticated() {
+	// Arrange
+	apiKeys := new(mocks.MockAPIKeyService)
+	apiKeys.On("Authenticate", "lk_secret").Return(links.APIKey{ID: "k0"}, nil).Once()
+	s.mockLinkProcessor.On("ListBatches", mock.MatchedBy(func(req links.ListBatchesRequest) bool { return req.Query.APIKeyID == "k0" })).
+		Return(links.ListBatchesResponse{}, nil).Once()
+	server := httptest.NewServer(handler.NewRouter(handler.NewHandler(s.mockLinkProcessor, new(mocks.MockMonitorService), handler.WithAPIKeys(apiKeys))))
+	defer server.Close()
+	c, err := client.New(server.URL, client.WithAPIKey("lk_secret"))
+	s.Require().NoError(err)
+
+	// Act
+	_, err = c.ListBatches(context.Background(), links.BatchQuery{})
+
+	// Assert
+	s.NoError(err)
+	apiKeys.AssertExpectations(s.T())
+}
+
+func (s *clientTestSuite) TestSubmit_WhenServiceIsUnreachable_ThenNetworkErrorIsReturned() {
+	// Arrange
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	// Act
+	_, err := s.newClient(server.URL).Submit(context.Background(), client.FromURLs([]string{"https://example.com"}), client.BatchOptions{})
+
+	// Assert
+	var apiErr *client.APIError
+	s.Error(err)
+	s.False(errors.As(err, &apiErr))
+}
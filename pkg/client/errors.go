@@ -0,0 +1,34 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// APIError - error response of the service with the messages of its Errors array
+type APIError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("links api responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("links api responded with status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// Is - matches the errors of the service by their message, so errors.Is(err, repository.ErrBatchNotFound)
+// holds for a batch which doesn't exist. Messages which extend an error of the service,
+// like "invalid query parameter limit, expected ...", match it as well.
+func (e *APIError) Is(target error) bool {
+	if target == nil {
+		return false
+	}
+	for _, message := range e.Errors {
+		if message == target.Error() || strings.HasPrefix(message, target.Error()+" ") || strings.HasPrefix(message, target.Error()+":") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// readCompletedEvent - reads the Server-Sent Events of a batch until the `completed` event and returns
+// its batch. A nil batch means the stream ended first, received is the number of events read until then.
+func readCompletedEvent(stream io.Reader) (batch *links.Batch, received int, err error) {
+	reader := bufio.NewReader(stream)
+	var eventType string
+	var data strings.Builder
+	for {
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "" && readErr == nil:
+			// a blank line dispatches the event
+			if eventType == string(links.BatchEventCompleted) {
+				batch = &links.Batch{}
+				if err := json.Unmarshal([]byte(data.String()), batch); err != nil {
+					return nil, received, fmt.Errorf("%w completed event %v", ErrUnexpectedResponse, err)
+				}
+				return batch, received, nil
+			}
+			if eventType != "" || data.Len() > 0 {
+				received++
+			}
+			eventType = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteString("\n")
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if readErr != nil {
+			return nil, received, nil
+		}
+	}
+}
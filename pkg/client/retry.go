@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy - how failed requests are retried. Reads are retried after network errors and
+// 429, 502, 503 and 504 responses. Batch submissions aren't idempotent so they are only retried
+// when the service couldn't be reached or rejected them with 429 or 503 before processing.
+// The Retry-After header of a response takes precedence over the backoff.
+type RetryPolicy struct {
+	MaxAttempts   int           // including the first attempt, 1 disables retries
+	Backoff       time.Duration // wait before the first retry, doubled for every further retry
+	MaxBackoff    time.Duration
+	MaxRetryAfter time.Duration // responses with a longer Retry-After are returned instead, like an exhausted daily quota
+}
+
+// DefaultRetryPolicy - 3 attempts starting with a 250ms backoff, Retry-After is waited for up to a minute
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 250 * time.Millisecond, MaxBackoff: 5 * time.Second, MaxRetryAfter: time.Minute}
+
+// next - backoff of the retry after the one waiting for backoff
+func (p RetryPolicy) next(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+func retryable(req request, resp *http.Response, err error) bool {
+	idempotent := req.method == http.MethodGet
+	if err != nil {
+		var opErr *net.OpError
+		return idempotent || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// retryAfter - wait requested by the Retry-After header, in seconds or as an http date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidSource = errors.New("invalid url source")
+
+// URLSource - url file of a batch with one url per line, read once per submission.
+// See FromFile, FromReader and FromURLs.
+type URLSource func() (name string, content []byte, err error)
+
+// FromFile - urls of the file at path
+func FromFile(path string) URLSource {
+	return func() (string, []byte, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", nil, err
+		}
+		return filepath.Base(path), content, nil
+	}
+}
+
+// FromReader - urls read from r, a reader can only be submitted once
+func FromReader(r io.Reader) URLSource {
+	return func() (string, []byte, error) {
+		if r == nil {
+			return "", nil, ErrInvalidSource
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return "", nil, err
+		}
+		return "urls.txt", content, nil
+	}
+}
+
+// FromURLs - the urls as they are
+func FromURLs(urls []string) URLSource {
+	return func() (string, []byte, error) {
+		return "urls.txt", []byte(strings.Join(urls, "\n")), nil
+	}
+}
@@ -6,9 +6,32 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/url"
+	"strings"
 )
 
+// ErrInvalidURL - the line parses as a url but has no scheme or host
+var ErrInvalidURL = errors.New("invalid url")
+
+// URLError - bad url on a line of the url file, Line starts at 1
+type URLError struct {
+	Line int
+	URL  string // empty when the line doesn't parse as a url
+	Err  error
+}
+
+func (e *URLError) Error() string {
+	if errors.Is(e.Err, ErrInvalidURL) {
+		return fmt.Sprintf("bad url at line %v %s %v", e.Line, e.URL, e.Err)
+	}
+	return fmt.Sprintf("bad url at line %v: %v", e.Line, e.Err)
+}
+
+func (e *URLError) Unwrap() error {
+	return e.Err
+}
+
 // GatherUrls - expects multi-line text with a valid url on each line
 func GatherUrls(r io.ReadCloser) ([]*url.URL, error) {
 	urls := make([]*url.URL, 0, 64)
@@ -31,11 +54,43 @@ func GatherUrls(r io.ReadCloser) ([]*url.URL, error) {
 func validateUrl(scanner *bufio.Scanner, line int) (*url.URL, error) {
 	parsedURL, err := url.Parse(scanner.Text())
 	if err != nil {
-		return nil, fmt.Errorf("bad url at line %v: %w", line, err)
+		return nil, &URLError{Line: line, Err: err}
 	}
 
 	if parsedURL.Scheme == "" || parsedURL.Host == "" { // url.Parse doesn't always return an error so some extra checks are needed
-		return nil, fmt.Errorf("bad url at line %v %s %w", line, parsedURL, errors.New("invalid url"))
+		return nil, &URLError{Line: line, URL: parsedURL.String(), Err: ErrInvalidURL}
 	}
 	return parsedURL, nil
 }
+
+// NormalizeURL - canonical form of a page url so the same page can be matched across batches.
+// Scheme and host are lower-cased, default ports and fragments are dropped and an empty path becomes "/".
+// Values which aren't valid urls are returned unchanged.
+func NormalizeURL(rawURL string) string {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsedURL.Host == "" {
+		return rawURL
+	}
+
+	parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+	host, port := strings.ToLower(parsedURL.Hostname()), parsedURL.Port()
+	if (parsedURL.Scheme == "http" && port == "80") || (parsedURL.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		parsedURL.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"): // ipv6 literal
+		parsedURL.Host = "[" + host + "]"
+	default:
+		parsedURL.Host = host
+	}
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+	parsedURL.Fragment = ""
+	parsedURL.RawFragment = ""
+	parsedURL.ForceQuery = false
+
+	return parsedURL.String()
+}
@@ -60,9 +60,24 @@ https://www.facebook.com`)),
 
 }
 
+func TestGatherUrls_BadUrlOnSecondLine_ThenURLErrorHasTheLine(t *testing.T) {
+	// Arrange
+	testData := io.NopCloser(strings.NewReader("https://www.google.com\nnot an url"))
+
+	// Act
+	_, err := helpers.GatherUrls(testData)
+
+	// Assert
+	var urlErr *helpers.URLError
+	assert.ErrorAs(t, err, &urlErr)
+	assert.Equal(t, 2, urlErr.Line)
+	assert.ErrorIs(t, err, helpers.ErrInvalidURL)
+	assert.EqualError(t, err, "bad url at line 2 not%20an%20url invalid url")
+}
+
 func TestGatherUrlsFromFile(t *testing.T) {
 	// Arrange
-	file, err := os.Open(`testdata\urls.txt`)
+	file, err := os.Open(`testdata/urls.txt`)
 	assert.NoError(t, err)
 
 	// Act
@@ -73,3 +88,23 @@ func TestGatherUrlsFromFile(t *testing.T) {
 	assert.Equal(t, "www.google.com", gatheredUrls[0].Host)
 	assert.Equal(t, "www.facebook.com", gatheredUrls[1].Host)
 }
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		expected string
+	}{
+		{name: "empty path", rawURL: "https://www.google.com", expected: "https://www.google.com/"},
+		{name: "case and default port", rawURL: "HTTPS://WWW.Google.com:443/Search?q=Go", expected: "https://www.google.com/Search?q=Go"},
+		{name: "non default port is kept", rawURL: "http://localhost:8080", expected: "http://localhost:8080/"},
+		{name: "fragment is dropped", rawURL: "https://www.facebook.com/about#team", expected: "https://www.facebook.com/about"},
+		{name: "ipv6 host", rawURL: "http://[::1]:80/a", expected: "http://[::1]/a"},
+		{name: "not an url", rawURL: "not an url", expected: "not an url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, helpers.NormalizeURL(tt.rawURL))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFetchObserver struct {
+	mock.Mock
+}
+
+func (m *MockFetchObserver) FetchStarted(pageURL *url.URL) {
+	m.Called(pageURL)
+}
+
+func (m *MockFetchObserver) FetchFinished(pageURL *url.URL, duration time.Duration, err error) {
+	m.Called(pageURL, duration, err)
+}
@@ -12,7 +12,31 @@ type MockScraper struct {
 	mock.Mock
 }
 
-func (m *MockScraper) Scrape(ctx context.Context, urls []*url.URL) []scraper.Result {
-	args := m.Called(urls)
+func (m *MockScraper) Scrape(ctx context.Context, urls []*url.URL, opts scraper.Options) []scraper.Result {
+	args := m.Called(urls, opts)
 	return args.Get(0).([]scraper.Result)
 }
+
+// ScrapeStream - streams the mocked results, the optional second return value is the error the stream ends with
+func (m *MockScraper) ScrapeStream(ctx context.Context, urls []*url.URL, opts scraper.Options) *scraper.Stream {
+	args := m.Called(urls, opts)
+	results := args.Get(0).([]scraper.Result)
+	var err error
+	if len(args) > 1 {
+		err = args.Error(1)
+	}
+
+	stream, resultsChan := scraper.NewStream()
+	go func() {
+		for _, result := range results {
+			select {
+			case resultsChan <- result:
+			case <-ctx.Done():
+				stream.Close(ctx.Err())
+				return
+			}
+		}
+		stream.Close(err)
+	}()
+	return stream
+}
@@ -7,4 +7,6 @@ type Result struct {
 	ExternalLinksNum uint
 	Success          bool
 	Error            error
+	InternalLinks    []string // absolute urls, only set with Options.CollectLinks
+	ExternalLinks    []string // absolute urls, only set with Options.CollectLinks
 }
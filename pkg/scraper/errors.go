@@ -0,0 +1,49 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+)
+
+// StatusError - the page was fetched but its status code isn't a success
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return "bad status code"
+}
+
+// ErrorCategory - coarse reason a page couldn't be scraped
+type ErrorCategory string
+
+const (
+	ErrorCategoryTimeout      ErrorCategory = "timeout"        // the page didn't respond within the timeout
+	ErrorCategoryStatus       ErrorCategory = "status"         // the page responded with a status other than 2xx
+	ErrorCategoryBodyTooLarge ErrorCategory = "body_too_large" // the page is bigger than the max body size
+	ErrorCategoryNetwork      ErrorCategory = "network"        // dns, connection, tls and redirect errors
+	ErrorCategoryOther        ErrorCategory = "other"
+)
+
+// ClassifyError - category of a scrape error and the status code of the page when it responded,
+// an empty category for nil
+func ClassifyError(err error) (ErrorCategory, int) {
+	var statusErr *StatusError
+	var netErr net.Error
+	var urlErr *url.Error
+	switch {
+	case err == nil:
+		return "", 0
+	case errors.As(err, &statusErr):
+		return ErrorCategoryStatus, statusErr.StatusCode
+	case errors.Is(err, ErrBodyTooLarge):
+		return ErrorCategoryBodyTooLarge, 0
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorCategoryTimeout, 0
+	case errors.As(err, &urlErr), errors.As(err, &netErr):
+		return ErrorCategoryNetwork, 0
+	}
+	return ErrorCategoryOther, 0
+}
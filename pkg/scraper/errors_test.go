@@ -0,0 +1,37 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantCategory ErrorCategory
+		wantStatus   int
+	}{
+		{name: "no error", err: nil, wantCategory: ""},
+		{name: "status", err: &StatusError{StatusCode: 404}, wantCategory: ErrorCategoryStatus, wantStatus: 404},
+		{name: "body too large", err: ErrBodyTooLarge, wantCategory: ErrorCategoryBodyTooLarge},
+		{name: "deadline", err: &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}, wantCategory: ErrorCategoryTimeout},
+		{name: "dns", err: &url.Error{Op: "Get", URL: "https://example.invalid", Err: &net.DNSError{Err: "no such host"}}, wantCategory: ErrorCategoryNetwork},
+		{name: "redirect", err: &url.Error{Op: "Get", URL: "https://example.com", Err: fmt.Errorf("stopped after %d redirects", 10)}, wantCategory: ErrorCategoryNetwork},
+		{name: "other", err: errors.New("unexpected"), wantCategory: ErrorCategoryOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category, status := ClassifyError(tt.err)
+
+			assert.Equal(t, tt.wantCategory, category)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
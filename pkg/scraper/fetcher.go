@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Fetcher - retrieves the page that is going to be scraped.
+// Implementations decide where the page comes from (live http, local files, archives, recordings).
+type Fetcher interface {
+	Fetch(ctx context.Context, pageURL *url.URL, opts Options) (*Page, error)
+}
+
+// Page - fetched page, the caller is responsible for closing the Body
+type Page struct {
+	URL        *url.URL // final url of the page, differs from the requested one after redirects
+	StatusCode int
+	Header     http.Header
+	Body       io.ReadCloser
+}
+
+// Option - functional option for NewScraper
+type Option func(s *Scraper)
+
+// WithFetcher - scrape pages using the given fetcher
+func WithFetcher(fetcher Fetcher) Option {
+	return func(s *Scraper) {
+		s.fetcher = fetcher
+	}
+}
+
+// WithHTTPClient - scrape live pages using the given http client
+func WithHTTPClient(client *http.Client) Option {
+	return WithFetcher(NewHTTPFetcher(client))
+}
+
+// WithFileRoot - scrape pages from the root directory of the local filesystem, see FileFetcher.
+// When root isn't a directory every page fails with the error of NewFileFetcher.
+func WithFileRoot(root string) Option {
+	fetcher, err := NewFileFetcher(root)
+	if err != nil {
+		return WithFetcher(failingFetcher{err: err})
+	}
+	return WithFetcher(fetcher)
+}
+
+// failingFetcher - fails every page with the error it couldn't be created with
+type failingFetcher struct {
+	err error
+}
+
+func (f failingFetcher) Fetch(ctx context.Context, pageURL *url.URL, opts Options) (*Page, error) {
+	return nil, f.err
+}
+
+// WithRecording - record every http response of the scrape to dir, see NewRecordingFetcher.
+// The client of an http fetcher selected by the options before it is kept.
+func WithRecording(dir string) Option {
+	return func(s *Scraper) {
+		var client *http.Client
+		if fetcher, ok := s.fetcher.(*HTTPFetcher); ok {
+			client = fetcher.httpClient
+		}
+		s.fetcher = NewRecordingFetcher(client, dir)
+	}
+}
+
+// WithReplay - serve pages from recordings in dir instead of fetching them, see NewReplayFetcher
+func WithReplay(dir string) Option {
+	return WithFetcher(NewReplayFetcher(dir))
+}
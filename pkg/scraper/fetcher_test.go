@@ -0,0 +1,146 @@
+package scraper_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFetcher(t *testing.T) {
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.html"), []byte("<html></html>"), 0o644))
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "example.com"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "example.com", "page.html"), []byte("<html></html>"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.html"), filepath.Join(root, "example.com", "secret.html")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "example.com", "outside")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "example.com", "page.html"), filepath.Join(root, "example.com", "alias.html")))
+
+	tests := []struct {
+		name         string
+		root         string
+		url          string
+		wantedStatus int
+		wantedErr    error
+	}{
+		{name: "http url is mapped to root/host/path", root: "testdata/site", url: "https://example.com/", wantedStatus: http.StatusOK},
+		{name: "directory is served from its index", root: "testdata/site", url: "https://example.com/docs", wantedStatus: http.StatusOK},
+		{name: "file url is read relative to root", root: "testdata/site", url: "file:///example.com/docs/index.html", wantedStatus: http.StatusOK},
+		{name: "path can't escape root", root: "testdata/site/example.com", url: "https://example.com/../../links_success.html", wantedStatus: http.StatusNotFound},
+		{name: "file url can't escape root", root: "testdata/site/example.com", url: "file:///../../links_success.html", wantedStatus: http.StatusNotFound},
+		{name: "missing file", root: "testdata/site", url: "https://example.com/missing.html", wantedStatus: http.StatusNotFound},
+		{name: "symlink inside root", root: root, url: "https://example.com/alias.html", wantedStatus: http.StatusOK},
+		{name: "symlink to a file outside root", root: root, url: "https://example.com/secret.html", wantedErr: scraper.ErrOutsideRoot},
+		{name: "symlink to a directory outside root", root: root, url: "file:///example.com/outside/secret.html", wantedErr: scraper.ErrOutsideRoot},
+		{name: "unsupported scheme", root: "testdata/site", url: "ftp://example.com/", wantedErr: errors.New(`unsupported scheme "ftp"`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageURL, _ := url.Parse(tt.url)
+			fetcher, err := scraper.NewFileFetcher(tt.root)
+			require.NoError(t, err)
+
+			page, err := fetcher.Fetch(context.Background(), pageURL, scraper.DefaultOptions())
+
+			if tt.wantedErr != nil {
+				assert.ErrorContains(t, err, tt.wantedErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			defer page.Body.Close()
+			assert.Equal(t, tt.wantedStatus, page.StatusCode)
+		})
+	}
+}
+
+func TestNewFileFetcher_WhenRootIsNotADirectory_ThenFail(t *testing.T) {
+	for _, root := range []string{"", "testdata/missing", "testdata/pages.warc"} {
+		_, err := scraper.NewFileFetcher(root)
+
+		assert.Error(t, err, root)
+	}
+}
+
+func TestWARCFetcher(t *testing.T) {
+	raw, err := os.ReadFile("testdata/pages.warc")
+	require.NoError(t, err)
+
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	_, err = gz.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	archives := map[string][]byte{"plain": raw, "gzip": compressed.Bytes()}
+	for name, archive := range archives {
+		t.Run(name, func(t *testing.T) {
+			fetcher, err := scraper.NewWARCFetcher(bytes.NewReader(archive))
+			require.NoError(t, err)
+
+			tests := []struct {
+				name         string
+				url          string
+				opts         scraper.Options
+				wantedStatus int
+				wantedURL    string
+				wantedBody   bool
+			}{
+				{name: "archived page", url: "https://archive.example.com/home", wantedStatus: http.StatusOK, wantedURL: "https://archive.example.com/home", wantedBody: true},
+				{name: "archived redirect is followed", url: "https://archive.example.com/", wantedStatus: http.StatusOK, wantedURL: "https://archive.example.com/home", wantedBody: true},
+				{name: "archived redirect is not followed", url: "https://archive.example.com/", opts: scraper.Options{RedirectPolicy: scraper.RedirectNone}, wantedStatus: http.StatusMovedPermanently, wantedURL: "https://archive.example.com/"},
+				{name: "archived error", url: "https://archive.example.com/gone", wantedStatus: http.StatusNotFound, wantedURL: "https://archive.example.com/gone"},
+				{name: "page missing from the archive", url: "https://archive.example.com/missing", wantedStatus: http.StatusNotFound, wantedURL: "https://archive.example.com/missing"},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					pageURL, _ := url.Parse(tt.url)
+
+					page, err := fetcher.Fetch(context.Background(), pageURL, tt.opts.WithDefaults())
+
+					require.NoError(t, err)
+					defer page.Body.Close()
+					body, _ := io.ReadAll(page.Body)
+					assert.Equal(t, tt.wantedStatus, page.StatusCode)
+					assert.Equal(t, tt.wantedURL, page.URL.String())
+					assert.Equal(t, tt.wantedBody, bytes.Contains(body, []byte(`<a href="/a">`)))
+				})
+			}
+		})
+	}
+}
+
+func TestWARCFetcher_WhenArchiveIsMalformed_ThenFail(t *testing.T) {
+	_, err := scraper.NewWARCFetcher(bytes.NewReader([]byte("not a warc file\r\n")))
+
+	assert.Error(t, err)
+}
+
+func TestReplayFetcher_WhenNotRecorded_ThenFail(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/")
+
+	_, err := scraper.NewReplayFetcher(t.TempDir()).Fetch(context.Background(), pageURL, scraper.DefaultOptions())
+
+	assert.ErrorIs(t, err, scraper.ErrNoRecording)
+}
+
+func TestNewScraper_WithFileRoot(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/")
+
+	results := scraper.NewScraper(scraper.WithFileRoot("testdata/site")).Scrape(context.Background(), []*url.URL{pageURL}, scraper.Options{})
+
+	require.Equal(t, 1, len(results))
+	assert.True(t, results[0].Success)
+	assert.Equal(t, uint(2), results[0].InternalLinksNum)
+	assert.Equal(t, uint(1), results[0].ExternalLinksNum)
+}
@@ -0,0 +1,102 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideRoot - the page resolves to a file outside the root directory of the FileFetcher, e.g. through a symlink
+var ErrOutsideRoot = errors.New("path is outside the root directory")
+
+// FileFetcher - reads pages from a root directory of the local filesystem.
+// file:// urls are read from their path relative to the root,
+// http(s) urls are mapped to root/<host>/<path> like a mirrored website.
+// Directories and paths ending with a slash are served from their index.html.
+// Pages resolving to a file outside the root, e.g. through a symlink, fail with ErrOutsideRoot.
+type FileFetcher struct {
+	root string // absolute, without symlinks
+}
+
+// NewFileFetcher - root must be an existing directory
+func NewFileFetcher(root string) (*FileFetcher, error) {
+	if root == "" {
+		return nil, errors.New("root directory is required")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory %w", err)
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("root %s is not a directory", root)
+	}
+	return &FileFetcher{root: resolved}, nil
+}
+
+// Fetch - missing files are reported with a 404 status code like a web server would
+func (f *FileFetcher) Fetch(ctx context.Context, pageURL *url.URL, opts Options) (*Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	name, err := f.filePath(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		name = filepath.Join(name, "index.html")
+	}
+
+	resolved, err := filepath.EvalSymlinks(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Page{URL: pageURL, StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(f.root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%w: %s", ErrOutsideRoot, pageURL)
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	return &Page{URL: pageURL, StatusCode: http.StatusOK, Header: header, Body: file}, nil
+}
+
+func (f *FileFetcher) filePath(pageURL *url.URL) (string, error) {
+	urlPath := path.Clean("/" + pageURL.Path) // cleaning a rooted path removes any ../ so we can't leave the root
+	if strings.HasSuffix(pageURL.Path, "/") {
+		urlPath = path.Join(urlPath, "index.html")
+	}
+
+	switch pageURL.Scheme {
+	case "file":
+		return filepath.Join(f.root, filepath.FromSlash(urlPath)), nil
+	case "http", "https":
+		return filepath.Join(f.root, pageURL.Hostname(), filepath.FromSlash(urlPath)), nil
+	default:
+		return "", fmt.Errorf("unsupported scheme %q", pageURL.Scheme)
+	}
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fixture - recorded response stored as an indented json file so recordings are easy to review and edit
+type fixture struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// fixturePath - readable and stable file name for the request, e.g. dir/GET_www.google.com_3f2a9c1b0d4e5f60.json
+func fixturePath(dir, method, rawURL string) string {
+	sum := sha256.Sum256([]byte(method + " " + rawURL))
+	host := rawURL
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	name := fmt.Sprintf("%s_%s_%s.json", method, unsafeFileChars.ReplaceAllString(host, "_"), hex.EncodeToString(sum[:8]))
+	return filepath.Join(dir, name)
+}
+
+func readFixture(dir, method, rawURL string) (*fixture, error) {
+	content, err := os.ReadFile(fixturePath(dir, method, rawURL))
+	if err != nil {
+		return nil, err
+	}
+
+	fx := &fixture{}
+	if err := json.Unmarshal(content, fx); err != nil {
+		return nil, fmt.Errorf("malformed fixture for %s %w", rawURL, err)
+	}
+	return fx, nil
+}
+
+func writeFixture(dir string, fx *fixture) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fixturePath(dir, fx.Method, fx.URL), append(content, '\n'), 0o644)
+}
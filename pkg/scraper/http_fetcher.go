@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// HTTPFetcher - fetches live pages over http(s)
+type HTTPFetcher struct {
+	httpClient *http.Client
+}
+
+// NewHTTPFetcher - when client is nil a cleanhttp default client is used
+func NewHTTPFetcher(client *http.Client) *HTTPFetcher {
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+	}
+	return &HTTPFetcher{httpClient: client}
+}
+
+// Fetch - GET the page with the user agent, headers and redirect policy from opts
+func (f *HTTPFetcher) Fetch(ctx context.Context, pageURL *url.URL, opts Options) (*Page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("User-Agent", opts.UserAgent)
+
+	resp, err := f.clientFor(opts).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{URL: resp.Request.URL, StatusCode: resp.StatusCode, Header: resp.Header, Body: resp.Body}, nil
+}
+
+// clientFor - shallow copy of the shared client (the transport is reused) with the redirect policy applied
+func (f *HTTPFetcher) clientFor(opts Options) *http.Client {
+	client := *f.httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return checkRedirect(req.URL, via[0].URL, len(via), opts)
+	}
+	return &client
+}
+
+// checkRedirect - applies the redirect policy to a redirect to next, hops is the number of requests made so far
+func checkRedirect(next, original *url.URL, hops int, opts Options) error {
+	switch {
+	case opts.RedirectPolicy == RedirectNone:
+		return http.ErrUseLastResponse
+	case hops > opts.MaxRedirects:
+		return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+	case opts.RedirectPolicy == RedirectSameHost && next.Hostname() != original.Hostname():
+		return fmt.Errorf("redirect to another host %s is not allowed", next.Hostname())
+	}
+	return nil
+}
@@ -3,16 +3,57 @@ package scraper
 import (
 	"log"
 	"net/url"
+	"strings"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
 )
 
 // CountLinks extracts external & internal links count from a html document
+// using the default link rules
 func CountLinks(page *url.URL, document *html.Node) (external, internal uint, err error) {
+	return CountLinksWithOptions(page, document, DefaultOptions())
+}
+
+// CountLinksWithOptions extracts external & internal links count from a html document.
+// Only the elements from opts.LinkCategories are inspected and opts.InternalPolicy
+// together with opts.InternalDomains decide whether a link is internal.
+func CountLinksWithOptions(page *url.URL, document *html.Node, opts Options) (external, internal uint, err error) {
+	visitLinks(page, document, opts, func(link *url.URL, isInternal bool) {
+		if isInternal {
+			internal++
+			return
+		}
+		external++
+	})
+	return
+}
+
+// ExtractLinks extracts the external & internal links of a html document following the same rules
+// as CountLinksWithOptions. Links are resolved against the page url so they are always absolute.
+func ExtractLinks(page *url.URL, document *html.Node, opts Options) (external, internal []string) {
+	visitLinks(page, document, opts, func(link *url.URL, isInternal bool) {
+		resolved := page.ResolveReference(link).String()
+		if isInternal {
+			internal = append(internal, resolved)
+			return
+		}
+		external = append(external, resolved)
+	})
+	return
+}
+
+// visitLinks - calls visit for every link of the document from the requested link categories
+func visitLinks(page *url.URL, document *html.Node, opts Options, visit func(link *url.URL, internal bool)) {
+	categories := map[string]bool{}
+	for _, category := range opts.WithDefaults().LinkCategories {
+		categories[categoryElement(category)] = true
+	}
+
 	var f func(*html.Node)
 
 	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" { // only get links from a tags
+		if n.Type == html.ElementNode && categories[n.Data] { // only get links from the requested tags
 			for _, attr := range n.Attr {
 				if attr.Key == "href" && attr.Val != "" {
 					hrefURL, err := url.Parse(attr.Val)
@@ -20,12 +61,7 @@ func CountLinks(page *url.URL, document *html.Node) (external, internal uint, er
 						log.Println("malformed href value: ", attr.Val, err)
 						break // nested links are forbidden => assuming there is only one href per node, we can break from the loop
 					}
-					if (hrefURL.Hostname() == page.Hostname()) ||
-						(hrefURL.Hostname() == "" && hrefURL.Path != "") { // if host isn't set but path is set, then the link is most likely internal
-						internal++
-						continue
-					}
-					external++
+					visit(hrefURL, isInternal(page, hrefURL, opts))
 				}
 			}
 		}
@@ -35,5 +71,45 @@ func CountLinks(page *url.URL, document *html.Node) (external, internal uint, er
 	}
 
 	f(document)
-	return
+}
+
+func categoryElement(category LinkCategory) string {
+	if category == LinkAnchor {
+		return "a"
+	}
+	return string(category)
+}
+
+func isInternal(page, link *url.URL, opts Options) bool {
+	if link.Hostname() == "" && link.Path != "" { // if host isn't set but path is set, then the link is most likely internal
+		return true
+	}
+
+	linkHost := strings.ToLower(link.Hostname())
+	if linkHost == "" {
+		return false
+	}
+	for _, domain := range opts.InternalDomains {
+		if linkHost == strings.ToLower(domain) {
+			return true
+		}
+	}
+
+	pageHost := strings.ToLower(page.Hostname())
+	if linkHost == pageHost {
+		return true
+	}
+	if opts.InternalPolicy == InternalSameDomain {
+		return registrableDomain(linkHost) == registrableDomain(pageHost)
+	}
+	return false
+}
+
+// registrableDomain - eTLD+1 of the host, falls back to the host itself (e.g. for localhost or IPs)
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
 }
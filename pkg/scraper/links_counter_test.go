@@ -79,3 +79,79 @@ func TestCountLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestCountLinksWithOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           Options
+		wantedExternal uint
+		wantedInternal uint
+	}{
+		{
+			name:           "default options only count anchors on the same host",
+			opts:           Options{},
+			wantedExternal: 3,
+			wantedInternal: 1,
+		},
+		{
+			name:           "same domain policy counts subdomains as internal",
+			opts:           Options{InternalPolicy: InternalSameDomain},
+			wantedExternal: 2,
+			wantedInternal: 2,
+		},
+		{
+			name:           "internal domains are always internal",
+			opts:           Options{InternalDomains: []string{"CDN.partner.org"}},
+			wantedExternal: 2,
+			wantedInternal: 2,
+		},
+		{
+			name:           "all link categories",
+			opts:           Options{InternalPolicy: InternalSameDomain, LinkCategories: []LinkCategory{LinkAnchor, LinkArea, LinkLink}},
+			wantedExternal: 3,
+			wantedInternal: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := url.Parse("https://example.com/")
+			assert.NoError(t, err)
+			f, err := os.Open("testdata/links_options.html")
+			assert.NoError(t, err)
+			defer f.Close()
+			document, err := html.Parse(f)
+			assert.NoError(t, err)
+
+			actualExternal, actualInternal, err := CountLinksWithOptions(page, document, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantedExternal, actualExternal)
+			assert.Equal(t, tt.wantedInternal, actualInternal)
+		})
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	page, err := url.Parse("https://example.com/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open("testdata/links_options.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	document, err := html.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	external, internal := ExtractLinks(page, document, Options{LinkCategories: []LinkCategory{LinkAnchor, LinkArea}})
+
+	assert.Equal(t, []string{"https://example.com/about", "https://example.com/contact"}, internal)
+	assert.Equal(t, []string{"https://blog.example.com/", "https://cdn.partner.org/page", "https://other.org/", "https://maps.other.org/"}, external)
+
+	countedExternal, countedInternal, err := CountLinksWithOptions(page, document, Options{LinkCategories: []LinkCategory{LinkAnchor, LinkArea}})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(len(external)), countedExternal)
+	assert.Equal(t, uint(len(internal)), countedInternal)
+}
@@ -0,0 +1,20 @@
+package scraper
+
+import (
+	"net/url"
+	"time"
+)
+
+// FetchObserver - notified about every page the scraper fetches, e.g. to export metrics
+type FetchObserver interface {
+	FetchStarted(pageURL *url.URL)
+	// FetchFinished - the page was fetched and parsed, err is the error of its result
+	FetchFinished(pageURL *url.URL, duration time.Duration, err error)
+}
+
+// WithFetchObserver - report every fetch of the scraper to the observer
+func WithFetchObserver(observer FetchObserver) Option {
+	return func(s *Scraper) {
+		s.observer = observer
+	}
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ErrInvalidOptions is returned (wrapped) when scrape options fail validation
+var ErrInvalidOptions = errors.New("invalid scrape options")
+
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultUserAgent    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:100.0) Gecko/20100101 Firefox/100.0"
+	DefaultMaxBodySize  = 10 << 20 // 10 MiB
+	DefaultMaxRedirects = 10
+
+	maxTimeout      = 5 * time.Minute
+	maxBodySize     = 100 << 20 // 100 MiB
+	maxRedirectsCap = 20
+)
+
+// RedirectPolicy - decides which redirects are followed while fetching a page
+type RedirectPolicy string
+
+const (
+	RedirectFollow   RedirectPolicy = "follow"    // follow any redirect up to MaxRedirects
+	RedirectNone     RedirectPolicy = "none"      // never follow, a redirect response is treated as a failure
+	RedirectSameHost RedirectPolicy = "same_host" // only follow redirects that stay on the original host
+)
+
+// InternalPolicy - decides when a link is counted as internal
+type InternalPolicy string
+
+const (
+	InternalSameHost   InternalPolicy = "same_host"   // link host must match the page host exactly
+	InternalSameDomain InternalPolicy = "same_domain" // link host must share the page's registrable domain (e.g. blog.example.com and example.com)
+)
+
+// LinkCategory - kind of html element the links are taken from
+type LinkCategory string
+
+const (
+	LinkAnchor LinkCategory = "anchor" // <a href>
+	LinkArea   LinkCategory = "area"   // <area href>
+	LinkLink   LinkCategory = "link"   // <link href>
+)
+
+// Options - per batch scraping options.
+// Zero values are replaced by defaults in WithDefaults.
+type Options struct {
+	Timeout         time.Duration
+	UserAgent       string
+	Headers         map[string]string
+	MaxBodySize     int64
+	RedirectPolicy  RedirectPolicy
+	MaxRedirects    int
+	InternalPolicy  InternalPolicy
+	InternalDomains []string // extra hosts which are always counted as internal
+	LinkCategories  []LinkCategory
+	CollectLinks    bool // keep the links of every page in Result, not only their count
+}
+
+// DefaultOptions - options used when nothing was requested
+func DefaultOptions() Options {
+	return Options{}.WithDefaults()
+}
+
+// WithDefaults - returns a copy of the options where every unset field is filled with its default
+func (o Options) WithDefaults() Options {
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = DefaultUserAgent
+	}
+	if o.MaxBodySize == 0 {
+		o.MaxBodySize = DefaultMaxBodySize
+	}
+	if o.RedirectPolicy == "" {
+		o.RedirectPolicy = RedirectFollow
+	}
+	if o.MaxRedirects == 0 {
+		o.MaxRedirects = DefaultMaxRedirects
+	}
+	if o.InternalPolicy == "" {
+		o.InternalPolicy = InternalSameHost
+	}
+	if len(o.LinkCategories) == 0 {
+		o.LinkCategories = []LinkCategory{LinkAnchor}
+	}
+	return o
+}
+
+// Validate - checks that the options are in range, errors wrap ErrInvalidOptions
+func (o Options) Validate() error {
+	if o.Timeout < 0 || o.Timeout > maxTimeout {
+		return fmt.Errorf("%w: timeout must be between 0 and %s", ErrInvalidOptions, maxTimeout)
+	}
+	if o.MaxBodySize < 0 || o.MaxBodySize > maxBodySize {
+		return fmt.Errorf("%w: max body size must be between 0 and %d bytes", ErrInvalidOptions, maxBodySize)
+	}
+	if o.MaxRedirects < 0 || o.MaxRedirects > maxRedirectsCap {
+		return fmt.Errorf("%w: max redirects must be between 0 and %d", ErrInvalidOptions, maxRedirectsCap)
+	}
+	if !httpguts.ValidHeaderFieldValue(o.UserAgent) {
+		return fmt.Errorf("%w: invalid user agent", ErrInvalidOptions)
+	}
+	for name, value := range o.Headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("%w: invalid header %q", ErrInvalidOptions, name)
+		}
+		if strings.EqualFold(name, "Host") {
+			return fmt.Errorf("%w: header %q can't be overridden", ErrInvalidOptions, name)
+		}
+	}
+	switch o.RedirectPolicy {
+	case "", RedirectFollow, RedirectNone, RedirectSameHost:
+	default:
+		return fmt.Errorf("%w: unknown redirect policy %q", ErrInvalidOptions, o.RedirectPolicy)
+	}
+	switch o.InternalPolicy {
+	case "", InternalSameHost, InternalSameDomain:
+	default:
+		return fmt.Errorf("%w: unknown internal policy %q", ErrInvalidOptions, o.InternalPolicy)
+	}
+	for _, domain := range o.InternalDomains {
+		if strings.TrimSpace(domain) == "" || strings.ContainsAny(domain, "/:") {
+			return fmt.Errorf("%w: invalid internal domain %q", ErrInvalidOptions, domain)
+		}
+	}
+	for _, category := range o.LinkCategories {
+		switch category {
+		case LinkAnchor, LinkArea, LinkLink:
+		default:
+			return fmt.Errorf("%w: unknown link category %q", ErrInvalidOptions, category)
+		}
+	}
+	return nil
+}
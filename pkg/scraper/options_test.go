@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsWithDefaults(t *testing.T) {
+	opts := Options{Timeout: time.Second, RedirectPolicy: RedirectNone}.WithDefaults()
+
+	assert.Equal(t, time.Second, opts.Timeout)
+	assert.Equal(t, RedirectNone, opts.RedirectPolicy)
+	assert.Equal(t, DefaultUserAgent, opts.UserAgent)
+	assert.Equal(t, int64(DefaultMaxBodySize), opts.MaxBodySize)
+	assert.Equal(t, DefaultMaxRedirects, opts.MaxRedirects)
+	assert.Equal(t, InternalSameHost, opts.InternalPolicy)
+	assert.Equal(t, []LinkCategory{LinkAnchor}, opts.LinkCategories)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "empty options are valid", opts: Options{}},
+		{name: "defaults are valid", opts: DefaultOptions()},
+		{name: "negative timeout", opts: Options{Timeout: -time.Second}, wantErr: true},
+		{name: "timeout too long", opts: Options{Timeout: time.Hour}, wantErr: true},
+		{name: "negative max body size", opts: Options{MaxBodySize: -1}, wantErr: true},
+		{name: "too many redirects", opts: Options{MaxRedirects: 100}, wantErr: true},
+		{name: "unknown redirect policy", opts: Options{RedirectPolicy: "sometimes"}, wantErr: true},
+		{name: "unknown internal policy", opts: Options{InternalPolicy: "same_planet"}, wantErr: true},
+		{name: "unknown link category", opts: Options{LinkCategories: []LinkCategory{"img"}}, wantErr: true},
+		{name: "invalid header name", opts: Options{Headers: map[string]string{"Bad Header": "x"}}, wantErr: true},
+		{name: "host header", opts: Options{Headers: map[string]string{"host": "example.com"}}, wantErr: true},
+		{name: "invalid user agent", opts: Options{UserAgent: "bot\r\nX-Injected: 1"}, wantErr: true},
+		{name: "invalid internal domain", opts: Options{InternalDomains: []string{"https://example.com"}}, wantErr: true},
+		{
+			name: "all options set",
+			opts: Options{
+				Timeout:         10 * time.Second,
+				UserAgent:       "links-bot",
+				Headers:         map[string]string{"Accept-Language": "en"},
+				MaxBodySize:     1024,
+				RedirectPolicy:  RedirectSameHost,
+				MaxRedirects:    3,
+				InternalPolicy:  InternalSameDomain,
+				InternalDomains: []string{"cdn.example.com"},
+				LinkCategories:  []LinkCategory{LinkAnchor, LinkArea, LinkLink},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOptions)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package scraper
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// ErrNoRecording is returned by the replay fetcher when a page was never recorded
+var ErrNoRecording = errors.New("no recording for url")
+
+// NewRecordingFetcher - http fetcher which stores every response of the client, redirect hops included,
+// as a fixture in dir, see RecordingTransport. When client is nil a cleanhttp client is used
+func NewRecordingFetcher(client *http.Client, dir string) *HTTPFetcher {
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+	}
+	recording := *client
+	recording.Transport = NewRecordingTransport(dir, client.Transport)
+	return NewHTTPFetcher(&recording)
+}
+
+// NewReplayFetcher - http fetcher serving the responses recorded in dir, never touches the network.
+// Redirects are replayed hop by hop, so the redirect policy of the options still applies
+func NewReplayFetcher(dir string) *HTTPFetcher {
+	client := cleanhttp.DefaultClient()
+	client.Transport = NewReplayTransport(dir)
+	return NewHTTPFetcher(client)
+}
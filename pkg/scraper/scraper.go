@@ -3,94 +3,242 @@ package scraper
 import (
 	"context"
 	"errors"
+	"io"
 	"log"
-	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/hashicorp/go-cleanhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
 )
 
 var concurencyLimit = 1000
 
+var ErrBodyTooLarge = errors.New("response body too large")
+
 // ScraperService ...
 type ScraperService interface {
-	Scrape(ctx context.Context, urls []*url.URL) []Result
+	Scrape(ctx context.Context, urls []*url.URL, opts Options) []Result
+	ScrapeStream(ctx context.Context, urls []*url.URL, opts Options) *Stream
 }
 
 type Scraper struct {
-	httpClient *http.Client
-	wg         *sync.WaitGroup
+	fetcher     Fetcher
+	concurrency int
+	observer    FetchObserver
+	tracer      trace.Tracer
 }
 
-func NewScraper() *Scraper {
-	return &Scraper{httpClient: cleanhttp.DefaultClient(), wg: &sync.WaitGroup{}}
+// NewScraper - by default pages are fetched over http with a cleanhttp client,
+// use the options to select another Fetcher
+func NewScraper(options ...Option) *Scraper {
+	s := &Scraper{fetcher: NewHTTPFetcher(nil), concurrency: concurencyLimit, tracer: noopTracer}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
-// Scrape - adds to waitgroup and starts a goroutine for each url that we have passed
-// each goroutine decrements waitgroup when finished. It is finished when our scraping worker
-// finishes and sends to the channel.
-// After all goroutines finish, we iterate through the channel to gather the results
-func (s *Scraper) Scrape(ctx context.Context, urls []*url.URL) []Result {
-	resultsChan := make(chan Result)
+// WithConcurrency - number of pages fetched at the same time, values below 1 are ignored
+func WithConcurrency(limit int) Option {
+	return func(s *Scraper) {
+		if limit > 0 {
+			s.concurrency = limit
+		}
+	}
+}
+
+// Scrape - scrapes all urls concurrently and returns once every url is done,
+// see ScrapeStream for the results as they finish
+func (s *Scraper) Scrape(ctx context.Context, urls []*url.URL, opts Options) []Result {
+	ctx, span := s.tracer.Start(ctx, "Scraper.Scrape", trace.WithAttributes(attribute.Int("scraper.urls", len(urls))))
+	defer span.End()
 	results := make([]Result, 0, len(urls))
 
-	for _, url := range urls {
-		s.wg.Add(1)
-		url := url // https://go.dev/doc/faq#closures_and_goroutines
+	// wait for results
+	for res := range s.ScrapeStream(ctx, urls, opts).Results() {
+		results = append(results, res)
+	}
+	log.Println("Successfully fetched all internal and external links.")
+	return results
+}
+
+// Stream - results of ScrapeStream in the order they finish
+type Stream struct {
+	results chan Result
+	err     error
+}
 
+// NewStream - stream which is fed by the caller, send the results to the returned channel
+// and call Close once every result was sent
+func NewStream() (*Stream, chan<- Result) {
+	stream := &Stream{results: make(chan Result)}
+	return stream, stream.results
+}
+
+// Results - closed after the last result, or once the context of the scrape is done
+func (s *Stream) Results() <-chan Result {
+	return s.results
+}
+
+// Err - reason the stream ended before every url was scraped, nil when all results were sent.
+// Only valid once Results is closed
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Close - ends the stream with the given error, see Err
+func (s *Stream) Close(err error) {
+	s.err = err
+	close(s.results)
+}
+
+// ScrapeStream - starts a worker for each url up to the concurrency limit, the workers take the urls
+// one after another and fetch their page once a slot of the context is granted, see WithSlots.
+// Results are sent in the order they finish. Once the context is done the results which weren't
+// received yet are dropped, the stream is closed and Err returns the context error. When a slot
+// can't be acquired for another reason the stream ends with the first such error.
+// The span of the scrape ends with the stream.
+func (s *Scraper) ScrapeStream(ctx context.Context, urls []*url.URL, opts Options) *Stream {
+	ctx, span := s.tracer.Start(ctx, "Scraper.ScrapeStream", trace.WithAttributes(attribute.Int("scraper.urls", len(urls))))
+	stream, resultsChan := NewStream()
+	wg := &sync.WaitGroup{}
+	opts = opts.WithDefaults()
+	acquire := slotsOf(ctx)
+	jobs := make(chan *url.URL)
+	stopped := make(chan struct{}) // closed once every worker exited, so the urls aren't fed anymore
+	var sent int64
+	var acquireErr error
+	acquireErrOnce := &sync.Once{}
+
+	workers := s.concurrency
+	if len(urls) < workers {
+		workers = len(urls)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
 		go func() {
-			defer s.wg.Done()
-			select {
-			case resultsChan <- s.startScrapingWorker(ctx, url, resultsChan):
+			defer wg.Done()
+			for url := range jobs {
+				release, err := acquire(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						acquireErrOnce.Do(func() { acquireErr = err })
+					}
+					return
+				}
+				result := s.scrapePage(ctx, url, opts)
+				release()
+				if ctx.Err() != nil && isContextError(result.Error) { // the fetch was aborted by the cancellation
+					return
+				}
+				select {
+				case resultsChan <- result: // a finished result is still sent if it's being received
+					atomic.AddInt64(&sent, 1)
+					continue
+				default:
+				}
+				select {
+				case resultsChan <- result:
+					atomic.AddInt64(&sent, 1)
+				case <-ctx.Done():
+					return
+				}
 			}
 		}()
 	}
 
 	go func() {
-		s.wg.Wait()
-		close(resultsChan)
+		defer close(jobs)
+		for _, url := range urls {
+			select {
+			case jobs <- url:
+			case <-ctx.Done():
+				return
+			case <-stopped:
+				return
+			}
+		}
 	}()
 
-	// wait for results
-	for res := range resultsChan {
-		results = append(results, res)
-	}
-	log.Println("Successfully fetched all internal and external links.")
-	return results
+	go func() {
+		wg.Wait()
+		close(stopped)
+		var err error
+		if atomic.LoadInt64(&sent) < int64(len(urls)) {
+			err = ctx.Err()
+			if err == nil {
+				err = acquireErr
+			}
+		}
+		endSpan(span, err)
+		stream.Close(err)
+	}()
+
+	return stream
 }
 
-func (s *Scraper) startScrapingWorker(ctx context.Context, url *url.URL, resultsChan chan Result) Result {
-	result := Result{PageURL: url.String(), Success: false}
+// isContextError - whether the error comes from a cancelled context or one past its deadline
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
-	if err != nil {
-		result.Error = err
-		return result
+// scrapePage - scrapes the page in its own span and reports the fetch to the observer of the scraper
+func (s *Scraper) scrapePage(ctx context.Context, url *url.URL, opts Options) (result Result) {
+	ctx, span := s.startFetchSpan(ctx, url)
+	defer func() { endFetchSpan(span, result) }()
+	if s.observer == nil {
+		return s.startScrapingWorker(ctx, url, opts)
 	}
 
-	req.Header.Add("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:100.0) Gecko/20100101 Firefox/100.0")
+	s.observer.FetchStarted(url)
+	start := time.Now()
+	result = s.startScrapingWorker(ctx, url, opts)
+	s.observer.FetchFinished(url, time.Since(start), result.Error)
+	return result
+}
+
+func (s *Scraper) startScrapingWorker(ctx context.Context, url *url.URL, opts Options) Result {
+	result := Result{PageURL: url.String(), Success: false}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
 
-	resp, err := s.httpClient.Do(req)
+	fetchCtx, finishHTTPTrace := s.traceHTTP(ctx)
+	page, err := s.fetcher.Fetch(fetchCtx, url, opts)
+	finishHTTPTrace(err)
 	if err != nil {
 		result.Error = err
 		return result
 	}
-	defer resp.Body.Close()
+	defer page.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		result.Error = errors.New("bad status code")
+	if page.StatusCode >= 300 { // redirects which weren't followed are failures as well
+		result.Error = &StatusError{StatusCode: page.StatusCode}
 		return result
 	}
 
-	document, err := html.Parse(resp.Body)
+	// the body is read while it's parsed, so the span includes downloading it
+	_, parseSpan := s.tracer.Start(ctx, "scraper.parse")
+	defer func() { endSpan(parseSpan, result.Error) }()
+	document, err := html.Parse(&limitedReader{r: page.Body, remaining: opts.MaxBodySize})
 	if err != nil {
 		result.Error = err
 		return result
 	}
 
-	external, internal, err := CountLinks(url, document)
+	if opts.CollectLinks {
+		result.ExternalLinks, result.InternalLinks = ExtractLinks(url, document, opts)
+		result.ExternalLinksNum = uint(len(result.ExternalLinks))
+		result.InternalLinksNum = uint(len(result.InternalLinks))
+		result.Success = true
+		return result
+	}
+
+	external, internal, err := CountLinksWithOptions(url, document, opts)
 	if err != nil {
 		result.Error = err
 		result.ExternalLinksNum = external
@@ -104,3 +252,27 @@ func (s *Scraper) startScrapingWorker(ctx context.Context, url *url.URL, results
 
 	return result
 }
+
+// limitedReader - like io.LimitedReader but fails with ErrBodyTooLarge instead of silently truncating
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// probe for one more byte to tell apart a body of exactly the limit from a bigger one
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -2,11 +2,26 @@ package scraper_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Lockwarr/codefi/pkg/mocks"
 	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/pkg/sitegen"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type scraperTestSuite struct {
@@ -15,7 +30,8 @@ type scraperTestSuite struct {
 }
 
 func (s *scraperTestSuite) SetupTest() {
-	s.scraper = scraper.NewScraper()
+	// run against the recorded responses, SCRAPER_RECORD=1 records them again
+	s.scraper = scraper.NewScraper(scraper.WithFixtures("testdata/recordings"))
 }
 
 func (s *scraperTestSuite) AfterTest(suite string, testName string) {
@@ -31,8 +47,385 @@ func (s *scraperTestSuite) TestCreateResults_ThenSuccess() {
 	urlGenerated, _ := url.Parse("http://google.com")
 
 	//Act
-	actualResults := s.scraper.Scrape(ctx, []*url.URL{urlGenerated})
+	actualResults := s.scraper.Scrape(ctx, []*url.URL{urlGenerated}, scraper.Options{})
 
 	// Assert
 	s.Equal(1, len(actualResults))
+	s.True(actualResults[0].Success, actualResults[0].Error)
+	// links are counted against the requested host, so the absolute www.google.com link is external
+	s.Equal(uint(5), actualResults[0].InternalLinksNum)
+	s.Equal(uint(14), actualResults[0].ExternalLinksNum)
+}
+
+func (s *scraperTestSuite) TestScrape_WhenOptionsAreSet_ThenTheyAreApplied() {
+	// Arrange
+	mux := http.NewServeMux()
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() != "links-bot" || r.Header.Get("Accept-Language") != "en" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`<a href="/a">a</a><a href="https://other.org">b</a>`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>" + strings.Repeat("a", 2048) + "</p>"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/headers", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	baseOpts := scraper.Options{UserAgent: "links-bot", Headers: map[string]string{"Accept-Language": "en"}}
+	tests := []struct {
+		name           string
+		path           string
+		opts           func(opts scraper.Options) scraper.Options
+		wantedSuccess  bool
+		wantedInternal uint
+		wantedExternal uint
+	}{
+		{name: "user agent and headers are sent", path: "/headers", wantedSuccess: true, wantedInternal: 1, wantedExternal: 1},
+		{name: "timeout", path: "/slow", opts: func(o scraper.Options) scraper.Options { o.Timeout = 50 * time.Millisecond; return o }},
+		{name: "max body size", path: "/big", opts: func(o scraper.Options) scraper.Options { o.MaxBodySize = 1024; return o }},
+		{name: "redirects are followed", path: "/redirect", wantedSuccess: true, wantedInternal: 1, wantedExternal: 1},
+		{name: "redirects are not followed", path: "/redirect", opts: func(o scraper.Options) scraper.Options { o.RedirectPolicy = scraper.RedirectNone; return o }},
+	}
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			opts := baseOpts
+			if tt.opts != nil {
+				opts = tt.opts(opts)
+			}
+			pageURL, _ := url.Parse(server.URL + tt.path)
+
+			// Act
+			actualResults := scraper.NewScraper().Scrape(context.Background(), []*url.URL{pageURL}, opts)
+
+			// Assert
+			s.Equal(1, len(actualResults))
+			s.Equal(tt.wantedSuccess, actualResults[0].Success, actualResults[0].Error)
+			s.Equal(tt.wantedInternal, actualResults[0].InternalLinksNum)
+			s.Equal(tt.wantedExternal, actualResults[0].ExternalLinksNum)
+		})
+	}
+}
+
+func (s *scraperTestSuite) TestScrape_WhenLinksAreCollected_ThenTheyAreReturned() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/a">a</a><a href="b">b</a><a href="https://other.org">c</a>`))
+	}))
+	defer server.Close()
+	pageURL, _ := url.Parse(server.URL + "/docs/")
+
+	// Act
+	actualResults := scraper.NewScraper().Scrape(context.Background(), []*url.URL{pageURL}, scraper.Options{CollectLinks: true})
+
+	// Assert
+	s.Equal(1, len(actualResults))
+	s.True(actualResults[0].Success)
+	s.Equal([]string{server.URL + "/a", server.URL + "/docs/b"}, actualResults[0].InternalLinks)
+	s.Equal([]string{"https://other.org"}, actualResults[0].ExternalLinks)
+	s.Equal(uint(2), actualResults[0].InternalLinksNum)
+	s.Equal(uint(1), actualResults[0].ExternalLinksNum)
+}
+
+func (s *scraperTestSuite) TestScrape_WhenGeneratedSite_ThenResultsMatchGroundTruth() {
+	// Arrange
+	site := sitegen.New(sitegen.Config{Seed: 26, Pages: 300, ExternalRatio: 0.4, BrokenRatio: 0.1, RedirectRatio: 0.2, ErrorRate: 0.1})
+	server := site.Start()
+	defer server.Close()
+	expected := site.Expected()
+
+	// Act
+	actualResults := scraper.NewScraper().Scrape(context.Background(), site.URLs(server.URL), scraper.Options{})
+
+	// Assert
+	s.Equal(len(expected), len(actualResults))
+	for _, result := range actualResults {
+		pageURL, _ := url.Parse(result.PageURL)
+		want := expected[pageURL.Path]
+		s.Equal(want.Success, result.Success, result.PageURL)
+		s.Equal(want.InternalLinksNum, result.InternalLinksNum, result.PageURL)
+		s.Equal(want.ExternalLinksNum, result.ExternalLinksNum, result.PageURL)
+	}
+}
+
+func BenchmarkScrape(b *testing.B) {
+	site := sitegen.New(sitegen.Config{Seed: 1, Pages: 500, MinLinks: 20, MaxLinks: 100, ExternalRatio: 0.3})
+	server := site.Start()
+	defer server.Close()
+	urls := site.URLs(server.URL)
+	s := scraper.NewScraper()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Scrape(context.Background(), urls, scraper.Options{})
+	}
+}
+
+func (s *scraperTestSuite) TestScrapeStream_ThenResultsAreSentAsTheyFinish() {
+	// Arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	fast, _ := url.Parse(server.URL + "/fast")
+	slow, _ := url.Parse(server.URL + "/slow")
+
+	// Act
+	stream := scraper.NewScraper().ScrapeStream(context.Background(), []*url.URL{slow, fast}, scraper.Options{})
+	first := <-stream.Results()
+	close(release)
+	second := <-stream.Results()
+	_, open := <-stream.Results()
+
+	// Assert
+	s.Equal(fast.String(), first.PageURL)
+	s.Equal(slow.String(), second.PageURL)
+	s.True(second.Success)
+	s.False(open)
+	s.NoError(stream.Err())
+}
+
+func (s *scraperTestSuite) TestScrapeStream_WhenContextIsCancelled_ThenStreamEndsWithContextError() {
+	// Arrange
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	fast, _ := url.Parse(server.URL + "/fast")
+	slow, _ := url.Parse(server.URL + "/slow")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act
+	stream := scraper.NewScraper().ScrapeStream(ctx, []*url.URL{slow, fast}, scraper.Options{})
+	first := <-stream.Results()
+	cancel()
+	received := 0
+	for range stream.Results() {
+		received++
+	}
+
+	// Assert
+	s.Equal(fast.String(), first.PageURL)
+	s.LessOrEqual(received, 1)
+	s.ErrorIs(stream.Err(), context.Canceled)
+}
+
+func (s *scraperTestSuite) TestScrapeStream_WhenContextIsCancelledAfterAFetch_ThenItsResultIsKept() {
+	// Arrange
+	pageURL, _ := url.Parse("https://example.com/")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fetcher := fetcherFunc(func(ctx context.Context, pageURL *url.URL, opts scraper.Options) (*scraper.Page, error) {
+		cancel()
+		return &scraper.Page{URL: pageURL, StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`<a href="/a">a</a>`))}, nil
+	})
+
+	// Act
+	results := []scraper.Result{}
+	stream := scraper.NewScraper(scraper.WithFetcher(fetcher)).ScrapeStream(ctx, []*url.URL{pageURL}, scraper.Options{})
+	for result := range stream.Results() {
+		results = append(results, result)
+	}
+
+	// Assert
+	s.Equal(1, len(results))
+	s.True(results[0].Success)
+	s.NoError(stream.Err())
+}
+
+func (s *scraperTestSuite) TestScrape_WhenConcurrencyIsLimited_ThenAtMostThatManyPagesAreFetchedAtOnce() {
+	// Arrange
+	var inFlight, maxInFlight int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	urls := []*url.URL{}
+	for i := 0; i < 8; i++ {
+		pageURL, _ := url.Parse(fmt.Sprintf("%s/%d", server.URL, i))
+		urls = append(urls, pageURL)
+	}
+
+	// Act
+	results := scraper.NewScraper(scraper.WithConcurrency(2)).Scrape(context.Background(), urls, scraper.Options{})
+
+	// Assert
+	s.Equal(8, len(results))
+	s.LessOrEqual(atomic.LoadInt64(&maxInFlight), int64(2))
+}
+
+func (s *scraperTestSuite) TestScrapeStream_WhenSlotsAreSet_ThenPagesAreOnlyFetchedInSlots() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	urls := []*url.URL{}
+	for i := 0; i < 4; i++ {
+		pageURL, _ := url.Parse(fmt.Sprintf("%s/%d", server.URL, i))
+		urls = append(urls, pageURL)
+	}
+	var acquired, released int64
+	ctx := scraper.WithSlots(context.Background(), func(ctx context.Context) (func(), error) {
+		atomic.AddInt64(&acquired, 1)
+		return func() { atomic.AddInt64(&released, 1) }, nil
+	})
+
+	// Act
+	results := scraper.NewScraper().Scrape(ctx, urls, scraper.Options{})
+
+	// Assert
+	s.Equal(4, len(results))
+	s.Equal(int64(4), atomic.LoadInt64(&acquired))
+	s.Equal(int64(4), atomic.LoadInt64(&released))
+}
+
+func (s *scraperTestSuite) TestScrapeStream_WhenNoSlotIsGranted_ThenStreamEndsWithTheError() {
+	// Arrange
+	pageURL, _ := url.Parse("http://google.com")
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = scraper.WithSlots(ctx, func(ctx context.Context) (func(), error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	// Act
+	stream := s.scraper.ScrapeStream(ctx, []*url.URL{pageURL}, scraper.Options{})
+	cancel()
+	received := 0
+	for range stream.Results() {
+		received++
+	}
+
+	// Assert
+	s.Equal(0, received)
+	s.ErrorIs(stream.Err(), context.Canceled)
+}
+
+func (s *scraperTestSuite) TestScrapeStream_WhenSlotsCantBeAcquired_ThenStreamEndsWithTheFirstError() {
+	// Arrange
+	urls := []*url.URL{}
+	for i := 0; i < 3; i++ {
+		pageURL, _ := url.Parse(fmt.Sprintf("https://example.com/%d", i))
+		urls = append(urls, pageURL)
+	}
+	ctx := scraper.WithSlots(context.Background(), func(ctx context.Context) (func(), error) {
+		return nil, errors.New("no slots")
+	})
+
+	// Act
+	stream := scraper.NewScraper(scraper.WithConcurrency(1)).ScrapeStream(ctx, urls, scraper.Options{})
+	received := 0
+	for range stream.Results() {
+		received++
+	}
+
+	// Assert
+	s.Equal(0, received)
+	s.EqualError(stream.Err(), "no slots")
+}
+
+func (s *scraperTestSuite) TestScrape_WhenObserverIsSet_ThenEveryFetchIsReported() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	found, _ := url.Parse(server.URL + "/found")
+	missing, _ := url.Parse(server.URL + "/missing")
+	observer := new(mocks.MockFetchObserver)
+	observer.On("FetchStarted", found).Return().Once()
+	observer.On("FetchFinished", found, mock.Anything, nil).Return().Once()
+	observer.On("FetchStarted", missing).Return().Once()
+	observer.On("FetchFinished", missing, mock.Anything, &scraper.StatusError{StatusCode: http.StatusNotFound}).Return().Once()
+
+	// Act
+	results := scraper.NewScraper(scraper.WithFetchObserver(observer)).Scrape(context.Background(), []*url.URL{found, missing}, scraper.Options{})
+
+	// Assert
+	s.Equal(2, len(results))
+	observer.AssertExpectations(s.T())
+}
+
+func (s *scraperTestSuite) TestScrape_WhenTracerProviderIsSet_ThenEveryFetchIsTracedWithItsPhases() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	found, _ := url.Parse(server.URL + "/found")
+	missing, _ := url.Parse(server.URL + "/missing")
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	scraperWithTracing := scraper.NewScraper(scraper.WithTracerProvider(provider), scraper.WithConcurrency(1))
+
+	// Act
+	scraperWithTracing.Scrape(context.Background(), []*url.URL{found, missing}, scraper.Options{})
+
+	// Assert
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	s.Len(spans["Scraper.Scrape"], 1)
+	s.Len(spans["Scraper.ScrapeStream"], 1)
+	s.Equal(spans["Scraper.Scrape"][0].SpanContext().SpanID(), spans["Scraper.ScrapeStream"][0].Parent().SpanID())
+	s.Len(spans["scraper.fetch"], 2)
+	s.NotEmpty(spans["connect"])
+	s.Len(spans["server"], 2)
+	s.Len(spans["scraper.parse"], 1)
+	for _, fetch := range spans["scraper.fetch"] {
+		s.Equal(spans["Scraper.ScrapeStream"][0].SpanContext().SpanID(), fetch.Parent().SpanID())
+		s.Equal(spans["Scraper.Scrape"][0].SpanContext().TraceID(), fetch.SpanContext().TraceID())
+		if fetch.Status().Code == codes.Error {
+			s.Contains(fetch.Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+		} else {
+			s.Contains(fetch.Attributes(), attribute.Int("scraper.internal_links", 1))
+		}
+	}
+}
+
+type fetcherFunc func(ctx context.Context, pageURL *url.URL, opts scraper.Options) (*scraper.Page, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, pageURL *url.URL, opts scraper.Options) (*scraper.Page, error) {
+	return f(ctx, pageURL, opts)
 }
@@ -0,0 +1,23 @@
+package scraper
+
+import "context"
+
+// SlotAcquirer - waits until a page may be fetched, release must be called once the page is fetched.
+// An error ends the wait without a slot, e.g. when the context is done.
+type SlotAcquirer func(ctx context.Context) (release func(), err error)
+
+type slotsKey struct{}
+
+// WithSlots - pages scraped with the returned context are only fetched in slots granted by acquire,
+// on top of the concurrency of the scraper. It lets a limit of the whole service be shared between scrapes.
+func WithSlots(ctx context.Context, acquire SlotAcquirer) context.Context {
+	return context.WithValue(ctx, slotsKey{}, acquire)
+}
+
+// slotsOf - the slot acquirer of the context, pages are fetched right away without one
+func slotsOf(ctx context.Context) SlotAcquirer {
+	if acquire, ok := ctx.Value(slotsKey{}).(SlotAcquirer); ok {
+		return acquire
+	}
+	return func(context.Context) (func(), error) { return func() {}, nil }
+}
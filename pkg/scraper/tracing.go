@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/Lockwarr/codefi/pkg/scraper"
+
+// noopTracer - tracer of scrapers without a tracer provider
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// WithTracerProvider - trace the scrapes with a span for every fetch. The http fetches get child spans
+// for resolving the host, connecting, the tls handshake and waiting for the server, see traceHTTP
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(s *Scraper) {
+		s.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// startFetchSpan - span of fetching and parsing a page, ended by endFetchSpan
+func (s *Scraper) startFetchSpan(ctx context.Context, pageURL *url.URL) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "scraper.fetch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("url.full", pageURL.String()),
+			attribute.String("server.address", pageURL.Hostname()),
+		))
+}
+
+// endFetchSpan - records the outcome of the page on its span
+func endFetchSpan(span trace.Span, result Result) {
+	span.SetAttributes(
+		attribute.Bool("scraper.success", result.Success),
+		attribute.Int("scraper.internal_links", int(result.InternalLinksNum)),
+		attribute.Int("scraper.external_links", int(result.ExternalLinksNum)),
+	)
+	if result.Error != nil {
+		category, statusCode := ClassifyError(result.Error)
+		span.SetAttributes(attribute.String("error.type", string(category)))
+		if statusCode != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+		}
+		span.RecordError(result.Error)
+		span.SetStatus(codes.Error, result.Error.Error())
+	}
+	span.End()
+}
+
+// httpTrace - child spans of a fetch for the phases of its http requests, phases skipped on a reused
+// connection have no span. The hooks may be called from the goroutines of the transport.
+type httpTrace struct {
+	ctx    context.Context
+	tracer trace.Tracer
+
+	mu       sync.Mutex
+	dns      trace.Span
+	connects map[string]trace.Span // address -> dial, several addresses may be dialed at once
+	tls      trace.Span
+	server   trace.Span // from the request being written until the first byte of the response
+}
+
+// traceHTTP - context whose http requests are traced as children of the span of ctx, finish ends the
+// spans still open once the fetch is done, e.g. when it timed out
+func (s *Scraper) traceHTTP(ctx context.Context) (context.Context, func(err error)) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, func(error) {}
+	}
+
+	t := &httpTrace{ctx: ctx, tracer: s.tracer, connects: map[string]trace.Span{}}
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.start(&t.dns, "dns", attribute.String("server.address", info.Host))
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.end(&t.dns, info.Err)
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			_, t.connects[addr] = t.tracer.Start(t.ctx, "connect", trace.WithAttributes(
+				attribute.String("network.transport", network),
+				attribute.String("network.peer.address", addr),
+			))
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if span, ok := t.connects[addr]; ok {
+				endSpan(span, err)
+				delete(t.connects, addr)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.start(&t.tls, "tls")
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.end(&t.tls, err)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.SpanFromContext(t.ctx).AddEvent("connection", trace.WithAttributes(attribute.Bool("reused", info.Reused)))
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				t.start(&t.server, "server")
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.end(&t.server, nil)
+		},
+	})
+	return ctx, t.finish
+}
+
+func (t *httpTrace) start(span *trace.Span, name string, attributes ...attribute.KeyValue) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, *span = t.tracer.Start(t.ctx, name, trace.WithAttributes(attributes...))
+}
+
+func (t *httpTrace) end(span *trace.Span, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if *span != nil {
+		endSpan(*span, err)
+		*span = nil
+	}
+}
+
+func (t *httpTrace) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, span := range []*trace.Span{&t.dns, &t.tls, &t.server} {
+		if *span != nil {
+			endSpan(*span, err)
+			*span = nil
+		}
+	}
+	for addr, span := range t.connects {
+		endSpan(span, err)
+		delete(t.connects, addr)
+	}
+}
+
+// endSpan - ends the span, as failed when err isn't nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
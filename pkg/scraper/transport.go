@@ -0,0 +1,115 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// RecordEnv - when this environment variable is set to 1, WithFixtures records instead of replaying
+const RecordEnv = "SCRAPER_RECORD"
+
+// RecordingTransport - http.RoundTripper which sends requests with the wrapped transport
+// and stores every response (redirect hops included) as a fixture in dir
+type RecordingTransport struct {
+	next http.RoundTripper
+	dir  string
+}
+
+// NewRecordingTransport - when next is nil a cleanhttp transport is used
+func NewRecordingTransport(dir string, next http.RoundTripper) *RecordingTransport {
+	if next == nil {
+		next = cleanhttp.DefaultTransport()
+	}
+	return &RecordingTransport{next: next, dir: dir}
+}
+
+// RoundTrip - the response body is read fully so it can be both stored and returned
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	fx := &fixture{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)}
+	if err := writeFixture(t.dir, fx); err != nil {
+		return nil, fmt.Errorf("failed to record %s %w", req.URL, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// ReplayTransport - http.RoundTripper which serves responses recorded by the RecordingTransport
+// and never touches the network
+type ReplayTransport struct {
+	dir string
+}
+
+// NewReplayTransport ..
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{dir: dir}
+}
+
+// RoundTrip - returns an error wrapping ErrNoRecording for requests which weren't recorded
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	fx, err := readFixture(t.dir, req.Method, req.URL.String())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w %s %s", ErrNoRecording, req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := fx.Header
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fx.StatusCode, http.StatusText(fx.StatusCode)),
+		StatusCode:    fx.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(fx.Body)),
+		ContentLength: int64(len(fx.Body)),
+		Request:       req,
+	}, nil
+}
+
+// WithTransport - scrape live pages using a cleanhttp client with the given transport
+func WithTransport(transport http.RoundTripper) Option {
+	client := cleanhttp.DefaultClient()
+	client.Transport = transport
+	return WithHTTPClient(client)
+}
+
+// WithFixtures - replay the http responses recorded in dir, or record them again
+// when the SCRAPER_RECORD environment variable is set to 1. Meant for tests:
+//
+//	SCRAPER_RECORD=1 go test ./...
+func WithFixtures(dir string) Option {
+	if os.Getenv(RecordEnv) == "1" {
+		return WithRecording(dir)
+	}
+	return WithReplay(dir)
+}
@@ -0,0 +1,85 @@
+package scraper_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingAndReplay(t *testing.T) {
+	// Arrange
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/home", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/a">a</a><a href="/b">b</a><a href="https://other.org/">c</a>`))
+	})
+	server := httptest.NewServer(mux)
+	dir := t.TempDir()
+	pageURL, _ := url.Parse(server.URL + "/")
+
+	// Act
+	recorded := scraper.NewScraper(scraper.WithRecording(dir)).Scrape(context.Background(), []*url.URL{pageURL}, scraper.Options{})
+	server.Close() // replaying must not need the server anymore
+	replayed := scraper.NewScraper(scraper.WithReplay(dir)).Scrape(context.Background(), []*url.URL{pageURL}, scraper.Options{})
+	notFollowed := scraper.NewScraper(scraper.WithReplay(dir)).Scrape(context.Background(), []*url.URL{pageURL}, scraper.Options{RedirectPolicy: scraper.RedirectNone})
+
+	// Assert
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(files)) // the redirect and the page
+	require.Equal(t, 1, len(recorded))
+	require.Equal(t, 1, len(replayed))
+	assert.True(t, recorded[0].Success)
+	assert.Equal(t, recorded[0], replayed[0])
+	assert.Equal(t, uint(2), replayed[0].InternalLinksNum)
+	assert.Equal(t, uint(1), replayed[0].ExternalLinksNum)
+	assert.False(t, notFollowed[0].Success)
+}
+
+func TestReplayTransport_WhenNotRecorded_ThenFail(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+
+	_, err := scraper.NewReplayTransport(t.TempDir()).RoundTrip(req)
+
+	assert.ErrorIs(t, err, scraper.ErrNoRecording)
+}
+
+func TestRecording_WhenHTTPClientIsSet_ThenItIsRecorded(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+	sent := 0
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	dir := t.TempDir()
+	pageURL, _ := url.Parse(server.URL + "/")
+
+	// Act
+	results := scraper.NewScraper(scraper.WithHTTPClient(client), scraper.WithRecording(dir)).Scrape(context.Background(), []*url.URL{pageURL}, scraper.Options{})
+
+	// Assert
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, 1, sent)
+	assert.True(t, results[0].Success)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
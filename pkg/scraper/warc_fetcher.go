@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WARCFetcher - serves pages from the response records of a WARC archive (ISO 28500).
+// Plain and gzip compressed archives are supported. The archive is read into memory
+// once and redirects recorded in it are followed according to the redirect policy.
+type WARCFetcher struct {
+	responses map[string][]byte // raw http response by target uri
+}
+
+// OpenWARCFetcher - reads the archive at path
+func OpenWARCFetcher(path string) (*WARCFetcher, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewWARCFetcher(file)
+}
+
+// NewWARCFetcher - reads the whole archive from r
+func NewWARCFetcher(r io.Reader) (*WARCFetcher, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br) // multistream by default, so per record gzip members are read one after another
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip warc %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	f := &WARCFetcher{responses: map[string][]byte{}}
+	tp := textproto.NewReader(br)
+
+	for {
+		version, err := readVersionLine(tp)
+		if errors.Is(err, io.EOF) {
+			return f, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(version, "WARC/") {
+			return nil, fmt.Errorf("malformed warc record, unexpected line %q", version)
+		}
+
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return nil, fmt.Errorf("malformed warc record header %w", err)
+		}
+
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("malformed warc record content length %q", header.Get("Content-Length"))
+		}
+
+		block := make([]byte, length)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil, fmt.Errorf("truncated warc record %w", err)
+		}
+
+		if header.Get("WARC-Type") == "response" && strings.HasPrefix(header.Get("Content-Type"), "application/http") {
+			target := strings.Trim(header.Get("WARC-Target-URI"), "<>") // WARC/0.x wraps the uri in angle brackets
+			f.responses[target] = block                                 // later captures of the same uri win
+		}
+	}
+}
+
+// readVersionLine - skips the blank lines separating records
+func readVersionLine(tp *textproto.Reader) (string, error) {
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if line != "" {
+			return line, nil
+		}
+	}
+}
+
+// Fetch - pages missing from the archive are reported with a 404 status code
+func (f *WARCFetcher) Fetch(ctx context.Context, pageURL *url.URL, opts Options) (*Page, error) {
+	current := pageURL
+
+	for hops := 1; ; hops++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		raw, ok := f.responses[current.String()]
+		if !ok {
+			return &Page{URL: current, StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+
+		// the request is only used for the method and to resolve relative redirect locations
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), &http.Request{Method: http.MethodGet, URL: current})
+		if err != nil {
+			return nil, fmt.Errorf("malformed archived response for %s %w", current, err)
+		}
+
+		location, err := resp.Location()
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || err != nil {
+			return &Page{URL: current, StatusCode: resp.StatusCode, Header: resp.Header, Body: resp.Body}, nil
+		}
+
+		if err := checkRedirect(location, pageURL, hops, opts); err != nil {
+			if errors.Is(err, http.ErrUseLastResponse) {
+				return &Page{URL: current, StatusCode: resp.StatusCode, Header: resp.Header, Body: resp.Body}, nil
+			}
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body.Close()
+		current = location
+	}
+}
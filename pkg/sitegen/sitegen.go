@@ -0,0 +1,208 @@
+// Package sitegen serves deterministic synthetic websites for correctness and load testing.
+// The same Config (seed included) always generates the same pages, links, redirects, errors
+// and latencies, and the expected scrape result of every page is exposed as ground truth.
+package sitegen
+
+import (
+	"fmt"
+	"html"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config - shape of the generated website, zero values are replaced by defaults in New
+type Config struct {
+	Seed          int64
+	Pages         int           // number of content pages, default 100
+	MinLinks      int           // minimum links per page
+	MaxLinks      int           // maximum links per page, default 20
+	ExternalRatio float64       // share of links pointing to other hosts
+	BrokenRatio   float64       // share of internal links pointing to pages which don't exist
+	RedirectRatio float64       // share of pages which also have a redirecting entry url
+	ErrorRate     float64       // share of pages answering with a 500 status code
+	Latency       time.Duration // base latency of every response
+	LatencyJitter time.Duration // extra random latency added to the base latency
+}
+
+// Page - generated page, the path of a content page is /p/<index>
+type Page struct {
+	Path          string
+	Status        int
+	RedirectPath  string // when set, the page can also be reached by following a redirect from this path
+	Latency       time.Duration
+	InternalLinks []string // paths on the same host, some are broken
+	ExternalLinks []string // absolute urls on other hosts
+}
+
+// Expectation - ground truth for scraping one entry url with the default scrape options
+type Expectation struct {
+	Success          bool
+	InternalLinksNum uint
+	ExternalLinksNum uint
+}
+
+// Site - generated website
+type Site struct {
+	cfg       Config
+	pages     []Page
+	redirects map[string]int // redirect path -> page index
+}
+
+// New - generates the website described by cfg
+func New(cfg Config) *Site {
+	cfg = cfg.withDefaults()
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	s := &Site{cfg: cfg, pages: make([]Page, cfg.Pages), redirects: map[string]int{}}
+	for i := range s.pages {
+		page := Page{Path: pagePath(i), Status: http.StatusOK}
+
+		if rng.Float64() < cfg.ErrorRate {
+			page.Status = http.StatusInternalServerError
+		}
+		if rng.Float64() < cfg.RedirectRatio {
+			page.RedirectPath = "/r/" + strconv.Itoa(i)
+			s.redirects[page.RedirectPath] = i
+		}
+		if cfg.LatencyJitter > 0 {
+			page.Latency = cfg.Latency + time.Duration(rng.Int63n(int64(cfg.LatencyJitter)))
+		} else {
+			page.Latency = cfg.Latency
+		}
+
+		links := cfg.MinLinks + rng.Intn(cfg.MaxLinks-cfg.MinLinks+1)
+		for l := 0; l < links; l++ {
+			switch {
+			case rng.Float64() < cfg.ExternalRatio:
+				page.ExternalLinks = append(page.ExternalLinks,
+					fmt.Sprintf("https://ext-%d.example.com/page/%d", rng.Intn(50), rng.Intn(1000)))
+			case rng.Float64() < cfg.BrokenRatio:
+				page.InternalLinks = append(page.InternalLinks, "/missing/"+strconv.Itoa(rng.Intn(1000)))
+			default:
+				page.InternalLinks = append(page.InternalLinks, pagePath(rng.Intn(cfg.Pages)))
+			}
+		}
+
+		s.pages[i] = page
+	}
+
+	return s
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Pages <= 0 {
+		cfg.Pages = 100
+	}
+	if cfg.MinLinks < 0 {
+		cfg.MinLinks = 0
+	}
+	if cfg.MaxLinks <= 0 {
+		cfg.MaxLinks = 20
+	}
+	if cfg.MaxLinks < cfg.MinLinks {
+		cfg.MaxLinks = cfg.MinLinks
+	}
+	return cfg
+}
+
+func pagePath(i int) string {
+	return "/p/" + strconv.Itoa(i)
+}
+
+// Pages - every generated page in index order
+func (s *Site) Pages() []Page {
+	return s.pages
+}
+
+// Paths - entry paths of the website: every page and every redirect to a page
+func (s *Site) Paths() []string {
+	paths := make([]string, 0, len(s.pages)+len(s.redirects))
+	for _, page := range s.pages {
+		paths = append(paths, page.Path)
+		if page.RedirectPath != "" {
+			paths = append(paths, page.RedirectPath)
+		}
+	}
+	return paths
+}
+
+// URLs - absolute entry urls of the website served at baseURL
+func (s *Site) URLs(baseURL string) []*url.URL {
+	urls := make([]*url.URL, 0, len(s.pages)+len(s.redirects))
+	for _, path := range s.Paths() {
+		u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + path)
+		if err != nil {
+			panic(fmt.Sprintf("sitegen: invalid base url %q", baseURL)) // a programming error in the test
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// Expected - ground truth for every entry path, see Paths
+func (s *Site) Expected() map[string]Expectation {
+	expected := make(map[string]Expectation, len(s.pages)+len(s.redirects))
+	for _, page := range s.pages {
+		expectation := Expectation{Success: page.Status == http.StatusOK}
+		if expectation.Success {
+			expectation.InternalLinksNum = uint(len(page.InternalLinks))
+			expectation.ExternalLinksNum = uint(len(page.ExternalLinks))
+		}
+		expected[page.Path] = expectation
+		if page.RedirectPath != "" {
+			expected[page.RedirectPath] = expectation
+		}
+	}
+	return expected
+}
+
+// Start - serves the website on a local httptest server, the caller must Close it
+func (s *Site) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// ServeHTTP - serves /p/<index> pages and /r/<index> redirects, everything else is a 404
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if i, ok := s.redirects[r.URL.Path]; ok {
+		http.Redirect(w, r, s.pages[i].Path, http.StatusFound)
+		return
+	}
+
+	i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+	if !strings.HasPrefix(r.URL.Path, "/p/") || err != nil || i < 0 || i >= len(s.pages) {
+		http.NotFound(w, r)
+		return
+	}
+	page := s.pages[i]
+
+	if page.Latency > 0 {
+		select {
+		case <-time.After(page.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if page.Status != http.StatusOK {
+		http.Error(w, http.StatusText(page.Status), page.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>page %d</title></head><body>\n", i)
+	for l, link := range page.InternalLinks {
+		if l%2 == 1 { // every other internal link is absolute so both forms are exercised
+			link = "http://" + r.Host + link
+		}
+		fmt.Fprintf(w, "<p><a href=\"%s\">internal %d</a></p>\n", html.EscapeString(link), l)
+	}
+	for l, link := range page.ExternalLinks {
+		fmt.Fprintf(w, "<p><a href=\"%s\">external %d</a></p>\n", html.EscapeString(link), l)
+	}
+	fmt.Fprint(w, "</body></html>\n")
+}
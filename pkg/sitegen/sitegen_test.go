@@ -0,0 +1,83 @@
+package sitegen_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lockwarr/codefi/pkg/sitegen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_WhenSameSeed_ThenSameSite(t *testing.T) {
+	cfg := sitegen.Config{Seed: 42, Pages: 50, ExternalRatio: 0.3, BrokenRatio: 0.1, RedirectRatio: 0.2, ErrorRate: 0.1}
+
+	assert.Equal(t, sitegen.New(cfg).Pages(), sitegen.New(cfg).Pages())
+
+}
+
+func TestNew_WhenOnlySeedDiffers_ThenDifferentSite(t *testing.T) {
+	cfg := sitegen.Config{Seed: 42, Pages: 50, ExternalRatio: 0.3, BrokenRatio: 0.1, RedirectRatio: 0.2, ErrorRate: 0.1}
+	other := cfg
+	other.Seed = 43
+
+	assert.NotEqual(t, sitegen.New(cfg).Pages(), sitegen.New(other).Pages())
+}
+
+func TestNew_RespectsConfig(t *testing.T) {
+	site := sitegen.New(sitegen.Config{Seed: 1, Pages: 1000, MinLinks: 5, MaxLinks: 10, ExternalRatio: 0.5, ErrorRate: 0.2, RedirectRatio: 0.1})
+
+	var links, external, errors, redirects int
+	for _, page := range site.Pages() {
+		pageLinks := len(page.InternalLinks) + len(page.ExternalLinks)
+		assert.GreaterOrEqual(t, pageLinks, 5)
+		assert.LessOrEqual(t, pageLinks, 10)
+		links += pageLinks
+		external += len(page.ExternalLinks)
+		if page.Status != http.StatusOK {
+			errors++
+		}
+		if page.RedirectPath != "" {
+			redirects++
+		}
+	}
+
+	assert.Equal(t, 1000, len(site.Pages()))
+	assert.Equal(t, 1000+redirects, len(site.Paths()))
+	assert.InDelta(t, 0.5, float64(external)/float64(links), 0.05)
+	assert.InDelta(t, 0.2, float64(errors)/1000, 0.05)
+	assert.InDelta(t, 0.1, float64(redirects)/1000, 0.05)
+}
+
+func TestSite_ServeHTTP(t *testing.T) {
+	site := sitegen.New(sitegen.Config{Seed: 7, Pages: 20, ErrorRate: 0.3, RedirectRatio: 0.5})
+	expected := site.Expected()
+
+	for _, path := range site.Paths() {
+		rr := httptest.NewRecorder()
+		site.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+
+		switch {
+		case path[:3] == "/r/":
+			assert.Equal(t, http.StatusFound, rr.Code, path)
+		case expected[path].Success:
+			assert.Equal(t, http.StatusOK, rr.Code, path)
+		default:
+			assert.Equal(t, http.StatusInternalServerError, rr.Code, path)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	site.ServeHTTP(rr, httptest.NewRequest("GET", "/missing/1", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestSite_URLs(t *testing.T) {
+	site := sitegen.New(sitegen.Config{Seed: 3, Pages: 5})
+
+	urls := site.URLs("http://127.0.0.1:8080/")
+
+	require.Equal(t, 5, len(urls))
+	assert.Equal(t, "http://127.0.0.1:8080/p/0", urls[0].String())
+}
@@ -0,0 +1,82 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+var webhookTimeout = 10 * time.Second
+
+// WebhookNotifier - POSTs alerts as JSON to the url of the sink
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookNotifier - when client is nil a cleanhttp client with a 10s timeout is used
+func NewWebhookNotifier(client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+		client.Timeout = webhookTimeout
+	}
+	return &WebhookNotifier{client: client}
+}
+
+// Notify - any response other than 2xx is an error
+func (n *WebhookNotifier) Notify(ctx context.Context, sink links.AlertSink, alert links.Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// LogNotifier - appends alerts as JSON lines to a writer, usually the alerts log file
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogNotifier ..
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+// Notify - writes the alert as a single line
+func (n *LogNotifier) Notify(ctx context.Context, sink links.AlertSink, alert links.Alert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err = n.w.Write(append(line, '\n'))
+	return err
+}
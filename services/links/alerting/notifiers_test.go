@@ -0,0 +1,79 @@
+package alerting_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/alerting"
+	"github.com/stretchr/testify/suite"
+)
+
+type notifiersTestSuite struct {
+	suite.Suite
+}
+
+func TestNotifiersTestSuite(t *testing.T) {
+	suite.Run(t, &notifiersTestSuite{})
+}
+
+func (s *notifiersTestSuite) TestWebhookNotifier_ThenAlertIsPosted() {
+	// Arrange
+	received := make(chan links.Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert links.Alert
+		_ = json.NewDecoder(r.Body).Decode(&alert)
+		s.Equal(http.MethodPost, r.Method)
+		s.Equal("application/json", r.Header.Get("Content-Type"))
+		received <- alert
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	notifier := alerting.NewWebhookNotifier(nil)
+
+	// Act
+	err := notifier.Notify(context.Background(), links.AlertSink{Type: links.AlertSinkWebhook, URL: server.URL}, links.Alert{ID: "a0", RuleID: "ar0"})
+
+	// Assert
+	s.Equal(nil, err)
+	alert := <-received
+	s.Equal("a0", alert.ID)
+	s.Equal("ar0", alert.RuleID)
+}
+
+func (s *notifiersTestSuite) TestWebhookNotifier_WhenStatusIsNotSuccessful_ThenFail() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	notifier := alerting.NewWebhookNotifier(server.Client())
+
+	// Act
+	err := notifier.Notify(context.Background(), links.AlertSink{Type: links.AlertSinkWebhook, URL: server.URL}, links.Alert{ID: "a0"})
+
+	// Assert
+	s.Equal("webhook responded with status 502", err.Error())
+}
+
+func (s *notifiersTestSuite) TestLogNotifier_ThenAlertsAreWrittenAsLines() {
+	// Arrange
+	var buf bytes.Buffer
+	notifier := alerting.NewLogNotifier(&buf)
+
+	// Act
+	firstErr := notifier.Notify(context.Background(), links.AlertSink{Type: links.AlertSinkLog}, links.Alert{ID: "a0"})
+	secondErr := notifier.Notify(context.Background(), links.AlertSink{Type: links.AlertSinkLog}, links.Alert{ID: "a1"})
+
+	// Assert
+	s.Equal(nil, firstErr)
+	s.Equal(nil, secondErr)
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	s.Len(lines, 2)
+	s.Contains(string(lines[0]), `"id":"a0"`)
+	s.Contains(string(lines[1]), `"id":"a1"`)
+}
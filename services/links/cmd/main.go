@@ -1,34 +1,100 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/alerting"
 	"github.com/Lockwarr/codefi/services/links/domain"
 	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/metrics"
 	"github.com/Lockwarr/codefi/services/links/repository"
-	"github.com/go-chi/chi/v5"
+	"github.com/Lockwarr/codefi/services/links/tracing"
+	"github.com/Lockwarr/codefi/services/links/webhooks"
 )
 
-var port = ":8080" // could be moved to cfg
+var port = ":8080"                                  // could be moved to cfg
+var alertsLogFile = "alerts.log"                    // could be moved to cfg
+var admissionLimits = domain.DefaultAdmissionLimits // could be moved to cfg
+
+// adminAPIKeyEnv - api keys are required once an admin key is set, the api is open otherwise
+const adminAPIKeyEnv = "LINKS_ADMIN_API_KEY"
+
+// tracesExporterEnv - exporter of the spans, see the tracing package for the values, tracing is off without it
+const tracesExporterEnv = "LINKS_TRACES_EXPORTER"
+
+// tracesFileEnv - file of the file exporter, traces.jsonl by default
+const tracesFileEnv = "LINKS_TRACES_FILE"
 
 func main() {
+	if err := run(); err != nil {
+		log.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+// run - serves the api until the server fails, returning makes the deferred cleanups run before main exits
+func run() error {
 	log.Println("Starting links service")
-	router := chi.NewRouter()
-	repo := repository.NewInMemoryDB()
-	scraper := scraper.NewScraper()
-	linksProcessor := domain.NewLinksProcessor(repo, scraper)
-	h := handler.NewHandler(linksProcessor)
-
-	router.Route("/api/v1/", func(r chi.Router) {
-		r.Post("/links", h.ProcessBatch)
-		r.Get("/links/{batchID}", h.GetBatch)
+	tracesFile := os.Getenv(tracesFileEnv)
+	if tracesFile == "" {
+		tracesFile = "traces.jsonl"
+	}
+	tracerProvider, shutdownTracing, err := tracing.NewTracerProvider(context.Background(), tracing.Config{
+		Exporter:    os.Getenv(tracesExporterEnv),
+		File:        tracesFile,
+		ServiceName: "links",
 	})
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing %w", err)
+	}
+	defer shutdownTracing(context.Background())
+	m := metrics.New()
+	repo := repository.NewInstrumentedRepository(repository.NewInMemoryDB(), m.ObserveRepository)
+	scraper := scraper.NewScraper(scraper.WithFetchObserver(m), scraper.WithTracerProvider(tracerProvider))
+	admission := domain.NewAdmissionController(admissionLimits)
+	m.WatchAdmission(admission)
+	callbacks := domain.NewCallbackDispatcher(repo, webhooks.NewSender(nil), domain.DefaultCallbackRetryPolicy)
+	processorOpts := []domain.ProcessorOption{
+		domain.WithCallbackDispatcher(callbacks),
+		domain.WithAdmissionController(admission),
+		domain.WithBatchObserver(m),
+		domain.WithTracerProvider(tracerProvider),
+	}
+	handlerOpts := []handler.HandlerOption{handler.WithMetrics(m), handler.WithTracerProvider(tracerProvider)}
+	if adminKey := os.Getenv(adminAPIKeyEnv); adminKey != "" {
+		apiKeys := domain.NewAPIKeyManager(repo)
+		if _, err := apiKeys.RegisterAPIKey(context.Background(), links.APIKeyRequest{Name: "admin", Admin: true}, adminKey); err != nil {
+			return fmt.Errorf("failed to register admin api key %w", err)
+		}
+		processorOpts = append(processorOpts, domain.WithQuotaLimiter(apiKeys))
+		handlerOpts = append(handlerOpts, handler.WithAPIKeys(apiKeys))
+	} else {
+		log.Println(adminAPIKeyEnv, "is not set, the api is open to everyone")
+	}
+	linksProcessor := domain.NewLinksProcessor(repo, scraper, processorOpts...)
+	alertsLog, err := os.OpenFile(alertsLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open alerts log file %w", err)
+	}
+	defer alertsLog.Close()
+	monitors := domain.NewMonitorScheduler(repo, linksProcessor,
+		domain.WithAlertNotifier(links.AlertSinkWebhook, alerting.NewWebhookNotifier(nil)),
+		domain.WithAlertNotifier(links.AlertSinkLog, alerting.NewLogNotifier(alertsLog)),
+	)
+	if err := monitors.Start(context.Background()); err != nil {
+		return fmt.Errorf("failed to start monitor scheduler %w", err)
+	}
+	h := handler.NewHandler(linksProcessor, monitors, handlerOpts...)
+	router := handler.NewRouter(h)
 
 	if err := http.ListenAndServe(port, router); err != nil {
-		log.Println(err.Error(), "failed to start http server")
-		os.Exit(1)
+		return fmt.Errorf("failed to start http server %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,24 @@
+Feature: Admission control
+
+    Background:
+        Given the links API is up and running with at most 1 queued batch
+        And the fixture website is up and running
+
+    Scenario: Batches over the limit of the service are rejected until a batch finishes
+        Given I have a urls file with:
+            """
+            {site}/slow
+            """
+        And I send a "POST" request to "/api/v1/links?async=true"
+        And I receive status 202
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 429
+        And the response has the header "Retry-After" "1"
+        And the response contains the error "server is busy: limit of 1 queued batches reached"
+        When I watch the events of the batch
+        And I have a urls file with:
+            """
+            {site}/links
+            """
+        And I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
@@ -0,0 +1,66 @@
+Feature: API keys
+
+    Background:
+        Given the links API is up and running with the admin api key "admin-key-0123456789"
+        And the fixture website is up and running
+
+    Scenario: Requests without a valid api key are rejected
+        When I send a "GET" request to "/api/v1/links"
+        Then I receive status 401
+        And the response contains the error "missing api key"
+        Given I use the api key "not-a-key-of-the-service"
+        When I send a "GET" request to "/api/v1/links"
+        Then I receive status 401
+        And the response contains the error "unknown or revoked api key"
+
+    Scenario: Client keys only see their own batches
+        Given I use the api key "admin-key-0123456789"
+        And I have a urls file with:
+            """
+            {site}/links
+            """
+        And I send a "POST" request to "/api/v1/links"
+        And I send a "POST" request to "/api/v1/keys" with:
+            """
+            {"name": "ci"}
+            """
+        And I receive status 201
+        And I use the api key "{issuedKey}"
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "GET" request to "/api/v1/links"
+        Then I receive status 200
+        And the response lists 1 batch
+        When I send a "GET" request to "/api/v1/keys"
+        Then I receive status 403
+        And the response contains the error "admin api key required"
+
+    Scenario: Batches over the daily quota of a key are rejected
+        Given I use the api key "admin-key-0123456789"
+        And I send a "POST" request to "/api/v1/keys" with:
+            """
+            {"name": "ci", "quota": {"urls_per_day": 2}}
+            """
+        And I use the api key "{issuedKey}"
+        And I have a urls file with:
+            """
+            {site}/links
+            {site}/empty
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 429
+        And the response contains the error "api key quota exceeded: limit of 2 urls per day reached"
+
+    Scenario: Revoked keys are rejected
+        Given I use the api key "admin-key-0123456789"
+        And I send a "POST" request to "/api/v1/keys" with:
+            """
+            {"name": "ci"}
+            """
+        When I send a "DELETE" request to "/api/v1/keys/{keyID}"
+        Then I receive status 204
+        Given I use the api key "{issuedKey}"
+        When I send a "GET" request to "/api/v1/links"
+        Then I receive status 401
+        And the response contains the error "unknown or revoked api key"
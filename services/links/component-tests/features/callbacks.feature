@@ -0,0 +1,30 @@
+Feature: Completion callbacks
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: A signed summary is delivered once the batch finishes
+        Given I have a urls file with:
+            """
+            {site}/links
+            """
+        And I use the callback "{site}/callback" with secret "s3cret"
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
+        And the callback of the batch is delivered after 2 attempts
+
+    Scenario: Invalid callback url
+        Given I have a urls file with:
+            """
+            {site}/links
+            """
+        And I use the callback "ftp://example.com/callback"
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 400
+        And the response contains the error "invalid callback: invalid callback url \"ftp://example.com/callback\""
+
+    Scenario: Callbacks of an unknown batch
+        When I send a "GET" request to "/api/v1/links/unknownBatch/callbacks"
+        Then I receive status 404
+        And the response contains the error "batch of results not found"
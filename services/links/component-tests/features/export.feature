@@ -0,0 +1,38 @@
+Feature: Exporting batch results
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+        And I have a urls file with:
+            """
+            {site}/links
+            {site}/error
+            {site}/missing
+            """
+        And I send a "POST" request to "/api/v1/links"
+
+    Scenario: Results are exported as CSV with the selected columns
+        When I send a "GET" request to "/api/v1/links/{batchID}?format=csv&sort=page_url&columns=page_url,success,internal_links_num,error_category,status_code"
+        Then I receive status 200
+        And the response is "text/csv" with:
+            """
+            page_url,success,internal_links_num,error_category,status_code
+            {site}/error,false,0,status,500
+            {site}/links,true,3,,
+            {site}/missing,false,0,status,404
+            """
+
+    Scenario: Failed results are exported as NDJSON
+        Given I accept "application/x-ndjson"
+        When I send a "GET" request to "/api/v1/links/{batchID}?success=false&sort=-page_url&columns=page_url,error,status_code"
+        Then I receive status 200
+        And the response is "application/x-ndjson" with:
+            """
+            {"page_url":"{site}/missing","error":"bad status code","status_code":404}
+            {"page_url":"{site}/error","error":"bad status code","status_code":500}
+            """
+
+    Scenario: Exporting an unknown column
+        When I send a "GET" request to "/api/v1/links/{batchID}?format=csv&columns=page_url,html"
+        Then I receive status 400
+        And the response contains the error "invalid query parameter columns, unknown column \"html\""
@@ -1,19 +1,54 @@
-#TODO implementation
 Feature: Internal External Links Extraction
-    
-    Scenario: Successful extraction
+
+    Background:
         Given the links API is up and running
-        When I send a "POST" request to "/api/v1/links" endpoint
-        And I have attached a correct expected file to the request
-        Then I receive "statusOK"
-        And I receive "expected results"
+        And the fixture website is up and running
+
+    Scenario: Successful extraction
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/empty
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
+        And the response contains 2 results
+        And all results are successful
+        And the result for "{site}/links" has 3 internal and 2 external links
+        And the result for "{site}/empty" has 0 internal and 0 external links
 
     Scenario: Successful retrieval of batch
-        Given the links API is up and running
-        And I send a "POST" request to "/api/v1/links" endpoint
-        And I have attached a correct expected file to the request
-        And I receive "statusOK"
-        And I receive "expected results"
-        When I send a "GET" request to "/api/v1/links/batchID" endpoint
-        Then I receive "statusOK"
-        And I receive "expected batch results"
\ No newline at end of file
+        Given I have a urls file with:
+            """
+            {site}/links
+            """
+        And I send a "POST" request to "/api/v1/links"
+        And I receive status 200
+        When I send a "GET" request to "/api/v1/links/{batchID}"
+        Then I receive status 200
+        And the response contains 1 result
+        And the result for "{site}/links" has 3 internal and 2 external links
+
+    Scenario: Redirects are followed
+        Given I have a urls file with:
+            """
+            {site}/redirect
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
+        And the result for "{site}/redirect" has 3 internal and 2 external links
+
+    Scenario: Redirects are not followed when the options forbid it
+        Given I have a urls file with:
+            """
+            {site}/redirect
+            {site}/links
+            """
+        And I use the scrape options:
+            """
+            {"redirect_policy": "none"}
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
+        And the result for "{site}/redirect" failed
+        And the result for "{site}/links" succeeded
@@ -0,0 +1,55 @@
+Feature: Invalid requests are rejected
+
+    Background:
+        Given the links API is up and running
+
+    Scenario: File with an invalid url
+        Given I have a urls file with:
+            """
+            https://www.google.com
+            invalidUrl
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 400
+        And the response contains the error "bad url at line 2 invalidUrl invalid url"
+
+    Scenario: Empty file
+        Given I have a urls file with:
+            """
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 400
+        And the response contains the error "no urls for processing"
+
+    Scenario: Missing file
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 400
+        And the response contains the error "bad file"
+
+    Scenario: Invalid scrape options
+        Given I have a urls file with:
+            """
+            https://www.google.com
+            """
+        And I use the scrape options:
+            """
+            {"internal_policy": "same_planet"}
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 400
+        And the response contains the error "invalid scrape options: unknown internal policy \"same_planet\""
+
+    Scenario: Unknown batch ID
+        When I send a "GET" request to "/api/v1/links/unknownBatch"
+        Then I receive status 404
+        And the response contains the error "batch of results not found"
+
+    Scenario: Unknown result ID
+        When I send a "GET" request to "/api/v1/results/unknownResult"
+        Then I receive status 404
+        And the response contains the error "result not found"
+
+    Scenario: Result of an unknown batch
+        When I send a "GET" request to "/api/v1/links/unknownBatch/results/unknownResult"
+        Then I receive status 404
+        And the response contains the error "batch of results not found"
@@ -0,0 +1,39 @@
+Feature: Listing batches
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: Batches are listed with their summary
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/error
+            {site}/missing
+            """
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "GET" request to "/api/v1/links"
+        Then I receive status 200
+        And the response lists 1 batch
+        And the listed batch "{batchID}" is "completed" with 3 urls, 1 successful and 2 failed
+
+    Scenario: Batches are listed page by page
+        Given I have a urls file with:
+            """
+            {site}/links
+            """
+        And I send a "POST" request to "/api/v1/links"
+        And I send a "POST" request to "/api/v1/links"
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "GET" request to "/api/v1/links?limit=2"
+        Then I receive status 200
+        And the response lists 2 batches
+        When I send a "GET" request to "/api/v1/links?limit=2&cursor={nextCursor}"
+        Then I receive status 200
+        And the response lists 1 batch
+        And the response has no next cursor
+
+    Scenario: Invalid listing parameters
+        When I send a "GET" request to "/api/v1/links?sort=name"
+        Then I receive status 400
+        And the response contains the error "invalid sort field"
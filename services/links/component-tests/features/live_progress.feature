@@ -0,0 +1,28 @@
+Feature: Live progress of a batch
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: A running batch streams its results and completion
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/slow
+            """
+        When I send a "POST" request to "/api/v1/links?async=true"
+        Then I receive status 202
+        When I watch the events of the batch
+        Then I receive status 200
+        And the stream starts with "progress", has 2 "result" events and ends with "completed"
+        And the last event contains "\"status\":\"completed\",\"url_count\":2,\"success_count\":2"
+
+    Scenario: A finished batch only streams its completion
+        Given I have a urls file with:
+            """
+            {site}/links
+            """
+        And I send a "POST" request to "/api/v1/links"
+        When I watch the events of the batch
+        Then I receive status 200
+        And the stream starts with "completed", has 1 "completed" events and ends with "completed"
@@ -0,0 +1,75 @@
+Feature: Scheduled monitors
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: Every run of a monitor is its own batch
+        When I send a "POST" request to "/api/v1/monitors" with:
+            """
+            {"name": "fixture site", "schedule": "@weekly", "urls": ["{site}/links", "{site}/error"]}
+            """
+        Then I receive status 201
+        And the monitor is named "fixture site"
+        When I send a "POST" request to "/api/v1/monitors/{monitorID}/run"
+        Then I receive status 200
+        And the response contains 2 results
+        And the result for "{site}/links" has 3 internal and 2 external links
+        When I send a "POST" request to "/api/v1/monitors/{monitorID}/run"
+        And I send a "GET" request to "/api/v1/links?monitor_id={monitorID}"
+        Then I receive status 200
+        And the response lists 2 batches
+
+    Scenario: A monitor is paused and deleted
+        Given I send a "POST" request to "/api/v1/monitors" with:
+            """
+            {"name": "fixture site", "schedule": "0 9 * * 1", "urls": ["{site}/links"]}
+            """
+        When I send a "PUT" request to "/api/v1/monitors/{monitorID}" with:
+            """
+            {"name": "paused fixture site", "schedule": "0 9 * * 1", "urls": ["{site}/links"], "paused": true}
+            """
+        Then I receive status 200
+        And the monitor is named "paused fixture site" and paused
+        When I send a "DELETE" request to "/api/v1/monitors/{monitorID}"
+        Then I receive status 204
+        When I send a "GET" request to "/api/v1/monitors/{monitorID}"
+        Then I receive status 404
+        And the response contains the error "monitor not found"
+
+    Scenario: Invalid schedule
+        When I send a "POST" request to "/api/v1/monitors" with:
+            """
+            {"name": "fixture site", "schedule": "every monday", "urls": ["{site}/links"]}
+            """
+        Then I receive status 400
+        And the response contains the error "invalid monitor: invalid schedule \"every monday\""
+
+    Scenario: A page failing after a successful run raises an alert
+        Given I send a "POST" request to "/api/v1/monitors" with:
+            """
+            {"name": "fixture site", "schedule": "@weekly", "urls": ["{site}/links", "{site}/fading"]}
+            """
+        When I send a "POST" request to "/api/v1/monitors/{monitorID}/rules" with:
+            """
+            {"name": "page down", "condition": "success_to_failure", "sinks": [{"type": "log"}]}
+            """
+        Then I receive status 201
+        When I send a "POST" request to "/api/v1/monitors/{monitorID}/run"
+        And I send a "POST" request to "/api/v1/monitors/{monitorID}/run"
+        And I send a "GET" request to "/api/v1/alerts?monitor_id={monitorID}"
+        Then I receive status 200
+        And the response lists 1 alert
+        And the alert for "{site}/fading" is "success_to_failure" and was delivered
+
+    Scenario: Invalid alert rule
+        Given I send a "POST" request to "/api/v1/monitors" with:
+            """
+            {"name": "fixture site", "schedule": "@weekly", "urls": ["{site}/links"]}
+            """
+        When I send a "POST" request to "/api/v1/monitors/{monitorID}/rules" with:
+            """
+            {"name": "more links", "condition": "external_links_change"}
+            """
+        Then I receive status 400
+        And the response contains the error "invalid alert rule: threshold_percent is required for external_links_change"
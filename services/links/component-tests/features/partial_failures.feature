@@ -0,0 +1,75 @@
+Feature: Batches with partial failures
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: Failing pages don't fail the whole batch
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/error
+            {site}/missing
+            {site}/slow
+            """
+        And I use the scrape options:
+            """
+            {"timeout_ms": 200}
+            """
+        When I send a "POST" request to "/api/v1/links"
+        Then I receive status 200
+        And the response contains 4 results
+        And the result for "{site}/links" succeeded
+        And the result for "{site}/error" failed
+        And the result for "{site}/missing" failed
+        And the result for "{site}/slow" failed
+
+    Scenario: Failed results are kept in the batch
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/error
+            """
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "GET" request to "/api/v1/links/{batchID}"
+        Then I receive status 200
+        And the response contains 2 results
+        And the result for "{site}/error" failed
+
+    Scenario: Failed results are retried in place
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/flaky
+            {site}/error
+            """
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "POST" request to "/api/v1/links/{batchID}/retry"
+        Then I receive status 200
+        And the retried batch is "completed" with 2 successful and 1 failed
+        And the response contains 2 results
+        And the result for "{site}/flaky" succeeded
+        And the result for "{site}/flaky" has 1 previous attempt
+        And the result for "{site}/error" failed
+        When I send a "GET" request to "/api/v1/links/{batchID}"
+        Then the response contains 3 results
+        And the result for "{site}/flaky" has 1 internal and 0 external links
+
+    Scenario: Only the selected results are retried
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/error
+            """
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "POST" request to "/api/v1/links/{batchID}/retry" with:
+            """
+            {"result_ids": ["unknownResult"]}
+            """
+        Then I receive status 404
+        And the response contains the error "result not found"
+
+    Scenario: Retrying an unknown batch
+        When I send a "POST" request to "/api/v1/links/unknownBatch/retry"
+        Then I receive status 404
+        And the response contains the error "batch of results not found"
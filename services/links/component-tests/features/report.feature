@@ -0,0 +1,30 @@
+Feature: HTML report of a batch
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: A finished batch is reported as a standalone page
+        Given I have a urls file with:
+            """
+            {site}/links
+            {site}/error
+            {site}/missing
+            """
+        And I use the scrape options:
+            """
+            {"collect_links": true}
+            """
+        And I send a "POST" request to "/api/v1/links"
+        When I send a "GET" request to "/api/v1/links/{batchID}/report"
+        Then I receive status 200
+        And the response body contains "<title>Links report {batchID}</title>"
+        And the response body contains "<td class=\"url\">{site}/links</td><td class=\"number\">2</td><td class=\"number\">3</td>"
+        And the response body contains "<td>status</td><td class=\"number\">2</td>"
+        And the response body contains "<td>cdn.example.org</td>"
+        And the response body contains "<td>external.example.com</td>"
+
+    Scenario: Reporting an unknown batch
+        When I send a "GET" request to "/api/v1/links/unknownBatch/report"
+        Then I receive status 404
+        And the response contains the error "batch of results not found"
@@ -0,0 +1,43 @@
+Feature: Versioned v2 api with a consistent response envelope
+
+    Background:
+        Given the links API is up and running
+        And the fixture website is up and running
+
+    Scenario: Bad urls are typed errors with their line
+        Given I have a urls file with:
+            """
+            {site}/links
+            invalidUrl
+            """
+        When I send a "POST" request to "/api/v2/links"
+        Then I receive status 400
+        And the response body contains "\"errors\":[{\"code\":\"invalid_url\",\"message\":\"bad url at line 2 invalidUrl invalid url\",\"field\":\"urlsFile\",\"line\":2}]"
+        And the response body contains "\"request_id\":\""
+
+    Scenario: Invalid query parameters name the parameter
+        When I send a "GET" request to "/api/v2/links?status=done"
+        Then I receive status 400
+        And the response body contains "\"code\":\"invalid_query_parameter\""
+        And the response body contains "\"field\":\"status\""
+
+    Scenario: Batches are listed page by page with pagination metadata
+        Given I have a urls file with:
+            """
+            {site}/links
+            """
+        And I send a "POST" request to "/api/v2/links"
+        And I send a "POST" request to "/api/v2/links"
+        And I send a "POST" request to "/api/v2/links"
+        When I send a "GET" request to "/api/v2/links?limit=2"
+        Then I receive status 200
+        And the response body contains "\"has_more\":true"
+        When I send a "GET" request to "/api/v2/links?limit=2&cursor={nextCursor}"
+        Then I receive status 200
+        And the response body contains "\"pagination\":{\"limit\":2,\"has_more\":false}"
+        And the response body contains "\"url_count\":1"
+
+    Scenario: Unknown batches are typed errors
+        When I send a "GET" request to "/api/v2/links/unknown"
+        Then I receive status 404
+        And the response body contains "\"code\":\"batch_not_found\""
@@ -0,0 +1,616 @@
+//go:build component
+// +build component
+
+package component_tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/alerting"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/Lockwarr/codefi/services/links/webhooks"
+	"github.com/cucumber/godog"
+)
+
+// TestFeatures - runs the scenarios from the features folder against the real router
+func TestFeatures(t *testing.T) {
+	suite := godog.TestSuite{
+		ScenarioInitializer: initializeScenario,
+		Options: &godog.Options{
+			Format:   "pretty",
+			Paths:    []string{"features"},
+			Strict:   true,
+			TestingT: t,
+		},
+	}
+
+	if suite.Run() != 0 {
+		t.Fatal("non-zero status returned, failed to run feature tests")
+	}
+}
+
+// result - links.Result as seen by an api client, the error is kept raw as its shape depends on the error
+type result struct {
+	ID               string            `json:"id"`
+	BatchID          string            `json:"batch_id"`
+	PageURL          string            `json:"page_url"`
+	InternalLinksNum uint              `json:"internal_links_num"`
+	ExternalLinksNum uint              `json:"external_links_num"`
+	Success          bool              `json:"success"`
+	Error            json.RawMessage   `json:"error"`
+	Attempts         []json.RawMessage `json:"attempts"`
+}
+
+// batch - links.Batch summary as seen by an api client
+type batch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	URLCount     int    `json:"url_count"`
+	SuccessCount int    `json:"success_count"`
+	FailureCount int    `json:"failure_count"`
+}
+
+// alert - links.Alert as seen by an api client
+type alert struct {
+	RuleID     string `json:"rule_id"`
+	PageURL    string `json:"page_url"`
+	Condition  string `json:"condition"`
+	Deliveries []struct {
+		Error string `json:"error"`
+	} `json:"deliveries"`
+}
+
+// callbackAttempt - links.CallbackAttempt as seen by an api client
+type callbackAttempt struct {
+	Attempt    int  `json:"attempt"`
+	StatusCode int  `json:"status_code"`
+	Success    bool `json:"success"`
+}
+
+// envelope - links.Envelope of the v2 api as seen by an api client
+type envelope struct {
+	Pagination struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"pagination"`
+}
+
+type response struct {
+	Errors []string `json:"errors"`
+	Data   struct {
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		Paused     bool   `json:"paused"`
+		Results    []result
+		Batch      batch
+		Batches    []batch
+		NextCursor string
+		Alerts     []alert
+		Attempts   []callbackAttempt
+		Key        string `json:"key"`
+	} `json:"data"`
+}
+
+// scenario - state shared between the steps of one scenario
+type scenario struct {
+	api            *httptest.Server
+	monitors       *domain.MonitorScheduler
+	callbacks      *domain.CallbackDispatcher
+	site           *httptest.Server
+	urlsFile       *string
+	options        string
+	callbackURL    string
+	callbackSecret string
+	batchID        string
+	monitorID      string
+	apiKey         string // api key the requests are sent with
+	issuedKey      string // last api key issued by the api
+	keyID          string // id of the last api key issued by the api
+	nextCursor     string
+	status         int
+	response       response
+	accept         string
+	body           string      // raw body of responses other than JSON
+	header         http.Header // headers of the last response
+	events         []string    // event types of the watched stream
+	lastEvent      string      // data of the last event of the watched stream
+}
+
+func initializeScenario(ctx *godog.ScenarioContext) {
+	s := &scenario{}
+
+	ctx.After(func(ctx context.Context, sc *godog.Scenario, err error) (context.Context, error) {
+		if s.api != nil {
+			s.api.Close()
+		}
+		if s.monitors != nil {
+			s.monitors.Stop()
+		}
+		if s.callbacks != nil {
+			s.callbacks.Wait()
+		}
+		if s.site != nil {
+			s.site.Close()
+		}
+		return ctx, nil
+	})
+
+	ctx.Step(`^the links API is up and running$`, s.theLinksAPIIsUpAndRunning)
+	ctx.Step(`^the links API is up and running with the admin api key "([^"]*)"$`, s.theLinksAPIIsUpAndRunningWithTheAdminAPIKey)
+	ctx.Step(`^I use the api key "([^"]*)"$`, s.iUseTheAPIKey)
+	ctx.Step(`^the links API is up and running with at most (\d+) queued batch(?:es)?$`, s.theLinksAPIIsUpAndRunningWithAtMostQueuedBatches)
+	ctx.Step(`^the fixture website is up and running$`, s.theFixtureWebsiteIsUpAndRunning)
+	ctx.Step(`^I have a urls file with:$`, s.iHaveAUrlsFileWith)
+	ctx.Step(`^I use the scrape options:$`, s.iUseTheScrapeOptions)
+	ctx.Step(`^I use the callback "([^"]*)"(?: with secret "([^"]*)")?$`, s.iUseTheCallback)
+	ctx.Step(`^I accept "([^"]*)"$`, s.iAccept)
+	ctx.Step(`^I send a "(GET|POST|PUT|DELETE)" request to "([^"]*)"$`, s.iSendARequestTo)
+	ctx.Step(`^I send a "(GET|POST|PUT|DELETE)" request to "([^"]*)" with:$`, s.iSendARequestToWith)
+	ctx.Step(`^I receive status (\d+)$`, s.iReceiveStatus)
+	ctx.Step(`^the response contains (\d+) results?$`, s.theResponseContainsResults)
+	ctx.Step(`^all results are successful$`, s.allResultsAreSuccessful)
+	ctx.Step(`^the result for "([^"]*)" has (\d+) internal and (\d+) external links$`, s.theResultHasLinks)
+	ctx.Step(`^the result for "([^"]*)" (succeeded|failed)$`, s.theResultSucceededOrFailed)
+	ctx.Step(`^the response contains the error "(.*)"$`, s.theResponseContainsTheError)
+	ctx.Step(`^the response lists (\d+) batch(?:es)?$`, s.theResponseListsBatches)
+	ctx.Step(`^the listed batch "([^"]*)" is "([^"]*)" with (\d+) urls, (\d+) successful and (\d+) failed$`, s.theListedBatchIs)
+	ctx.Step(`^the response has no next cursor$`, s.theResponseHasNoNextCursor)
+	ctx.Step(`^the retried batch is "([^"]*)" with (\d+) successful and (\d+) failed$`, s.theRetriedBatchIs)
+	ctx.Step(`^the result for "([^"]*)" has (\d+) previous attempts?$`, s.theResultHasPreviousAttempts)
+	ctx.Step(`^the monitor is named "([^"]*)"( and paused)?$`, s.theMonitorIsNamed)
+	ctx.Step(`^the response lists (\d+) alerts?$`, s.theResponseListsAlerts)
+	ctx.Step(`^the alert for "([^"]*)" is "([^"]*)" and was delivered$`, s.theAlertIsAndWasDelivered)
+	ctx.Step(`^the callback of the batch is delivered after (\d+) attempts?$`, s.theCallbackIsDeliveredAfterAttempts)
+	ctx.Step(`^I watch the events of the batch$`, s.iWatchTheEventsOfTheBatch)
+	ctx.Step(`^the stream starts with "([^"]*)", has (\d+) "([^"]*)" events and ends with "([^"]*)"$`, s.theStreamHasEvents)
+	ctx.Step(`^the last event contains "(.*)"$`, s.theLastEventContains)
+	ctx.Step(`^the response is "([^"]*)" with:$`, s.theResponseIsWith)
+	ctx.Step(`^the response body contains "(.*)"$`, s.theResponseBodyContains)
+	ctx.Step(`^the response has the header "([^"]*)" "([^"]*)"$`, s.theResponseHasTheHeader)
+}
+
+func (s *scenario) theLinksAPIIsUpAndRunning() error {
+	return s.startAPI(repository.NewInMemoryDB(), nil)
+}
+
+func (s *scenario) theLinksAPIIsUpAndRunningWithAtMostQueuedBatches(batches int) error {
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{MaxQueuedBatches: batches, RetryAfter: time.Second})
+	return s.startAPI(repository.NewInMemoryDB(), nil, domain.WithAdmissionController(admission))
+}
+
+func (s *scenario) theLinksAPIIsUpAndRunningWithTheAdminAPIKey(key string) error {
+	repo := repository.NewInMemoryDB()
+	apiKeys := domain.NewAPIKeyManager(repo)
+	if _, err := apiKeys.RegisterAPIKey(context.Background(), links.APIKeyRequest{Name: "admin", Admin: true}, key); err != nil {
+		return err
+	}
+	return s.startAPI(repo, apiKeys)
+}
+
+// startAPI - serves the router backed by repo, requests must be authenticated when apiKeys is set
+func (s *scenario) startAPI(repo links.Repository, apiKeys *domain.APIKeyManager, opts ...domain.ProcessorOption) error {
+	s.callbacks = domain.NewCallbackDispatcher(repo, webhooks.NewSender(nil), domain.CallbackRetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond})
+	processorOpts := append([]domain.ProcessorOption{domain.WithCallbackDispatcher(s.callbacks)}, opts...)
+	var handlerOpts []handler.HandlerOption
+	if apiKeys != nil {
+		processorOpts = append(processorOpts, domain.WithQuotaLimiter(apiKeys))
+		handlerOpts = append(handlerOpts, handler.WithAPIKeys(apiKeys))
+	}
+	processor := domain.NewLinksProcessor(repo, scraper.NewScraper(), processorOpts...)
+	s.monitors = domain.NewMonitorScheduler(repo, processor,
+		domain.WithAlertNotifier(links.AlertSinkLog, alerting.NewLogNotifier(io.Discard)))
+	if err := s.monitors.Start(context.Background()); err != nil {
+		return err
+	}
+	s.api = httptest.NewServer(handler.NewRouter(handler.NewHandler(processor, s.monitors, handlerOpts...)))
+	return nil
+}
+
+func (s *scenario) theFixtureWebsiteIsUpAndRunning() error {
+	s.site = newFixtureSite()
+	return nil
+}
+
+func (s *scenario) iHaveAUrlsFileWith(content *godog.DocString) error {
+	urlsFile := s.expand(content.Content)
+	s.urlsFile = &urlsFile
+	return nil
+}
+
+func (s *scenario) iUseTheScrapeOptions(content *godog.DocString) error {
+	s.options = content.Content
+	return nil
+}
+
+func (s *scenario) iUseTheCallback(callbackURL, secret string) error {
+	s.callbackURL = s.expand(callbackURL)
+	s.callbackSecret = secret
+	return nil
+}
+
+func (s *scenario) iUseTheAPIKey(key string) error {
+	s.apiKey = s.expand(key)
+	return nil
+}
+
+func (s *scenario) iSendARequestTo(method, path string) error {
+	var body io.Reader
+	contentType := ""
+
+	submitsBatch := strings.HasPrefix(path, "/api/v1/links") || strings.HasPrefix(path, "/api/v2/links")
+	if method == http.MethodPost && submitsBatch && !strings.Contains(path, "{batchID}") && s.urlsFile != nil {
+		buf := &bytes.Buffer{}
+		writer := multipart.NewWriter(buf)
+		fields := map[string]string{"options": s.options, "callback_url": s.callbackURL, "callback_secret": s.callbackSecret}
+		for name, value := range fields {
+			if value == "" {
+				continue
+			}
+			if err := writer.WriteField(name, value); err != nil {
+				return err
+			}
+		}
+		fw, err := writer.CreateFormFile("urlsFile", "urls.txt")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, *s.urlsFile); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		body = buf
+		contentType = writer.FormDataContentType()
+	}
+
+	return s.send(method, path, body, contentType)
+}
+
+func (s *scenario) iSendARequestToWith(method, path string, content *godog.DocString) error {
+	return s.send(method, path, strings.NewReader(s.expand(content.Content)), "application/json")
+}
+
+func (s *scenario) send(method, path string, body io.Reader, contentType string) error {
+	req, err := http.NewRequest(method, s.api.URL+s.expand(path), body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.accept != "" {
+		req.Header.Set("Accept", s.accept)
+	}
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	s.status = resp.StatusCode
+	s.header = resp.Header
+	s.response = response{}
+	s.body = ""
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		body, err := io.ReadAll(resp.Body)
+		s.body = string(body)
+		return err
+	}
+	if strings.HasPrefix(path, "/api/v2/") {
+		return s.readEnvelope(resp.Body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&s.response); err != nil {
+		return fmt.Errorf("failed to decode response %w", err)
+	}
+	if method == http.MethodPost && len(s.response.Data.Results) > 0 {
+		s.batchID = s.response.Data.Results[0].BatchID
+	}
+	if method == http.MethodPost && strings.HasPrefix(path, "/api/v1/links?async=true") && s.response.Data.ID != "" {
+		s.batchID = s.response.Data.ID
+	}
+	if method == http.MethodPost && path == "/api/v1/monitors" && s.response.Data.ID != "" {
+		s.monitorID = s.response.Data.ID
+	}
+	if method == http.MethodPost && path == "/api/v1/keys" && s.response.Data.Key != "" {
+		s.keyID = s.response.Data.ID
+		s.issuedKey = s.response.Data.Key
+	}
+	s.nextCursor = s.response.Data.NextCursor
+	return nil
+}
+
+// readEnvelope - keeps the raw body of a v2 response for the body steps and the cursor of its next page
+func (s *scenario) readEnvelope(r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.body = string(body)
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("failed to decode response %w", err)
+	}
+	s.nextCursor = env.Pagination.NextCursor
+	return nil
+}
+
+func (s *scenario) iAccept(mediaType string) error {
+	s.accept = mediaType
+	return nil
+}
+
+func (s *scenario) iReceiveStatus(status int) error {
+	if s.status != status {
+		return fmt.Errorf("expected status %d, got %d with errors %v", status, s.status, s.response.Errors)
+	}
+	return nil
+}
+
+func (s *scenario) theResponseContainsResults(count int) error {
+	if len(s.response.Data.Results) != count {
+		return fmt.Errorf("expected %d results, got %d", count, len(s.response.Data.Results))
+	}
+	return nil
+}
+
+func (s *scenario) allResultsAreSuccessful() error {
+	for _, res := range s.response.Data.Results {
+		if !res.Success {
+			return fmt.Errorf("result for %s failed with %s", res.PageURL, res.Error)
+		}
+	}
+	return nil
+}
+
+func (s *scenario) theResultHasLinks(pageURL string, internal, external int) error {
+	res, err := s.resultFor(pageURL)
+	if err != nil {
+		return err
+	}
+	if res.InternalLinksNum != uint(internal) || res.ExternalLinksNum != uint(external) {
+		return fmt.Errorf("expected %d internal and %d external links for %s, got %d and %d",
+			internal, external, res.PageURL, res.InternalLinksNum, res.ExternalLinksNum)
+	}
+	return nil
+}
+
+func (s *scenario) theResultSucceededOrFailed(pageURL, outcome string) error {
+	res, err := s.resultFor(pageURL)
+	if err != nil {
+		return err
+	}
+	if res.Success != (outcome == "succeeded") {
+		return fmt.Errorf("expected result for %s to have %s, success is %v", res.PageURL, outcome, res.Success)
+	}
+	return nil
+}
+
+func (s *scenario) theResponseContainsTheError(message string) error {
+	message = strings.ReplaceAll(message, `\"`, `"`)
+	for _, respErr := range s.response.Errors {
+		if respErr == message {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected error %q, got %v", message, s.response.Errors)
+}
+
+func (s *scenario) theResponseListsBatches(count int) error {
+	if len(s.response.Data.Batches) != count {
+		return fmt.Errorf("expected %d batches, got %d", count, len(s.response.Data.Batches))
+	}
+	return nil
+}
+
+func (s *scenario) theListedBatchIs(batchID, status string, urls, successful, failed int) error {
+	batchID = s.expand(batchID)
+	for _, b := range s.response.Data.Batches {
+		if b.ID != batchID {
+			continue
+		}
+		if b.Status != status || b.URLCount != urls || b.SuccessCount != successful || b.FailureCount != failed {
+			return fmt.Errorf("unexpected batch summary %+v", b)
+		}
+		return nil
+	}
+	return fmt.Errorf("batch %s is not listed", batchID)
+}
+
+func (s *scenario) theResponseHasNoNextCursor() error {
+	if s.response.Data.NextCursor != "" {
+		return fmt.Errorf("expected the last page, got next cursor %s", s.response.Data.NextCursor)
+	}
+	return nil
+}
+
+func (s *scenario) theRetriedBatchIs(status string, successful, failed int) error {
+	got := s.response.Data.Batch
+	if got.Status != status || got.SuccessCount != successful || got.FailureCount != failed {
+		return fmt.Errorf("expected %s batch with %d successful and %d failed, got %+v", status, successful, failed, got)
+	}
+	return nil
+}
+
+func (s *scenario) theResultHasPreviousAttempts(pageURL string, attempts int) error {
+	res, err := s.resultFor(pageURL)
+	if err != nil {
+		return err
+	}
+	if len(res.Attempts) != attempts {
+		return fmt.Errorf("expected %d previous attempts for %s, got %d", attempts, pageURL, len(res.Attempts))
+	}
+	return nil
+}
+
+func (s *scenario) theMonitorIsNamed(name, paused string) error {
+	if s.response.Data.Name != name || s.response.Data.Paused != (paused != "") {
+		return fmt.Errorf("expected monitor %q paused %t, got %q paused %t", name, paused != "", s.response.Data.Name, s.response.Data.Paused)
+	}
+	return nil
+}
+
+func (s *scenario) theResponseListsAlerts(count int) error {
+	if len(s.response.Data.Alerts) != count {
+		return fmt.Errorf("expected %d alerts, got %d", count, len(s.response.Data.Alerts))
+	}
+	return nil
+}
+
+func (s *scenario) theAlertIsAndWasDelivered(pageURL, condition string) error {
+	pageURL = s.expand(pageURL)
+	for _, a := range s.response.Data.Alerts {
+		if a.PageURL != pageURL {
+			continue
+		}
+		if a.Condition != condition {
+			return fmt.Errorf("expected alert for %s to be %q, got %q", pageURL, condition, a.Condition)
+		}
+		for _, delivery := range a.Deliveries {
+			if delivery.Error != "" {
+				return fmt.Errorf("expected alert for %s to be delivered, got %q", pageURL, delivery.Error)
+			}
+		}
+		if len(a.Deliveries) == 0 {
+			return fmt.Errorf("expected alert for %s to be delivered to its sinks", pageURL)
+		}
+		return nil
+	}
+	return fmt.Errorf("no alert for %s", pageURL)
+}
+
+// theCallbackIsDeliveredAfterAttempts - callbacks are delivered in the background so the attempts are polled
+func (s *scenario) theCallbackIsDeliveredAfterAttempts(count int) error {
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if err := s.send(http.MethodGet, "/api/v1/links/{batchID}/callbacks", nil, ""); err != nil {
+			return err
+		}
+		attempts := s.response.Data.Attempts
+		if len(attempts) > 0 && attempts[len(attempts)-1].Success {
+			if len(attempts) != count {
+				return fmt.Errorf("expected the callback to be delivered after %d attempts, got %d", count, len(attempts))
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("callback wasn't delivered, got %d attempts", len(attempts))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// iWatchTheEventsOfTheBatch - reads the Server-Sent Events of the batch until the stream ends
+func (s *scenario) iWatchTheEventsOfTheBatch() error {
+	resp, err := http.Get(s.api.URL + s.expand("/api/v1/links/{batchID}/events"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	s.status = resp.StatusCode
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		return fmt.Errorf("expected an event stream, got %q", contentType)
+	}
+
+	s.events = nil
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			s.events = append(s.events, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			s.lastEvent = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *scenario) theStreamHasEvents(first string, count int, eventType, last string) error {
+	if len(s.events) == 0 || s.events[0] != first || s.events[len(s.events)-1] != last {
+		return fmt.Errorf("expected the stream to start with %s and end with %s, got %v", first, last, s.events)
+	}
+	found := 0
+	for _, event := range s.events {
+		if event == eventType {
+			found++
+		}
+	}
+	if found != count {
+		return fmt.Errorf("expected %d %s events, got %v", count, eventType, s.events)
+	}
+	return nil
+}
+
+func (s *scenario) theLastEventContains(text string) error {
+	if !strings.Contains(s.lastEvent, strings.ReplaceAll(text, `\"`, `"`)) {
+		return fmt.Errorf("expected the last event to contain %s, got %s", text, s.lastEvent)
+	}
+	return nil
+}
+
+func (s *scenario) theResponseIsWith(contentType string, content *godog.DocString) error {
+	expected := s.expand(content.Content) + "\n"
+	if s.body != expected {
+		return fmt.Errorf("expected %s response:\n%s\ngot:\n%s", contentType, expected, s.body)
+	}
+	return nil
+}
+
+func (s *scenario) theResponseBodyContains(text string) error {
+	expected := s.expand(strings.ReplaceAll(text, `\"`, `"`))
+	if !strings.Contains(s.body, expected) {
+		return fmt.Errorf("expected the response body to contain %s", expected)
+	}
+	return nil
+}
+
+func (s *scenario) theResponseHasTheHeader(name, value string) error {
+	if actual := s.header.Get(name); actual != value {
+		return fmt.Errorf("expected header %s to be %q but it is %q", name, value, actual)
+	}
+	return nil
+}
+
+func (s *scenario) resultFor(pageURL string) (result, error) {
+	pageURL = s.expand(pageURL)
+	for _, res := range s.response.Data.Results {
+		if res.PageURL == pageURL {
+			return res, nil
+		}
+	}
+	return result{}, fmt.Errorf("no result for %s", pageURL)
+}
+
+// expand - replaces the {site}, {batchID} and {nextCursor} placeholders used in the feature files
+func (s *scenario) expand(text string) string {
+	replacements := []string{"{batchID}", s.batchID, "{nextCursor}", s.nextCursor, "{monitorID}", s.monitorID,
+		"{keyID}", s.keyID, "{issuedKey}", s.issuedKey}
+	if s.site != nil {
+		replacements = append(replacements, "{site}", s.site.URL)
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
+}
@@ -0,0 +1,93 @@
+//go:build component
+// +build component
+
+package component_tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links/webhooks"
+)
+
+// newFixtureSite - in-process website with known link counts, redirects, errors and slow pages
+// which the feature scenarios scrape instead of the real internet.
+//
+//	/links    - 3 internal and 2 external links
+//	/empty    - no links
+//	/redirect - redirects to /links
+//	/error    - 500 status code
+//	/slow     - answers after 2 seconds
+//	/flaky    - 503 status code on the first request, 1 internal link afterwards
+//	/fading   - 1 internal link on the first request, 500 status code afterwards
+//	/callback - accepts batch callbacks signed with the "s3cret" secret, the first one fails with 503
+//
+// Every other path is a 404.
+func newFixtureSite() *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/links", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body>
+			<a href="/a">a</a>
+			<a href="b">b</a>
+			<a href="%s/c">c</a>
+			<a href="https://external.example.com/">d</a>
+			<a href="//cdn.example.org/e">e</a>
+		</body></html>`, server.URL)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>nothing to see here</p></body></html>`)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/links", http.StatusFound)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+			fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+		case <-r.Context().Done():
+		}
+	})
+
+	var flakyRequests int32
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flakyRequests, 1) == 1 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+	})
+
+	var fadingRequests int32
+	mux.HandleFunc("/fading", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fadingRequests, 1) > 1 {
+			http.Error(w, "gone", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+	})
+
+	var callbackRequests int32
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(webhooks.SignatureHeader) != webhooks.Sign("s3cret", body) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if atomic.AddInt32(&callbackRequests, 1) == 1 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return server
+}
@@ -14,7 +14,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"syscall"
 	"testing"
 
 	"github.com/Lockwarr/codefi/pkg/scraper"
@@ -32,6 +31,7 @@ type e2eTestSuite struct {
 	processor links.Processor
 	repo      links.Repository
 	listener  net.Listener
+	server    *http.Server
 	router    *chi.Mux
 }
 
@@ -42,31 +42,25 @@ func TestE2ETestSuite(t *testing.T) {
 func (s *e2eTestSuite) SetupSuite() {
 	// Arrange
 	var err error
-	s.router = chi.NewRouter()
 	s.listener, err = net.Listen("tcp", "localhost:0")
 	if err != nil {
 		s.T().Fatal(err)
 	}
-	srv := &http.Server{Handler: s.router}
-
 	s.repo = repository.NewInMemoryDB()
-	s.scraper = scraper.NewScraper()
+	// run against the recorded responses, SCRAPER_RECORD=1 records them again
+	s.scraper = scraper.NewScraper(scraper.WithFixtures("testdata/recordings"))
 	s.processor = domain.NewLinksProcessor(s.repo, s.scraper)
-	h := handler.NewHandler(s.processor)
-
-	s.router.Route("/api/v1/", func(r chi.Router) {
-		r.Post("/links", h.ProcessBatch)
-		r.Get("/links/{batchID}", h.GetBatch)
-	})
+	h := handler.NewHandler(s.processor, domain.NewMonitorScheduler(s.repo, s.processor))
+	s.router = handler.NewRouter(h)
+	s.server = &http.Server{Handler: s.router}
 
 	go func() {
-		srv.Serve(s.listener)
+		s.server.Serve(s.listener)
 	}()
 }
 
 func (s *e2eTestSuite) TearDownSuite() {
-	p, _ := os.FindProcess(syscall.Getpid())
-	p.Signal(syscall.SIGINT)
+	s.server.Close()
 }
 
 func (s *e2eTestSuite) SetupTest() {
@@ -77,7 +71,7 @@ func (s *e2eTestSuite) TearDownTest() {
 
 func (s *e2eTestSuite) Test_EndToEnd_SuccessfulExtraction() {
 	// Arrange
-	req := createRequestWithAttachedFile("POST", "http://"+s.listener.Addr().String()+"/api/v1/links", `testdata\goodUrls.txt`)
+	req := createRequestWithAttachedFile("POST", "http://"+s.listener.Addr().String()+"/api/v1/links", `testdata/goodUrls.txt`)
 
 	// Act
 	resp := httptest.NewRecorder()
@@ -97,11 +91,20 @@ func (s *e2eTestSuite) Test_EndToEnd_SuccessfulExtraction() {
 	s.Equal(2, len(results.Results))
 	s.Equal(true, results.Results[0].Success)
 	s.Equal(true, results.Results[1].Success)
+
+	counts := map[string][2]uint{}
+	for _, result := range results.Results {
+		counts[result.PageURL] = [2]uint{result.InternalLinksNum, result.ExternalLinksNum}
+	}
+	s.Equal(map[string][2]uint{
+		"https://www.google.com":   {6, 13},
+		"https://www.facebook.com": {27, 20},
+	}, counts)
 }
 
 func (s *e2eTestSuite) Test_EndToEnd_BadUrls_ThenFail() {
 	// Arrange
-	req := createRequestWithAttachedFile("POST", "http://"+s.listener.Addr().String()+"/api/v1/links", `testdata\badUrls.txt`)
+	req := createRequestWithAttachedFile("POST", "http://"+s.listener.Addr().String()+"/api/v1/links", `testdata/badUrls.txt`)
 
 	// Act
 	resp := httptest.NewRecorder()
@@ -1,9 +1,68 @@
 package links
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Processor
 type Processor interface {
 	ProcessBatch(ctx context.Context, req ProcessBatchRequest) ([]Result, error)
-	GetBatch(ctx context.Context, req GetBatchRequest) ([]Result, error)
+	StartBatch(ctx context.Context, req ProcessBatchRequest) (Batch, error)
+	WatchBatch(ctx context.Context, batchID string) (<-chan BatchEvent, error)
+	GetBatch(ctx context.Context, req GetBatchRequest) (GetBatchResponse, error)
+	ExportBatch(ctx context.Context, req GetBatchRequest, write func(Result) error) error
+	ListBatches(ctx context.Context, req ListBatchesRequest) (ListBatchesResponse, error)
+	GetResult(ctx context.Context, req GetResultRequest) (Result, error)
+	RetryBatch(ctx context.Context, req RetryBatchRequest) (RetryBatchResponse, error)
+	DiffBatches(ctx context.Context, req DiffBatchesRequest) (BatchDiff, error)
+	ReportBatch(ctx context.Context, batchID string) (BatchReport, error)
+	ListCallbackAttempts(ctx context.Context, batchID string) (ListCallbackAttemptsResponse, error)
+}
+
+// MonitorService - scheduled monitors which process their urls again on every run
+type MonitorService interface {
+	CreateMonitor(ctx context.Context, req MonitorRequest) (Monitor, error)
+	GetMonitor(ctx context.Context, monitorID string) (Monitor, error)
+	UpdateMonitor(ctx context.Context, req MonitorRequest) (Monitor, error)
+	DeleteMonitor(ctx context.Context, monitorID string) error
+	ListMonitors(ctx context.Context) (ListMonitorsResponse, error)
+	RunMonitor(ctx context.Context, monitorID string) ([]Result, error)
+	CreateAlertRule(ctx context.Context, req AlertRuleRequest) (AlertRule, error)
+	ListAlertRules(ctx context.Context, monitorID string) (ListAlertRulesResponse, error)
+	DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error
+	ListAlerts(ctx context.Context, query AlertQuery) (ListAlertsResponse, error)
+}
+
+// AlertNotifier - delivers alerts to one type of sink
+type AlertNotifier interface {
+	Notify(ctx context.Context, sink AlertSink, alert Alert) error
+}
+
+// CallbackSender - POSTs a callback event to the callback url, the status code is returned
+// whenever the callback url responded
+type CallbackSender interface {
+	Send(ctx context.Context, callback Callback, event CallbackEvent) (int, error)
+}
+
+// APIKeyService - issues, revokes and authenticates the api keys of the clients
+type APIKeyService interface {
+	IssueAPIKey(ctx context.Context, req APIKeyRequest) (IssuedAPIKey, error)
+	ListAPIKeys(ctx context.Context) (ListAPIKeysResponse, error)
+	RevokeAPIKey(ctx context.Context, keyID string) error
+	Authenticate(ctx context.Context, key string) (APIKey, error)
+}
+
+// QuotaLimiter - enforces the quotas of api keys on the batches they create. Release must be called
+// once the batch is processed, the error is a *QuotaError when a quota doesn't allow the batch.
+type QuotaLimiter interface {
+	AcquireBatch(ctx context.Context, keyID string, urls int) (release func(), err error)
+}
+
+// BatchObserver - notified about the batches processed and the pages scraped, e.g. to export metrics.
+// The error of a rejected batch is a *QuotaError or a *CapacityError.
+type BatchObserver interface {
+	BatchRejected(err error)
+	BatchFinished(batch Batch, duration time.Duration)
+	PageScraped(result Result)
 }
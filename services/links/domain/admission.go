@@ -0,0 +1,194 @@
+package domain
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// AdmissionLimits - limits of the whole service on the batches processed at the same time,
+// 0 disables a limit
+type AdmissionLimits struct {
+	MaxInFlightURLs  int           // pages fetched at the same time by all batches
+	MaxQueuedBatches int           // batches being processed, including the ones waiting for a fetch slot
+	RetryAfter       time.Duration // suggested to the clients of rejected batches
+}
+
+// DefaultAdmissionLimits ..
+var DefaultAdmissionLimits = AdmissionLimits{
+	MaxInFlightURLs:  200,
+	MaxQueuedBatches: 100,
+	RetryAfter:       10 * time.Second,
+}
+
+// AdmissionController - rejects batches once MaxQueuedBatches are being processed and shares the
+// MaxInFlightURLs fetch slots between the clients. The slots are granted to the waiting clients in
+// turns, so a client with a huge batch gets as many slots as a client with a small one.
+type AdmissionController struct {
+	limits AdmissionLimits
+
+	mu      sync.Mutex
+	batches int                       // admitted batches which weren't released yet
+	free    int                       // fetch slots nobody holds
+	waiting map[string][]*slotRequest // client -> requests waiting for a fetch slot, oldest first
+	turns   []string                  // clients with waiting requests, the first one gets the next slot
+}
+
+// AdmissionStats - current load of the admission controller
+type AdmissionStats struct {
+	QueuedBatches int // admitted batches being processed
+	InFlightURLs  int // pages holding a fetch slot, always 0 without MaxInFlightURLs
+	WaitingURLs   int // pages waiting for a fetch slot
+}
+
+// slotRequest - a fetch waiting for a slot, granted is set and ready closed once it has one
+type slotRequest struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewAdmissionController ..
+func NewAdmissionController(limits AdmissionLimits) *AdmissionController {
+	return &AdmissionController{
+		limits:  limits,
+		free:    limits.MaxInFlightURLs,
+		waiting: map[string][]*slotRequest{},
+	}
+}
+
+// AdmitBatch - takes a place for a batch, the error is a *links.CapacityError when MaxQueuedBatches
+// are being processed already. Release must be called once the batch is processed.
+func (a *AdmissionController) AdmitBatch() (func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.limits.MaxQueuedBatches > 0 && a.batches >= a.limits.MaxQueuedBatches {
+		return nil, &links.CapacityError{Limit: "queued_batches", Max: a.limits.MaxQueuedBatches, RetryAfter: a.limits.RetryAfter}
+	}
+	a.batches++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.batches--
+		})
+	}, nil
+}
+
+// Stats - current load of the admission controller
+func (a *AdmissionController) Stats() AdmissionStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := AdmissionStats{QueuedBatches: a.batches}
+	if a.limits.MaxInFlightURLs > 0 {
+		stats.InFlightURLs = a.limits.MaxInFlightURLs - a.free
+	}
+	for _, queue := range a.waiting {
+		stats.WaitingURLs += len(queue)
+	}
+	return stats
+}
+
+// Slots - acquires the fetch slots of a client, see scraper.WithSlots
+func (a *AdmissionController) Slots(client string) scraper.SlotAcquirer {
+	return func(ctx context.Context) (func(), error) {
+		return a.acquireSlot(ctx, client)
+	}
+}
+
+// acquireSlot - waits for a fetch slot until the context is done, release gives the slot to the
+// client whose turn is next
+func (a *AdmissionController) acquireSlot(ctx context.Context, client string) (func(), error) {
+	if a.limits.MaxInFlightURLs <= 0 {
+		return func() {}, nil
+	}
+
+	a.mu.Lock()
+	if a.free > 0 && len(a.turns) == 0 {
+		a.free--
+		a.mu.Unlock()
+		return a.slotRelease(), nil
+	}
+	req := &slotRequest{ready: make(chan struct{})}
+	if len(a.waiting[client]) == 0 {
+		a.turns = append(a.turns, client)
+	}
+	a.waiting[client] = append(a.waiting[client], req)
+	a.mu.Unlock()
+
+	select {
+	case <-req.ready:
+		return a.slotRelease(), nil
+	case <-ctx.Done():
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if req.granted { // granted while giving up, pass the slot on
+			a.releaseSlotLocked()
+		} else {
+			a.removeRequestLocked(client, req)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (a *AdmissionController) slotRelease() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.releaseSlotLocked()
+		})
+	}
+}
+
+// releaseSlotLocked - grants the slot to the oldest request of the client whose turn it is,
+// the client waits for its next turn behind the other clients
+func (a *AdmissionController) releaseSlotLocked() {
+	if len(a.turns) == 0 {
+		a.free++
+		return
+	}
+
+	client := a.turns[0]
+	a.turns = a.turns[1:]
+	queue := a.waiting[client]
+	req := queue[0]
+	if len(queue) > 1 {
+		a.waiting[client] = queue[1:]
+		a.turns = append(a.turns, client)
+	} else {
+		delete(a.waiting, client)
+	}
+
+	req.granted = true
+	close(req.ready)
+}
+
+func (a *AdmissionController) removeRequestLocked(client string, req *slotRequest) {
+	queue := a.waiting[client]
+	for i, waiting := range queue {
+		if waiting == req {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		a.waiting[client] = queue
+		return
+	}
+
+	delete(a.waiting, client)
+	for i, turn := range a.turns {
+		if turn == client {
+			a.turns = append(a.turns[:i:i], a.turns[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type admissionControllerTestSuite struct {
+	suite.Suite
+}
+
+func TestAdmissionControllerTestSuite(t *testing.T) {
+	suite.Run(t, &admissionControllerTestSuite{})
+}
+
+func (s *admissionControllerTestSuite) TestAdmitBatch_WhenQueueIsFull_ThenBatchIsRejectedUntilOneIsReleased() {
+	// Arrange
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{MaxQueuedBatches: 2, RetryAfter: 5 * time.Second})
+	first, _ := admission.AdmitBatch()
+	_, _ = admission.AdmitBatch()
+
+	// Act
+	_, rejectedErr := admission.AdmitBatch()
+	first()
+	first() // releasing twice frees one place only
+	_, admittedErr := admission.AdmitBatch()
+	_, rejectedAgainErr := admission.AdmitBatch()
+
+	// Assert
+	var capacityErr *links.CapacityError
+	s.True(errors.As(rejectedErr, &capacityErr))
+	s.Equal(5*time.Second, capacityErr.RetryAfter)
+	s.EqualError(rejectedErr, "server is busy: limit of 2 queued batches reached")
+	s.NoError(admittedErr)
+	s.ErrorIs(rejectedAgainErr, links.ErrServerBusy)
+	s.Equal(2, admission.Stats().QueuedBatches)
+}
+
+func (s *admissionControllerTestSuite) TestSlots_WhenAllAreTaken_ThenClientsGetTheReleasedSlotsInTurns() {
+	// Arrange
+	ctx := context.Background()
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{MaxInFlightURLs: 1})
+	release, _ := admission.Slots("big")(ctx)
+	granted := make(chan string)
+	wait := func(client string, waiting int) {
+		go func() {
+			release, err := admission.Slots(client)(ctx)
+			s.NoError(err)
+			granted <- client
+			release()
+		}()
+		s.Eventually(func() bool { return admission.Stats().WaitingURLs == waiting }, time.Second, time.Millisecond)
+	}
+	wait("big", 1)
+	wait("big", 2)
+	wait("big", 3)
+	wait("small", 4)
+
+	// Act
+	release()
+	order := []string{}
+	for i := 0; i < 4; i++ {
+		order = append(order, <-granted)
+	}
+
+	// Assert
+	s.Equal([]string{"big", "small", "big", "big"}, order)
+	s.Eventually(func() bool { return admission.Stats() == domain.AdmissionStats{} }, time.Second, time.Millisecond)
+}
+
+func (s *admissionControllerTestSuite) TestSlots_WhenContextIsDoneWhileWaiting_ThenTheSlotIsNotLost() {
+	// Arrange
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{MaxInFlightURLs: 1})
+	release, _ := admission.Slots("a")(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, waitErr := admission.Slots("b")(ctx)
+	release()
+	_, err := admission.Slots("b")(context.Background())
+
+	// Assert
+	s.ErrorIs(waitErr, context.DeadlineExceeded)
+	s.NoError(err)
+	s.Equal(domain.AdmissionStats{InFlightURLs: 1}, admission.Stats())
+}
+
+func (s *admissionControllerTestSuite) TestSlots_WhenThereIsNoLimit_ThenSlotsAreGrantedRightAway() {
+	// Arrange
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{})
+
+	// Act
+	_, err := admission.Slots("a")(context.Background())
+	_, otherErr := admission.Slots("a")(context.Background())
+
+	// Assert
+	s.NoError(err)
+	s.NoError(otherErr)
+	s.Equal(domain.AdmissionStats{}, admission.Stats())
+}
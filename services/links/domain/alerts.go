@@ -0,0 +1,383 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/helpers"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/google/uuid"
+)
+
+// MonitorOption - optional configuration of the MonitorScheduler
+type MonitorOption func(*MonitorScheduler)
+
+// WithAlertNotifier - delivers alerts of the given sink type with the notifier,
+// alerts for sink types without a notifier are only kept in the alert history
+func WithAlertNotifier(sinkType links.AlertSinkType, notifier links.AlertNotifier) MonitorOption {
+	return func(m *MonitorScheduler) {
+		m.notifiers[sinkType] = notifier
+	}
+}
+
+// CreateAlertRule - validate and store a new alert rule of a monitor
+func (m *MonitorScheduler) CreateAlertRule(ctx context.Context, req links.AlertRuleRequest) (links.AlertRule, error) {
+	if err := validateAlertRule(req); err != nil {
+		return links.AlertRule{}, err
+	}
+	if err := m.authorizeMonitor(ctx, req.MonitorID); err != nil {
+		return links.AlertRule{}, fmt.Errorf("failed to create alert rule %w", err)
+	}
+
+	rule := links.AlertRule{
+		ID:               uuid.NewString(),
+		MonitorID:        req.MonitorID,
+		Name:             req.Name,
+		Condition:        req.Condition,
+		PageURL:          req.PageURL,
+		ThresholdPercent: req.ThresholdPercent,
+		Sinks:            req.Sinks,
+		CreatedAt:        time.Now().UTC(),
+	}
+	if rule.Sinks == nil {
+		rule.Sinks = []links.AlertSink{}
+	}
+	if err := m.repo.CreateAlertRule(ctx, rule); err != nil {
+		return links.AlertRule{}, fmt.Errorf("failed to create alert rule %w", err)
+	}
+
+	return rule, nil
+}
+
+// ListAlertRules - alert rules of a monitor, oldest first
+func (m *MonitorScheduler) ListAlertRules(ctx context.Context, monitorID string) (links.ListAlertRulesResponse, error) {
+	if err := m.authorizeMonitor(ctx, monitorID); err != nil {
+		return links.ListAlertRulesResponse{}, fmt.Errorf("failed to list alert rules %w", err)
+	}
+	rules, err := m.repo.ListAlertRules(ctx, monitorID)
+	if err != nil {
+		return links.ListAlertRulesResponse{}, fmt.Errorf("failed to list alert rules %w", err)
+	}
+
+	return links.ListAlertRulesResponse{Rules: rules}, nil
+}
+
+// DeleteAlertRule - delete an alert rule of a monitor, its alerts are kept
+func (m *MonitorScheduler) DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error {
+	if err := m.authorizeMonitor(ctx, monitorID); err != nil {
+		return fmt.Errorf("failed to delete alert rule %w", err)
+	}
+	if err := m.repo.DeleteAlertRule(ctx, monitorID, ruleID); err != nil {
+		return fmt.Errorf("failed to delete alert rule %w", err)
+	}
+
+	return nil
+}
+
+// ListAlerts - alert history, newest first. Only the alerts of the monitors of the api key scope
+// of the context are listed.
+func (m *MonitorScheduler) ListAlerts(ctx context.Context, query links.AlertQuery) (links.ListAlertsResponse, error) {
+	if _, ok := links.APIKeyScopeOf(ctx); ok && query.MonitorID == "" {
+		return m.listScopedAlerts(ctx, query)
+	}
+	if err := m.authorizeMonitor(ctx, query.MonitorID); err != nil {
+		return links.ListAlertsResponse{}, fmt.Errorf("failed to list alerts %w", err)
+	}
+	alerts, err := m.repo.ListAlerts(ctx, query)
+	if err != nil {
+		return links.ListAlertsResponse{}, fmt.Errorf("failed to list alerts %w", err)
+	}
+
+	return links.ListAlertsResponse{Alerts: alerts}, nil
+}
+
+// listScopedAlerts - alerts of every monitor of the api key scope of the context, the limit is applied
+// once the alerts of other monitors are left out
+func (m *MonitorScheduler) listScopedAlerts(ctx context.Context, query links.AlertQuery) (links.ListAlertsResponse, error) {
+	monitors, err := m.scopedMonitors(ctx)
+	if err != nil {
+		return links.ListAlertsResponse{}, err
+	}
+	scoped := map[string]bool{}
+	for _, monitor := range monitors {
+		scoped[monitor.ID] = true
+	}
+
+	limit := query.Limit
+	query.Limit = 0
+	alerts, err := m.repo.ListAlerts(ctx, query)
+	if err != nil {
+		return links.ListAlertsResponse{}, fmt.Errorf("failed to list alerts %w", err)
+	}
+	filtered := make([]links.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if !scoped[alert.MonitorID] {
+			continue
+		}
+		filtered = append(filtered, alert)
+		if len(filtered) == limit {
+			break
+		}
+	}
+
+	return links.ListAlertsResponse{Alerts: filtered}, nil
+}
+
+// evaluateAlerts - evaluates the alert rules of the monitor on a completed run against its previous run,
+// matches are delivered to the sinks of their rule and stored in the alert history
+func (m *MonitorScheduler) evaluateAlerts(ctx context.Context, monitorID, previousBatchID, batchID string) error {
+	if previousBatchID == "" || batchID == "" || previousBatchID == batchID {
+		return nil
+	}
+
+	rules, err := m.repo.ListAlertRules(ctx, monitorID)
+	if err != nil {
+		return fmt.Errorf("failed to list alert rules %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	previous, err := m.repo.GetBatch(ctx, previousBatchID)
+	if err != nil {
+		return fmt.Errorf("failed to get batch %w", err)
+	}
+	current, err := m.repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return fmt.Errorf("failed to get batch %w", err)
+	}
+	previousResults, err := m.repo.GetBatchResults(ctx, previousBatchID)
+	if err != nil {
+		return fmt.Errorf("failed to get batch results %w", err)
+	}
+	currentResults, err := m.repo.GetBatchResults(ctx, batchID)
+	if err != nil {
+		return fmt.Errorf("failed to get batch results %w", err)
+	}
+
+	run := alertRun{
+		previous:      previous,
+		current:       current,
+		previousPages: resultsByPage(previousResults),
+		currentPages:  resultsByPage(currentResults),
+	}
+	alerts := []links.Alert{}
+	for _, rule := range rules {
+		alerts = append(alerts, run.evaluate(rule)...)
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	for i := range alerts {
+		alerts[i].Deliveries = m.deliver(ctx, rules, alerts[i])
+	}
+	if err := m.repo.CreateAlerts(ctx, alerts); err != nil {
+		return fmt.Errorf("failed to create alerts %w", err)
+	}
+
+	return nil
+}
+
+// deliver - sends the alert to every sink of its rule, a failing sink doesn't stop the others
+func (m *MonitorScheduler) deliver(ctx context.Context, rules []links.AlertRule, alert links.Alert) []links.AlertDelivery {
+	deliveries := []links.AlertDelivery{}
+	for _, rule := range rules {
+		if rule.ID != alert.RuleID {
+			continue
+		}
+		for _, sink := range rule.Sinks {
+			delivery := links.AlertDelivery{Sink: sink}
+			notifier, ok := m.notifiers[sink.Type]
+			if !ok {
+				delivery.Error = fmt.Sprintf("no notifier configured for %s sinks", sink.Type)
+			} else if err := notifier.Notify(ctx, sink, alert); err != nil {
+				log.Println("failed to deliver alert", alert.ID, "to", sink.Type, err)
+				delivery.Error = err.Error()
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// alertRun - a completed run of a monitor and the run before it
+type alertRun struct {
+	previous, current           links.Batch
+	previousPages, currentPages map[string]links.Result
+}
+
+// evaluate - alerts of the rule for the pages scraped by both runs, sorted by page url
+func (r alertRun) evaluate(rule links.AlertRule) []links.Alert {
+	rulePage := ""
+	if rule.PageURL != "" {
+		rulePage = helpers.NormalizeURL(rule.PageURL)
+	}
+
+	pageURLs := make([]string, 0, len(r.currentPages))
+	for pageURL := range r.currentPages {
+		if rulePage != "" && pageURL != rulePage {
+			continue
+		}
+		if _, ok := r.previousPages[pageURL]; ok {
+			pageURLs = append(pageURLs, pageURL)
+		}
+	}
+	sort.Strings(pageURLs)
+
+	alerts := []links.Alert{}
+	for _, pageURL := range pageURLs {
+		alert, ok := r.match(rule, r.previousPages[pageURL], r.currentPages[pageURL])
+		if !ok {
+			continue
+		}
+		alert.ID = uuid.NewString()
+		alert.RuleID = rule.ID
+		alert.RuleName = rule.Name
+		alert.MonitorID = rule.MonitorID
+		alert.BatchID = r.current.ID
+		alert.PreviousBatchID = r.previous.ID
+		alert.Condition = rule.Condition
+		alert.PageURL = pageURL
+		alert.CreatedAt = time.Now().UTC()
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// match - checks the condition of the rule on the previous and current result of a page
+func (r alertRun) match(rule links.AlertRule, previous, current links.Result) (links.Alert, bool) {
+	switch rule.Condition {
+	case links.AlertSuccessToFailure:
+		if !previous.Success || current.Success {
+			return links.Alert{}, false
+		}
+		return links.Alert{Message: fmt.Sprintf("page failed after succeeding in the previous run: %v", current.Error)}, true
+
+	case links.AlertExternalLinksChange, links.AlertInternalLinksChange:
+		if !previous.Success || !current.Success {
+			return links.Alert{}, false
+		}
+		before, after, kind := previous.ExternalLinksNum, current.ExternalLinksNum, "external"
+		if rule.Condition == links.AlertInternalLinksChange {
+			before, after, kind = previous.InternalLinksNum, current.InternalLinksNum, "internal"
+		}
+		change := percentChange(before, after)
+		if !exceedsThreshold(change, rule.ThresholdPercent) {
+			return links.Alert{}, false
+		}
+		return links.Alert{
+			Message:  fmt.Sprintf("%s links changed from %d to %d", kind, before, after),
+			Previous: before,
+			Current:  after,
+		}, true
+
+	case links.AlertNewExternalDomain:
+		// domains are only known when both runs collected the links of their pages
+		if !r.previous.Options.CollectLinks || !r.current.Options.CollectLinks || !previous.Success || !current.Success {
+			return links.Alert{}, false
+		}
+		known := linkDomains(previous.ExternalLinks)
+		domains := []string{}
+		for domain := range linkDomains(current.ExternalLinks) {
+			if !known[domain] {
+				domains = append(domains, domain)
+			}
+		}
+		if len(domains) == 0 {
+			return links.Alert{}, false
+		}
+		sort.Strings(domains)
+		return links.Alert{
+			Message: fmt.Sprintf("new external domains: %s", strings.Join(domains, ", ")),
+			Domains: domains,
+		}, true
+	}
+
+	return links.Alert{}, false
+}
+
+// percentChange - relative change of a link count, a rise from zero is an infinite rise
+func percentChange(before, after uint) float64 {
+	if before == 0 {
+		if after == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (float64(after) - float64(before)) / float64(before) * 100
+}
+
+// exceedsThreshold - positive thresholds match rises above them, negative thresholds match drops below them
+func exceedsThreshold(change, threshold float64) bool {
+	if threshold < 0 {
+		return change < threshold
+	}
+	return change > threshold
+}
+
+// linkDomains - set of the lower-cased hosts of the links
+func linkDomains(rawLinks []string) map[string]bool {
+	domains := make(map[string]bool, len(rawLinks))
+	for _, rawLink := range rawLinks {
+		if domain, ok := linkDomain(rawLink); ok {
+			domains[domain] = true
+		}
+	}
+	return domains
+}
+
+// linkDomain - lower-cased host of the link, false for links without a host
+func linkDomain(rawLink string) (string, bool) {
+	parsedLink, err := url.Parse(rawLink)
+	if err != nil || parsedLink.Hostname() == "" {
+		return "", false
+	}
+	return strings.ToLower(parsedLink.Hostname()), true
+}
+
+// validateAlertRule - checks the condition, threshold and sinks of a rule, errors wrap links.ErrInvalidAlertRule
+func validateAlertRule(req links.AlertRuleRequest) error {
+	switch req.Condition {
+	case links.AlertExternalLinksChange, links.AlertInternalLinksChange:
+		if req.ThresholdPercent == 0 {
+			return fmt.Errorf("%w: threshold_percent is required for %s", links.ErrInvalidAlertRule, req.Condition)
+		}
+	case links.AlertSuccessToFailure, links.AlertNewExternalDomain:
+		if req.ThresholdPercent != 0 {
+			return fmt.Errorf("%w: threshold_percent is not supported for %s", links.ErrInvalidAlertRule, req.Condition)
+		}
+	default:
+		return fmt.Errorf("%w: unknown condition %q", links.ErrInvalidAlertRule, req.Condition)
+	}
+
+	if req.PageURL != "" {
+		if parsedURL, err := url.Parse(req.PageURL); err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+			return fmt.Errorf("%w: invalid page_url %q", links.ErrInvalidAlertRule, req.PageURL)
+		}
+	}
+
+	for _, sink := range req.Sinks {
+		switch sink.Type {
+		case links.AlertSinkWebhook:
+			parsedURL, err := url.Parse(sink.URL)
+			if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+				return fmt.Errorf("%w: invalid webhook url %q", links.ErrInvalidAlertRule, sink.URL)
+			}
+		case links.AlertSinkLog:
+			if sink.URL != "" {
+				return fmt.Errorf("%w: url is not supported for log sinks", links.ErrInvalidAlertRule)
+			}
+		default:
+			return fmt.Errorf("%w: unknown sink type %q", links.ErrInvalidAlertRule, sink.Type)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/Lockwarr/codefi/services/links/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type alertsTestSuite struct {
+	suite.Suite
+	mockRepo      *mocks.MockRepository
+	mockProcessor *mocks.MockLinksProcessor
+	mockNotifier  *mocks.MockAlertNotifier
+	scheduler     *domain.MonitorScheduler
+}
+
+func (s *alertsTestSuite) SetupTest() {
+	s.mockRepo = new(mocks.MockRepository)
+	s.mockProcessor = new(mocks.MockLinksProcessor)
+	s.mockNotifier = new(mocks.MockAlertNotifier)
+	s.scheduler = domain.NewMonitorScheduler(s.mockRepo, s.mockProcessor,
+		domain.WithAlertNotifier(links.AlertSinkWebhook, s.mockNotifier))
+}
+
+func (s *alertsTestSuite) AfterTest(suite string, testName string) {
+	s.scheduler.Stop()
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockProcessor.AssertExpectations(s.T())
+	s.mockNotifier.AssertExpectations(s.T())
+}
+
+func TestAlertsTestSuite(t *testing.T) {
+	suite.Run(t, &alertsTestSuite{})
+}
+
+func (s *alertsTestSuite) TestCreateAlertRule_ThenItIsStored() {
+	// Arrange
+	s.mockRepo.On("CreateAlertRule", mock.MatchedBy(func(r links.AlertRule) bool {
+		return r.ID != "" && r.MonitorID == "m0" && r.ThresholdPercent == 20 && !r.CreatedAt.IsZero()
+	})).Return(nil)
+
+	// Act
+	rule, err := s.scheduler.CreateAlertRule(context.Background(), links.AlertRuleRequest{
+		MonitorID:        "m0",
+		Condition:        links.AlertExternalLinksChange,
+		ThresholdPercent: 20,
+		Sinks:            []links.AlertSink{{Type: links.AlertSinkWebhook, URL: "https://hooks.example.com/alerts"}},
+	})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal("m0", rule.MonitorID)
+}
+
+func (s *alertsTestSuite) TestCreateAlertRule_WhenInvalid_ThenFail() {
+	testCases := []struct {
+		name string
+		req  links.AlertRuleRequest
+	}{
+		{
+			name: "unknown condition",
+			req:  links.AlertRuleRequest{Condition: "links_gone"},
+		},
+		{
+			name: "missing threshold",
+			req:  links.AlertRuleRequest{Condition: links.AlertInternalLinksChange},
+		},
+		{
+			name: "unsupported threshold",
+			req:  links.AlertRuleRequest{Condition: links.AlertSuccessToFailure, ThresholdPercent: 10},
+		},
+		{
+			name: "invalid page url",
+			req:  links.AlertRuleRequest{Condition: links.AlertSuccessToFailure, PageURL: "www.google.com"},
+		},
+		{
+			name: "invalid webhook url",
+			req:  links.AlertRuleRequest{Condition: links.AlertSuccessToFailure, Sinks: []links.AlertSink{{Type: links.AlertSinkWebhook, URL: "ftp://example.com"}}},
+		},
+		{
+			name: "unknown sink",
+			req:  links.AlertRuleRequest{Condition: links.AlertSuccessToFailure, Sinks: []links.AlertSink{{Type: "email"}}},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			_, err := s.scheduler.CreateAlertRule(context.Background(), tc.req)
+
+			// Assert
+			s.ErrorIs(err, links.ErrInvalidAlertRule)
+		})
+	}
+}
+
+func (s *alertsTestSuite) TestRunMonitor_WhenRulesMatch_ThenAlertsAreDeliveredAndStored() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@daily", URLs: []string{"https://www.google.com"}, LastBatchID: "b0"}
+	webhook := links.AlertSink{Type: links.AlertSinkWebhook, URL: "https://hooks.example.com/alerts"}
+	rules := []links.AlertRule{
+		{ID: "ar0", MonitorID: "m0", Condition: links.AlertExternalLinksChange, PageURL: "https://WWW.google.com", ThresholdPercent: 20, Sinks: []links.AlertSink{webhook}},
+		{ID: "ar1", MonitorID: "m0", Condition: links.AlertSuccessToFailure, Sinks: []links.AlertSink{webhook, {Type: links.AlertSinkLog}}},
+		{ID: "ar2", MonitorID: "m0", Condition: links.AlertNewExternalDomain},
+		{ID: "ar3", MonitorID: "m0", Condition: links.AlertInternalLinksChange, ThresholdPercent: -50},
+	}
+	collectLinks := links.ScrapeOptions{CollectLinks: true}
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", mock.Anything).Return([]links.Result{{ID: "r2", BatchID: "b1"}}, nil)
+	s.mockRepo.On("UpdateMonitor", mock.Anything).Return(nil)
+	s.mockRepo.On("ListAlertRules", "m0").Return(rules, nil)
+	s.mockRepo.On("GetBatch", "b0").Return(links.Batch{ID: "b0", Options: collectLinks}, nil)
+	s.mockRepo.On("GetBatch", "b1").Return(links.Batch{ID: "b1", Options: collectLinks}, nil)
+	s.mockRepo.On("GetBatchResults", "b0").Return([]links.Result{
+		{ID: "r0", PageURL: "https://www.google.com", Success: true, InternalLinksNum: 4, ExternalLinksNum: 10, ExternalLinks: []string{"https://a.com/x"}},
+		{ID: "r1", PageURL: "https://www.facebook.com", Success: true, InternalLinksNum: 4},
+	}, nil)
+	s.mockRepo.On("GetBatchResults", "b1").Return([]links.Result{
+		{ID: "r2", PageURL: "https://www.google.com/", Success: true, InternalLinksNum: 3, ExternalLinksNum: 13, ExternalLinks: []string{"https://a.com/y", "https://B.com/z"}},
+		{ID: "r3", PageURL: "https://www.facebook.com", Error: errors.New("timeout")},
+		{ID: "r4", PageURL: "https://www.twitter.com", Error: errors.New("timeout")},
+	}, nil)
+	s.mockNotifier.On("Notify", webhook, mock.MatchedBy(func(a links.Alert) bool { return a.RuleID == "ar0" })).Return(nil)
+	s.mockNotifier.On("Notify", webhook, mock.MatchedBy(func(a links.Alert) bool { return a.RuleID == "ar1" })).Return(errors.New("unreachable"))
+	var stored []links.Alert
+	s.mockRepo.On("CreateAlerts", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).([]links.Alert)
+	}).Return(nil)
+
+	// Act
+	_, err := s.scheduler.RunMonitor(context.Background(), "m0")
+
+	// Assert
+	s.Equal(nil, err)
+	s.Len(stored, 3)
+
+	s.Equal("ar0", stored[0].RuleID)
+	s.Equal("https://www.google.com/", stored[0].PageURL)
+	s.Equal(uint(10), stored[0].Previous)
+	s.Equal(uint(13), stored[0].Current)
+	s.Equal("b0", stored[0].PreviousBatchID)
+	s.Equal("b1", stored[0].BatchID)
+	s.Equal([]links.AlertDelivery{{Sink: webhook}}, stored[0].Deliveries)
+
+	s.Equal("ar1", stored[1].RuleID)
+	s.Equal("https://www.facebook.com/", stored[1].PageURL)
+	s.Equal([]links.AlertDelivery{
+		{Sink: webhook, Error: "unreachable"},
+		{Sink: links.AlertSink{Type: links.AlertSinkLog}, Error: "no notifier configured for log sinks"},
+	}, stored[1].Deliveries)
+
+	s.Equal("ar2", stored[2].RuleID)
+	s.Equal([]string{"b.com"}, stored[2].Domains)
+	s.Equal([]links.AlertDelivery{}, stored[2].Deliveries)
+}
+
+func (s *alertsTestSuite) TestRunMonitor_WhenItIsTheFirstRun_ThenNoRulesAreEvaluated() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@daily", URLs: []string{"https://www.google.com"}}
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", mock.Anything).Return([]links.Result{{ID: "r0", BatchID: "b0"}}, nil)
+	s.mockRepo.On("UpdateMonitor", mock.Anything).Return(nil)
+
+	// Act
+	_, err := s.scheduler.RunMonitor(context.Background(), "m0")
+
+	// Assert
+	s.Equal(nil, err)
+	s.mockRepo.AssertNotCalled(s.T(), "ListAlertRules", mock.Anything)
+}
+
+func (s *alertsTestSuite) TestListAlerts_WhenRepositoryFails_ThenFail() {
+	// Arrange
+	s.mockRepo.On("ListAlerts", links.AlertQuery{MonitorID: "m0"}).Return([]links.Alert(nil), errors.New("error"))
+
+	// Act
+	_, err := s.scheduler.ListAlerts(context.Background(), links.AlertQuery{MonitorID: "m0"})
+
+	// Assert
+	s.Equal("failed to list alerts error", err.Error())
+}
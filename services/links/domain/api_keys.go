@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix - issued keys start with it so leaked keys are easy to recognize
+const apiKeyPrefix = "lk_"
+
+// apiKeyVisibleLength - characters of a key kept as its prefix
+const apiKeyVisibleLength = 10
+
+// minAPIKeyLength - registered keys must be at least this long
+const minAPIKeyLength = 16
+
+// concurrentRetryAfter - suggested wait when all the concurrent batches of a key are in use
+const concurrentRetryAfter = 30 * time.Second
+
+// APIKeyManager - issues and authenticates api keys and enforces their quotas. Only the hashes
+// of the keys are stored. The usage of the quotas is counted in memory by this instance.
+type APIKeyManager struct {
+	repo links.Repository
+
+	mu    sync.Mutex
+	usage map[string]*keyUsage // key id -> usage of its quotas
+}
+
+// keyUsage - usage of the quotas of an api key
+type keyUsage struct {
+	day     string // UTC day the urls are counted for
+	urls    int
+	batches int // batches being processed
+}
+
+// NewAPIKeyManager ..
+func NewAPIKeyManager(repo links.Repository) *APIKeyManager {
+	return &APIKeyManager{
+		repo:  repo,
+		usage: map[string]*keyUsage{},
+	}
+}
+
+// IssueAPIKey - generate and store a new api key, the key itself is only returned here
+func (m *APIKeyManager) IssueAPIKey(ctx context.Context, req links.APIKeyRequest) (links.IssuedAPIKey, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return links.IssuedAPIKey{}, fmt.Errorf("failed to generate api key %w", err)
+	}
+
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return m.RegisterAPIKey(ctx, req, key)
+}
+
+// RegisterAPIKey - store an api key with a key chosen by the operator, like the admin key
+// passed to the service on startup
+func (m *APIKeyManager) RegisterAPIKey(ctx context.Context, req links.APIKeyRequest, key string) (links.IssuedAPIKey, error) {
+	if err := validateAPIKey(req, key); err != nil {
+		return links.IssuedAPIKey{}, err
+	}
+
+	apiKey := links.APIKey{
+		ID:        uuid.NewString(),
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    key[:apiKeyVisibleLength],
+		Hash:      hashAPIKey(key),
+		Admin:     req.Admin,
+		Quota:     req.Quota,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := m.repo.CreateAPIKey(ctx, apiKey); err != nil {
+		return links.IssuedAPIKey{}, fmt.Errorf("failed to create api key %w", err)
+	}
+
+	return links.IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys - all api keys including the revoked ones, oldest first
+func (m *APIKeyManager) ListAPIKeys(ctx context.Context) (links.ListAPIKeysResponse, error) {
+	keys, err := m.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return links.ListAPIKeysResponse{}, fmt.Errorf("failed to list api keys %w", err)
+	}
+
+	return links.ListAPIKeysResponse{Keys: keys}, nil
+}
+
+// RevokeAPIKey - the key can't be used from now on, revoking a revoked key is a no-op.
+// The batches created with the key are kept.
+func (m *APIKeyManager) RevokeAPIKey(ctx context.Context, keyID string) error {
+	apiKey, err := m.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to get api key %w", err)
+	}
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	revokedAt := time.Now().UTC()
+	apiKey.RevokedAt = &revokedAt
+	if err := m.repo.UpdateAPIKey(ctx, apiKey); err != nil {
+		return fmt.Errorf("failed to update api key %w", err)
+	}
+
+	return nil
+}
+
+// Authenticate - the api key of the key sent by a client, unknown and revoked keys are rejected
+func (m *APIKeyManager) Authenticate(ctx context.Context, key string) (links.APIKey, error) {
+	if key == "" {
+		return links.APIKey{}, links.ErrUnknownAPIKey
+	}
+
+	apiKey, err := m.repo.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil || apiKey.RevokedAt != nil {
+		return links.APIKey{}, links.ErrUnknownAPIKey
+	}
+
+	return apiKey, nil
+}
+
+// AcquireBatch - takes one of the concurrent batches of the key and counts the urls against its
+// daily quota. The urls stay counted after release, the daily quota resets at UTC midnight.
+func (m *APIKeyManager) AcquireBatch(ctx context.Context, keyID string, urls int) (func(), error) {
+	apiKey, err := m.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key %w", err)
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, links.ErrUnknownAPIKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	usage, ok := m.usage[keyID]
+	if !ok {
+		usage = &keyUsage{}
+		m.usage[keyID] = usage
+	}
+	if day := now.Format("2006-01-02"); usage.day != day {
+		usage.day = day
+		usage.urls = 0
+	}
+
+	quota := apiKey.Quota
+	if quota.ConcurrentBatches > 0 && usage.batches >= quota.ConcurrentBatches {
+		return nil, &links.QuotaError{Quota: "concurrent_batches", Limit: quota.ConcurrentBatches, RetryAfter: concurrentRetryAfter}
+	}
+	if quota.URLsPerDay > 0 && usage.urls+urls > quota.URLsPerDay {
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return nil, &links.QuotaError{Quota: "urls_per_day", Limit: quota.URLsPerDay, RetryAfter: midnight.Sub(now)}
+	}
+
+	usage.urls += urls
+	usage.batches++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			usage.batches--
+		})
+	}, nil
+}
+
+func validateAPIKey(req links.APIKeyRequest, key string) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: name is required", links.ErrInvalidAPIKey)
+	}
+	if req.Quota.URLsPerDay < 0 || req.Quota.ConcurrentBatches < 0 {
+		return fmt.Errorf("%w: quotas can't be negative", links.ErrInvalidAPIKey)
+	}
+	if len(key) < minAPIKeyLength {
+		return fmt.Errorf("%w: key must be at least %d characters", links.ErrInvalidAPIKey, minAPIKeyLength)
+	}
+	return nil
+}
+
+// hashAPIKey - hex encoded SHA-256 of the key
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
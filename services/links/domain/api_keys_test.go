@@ -0,0 +1,163 @@
+package domain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/stretchr/testify/suite"
+)
+
+type apiKeyManagerTestSuite struct {
+	suite.Suite
+	repo    links.Repository
+	manager *domain.APIKeyManager
+}
+
+func (s *apiKeyManagerTestSuite) SetupTest() {
+	s.repo = repository.NewInMemoryDB()
+	s.manager = domain.NewAPIKeyManager(s.repo)
+}
+
+func TestAPIKeyManagerTestSuite(t *testing.T) {
+	suite.Run(t, &apiKeyManagerTestSuite{})
+}
+
+func (s *apiKeyManagerTestSuite) TestIssueAPIKey_ThenOnlyItsHashIsStoredAndItAuthenticates() {
+	// Arrange
+	ctx := context.Background()
+	req := links.APIKeyRequest{Name: " ci ", Quota: links.APIKeyQuota{URLsPerDay: 100}}
+
+	// Act
+	issued, err := s.manager.IssueAPIKey(ctx, req)
+	authenticated, authErr := s.manager.Authenticate(ctx, issued.Key)
+	listed, listErr := s.manager.ListAPIKeys(ctx)
+
+	// Assert
+	s.Equal(nil, err)
+	s.True(strings.HasPrefix(issued.Key, "lk_"))
+	s.Equal(issued.Key[:10], issued.Prefix)
+	s.Equal("ci", issued.Name)
+	s.Len(issued.Hash, 64) // hex encoded SHA-256
+	s.Equal(nil, authErr)
+	s.Equal(issued.APIKey, authenticated)
+	s.Equal(nil, listErr)
+	s.Equal([]links.APIKey{issued.APIKey}, listed.Keys)
+}
+
+func (s *apiKeyManagerTestSuite) TestIssueAPIKey_DifferentInvalidRequests_ThenFail() {
+	testCases := []struct {
+		name            string
+		req             links.APIKeyRequest
+		expectedMessage string
+	}{
+		{name: "missing name", req: links.APIKeyRequest{Name: " "}, expectedMessage: "invalid api key: name is required"},
+		{name: "negative quota", req: links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{ConcurrentBatches: -1}},
+			expectedMessage: "invalid api key: quotas can't be negative"},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			_, err := s.manager.IssueAPIKey(context.Background(), tc.req)
+
+			// Assert
+			s.ErrorIs(err, links.ErrInvalidAPIKey)
+			s.Equal(tc.expectedMessage, err.Error())
+		})
+	}
+}
+
+func (s *apiKeyManagerTestSuite) TestRegisterAPIKey_WhenKeyIsTooShort_ThenFail() {
+	// Act
+	_, err := s.manager.RegisterAPIKey(context.Background(), links.APIKeyRequest{Name: "admin", Admin: true}, "short")
+
+	// Assert
+	s.ErrorIs(err, links.ErrInvalidAPIKey)
+}
+
+func (s *apiKeyManagerTestSuite) TestAuthenticate_WhenKeyIsUnknownOrRevoked_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+	issued, _ := s.manager.RegisterAPIKey(ctx, links.APIKeyRequest{Name: "admin", Admin: true}, "0123456789abcdef")
+
+	// Act
+	revokeErr := s.manager.RevokeAPIKey(ctx, issued.ID)
+	revokeAgainErr := s.manager.RevokeAPIKey(ctx, issued.ID)
+	revokeUnknownErr := s.manager.RevokeAPIKey(ctx, "unknown")
+	_, revokedErr := s.manager.Authenticate(ctx, "0123456789abcdef")
+	_, unknownErr := s.manager.Authenticate(ctx, "fedcba9876543210")
+	_, emptyErr := s.manager.Authenticate(ctx, "")
+	stored, _ := s.repo.GetAPIKey(ctx, issued.ID)
+
+	// Assert
+	s.Equal(nil, revokeErr)
+	s.Equal(nil, revokeAgainErr)
+	s.ErrorIs(revokeUnknownErr, repository.ErrAPIKeyNotFound)
+	s.Equal(links.ErrUnknownAPIKey, revokedErr)
+	s.Equal(links.ErrUnknownAPIKey, unknownErr)
+	s.Equal(links.ErrUnknownAPIKey, emptyErr)
+	s.NotNil(stored.RevokedAt)
+}
+
+func (s *apiKeyManagerTestSuite) TestAcquireBatch_WhenConcurrentBatchesAreInUse_ThenFailUntilOneIsReleased() {
+	// Arrange
+	ctx := context.Background()
+	issued, _ := s.manager.IssueAPIKey(ctx, links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{ConcurrentBatches: 1}})
+
+	// Act
+	release, firstErr := s.manager.AcquireBatch(ctx, issued.ID, 10)
+	_, secondErr := s.manager.AcquireBatch(ctx, issued.ID, 10)
+	release()
+	release() // releasing twice gives the batch back once
+	_, thirdErr := s.manager.AcquireBatch(ctx, issued.ID, 10)
+	_, fourthErr := s.manager.AcquireBatch(ctx, issued.ID, 10)
+
+	// Assert
+	s.Equal(nil, firstErr)
+	var quotaErr *links.QuotaError
+	s.Require().ErrorAs(secondErr, &quotaErr)
+	s.Equal(&links.QuotaError{Quota: "concurrent_batches", Limit: 1, RetryAfter: 30 * time.Second}, quotaErr)
+	s.Equal("api key quota exceeded: limit of 1 concurrent batches reached", secondErr.Error())
+	s.Equal(nil, thirdErr)
+	s.ErrorIs(fourthErr, links.ErrQuotaExceeded)
+}
+
+func (s *apiKeyManagerTestSuite) TestAcquireBatch_WhenDailyURLsAreUsedUp_ThenFailUntilMidnight() {
+	// Arrange
+	ctx := context.Background()
+	issued, _ := s.manager.IssueAPIKey(ctx, links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{URLsPerDay: 10}})
+
+	// Act
+	release, firstErr := s.manager.AcquireBatch(ctx, issued.ID, 6)
+	release() // released urls stay counted for the day
+	_, secondErr := s.manager.AcquireBatch(ctx, issued.ID, 5)
+	_, thirdErr := s.manager.AcquireBatch(ctx, issued.ID, 4)
+
+	// Assert
+	s.Equal(nil, firstErr)
+	var quotaErr *links.QuotaError
+	s.Require().ErrorAs(secondErr, &quotaErr)
+	s.Equal("urls_per_day", quotaErr.Quota)
+	s.Equal(10, quotaErr.Limit)
+	s.True(quotaErr.RetryAfter > 0 && quotaErr.RetryAfter <= 24*time.Hour)
+	s.Equal(nil, thirdErr)
+}
+
+func (s *apiKeyManagerTestSuite) TestAcquireBatch_WhenKeyIsRevokedOrUnknown_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+	issued, _ := s.manager.IssueAPIKey(ctx, links.APIKeyRequest{Name: "ci"})
+	_ = s.manager.RevokeAPIKey(ctx, issued.ID)
+
+	// Act
+	_, revokedErr := s.manager.AcquireBatch(ctx, issued.ID, 1)
+	_, unknownErr := s.manager.AcquireBatch(ctx, "unknown", 1)
+
+	// Assert
+	s.Equal(links.ErrUnknownAPIKey, revokedErr)
+	s.ErrorIs(unknownErr, repository.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/google/uuid"
+)
+
+// CallbackRetryPolicy - how often and how fast a failed callback delivery is retried
+type CallbackRetryPolicy struct {
+	MaxAttempts int           // attempts including the first one
+	Backoff     time.Duration // wait before the first retry, doubled for every further retry
+	MaxBackoff  time.Duration // upper bound of the wait between retries
+}
+
+// DefaultCallbackRetryPolicy - 5 attempts over roughly 15 seconds
+var DefaultCallbackRetryPolicy = CallbackRetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: time.Minute}
+
+// CallbackDispatcher - delivers callback events of finished batches in the background
+// and records every delivery attempt in the repository
+type CallbackDispatcher struct {
+	repo   links.Repository
+	sender links.CallbackSender
+	policy CallbackRetryPolicy
+	wg     sync.WaitGroup
+}
+
+// NewCallbackDispatcher ..
+func NewCallbackDispatcher(repo links.Repository, sender links.CallbackSender, policy CallbackRetryPolicy) *CallbackDispatcher {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &CallbackDispatcher{repo: repo, sender: sender, policy: policy}
+}
+
+// Dispatch - starts delivering the summary of a finished batch to its callback url
+func (d *CallbackDispatcher) Dispatch(batch links.Batch) {
+	if batch.Callback == nil {
+		return
+	}
+
+	eventType := links.CallbackBatchCompleted
+	if batch.Status == links.BatchStatusFailed {
+		eventType = links.CallbackBatchFailed
+	}
+	event := links.CallbackEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		Batch:     batch,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(context.Background(), *batch.Callback, event)
+	}()
+}
+
+// Wait - waits for the deliveries in progress, including their retries
+func (d *CallbackDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// deliver - sends the event until it is accepted, the error is permanent or the attempts run out
+func (d *CallbackDispatcher) deliver(ctx context.Context, callback links.Callback, event links.CallbackEvent) {
+	backoff := d.policy.Backoff
+	for attempt := 1; attempt <= d.policy.MaxAttempts; attempt++ {
+		statusCode, err := d.sender.Send(ctx, callback, event)
+
+		record := links.CallbackAttempt{
+			ID:          uuid.NewString(),
+			BatchID:     event.Batch.ID,
+			EventID:     event.ID,
+			URL:         callback.URL,
+			Attempt:     attempt,
+			StatusCode:  statusCode,
+			Success:     err == nil,
+			AttemptedAt: time.Now().UTC(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if recordErr := d.repo.CreateCallbackAttempt(ctx, record); recordErr != nil {
+			log.Println("failed to record callback attempt of batch", event.Batch.ID, recordErr)
+		}
+
+		if err == nil || !retryable(statusCode) || attempt == d.policy.MaxAttempts {
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if d.policy.MaxBackoff > 0 && backoff > d.policy.MaxBackoff {
+			backoff = d.policy.MaxBackoff
+		}
+	}
+}
+
+// retryable - network errors, server errors, timeouts and rate limits are retried,
+// other client errors mean the callback url won't accept the event
+func retryable(statusCode int) bool {
+	if statusCode == 0 || statusCode >= http.StatusInternalServerError {
+		return true
+	}
+	return statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+// validateCallback - the callback url must be an absolute http(s) url, errors wrap links.ErrInvalidCallback
+func validateCallback(callback *links.Callback) error {
+	if callback == nil {
+		return nil
+	}
+	parsedURL, err := url.Parse(callback.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("%w: invalid callback url %q", links.ErrInvalidCallback, callback.URL)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	pkgmocks "github.com/Lockwarr/codefi/pkg/mocks"
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/Lockwarr/codefi/services/links/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type callbacksTestSuite struct {
+	suite.Suite
+	mockRepo          *mocks.MockRepository
+	mockSender        *mocks.MockCallbackSender
+	mockScraperClient *pkgmocks.MockScraper
+	dispatcher        *domain.CallbackDispatcher
+	linkProcessor     links.Processor
+}
+
+func (s *callbacksTestSuite) SetupTest() {
+	s.mockRepo = new(mocks.MockRepository)
+	s.mockSender = new(mocks.MockCallbackSender)
+	s.mockScraperClient = new(pkgmocks.MockScraper)
+	s.dispatcher = domain.NewCallbackDispatcher(s.mockRepo, s.mockSender, domain.CallbackRetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	s.linkProcessor = domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithCallbackDispatcher(s.dispatcher))
+}
+
+func (s *callbacksTestSuite) AfterTest(suite string, testName string) {
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockSender.AssertExpectations(s.T())
+	s.mockScraperClient.AssertExpectations(s.T())
+}
+
+func TestCallbacksTestSuite(t *testing.T) {
+	suite.Run(t, &callbacksTestSuite{})
+}
+
+func (s *callbacksTestSuite) TestProcessBatch_WhenCallbackIsSet_ThenSummaryIsDelivered() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+	callback := &links.Callback{URL: "https://hooks.example.com/batches", Secret: "s3cret"}
+	s.mockRepo.On("CreateBatch", mock.MatchedBy(func(batch links.Batch) bool { return batch.Callback == callback })).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "test1", Success: true}}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+	s.mockSender.On("Send", *callback, mock.MatchedBy(func(event links.CallbackEvent) bool {
+		return event.ID != "" && event.Type == links.CallbackBatchCompleted &&
+			event.Batch.Status == links.BatchStatusCompleted && event.Batch.SuccessCount == 1
+	})).Return(200, nil).Once()
+	s.mockRepo.On("CreateCallbackAttempt", mock.MatchedBy(func(attempt links.CallbackAttempt) bool {
+		return attempt.Attempt == 1 && attempt.Success && attempt.StatusCode == 200 && attempt.URL == callback.URL
+	})).Return(nil).Once()
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}, Callback: callback})
+	s.dispatcher.Wait()
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(1, len(res))
+}
+
+func (s *callbacksTestSuite) TestProcessBatch_WhenCallbackURLIsInvalid_ThenFail() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+
+	// Act
+	_, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{
+		URLs:     []*url.URL{urlGenerated},
+		Callback: &links.Callback{URL: "hooks.example.com"},
+	})
+
+	// Assert
+	s.ErrorIs(err, links.ErrInvalidCallback)
+}
+
+func (s *callbacksTestSuite) TestDispatch_WhenDeliveryFails_ThenItIsRetriedUntilAccepted() {
+	// Arrange
+	batch := links.Batch{ID: "b0", Status: links.BatchStatusFailed, Callback: &links.Callback{URL: "https://hooks.example.com/batches"}}
+	s.mockSender.On("Send", *batch.Callback, mock.Anything).Return(0, errors.New("connection refused")).Once()
+	s.mockSender.On("Send", *batch.Callback, mock.Anything).Return(503, errors.New("callback responded with status 503")).Once()
+	s.mockSender.On("Send", *batch.Callback, mock.MatchedBy(func(event links.CallbackEvent) bool {
+		return event.Type == links.CallbackBatchFailed
+	})).Return(204, nil).Once()
+	var attempts []links.CallbackAttempt
+	s.mockRepo.On("CreateCallbackAttempt", mock.Anything).Run(func(args mock.Arguments) {
+		attempts = append(attempts, args.Get(0).(links.CallbackAttempt))
+	}).Return(nil)
+
+	// Act
+	s.dispatcher.Dispatch(batch)
+	s.dispatcher.Wait()
+
+	// Assert
+	s.Len(attempts, 3)
+	s.Equal("connection refused", attempts[0].Error)
+	s.Equal(503, attempts[1].StatusCode)
+	s.True(attempts[2].Success)
+	s.Equal(3, attempts[2].Attempt)
+	s.Equal(attempts[0].EventID, attempts[2].EventID)
+}
+
+func (s *callbacksTestSuite) TestDispatch_WhenCallbackRejectsTheEvent_ThenItIsNotRetried() {
+	// Arrange
+	batch := links.Batch{ID: "b0", Status: links.BatchStatusCompleted, Callback: &links.Callback{URL: "https://hooks.example.com/batches"}}
+	s.mockSender.On("Send", *batch.Callback, mock.Anything).Return(401, errors.New("callback responded with status 401")).Once()
+	s.mockRepo.On("CreateCallbackAttempt", mock.MatchedBy(func(attempt links.CallbackAttempt) bool {
+		return !attempt.Success && attempt.StatusCode == 401
+	})).Return(nil).Once()
+
+	// Act
+	s.dispatcher.Dispatch(batch)
+	s.dispatcher.Wait()
+}
+
+func (s *callbacksTestSuite) TestDispatch_WhenAttemptsRunOut_ThenDeliveryStops() {
+	// Arrange
+	batch := links.Batch{ID: "b0", Status: links.BatchStatusCompleted, Callback: &links.Callback{URL: "https://hooks.example.com/batches"}}
+	s.mockSender.On("Send", *batch.Callback, mock.Anything).Return(500, errors.New("callback responded with status 500")).Times(3)
+	s.mockRepo.On("CreateCallbackAttempt", mock.Anything).Return(nil).Times(3)
+
+	// Act
+	s.dispatcher.Dispatch(batch)
+	s.dispatcher.Wait()
+}
+
+func (s *callbacksTestSuite) TestListCallbackAttempts_WhenRepositoryFails_ThenFail() {
+	// Arrange
+	s.mockRepo.On("ListCallbackAttempts", "b0").Return([]links.CallbackAttempt(nil), errors.New("error"))
+
+	// Act
+	_, err := s.linkProcessor.ListCallbackAttempts(context.Background(), "b0")
+
+	// Assert
+	s.Equal("failed to list callback attempts error", err.Error())
+}
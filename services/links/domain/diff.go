@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"sort"
+
+	"github.com/Lockwarr/codefi/pkg/helpers"
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// diffBatches - compares the results of two batches page by page. Pages are matched by their
+// normalized url, when a batch scraped the same page more than once its first result is used.
+func diffBatches(base, target links.Batch, baseResults, targetResults []links.Result) links.BatchDiff {
+	diff := links.BatchDiff{
+		BaseBatchID:   base.ID,
+		TargetBatchID: target.ID,
+		LinkDetails:   base.Options.CollectLinks && target.Options.CollectLinks,
+		Pages:         []links.PageDiff{},
+	}
+
+	basePages := resultsByPage(baseResults)
+	targetPages := resultsByPage(targetResults)
+
+	for pageURL, targetResult := range targetPages {
+		baseResult, ok := basePages[pageURL]
+		if !ok {
+			diff.AddedCount++
+			diff.Pages = append(diff.Pages, links.PageDiff{
+				PageURL:            pageURL,
+				Change:             links.PageAdded,
+				TargetSuccess:      boolPtr(targetResult.Success),
+				InternalLinksDelta: int(targetResult.InternalLinksNum),
+				ExternalLinksDelta: int(targetResult.ExternalLinksNum),
+			})
+			continue
+		}
+
+		page := links.PageDiff{
+			PageURL:            pageURL,
+			Change:             links.PageChanged,
+			BaseSuccess:        boolPtr(baseResult.Success),
+			TargetSuccess:      boolPtr(targetResult.Success),
+			InternalLinksDelta: int(targetResult.InternalLinksNum) - int(baseResult.InternalLinksNum),
+			ExternalLinksDelta: int(targetResult.ExternalLinksNum) - int(baseResult.ExternalLinksNum),
+		}
+		if diff.LinkDetails && baseResult.Success && targetResult.Success {
+			page.LinksAdded, page.LinksRemoved = diffLinks(pageLinks(baseResult), pageLinks(targetResult))
+		}
+
+		if baseResult.Success == targetResult.Success && page.InternalLinksDelta == 0 && page.ExternalLinksDelta == 0 &&
+			len(page.LinksAdded) == 0 && len(page.LinksRemoved) == 0 {
+			diff.UnchangedCount++
+			continue
+		}
+		diff.ChangedCount++
+		diff.Pages = append(diff.Pages, page)
+	}
+
+	for pageURL, baseResult := range basePages {
+		if _, ok := targetPages[pageURL]; ok {
+			continue
+		}
+		diff.RemovedCount++
+		diff.Pages = append(diff.Pages, links.PageDiff{
+			PageURL:            pageURL,
+			Change:             links.PageRemoved,
+			BaseSuccess:        boolPtr(baseResult.Success),
+			InternalLinksDelta: -int(baseResult.InternalLinksNum),
+			ExternalLinksDelta: -int(baseResult.ExternalLinksNum),
+		})
+	}
+
+	sort.Slice(diff.Pages, func(i, j int) bool {
+		return diff.Pages[i].PageURL < diff.Pages[j].PageURL
+	})
+
+	return diff
+}
+
+// resultsByPage - results by normalized page url, the first result of a page wins
+func resultsByPage(results []links.Result) map[string]links.Result {
+	pages := make(map[string]links.Result, len(results))
+	for _, result := range results {
+		pageURL := helpers.NormalizeURL(result.PageURL)
+		if _, ok := pages[pageURL]; !ok {
+			pages[pageURL] = result
+		}
+	}
+	return pages
+}
+
+// pageLinks - set of the normalized internal and external links of a page
+func pageLinks(result links.Result) map[string]bool {
+	set := make(map[string]bool, len(result.InternalLinks)+len(result.ExternalLinks))
+	for _, link := range result.InternalLinks {
+		set[helpers.NormalizeURL(link)] = true
+	}
+	for _, link := range result.ExternalLinks {
+		set[helpers.NormalizeURL(link)] = true
+	}
+	return set
+}
+
+// diffLinks - sorted links only in target (added) and only in base (removed)
+func diffLinks(base, target map[string]bool) (added, removed []string) {
+	for link := range target {
+		if !base[link] {
+			added = append(added, link)
+		}
+	}
+	for link := range base {
+		if !target[link] {
+			removed = append(removed, link)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
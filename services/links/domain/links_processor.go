@@ -2,59 +2,711 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/url"
 	"time"
 
 	"github.com/Lockwarr/codefi/pkg/scraper"
 	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const defaultPageSize = 50
+
+const defaultProgressInterval = 2 * time.Second
+
+// resultsChunkSize - results of a batch are saved in chunks of this size while the batch is processed
+const resultsChunkSize = 50
+
 type linkProcessor struct {
-	scraperClient scraper.ScraperService
-	repo          links.Repository
+	scraperClient    scraper.ScraperService
+	repo             links.Repository
+	callbacks        *CallbackDispatcher
+	progress         *progressHub
+	progressInterval time.Duration
+	quotas           links.QuotaLimiter
+	admission        *AdmissionController
+	observer         links.BatchObserver
+	tracer           trace.Tracer
+}
+
+// ProcessorOption - optional configuration of the links processor
+type ProcessorOption func(*linkProcessor)
+
+// WithCallbackDispatcher - deliver the callbacks of finished batches with the dispatcher,
+// without it the callbacks of batches are ignored
+func WithCallbackDispatcher(dispatcher *CallbackDispatcher) ProcessorOption {
+	return func(p *linkProcessor) {
+		p.callbacks = dispatcher
+	}
+}
+
+// WithProgressInterval - how often watchers of a running batch get a progress summary
+func WithProgressInterval(interval time.Duration) ProcessorOption {
+	return func(p *linkProcessor) {
+		p.progressInterval = interval
+	}
+}
+
+// WithQuotaLimiter - enforce the quotas of the api keys creating and retrying batches,
+// without it batches are never limited
+func WithQuotaLimiter(quotas links.QuotaLimiter) ProcessorOption {
+	return func(p *linkProcessor) {
+		p.quotas = quotas
+	}
+}
+
+// WithAdmissionController - limit the batches processed and the pages fetched at the same time by
+// the whole service, without it every batch is processed right away
+func WithAdmissionController(admission *AdmissionController) ProcessorOption {
+	return func(p *linkProcessor) {
+		p.admission = admission
+	}
+}
+
+// WithBatchObserver - report the batches and the scraped pages to the observer
+func WithBatchObserver(observer links.BatchObserver) ProcessorOption {
+	return func(p *linkProcessor) {
+		p.observer = observer
+	}
 }
 
 // NewLinksProcessor ..
-func NewLinksProcessor(repo links.Repository, scraperClient scraper.ScraperService) links.Processor {
-	return &linkProcessor{scraperClient: scraperClient, repo: repo}
+func NewLinksProcessor(repo links.Repository, scraperClient scraper.ScraperService, opts ...ProcessorOption) links.Processor {
+	p := &linkProcessor{
+		scraperClient:    scraperClient,
+		repo:             repo,
+		progress:         newProgressHub(),
+		progressInterval: defaultProgressInterval,
+		tracer:           noopTracer,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // ProcessBatch - process batch of urls to find external and internal links
-func (p *linkProcessor) ProcessBatch(ctx context.Context, req links.ProcessBatchRequest) ([]links.Result, error) {
+func (p *linkProcessor) ProcessBatch(ctx context.Context, req links.ProcessBatchRequest) (results []links.Result, err error) {
+	ctx, span := p.tracer.Start(ctx, "linkProcessor.ProcessBatch", batchAttributes("", len(req.URLs), req.APIKeyID))
+	defer func() { endSpan(span, err) }()
+	batch, opts, release, err := p.createBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("links.batch_id", batch.ID))
+
+	return p.processBatch(ctx, batch, req.URLs, opts, release)
+}
+
+// StartBatch - like ProcessBatch but returns the running batch right away, the urls are processed
+// in the background and the batch can be followed with WatchBatch
+func (p *linkProcessor) StartBatch(ctx context.Context, req links.ProcessBatchRequest) (_ links.Batch, err error) {
+	ctx, span := p.tracer.Start(ctx, "linkProcessor.StartBatch", batchAttributes("", len(req.URLs), req.APIKeyID))
+	defer func() { endSpan(span, err) }()
+	batch, opts, release, err := p.createBatch(ctx, req)
+	if err != nil {
+		return links.Batch{}, err
+	}
+	span.SetAttributes(attribute.String("links.batch_id", batch.ID))
+
+	// the request which started the batch is done long before the batch, which continues its trace
+	ctx, processSpan := p.tracer.Start(detachContext(ctx), "linkProcessor.processBatch", batchAttributes(batch.ID, len(req.URLs), req.APIKeyID))
+	go func() {
+		_, err := p.processBatch(ctx, batch, req.URLs, opts, release)
+		endSpan(processSpan, err)
+		if err != nil {
+			log.Println("failed to process batch", batch.ID, err)
+		}
+	}()
+
+	return batch, nil
+}
+
+// createBatch - validates the request, takes the quota of the api key and a place among the batches
+// of the service and stores the batch as running. release gives them back and must be called once the batch is processed.
+func (p *linkProcessor) createBatch(ctx context.Context, req links.ProcessBatchRequest) (links.Batch, scraper.Options, func(), error) {
+	opts, err := toScraperOptions(req.Options)
+	if err != nil {
+		return links.Batch{}, scraper.Options{}, nil, err
+	}
+	if err := validateCallback(req.Callback); err != nil {
+		return links.Batch{}, scraper.Options{}, nil, err
+	}
+	release, err := p.admit(ctx, req.APIKeyID, len(req.URLs))
+	if err != nil {
+		return links.Batch{}, scraper.Options{}, nil, err
+	}
+
+	now := time.Now().UTC()
+	batch := links.Batch{
+		ID:        uuid.NewString(),
+		Status:    links.BatchStatusRunning,
+		URLCount:  len(req.URLs),
+		Options:   fromScraperOptions(opts),
+		MonitorID: req.MonitorID,
+		Callback:  req.Callback,
+		APIKeyID:  req.APIKeyID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.repo.CreateBatch(ctx, batch); err != nil {
+		release()
+		return links.Batch{}, scraper.Options{}, nil, fmt.Errorf("failed to create batch %w", err)
+	}
+	p.progress.start(batch)
+
+	return batch, opts, release, nil
+}
+
+// admit - takes the quota of the api key for urls and a place among the batches of the service,
+// the quota isn't used up by batches the service can't admit
+func (p *linkProcessor) admit(ctx context.Context, keyID string, urls int) (func(), error) {
+	leave := func() {}
+	if p.admission != nil {
+		var err error
+		if leave, err = p.admission.AdmitBatch(); err != nil {
+			p.observeRejection(err)
+			return nil, err
+		}
+	}
+	release, err := p.acquireQuota(ctx, keyID, urls)
+	if err != nil {
+		leave()
+		p.observeRejection(err)
+		return nil, err
+	}
+
+	return func() {
+		release()
+		leave()
+	}, nil
+}
+
+// observeRejection - reports batches rejected by a quota or by the admission controller
+func (p *linkProcessor) observeRejection(err error) {
+	if p.observer != nil && (errors.Is(err, links.ErrQuotaExceeded) || errors.Is(err, links.ErrServerBusy)) {
+		p.observer.BatchRejected(err)
+	}
+}
+
+// acquireQuota - takes the quota of the api key for urls, batches without an api key or
+// processed without a quota limiter are never limited
+func (p *linkProcessor) acquireQuota(ctx context.Context, keyID string, urls int) (func(), error) {
+	if p.quotas == nil || keyID == "" {
+		return func() {}, nil
+	}
+	return p.quotas.AcquireBatch(ctx, keyID, urls)
+}
+
+// withFetchSlots - pages of the batch are fetched in the slots of the admission controller, shared
+// by the batches of one api key. Batches without an api key get their own turns.
+func (p *linkProcessor) withFetchSlots(ctx context.Context, batch links.Batch) context.Context {
+	if p.admission == nil {
+		return ctx
+	}
+	client := batch.APIKeyID
+	if client == "" {
+		client = batch.ID
+	}
+	return scraper.WithSlots(ctx, p.admission.Slots(client))
+}
+
+// processBatch - scrapes the urls of a created batch, every result is published to the watchers
+// of the batch as it finishes and saved in chunks of resultsChunkSize.
+// When saving a chunk fails the scrape is cancelled and the batch is marked as failed.
+// release is called before the watchers learn the batch finished, so they can submit the next one right away.
+// The urls are scraped in the trace of ctx even once ctx is done, and the results are stored with its id.
+func (p *linkProcessor) processBatch(ctx context.Context, batch links.Batch, urls []*url.URL, opts scraper.Options, release func()) ([]links.Result, error) {
+	defer func() { p.progress.finish(batch) }()
+	defer release()
+	start := time.Now()
 	batchResults := []links.Result{}
-	batchID := uuid.NewString()
+	traceID := traceIDOf(ctx)
 
-	results := p.scraperClient.Scrape(context.Background(), req.URLs)
+	scrapeCtx, cancel := context.WithCancel(p.withFetchSlots(detachContext(ctx), batch))
+	defer cancel()
+	stream := p.scraperClient.ScrapeStream(scrapeCtx, urls, opts)
 
-	for _, result := range results {
-		batchResults = append(batchResults, links.Result{
+	chunk := make([]links.Result, 0, resultsChunkSize)
+	saved := 0
+	var err error
+	for result := range stream.Results() {
+		batchResult := links.Result{
 			ID:               uuid.NewString(),
-			BatchID:          batchID,
+			BatchID:          batch.ID,
 			PageURL:          result.PageURL,
 			InternalLinksNum: result.InternalLinksNum,
 			ExternalLinksNum: result.ExternalLinksNum,
+			InternalLinks:    result.InternalLinks,
+			ExternalLinks:    result.ExternalLinks,
 			Success:          result.Success,
 			Error:            result.Error,
+			TraceID:          traceID,
 			CreatedAt:        time.Now().UTC(),
 			UpdatedAt:        time.Now().UTC(),
-		})
+		}
+		batchResults = append(batchResults, batchResult)
+		p.progress.publish(batchResult)
+		p.observePage(batchResult)
+
+		chunk = append(chunk, batchResult)
+		if len(chunk) < resultsChunkSize {
+			continue
+		}
+		if err = p.repo.AppendResults(ctx, chunk); err != nil {
+			err = fmt.Errorf("failed to create results %w", err)
+			cancel()
+			break
+		}
+		saved += len(chunk)
+		chunk = make([]links.Result, 0, resultsChunkSize)
 	}
 
-	err := p.repo.CreateResults(ctx, batchResults)
+	if err == nil {
+		if streamErr := stream.Err(); streamErr != nil {
+			err = fmt.Errorf("failed to scrape urls %w", streamErr)
+		} else if len(chunk) > 0 || saved == 0 {
+			// a batch without any results is reported by the repository
+			if err = p.repo.AppendResults(ctx, chunk); err != nil {
+				err = fmt.Errorf("failed to create results %w", err)
+			}
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create results %w", err)
+		batch.Status = links.BatchStatusFailed
+		batch.UpdatedAt = time.Now().UTC()
+		if updateErr := p.repo.UpdateBatch(ctx, batch); updateErr != nil {
+			log.Println("failed to mark batch", batch.ID, "as failed", updateErr)
+		}
+		p.observeBatch(batch, start)
+		p.dispatchCallback(batch)
+		return nil, err
+	}
+
+	batch.Status = links.BatchStatusCompleted
+	batch.SuccessCount, batch.FailureCount = countOutcomes(batchResults)
+	batch.UpdatedAt = time.Now().UTC()
+	if err := p.repo.UpdateBatch(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to update batch %w", err)
 	}
+	p.observeBatch(batch, start)
+	p.dispatchCallback(batch)
 
-	return batchResults, err
+	return batchResults, nil
 }
 
-// GetBatch - get batch of urls results
-func (s *linkProcessor) GetBatch(ctx context.Context, req links.GetBatchRequest) ([]links.Result, error) {
-	results, err := s.repo.GetBatchResults(ctx, req.BatchID)
-	if err != nil {
+// observeBatch - reports a finished batch processed since start
+func (p *linkProcessor) observeBatch(batch links.Batch, start time.Time) {
+	if p.observer != nil {
+		p.observer.BatchFinished(batch, time.Since(start))
+	}
+}
+
+// observePage - reports a scraped page
+func (p *linkProcessor) observePage(result links.Result) {
+	if p.observer != nil {
+		p.observer.PageScraped(result)
+	}
+}
+
+// WatchBatch - live events of a batch: every result as it finishes, a progress summary every
+// progress interval and the completed event last, after which the channel is closed.
+// Batches which aren't being processed by this instance only get the completed event.
+func (p *linkProcessor) WatchBatch(ctx context.Context, batchID string) (<-chan links.BatchEvent, error) {
+	if err := p.authorizeBatch(ctx, batchID); err != nil {
 		return nil, fmt.Errorf("failed to get batch %w", err)
 	}
+	watcher, ok := p.progress.subscribe(batchID)
+	if !ok {
+		batch, err := p.getBatch(ctx, batchID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch %w", err)
+		}
+		events := make(chan links.BatchEvent, 1)
+		events <- links.BatchEvent{Type: links.BatchEventCompleted, Batch: &batch}
+		close(events)
+		return events, nil
+	}
+
+	events := make(chan links.BatchEvent)
+	go p.forwardEvents(ctx, watcher, events)
+	return events, nil
+}
+
+// forwardEvents - sends the events of the watcher and the periodic progress summaries until the
+// batch finished or the context is done
+func (p *linkProcessor) forwardEvents(ctx context.Context, watcher *subscription, events chan<- links.BatchEvent) {
+	defer close(events)
+	defer p.progress.unsubscribe(watcher)
+
+	send := func(event links.BatchEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	sendProgress := func() bool {
+		progress, ok := p.progress.progress(watcher.batchID)
+		return !ok || send(links.BatchEvent{Type: links.BatchEventProgress, Progress: &progress})
+	}
+
+	// the batch may finish before the first summary is sent, the progress at subscription is always sent
+	initial := watcher.initial
+	if !send(links.BatchEvent{Type: links.BatchEventProgress, Progress: &initial}) {
+		return
+	}
+	ticker := time.NewTicker(p.progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.events:
+			if !ok {
+				batch := watcher.final
+				send(links.BatchEvent{Type: links.BatchEventCompleted, Batch: &batch})
+				return
+			}
+			if !send(event) {
+				return
+			}
+		case <-ticker.C:
+			if !sendProgress() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatchCallback - notifies the callback of a finished batch, if it has one
+func (p *linkProcessor) dispatchCallback(batch links.Batch) {
+	if batch.Callback == nil {
+		return
+	}
+	if p.callbacks == nil {
+		log.Println("callbacks are not configured, skipping callback of batch", batch.ID)
+		return
+	}
+	p.callbacks.Dispatch(batch)
+}
+
+// ListCallbackAttempts - callback delivery attempts of a batch, oldest first
+func (s *linkProcessor) ListCallbackAttempts(ctx context.Context, batchID string) (links.ListCallbackAttemptsResponse, error) {
+	if err := s.authorizeBatch(ctx, batchID); err != nil {
+		return links.ListCallbackAttemptsResponse{}, fmt.Errorf("failed to list callback attempts %w", err)
+	}
+	attempts, err := s.repo.ListCallbackAttempts(ctx, batchID)
+	if err != nil {
+		return links.ListCallbackAttemptsResponse{}, fmt.Errorf("failed to list callback attempts %w", err)
+	}
+
+	return links.ListCallbackAttemptsResponse{Attempts: attempts}, nil
+}
+
+// GetBatch - get batch of urls results, filtered and paginated by the request query
+func (s *linkProcessor) GetBatch(ctx context.Context, req links.GetBatchRequest) (links.GetBatchResponse, error) {
+	if err := s.authorizeBatch(ctx, req.BatchID); err != nil {
+		return links.GetBatchResponse{}, fmt.Errorf("failed to get batch %w", err)
+	}
+	page, err := s.repo.ListBatchResults(ctx, req.BatchID, req.Query)
+	if err != nil {
+		return links.GetBatchResponse{}, fmt.Errorf("failed to get batch %w", err)
+	}
+
+	return links.GetBatchResponse{Results: page.Results, NextCursor: page.NextCursor}, nil
+}
+
+// ExportBatch - calls write for every result of the batch matching the query as the repository
+// iterates them, so large batches are streamed without being paged through.
+// The limit and cursor of the query are ignored, the export always starts at the first result.
+func (p *linkProcessor) ExportBatch(ctx context.Context, req links.GetBatchRequest, write func(links.Result) error) error {
+	if err := p.authorizeBatch(ctx, req.BatchID); err != nil {
+		return fmt.Errorf("failed to get batch %w", err)
+	}
+	query := req.Query
+	query.Limit = 0
+	query.Cursor = ""
+
+	var writeErr error
+	err := p.repo.EachBatchResult(ctx, req.BatchID, query, func(result links.Result) error {
+		writeErr = write(result)
+		return writeErr
+	})
+	if writeErr != nil {
+		return fmt.Errorf("failed to write result %w", writeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get batch %w", err)
+	}
+	return nil
+}
+
+// ListBatches - list batches page by page, defaultPageSize batches per page when no limit is set.
+// Only the batches of the api key scope of the context are listed.
+func (s *linkProcessor) ListBatches(ctx context.Context, req links.ListBatchesRequest) (links.ListBatchesResponse, error) {
+	query := req.Query
+	if query.Limit <= 0 {
+		query.Limit = defaultPageSize
+	}
+	if apiKeyID, ok := links.APIKeyScopeOf(ctx); ok {
+		query.APIKeyID = apiKeyID
+	}
+
+	page, err := s.repo.ListBatches(ctx, query)
+	if err != nil {
+		return links.ListBatchesResponse{}, fmt.Errorf("failed to list batches %w", err)
+	}
+
+	return links.ListBatchesResponse{Batches: page.Batches, NextCursor: page.NextCursor}, nil
+}
+
+// GetResult - get a single result, when a batch id is passed the result must belong to that batch
+func (s *linkProcessor) GetResult(ctx context.Context, req links.GetResultRequest) (links.Result, error) {
+	var (
+		result links.Result
+		err    error
+	)
+	if req.BatchID == "" {
+		result, err = s.repo.GetResult(ctx, req.ResultID)
+		if err == nil && s.authorizeBatch(ctx, result.BatchID) != nil {
+			err = repository.ErrResultNotFound
+		}
+	} else if err = s.authorizeBatch(ctx, req.BatchID); err == nil {
+		result, err = s.repo.GetBatchResult(ctx, req.BatchID, req.ResultID)
+	}
+	if err != nil {
+		return links.Result{}, fmt.Errorf("failed to get result %w", err)
+	}
+
+	return result, nil
+}
+
+// RetryBatch - rescrape the selected results of a batch, or all failed ones when none are selected,
+// with the options the batch was processed with. The results are updated in place and their
+// previous outcome is kept in the attempt history. A failed batch stays failed as the pages it never
+// got to have no results to retry.
+func (p *linkProcessor) RetryBatch(ctx context.Context, req links.RetryBatchRequest) (_ links.RetryBatchResponse, err error) {
+	ctx, span := p.tracer.Start(ctx, "linkProcessor.RetryBatch", batchAttributes(req.BatchID, len(req.ResultIDs), req.APIKeyID))
+	defer func() { endSpan(span, err) }()
+	batch, err := p.getBatch(ctx, req.BatchID)
+	if err != nil {
+		return links.RetryBatchResponse{}, fmt.Errorf("failed to get batch %w", err)
+	}
+	if batch.Status == links.BatchStatusRunning {
+		return links.RetryBatchResponse{}, links.ErrBatchInProgress
+	}
+
+	retried, err := p.resultsToRetry(ctx, req)
+	if err != nil {
+		return links.RetryBatchResponse{}, err
+	}
+	if len(retried) == 0 {
+		return links.RetryBatchResponse{Batch: batch, Results: retried}, nil
+	}
+	release, err := p.admit(ctx, req.APIKeyID, len(retried))
+	if err != nil {
+		return links.RetryBatchResponse{}, err
+	}
+	defer release()
+
+	opts, err := toScraperOptions(batch.Options)
+	if err != nil {
+		return links.RetryBatchResponse{}, err
+	}
+
+	urls := make([]*url.URL, 0, len(retried))
+	positions := map[string][]int{} // page url -> positions of the results scraping it
+	for i, result := range retried {
+		pageURL, err := url.Parse(result.PageURL)
+		if err != nil {
+			return links.RetryBatchResponse{}, fmt.Errorf("failed to parse page url %w", err)
+		}
+		urls = append(urls, pageURL)
+		positions[pageURL.String()] = append(positions[pageURL.String()], i)
+	}
+
+	previous := batch
+	batch.Status = links.BatchStatusRunning
+	batch.UpdatedAt = time.Now().UTC()
+	if err := p.repo.UpdateBatchIf(ctx, batch, previous.Status); err != nil {
+		if errors.Is(err, links.ErrBatchStatusChanged) {
+			return links.RetryBatchResponse{}, links.ErrBatchInProgress
+		}
+		return links.RetryBatchResponse{}, fmt.Errorf("failed to update batch %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("links.urls", len(urls)))
+	traceID := traceIDOf(ctx)
+	for _, scraped := range p.scraperClient.Scrape(p.withFetchSlots(detachContext(ctx), batch), urls, opts) {
+		queue := positions[scraped.PageURL]
+		if len(queue) == 0 {
+			log.Println("unexpected scrape result for", scraped.PageURL)
+			continue
+		}
+		positions[scraped.PageURL] = queue[1:]
+
+		result := &retried[queue[0]]
+		now := time.Now().UTC()
+		result.Attempts = append(append([]links.Attempt(nil), result.Attempts...), links.Attempt{
+			InternalLinksNum: result.InternalLinksNum,
+			ExternalLinksNum: result.ExternalLinksNum,
+			Success:          result.Success,
+			Error:            result.Error,
+			TraceID:          result.TraceID,
+			AttemptedAt:      result.UpdatedAt,
+		})
+		result.InternalLinksNum = scraped.InternalLinksNum
+		result.ExternalLinksNum = scraped.ExternalLinksNum
+		result.InternalLinks = scraped.InternalLinks
+		result.ExternalLinks = scraped.ExternalLinks
+		result.Success = scraped.Success
+		result.Error = scraped.Error
+		result.TraceID = traceID
+		result.UpdatedAt = now
+		p.observePage(*result)
+	}
+
+	if err := p.repo.UpdateResults(ctx, retried); err != nil {
+		p.restoreBatch(ctx, previous)
+		return links.RetryBatchResponse{}, fmt.Errorf("failed to update results %w", err)
+	}
+	stored, err := p.repo.GetBatchResults(ctx, batch.ID)
+	if err != nil {
+		p.restoreBatch(ctx, previous)
+		return links.RetryBatchResponse{}, fmt.Errorf("failed to get results %w", err)
+	}
+
+	batch.SuccessCount, batch.FailureCount = countOutcomes(stored)
+	batch.Status = links.BatchStatusCompleted
+	if len(stored) < batch.URLCount {
+		batch.Status = links.BatchStatusFailed
+	}
+	batch.UpdatedAt = time.Now().UTC()
+	if err := p.repo.UpdateBatch(ctx, batch); err != nil {
+		p.restoreBatch(ctx, previous)
+		return links.RetryBatchResponse{}, fmt.Errorf("failed to update batch %w", err)
+	}
+
+	return links.RetryBatchResponse{Batch: batch, Results: retried}, nil
+}
+
+// restoreBatch - put back the batch as it was before a retry which couldn't be saved
+func (p *linkProcessor) restoreBatch(ctx context.Context, previous links.Batch) {
+	previous.UpdatedAt = time.Now().UTC()
+	if err := p.repo.UpdateBatch(ctx, previous); err != nil {
+		log.Println("failed to restore batch", previous.ID, err)
+	}
+}
+
+// resultsToRetry - the requested results of the batch, or all of its failed results
+func (p *linkProcessor) resultsToRetry(ctx context.Context, req links.RetryBatchRequest) ([]links.Result, error) {
+	if len(req.ResultIDs) == 0 {
+		failed := false
+		page, err := p.repo.ListBatchResults(ctx, req.BatchID, links.ResultQuery{Success: &failed})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch %w", err)
+		}
+		return page.Results, nil
+	}
 
+	results := make([]links.Result, 0, len(req.ResultIDs))
+	seen := map[string]bool{}
+	for _, resultID := range req.ResultIDs {
+		if seen[resultID] {
+			continue
+		}
+		seen[resultID] = true
+
+		result, err := p.repo.GetBatchResult(ctx, req.BatchID, resultID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get result %w", err)
+		}
+		results = append(results, result)
+	}
 	return results, nil
 }
+
+// DiffBatches - pages which differ between the base and the target batch
+func (s *linkProcessor) DiffBatches(ctx context.Context, req links.DiffBatchesRequest) (links.BatchDiff, error) {
+	batches := make([]links.Batch, 0, 2)
+	results := make([][]links.Result, 0, 2)
+	for _, batchID := range []string{req.BaseBatchID, req.TargetBatchID} {
+		batch, err := s.getBatch(ctx, batchID)
+		if err != nil {
+			return links.BatchDiff{}, fmt.Errorf("failed to get batch %w", err)
+		}
+		if batch.Status == links.BatchStatusRunning {
+			return links.BatchDiff{}, links.ErrBatchInProgress
+		}
+
+		batchResults, err := s.repo.GetBatchResults(ctx, batchID)
+		if err != nil {
+			return links.BatchDiff{}, fmt.Errorf("failed to get batch %w", err)
+		}
+
+		batches = append(batches, batch)
+		results = append(results, batchResults)
+	}
+
+	return diffBatches(batches[0], batches[1], results[0], results[1]), nil
+}
+
+// ReportBatch - summary of a finished batch for its report
+func (s *linkProcessor) ReportBatch(ctx context.Context, batchID string) (links.BatchReport, error) {
+	batch, err := s.getBatch(ctx, batchID)
+	if err != nil {
+		return links.BatchReport{}, fmt.Errorf("failed to get batch %w", err)
+	}
+	if batch.Status == links.BatchStatusRunning {
+		return links.BatchReport{}, links.ErrBatchInProgress
+	}
+
+	results, err := s.repo.GetBatchResults(ctx, batchID)
+	if err != nil {
+		return links.BatchReport{}, fmt.Errorf("failed to get batch %w", err)
+	}
+
+	return buildReport(batch, results), nil
+}
+
+// getBatch - get batch by id, batches of other api keys than the scope of the context aren't found
+func (p *linkProcessor) getBatch(ctx context.Context, batchID string) (links.Batch, error) {
+	batch, err := p.repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return links.Batch{}, err
+	}
+	if !links.InAPIKeyScope(ctx, batch.APIKeyID) {
+		return links.Batch{}, repository.ErrBatchNotFound
+	}
+	return batch, nil
+}
+
+// authorizeBatch - repository.ErrBatchNotFound when the batch can't be looked up with the api key scope
+// of the context, the batch is only read when the context has a scope
+func (p *linkProcessor) authorizeBatch(ctx context.Context, batchID string) error {
+	if _, ok := links.APIKeyScopeOf(ctx); !ok {
+		return nil
+	}
+	_, err := p.getBatch(ctx, batchID)
+	return err
+}
+
+// countOutcomes - number of successful and failed results
+func countOutcomes(results []links.Result) (success, failure int) {
+	for _, result := range results {
+		if result.Success {
+			success++
+			continue
+		}
+		failure++
+	}
+	return
+}
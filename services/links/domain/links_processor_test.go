@@ -3,16 +3,22 @@ package domain_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	pkgmocks "github.com/Lockwarr/codefi/pkg/mocks"
 	"github.com/Lockwarr/codefi/pkg/scraper"
 	"github.com/Lockwarr/codefi/services/links"
 	"github.com/Lockwarr/codefi/services/links/domain"
 	"github.com/Lockwarr/codefi/services/links/mocks"
+	"github.com/Lockwarr/codefi/services/links/repository"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type linkProcessorTestSuite struct {
@@ -44,8 +50,12 @@ func (s *linkProcessorTestSuite) TestProcessBatch_ThenProcessSucessfully() {
 		PageURL: "test1",
 	}
 
-	s.mockScraperClient.On("Scrape", []*url.URL{urlGenerated}).Return([]scraper.Result{expectedScraperResult}, nil)
-	s.mockRepo.On("CreateResults", mock.Anything).Return(nil)
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{expectedScraperResult}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusCompleted && batch.URLCount == 1 && batch.SuccessCount == 0 && batch.FailureCount == 1
+	})).Return(nil)
 
 	// Act
 	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
@@ -55,15 +65,19 @@ func (s *linkProcessorTestSuite) TestProcessBatch_ThenProcessSucessfully() {
 	s.Equal(1, len(res))
 }
 
-func (s *linkProcessorTestSuite) TestProcessBatch_WhenCreateResultsFails_ThenFail() {
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenAppendResultsFails_ThenFail() {
 	// Arrange
 	urlGenerated, _ := url.Parse("http://google.com")
 	expectedScraperResult := scraper.Result{
 		PageURL: "test1",
 	}
 
-	s.mockScraperClient.On("Scrape", []*url.URL{urlGenerated}).Return([]scraper.Result{expectedScraperResult}, nil)
-	s.mockRepo.On("CreateResults", mock.Anything).Return(errors.New("error"))
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{expectedScraperResult}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(errors.New("error"))
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusFailed
+	})).Return(nil)
 
 	// Act
 	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
@@ -73,30 +87,788 @@ func (s *linkProcessorTestSuite) TestProcessBatch_WhenCreateResultsFails_ThenFai
 	s.Equal([]links.Result([]links.Result(nil)), res)
 }
 
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenManyResults_ThenTheyAreSavedInChunks() {
+	// Arrange
+	urls := make([]*url.URL, 120)
+	scraperResults := make([]scraper.Result, 120)
+	for i := range urls {
+		urls[i], _ = url.Parse(fmt.Sprintf("http://google.com/%d", i))
+		scraperResults[i] = scraper.Result{PageURL: urls[i].String(), Success: true}
+	}
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", urls, scraper.DefaultOptions()).Return(scraperResults, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusCompleted && batch.SuccessCount == 120
+	})).Return(nil)
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: urls})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(120, len(res))
+	chunkSizes := []int{}
+	for _, call := range s.mockRepo.Calls {
+		if call.Method == "AppendResults" {
+			chunkSizes = append(chunkSizes, len(call.Arguments.Get(0).([]links.Result)))
+		}
+	}
+	s.Equal([]int{50, 50, 20}, chunkSizes)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenSavingAChunkFails_ThenTheRestIsNotSaved() {
+	// Arrange
+	urls := make([]*url.URL, 120)
+	scraperResults := make([]scraper.Result, 120)
+	for i := range urls {
+		urls[i], _ = url.Parse(fmt.Sprintf("http://google.com/%d", i))
+		scraperResults[i] = scraper.Result{PageURL: urls[i].String(), Success: true}
+	}
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", urls, scraper.DefaultOptions()).Return(scraperResults, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(errors.New("error")).Once()
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusFailed
+	})).Return(nil)
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: urls})
+
+	// Assert
+	s.Equal("failed to create results error", err.Error())
+	s.Nil(res)
+	s.mockRepo.AssertNumberOfCalls(s.T(), "AppendResults", 1)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenStreamEndsWithError_ThenFail() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{}, context.DeadlineExceeded)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusFailed
+	})).Return(nil)
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
+
+	// Assert
+	s.Equal("failed to scrape urls context deadline exceeded", err.Error())
+	s.Nil(res)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenOptionsAreSet_ThenTheyAreAppliedAndStored() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+	expectedOpts := scraper.DefaultOptions()
+	expectedOpts.Timeout = 5 * time.Second
+	expectedOpts.UserAgent = "links-bot"
+	expectedOpts.InternalPolicy = scraper.InternalSameDomain
+
+	s.mockRepo.On("CreateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Options.TimeoutMS == 5000 &&
+			batch.Options.UserAgent == "links-bot" &&
+			batch.Options.InternalPolicy == "same_domain" &&
+			batch.Options.RedirectPolicy == "follow" // defaults are stored as well
+	})).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, expectedOpts).Return([]scraper.Result{{PageURL: "test1", Success: true}}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusCompleted && batch.SuccessCount == 1 && batch.FailureCount == 0
+	})).Return(nil)
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{
+		URLs:    []*url.URL{urlGenerated},
+		Options: links.ScrapeOptions{TimeoutMS: 5000, UserAgent: "links-bot", InternalPolicy: "same_domain"},
+	})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(1, len(res))
+	s.Equal(res[0].BatchID, s.mockRepo.Calls[0].Arguments.Get(0).(links.Batch).ID)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenOptionsAreInvalid_ThenFail() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{
+		URLs:    []*url.URL{urlGenerated},
+		Options: links.ScrapeOptions{RedirectPolicy: "sometimes"},
+	})
+
+	// Assert
+	s.ErrorIs(err, scraper.ErrInvalidOptions)
+	s.Equal([]links.Result([]links.Result(nil)), res)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenCreateBatchFails_ThenFail() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(errors.New("error"))
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
+
+	// Assert
+	s.Equal("failed to create batch error", err.Error())
+	s.Equal([]links.Result([]links.Result(nil)), res)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenAPIKeyIsSet_ThenBatchRecordsItAndQuotaIsReleased() {
+	// Arrange
+	ctx := context.Background()
+	urlGenerated, _ := url.Parse("http://google.com")
+	apiKeys := domain.NewAPIKeyManager(repository.NewInMemoryDB())
+	issued, _ := apiKeys.IssueAPIKey(ctx, links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{ConcurrentBatches: 1}})
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithQuotaLimiter(apiKeys))
+
+	s.mockRepo.On("CreateBatch", mock.MatchedBy(func(batch links.Batch) bool { return batch.APIKeyID == issued.ID })).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "test1"}}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+
+	// Act
+	_, err := linkProcessor.ProcessBatch(ctx, links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}, APIKeyID: issued.ID})
+	release, acquireErr := apiKeys.AcquireBatch(ctx, issued.ID, 1)
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(nil, acquireErr)
+	release()
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenQuotaIsExceeded_ThenBatchIsNotCreated() {
+	// Arrange
+	ctx := context.Background()
+	urlGenerated, _ := url.Parse("http://google.com")
+	apiKeys := domain.NewAPIKeyManager(repository.NewInMemoryDB())
+	issued, _ := apiKeys.IssueAPIKey(ctx, links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{URLsPerDay: 1}})
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithQuotaLimiter(apiKeys))
+
+	// Act
+	res, err := linkProcessor.ProcessBatch(ctx, links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated, urlGenerated}, APIKeyID: issued.ID})
+
+	// Assert
+	s.ErrorIs(err, links.ErrQuotaExceeded)
+	s.Equal([]links.Result(nil), res)
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenServerIsBusy_ThenBatchIsNotCreatedAndQuotaIsKept() {
+	// Arrange
+	ctx := context.Background()
+	urlGenerated, _ := url.Parse("http://google.com")
+	apiKeys := domain.NewAPIKeyManager(repository.NewInMemoryDB())
+	issued, _ := apiKeys.IssueAPIKey(ctx, links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{ConcurrentBatches: 1}})
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{MaxQueuedBatches: 1})
+	_, _ = admission.AdmitBatch()
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithQuotaLimiter(apiKeys), domain.WithAdmissionController(admission))
+
+	// Act
+	res, err := linkProcessor.ProcessBatch(ctx, links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}, APIKeyID: issued.ID})
+	release, quotaErr := apiKeys.AcquireBatch(ctx, issued.ID, 1)
+
+	// Assert
+	s.ErrorIs(err, links.ErrServerBusy)
+	s.Equal([]links.Result(nil), res)
+	s.NoError(quotaErr)
+	release()
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenObserverIsSet_ThenBatchAndPagesAreReported() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+	observer := new(mocks.MockBatchObserver)
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithBatchObserver(observer))
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "http://google.com", Success: true}}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+	observer.On("PageScraped", mock.MatchedBy(func(result links.Result) bool {
+		return result.PageURL == "http://google.com" && result.Success
+	})).Return().Once()
+	observer.On("BatchFinished", mock.MatchedBy(func(batch links.Batch) bool {
+		return batch.Status == links.BatchStatusCompleted && batch.SuccessCount == 1
+	}), mock.Anything).Return().Once()
+
+	// Act
+	_, err := linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
+
+	// Assert
+	s.Equal(nil, err)
+	observer.AssertExpectations(s.T())
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenServerIsBusy_ThenRejectionIsReported() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+	observer := new(mocks.MockBatchObserver)
+	admission := domain.NewAdmissionController(domain.AdmissionLimits{MaxQueuedBatches: 1})
+	_, _ = admission.AdmitBatch()
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithAdmissionController(admission), domain.WithBatchObserver(observer))
+	observer.On("BatchRejected", mock.MatchedBy(func(err error) bool { return errors.Is(err, links.ErrServerBusy) })).Return().Once()
+
+	// Act
+	_, err := linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
+
+	// Assert
+	s.ErrorIs(err, links.ErrServerBusy)
+	observer.AssertExpectations(s.T())
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenTracerProviderIsSet_ThenResultsRecordTheTrace() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+	recorder := tracetest.NewSpanRecorder()
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient,
+		domain.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "http://google.com", Success: true}}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+
+	// Act
+	results, err := linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
+
+	// Assert
+	s.Equal(nil, err)
+	spans := recorder.Ended()
+	s.Equal(1, len(spans))
+	s.Equal("linkProcessor.ProcessBatch", spans[0].Name())
+	s.Contains(spans[0].Attributes(), attribute.String("links.batch_id", results[0].BatchID))
+	s.Equal(spans[0].SpanContext().TraceID().String(), results[0].TraceID)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenTracerProviderIsSet_ThenAttemptsKeepTheirTrace() {
+	// Arrange
+	createdAt := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 1, FailureCount: 1, CreatedAt: createdAt, UpdatedAt: createdAt}
+	failed := []links.Result{{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com", Error: errors.New("timeout"), TraceID: "previous", CreatedAt: createdAt, UpdatedAt: createdAt}}
+	google, _ := url.Parse("https://www.google.com")
+	noSuccess := false
+	recorder := tracetest.NewSpanRecorder()
+	linkProcessor := domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient,
+		domain.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: failed}, nil)
+	s.mockRepo.On("UpdateBatchIf", mock.Anything, links.BatchStatusCompleted).Return(nil)
+	s.mockScraperClient.On("Scrape", []*url.URL{google}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "https://www.google.com", Success: true}})
+	s.mockRepo.On("UpdateResults", mock.Anything).Return(nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return([]links.Result{{ID: "r1", Success: true}}, nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+
+	// Act
+	res, err := linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.Equal(nil, err)
+	spans := recorder.Ended()
+	s.Equal(1, len(spans))
+	s.Equal("linkProcessor.RetryBatch", spans[0].Name())
+	s.Equal(spans[0].SpanContext().TraceID().String(), res.Results[0].TraceID)
+	s.Equal("previous", res.Results[0].Attempts[0].TraceID)
+}
+
 func (s *linkProcessorTestSuite) TestGetBatch_ThenSucess() {
 	// Arrange
 	batchID := "batchID"
 
-	s.mockRepo.On("GetBatchResults", batchID).Return([]links.Result{}, nil)
+	s.mockRepo.On("ListBatchResults", batchID, links.ResultQuery{}).Return(links.ResultPage{Results: []links.Result{}}, nil)
 
 	// Act
 	res, err := s.linkProcessor.GetBatch(context.Background(), links.GetBatchRequest{BatchID: batchID})
 
 	// Assert
 	s.Equal(nil, err)
-	s.Equal(0, len(res))
+	s.Equal(0, len(res.Results))
+}
+
+func (s *linkProcessorTestSuite) TestGetBatch_WhenBatchOfAnotherAPIKey_ThenNotFound() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{ID: "batchID", APIKeyID: "k1"}, nil)
+
+	// Act
+	_, err := s.linkProcessor.GetBatch(links.WithAPIKeyScope(context.Background(), "k2"), links.GetBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.ErrorIs(err, repository.ErrBatchNotFound)
+}
+
+func (s *linkProcessorTestSuite) TestGetResult_WhenResultOfAnotherAPIKey_ThenNotFound() {
+	// Arrange
+	s.mockRepo.On("GetResult", "r1").Return(links.Result{ID: "r1", BatchID: "batchID"}, nil)
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{ID: "batchID", APIKeyID: "k1"}, nil)
+
+	// Act
+	_, err := s.linkProcessor.GetResult(links.WithAPIKeyScope(context.Background(), "k2"), links.GetResultRequest{ResultID: "r1"})
+
+	// Assert
+	s.ErrorIs(err, repository.ErrResultNotFound)
+}
+
+func (s *linkProcessorTestSuite) TestGetBatch_WhenQueryIsSet_ThenItIsPushedDown() {
+	// Arrange
+	batchID := "batchID"
+	success := true
+	query := links.ResultQuery{Limit: 1, SortBy: links.ResultSortExternalLinksNum, Descending: true, Success: &success}
+	page := links.ResultPage{Results: []links.Result{{ID: "test1", BatchID: batchID}}, NextCursor: "next"}
+
+	s.mockRepo.On("ListBatchResults", batchID, query).Return(page, nil)
+
+	// Act
+	res, err := s.linkProcessor.GetBatch(context.Background(), links.GetBatchRequest{BatchID: batchID, Query: query})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.GetBatchResponse{Results: page.Results, NextCursor: "next"}, res)
 }
 
 func (s *linkProcessorTestSuite) TestGetBatch_WhenGetBatchResultsFail_ThenFail() {
 	// Arrange
 	batchID := "batchID"
 
-	s.mockRepo.On("GetBatchResults", batchID).Return([]links.Result{}, errors.New("error"))
+	s.mockRepo.On("ListBatchResults", batchID, links.ResultQuery{}).Return(links.ResultPage{}, errors.New("error"))
 
 	// Act
 	res, err := s.linkProcessor.GetBatch(context.Background(), links.GetBatchRequest{BatchID: batchID})
 
 	// Assert
 	s.Equal("failed to get batch error", err.Error())
+	s.Equal(links.GetBatchResponse{}, res)
+}
+
+func (s *linkProcessorTestSuite) TestExportBatch_ThenEveryResultIsWritten() {
+	// Arrange
+	batchID := "batchID"
+	success := true
+	query := links.ResultQuery{Success: &success, SortBy: links.ResultSortPageURL}
+
+	s.mockRepo.On("EachBatchResult", batchID, query).Return([]links.Result{{ID: "r0"}, {ID: "r1"}, {ID: "r2"}}, nil)
+	written := []string{}
+
+	// Act
+	err := s.linkProcessor.ExportBatch(context.Background(), links.GetBatchRequest{BatchID: batchID, Query: links.ResultQuery{
+		Success: query.Success, SortBy: query.SortBy, Limit: 10, Cursor: "ignored",
+	}}, func(result links.Result) error {
+		written = append(written, result.ID)
+		return nil
+	})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal([]string{"r0", "r1", "r2"}, written)
+}
+
+func (s *linkProcessorTestSuite) TestExportBatch_WhenWriteFails_ThenFail() {
+	// Arrange
+	batchID := "batchID"
+
+	s.mockRepo.On("EachBatchResult", batchID, links.ResultQuery{}).Return([]links.Result{{ID: "r0"}, {ID: "r1"}}, nil)
+	written := 0
+
+	// Act
+	err := s.linkProcessor.ExportBatch(context.Background(), links.GetBatchRequest{BatchID: batchID}, func(result links.Result) error {
+		written++
+		return errors.New("broken pipe")
+	})
+
+	// Assert
+	s.Equal("failed to write result broken pipe", err.Error())
+	s.Equal(1, written)
+}
+
+func (s *linkProcessorTestSuite) TestExportBatch_WhenEachBatchResultFails_ThenFail() {
+	// Arrange
+	batchID := "batchID"
+
+	s.mockRepo.On("EachBatchResult", batchID, links.ResultQuery{}).Return([]links.Result{}, repository.ErrBatchNotFound)
+
+	// Act
+	err := s.linkProcessor.ExportBatch(context.Background(), links.GetBatchRequest{BatchID: batchID}, func(result links.Result) error {
+		return nil
+	})
+
+	// Assert
+	s.ErrorIs(err, repository.ErrBatchNotFound)
+}
+
+func (s *linkProcessorTestSuite) TestGetResult_WhenBatchIDIsPassed_ThenTheBatchIsSearched() {
+	// Arrange
+	result := links.Result{ID: "resultID", BatchID: "batchID"}
+	s.mockRepo.On("GetBatchResult", "batchID", "resultID").Return(result, nil)
+
+	// Act
+	res, err := s.linkProcessor.GetResult(context.Background(), links.GetResultRequest{BatchID: "batchID", ResultID: "resultID"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(result, res)
+}
+
+func (s *linkProcessorTestSuite) TestGetResult_WhenBatchIDIsMissing_ThenEveryBatchIsSearched() {
+	// Arrange
+	result := links.Result{ID: "resultID", BatchID: "batchID"}
+	s.mockRepo.On("GetResult", "resultID").Return(result, nil)
+
+	// Act
+	res, err := s.linkProcessor.GetResult(context.Background(), links.GetResultRequest{ResultID: "resultID"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(result, res)
+}
+
+func (s *linkProcessorTestSuite) TestGetResult_WhenRepositoryFails_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetResult", "resultID").Return(links.Result{}, errors.New("error"))
+
+	// Act
+	res, err := s.linkProcessor.GetResult(context.Background(), links.GetResultRequest{ResultID: "resultID"})
+
+	// Assert
+	s.Equal("failed to get result error", err.Error())
+	s.Equal(links.Result{}, res)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenNoResultsAreSelected_ThenFailedResultsAreRetried() {
+	// Arrange
+	createdAt := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 3, SuccessCount: 1, FailureCount: 2,
+		Options: links.ScrapeOptions{TimeoutMS: 1000}, CreatedAt: createdAt, UpdatedAt: createdAt}
+	failed := []links.Result{
+		{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com", Error: errors.New("timeout"), CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "r2", BatchID: "batchID", PageURL: "https://www.facebook.com", Error: errors.New("bad status code"), CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+	google, _ := url.Parse("https://www.google.com")
+	facebook, _ := url.Parse("https://www.facebook.com")
+	opts := scraper.Options{Timeout: time.Second}.WithDefaults()
+	noSuccess := false
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: failed}, nil)
+	s.mockRepo.On("UpdateBatchIf", mock.MatchedBy(func(b links.Batch) bool { return b.Status == links.BatchStatusRunning }), links.BatchStatusCompleted).Return(nil).Once()
+	s.mockScraperClient.On("Scrape", []*url.URL{google, facebook}, opts).Return([]scraper.Result{
+		{PageURL: "https://www.facebook.com", Error: errors.New("bad status code")},
+		{PageURL: "https://www.google.com", InternalLinksNum: 6, ExternalLinksNum: 13, Success: true},
+	})
+	s.mockRepo.On("UpdateResults", mock.Anything).Return(nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return([]links.Result{{ID: "r0", Success: true}, {ID: "r1", Success: true}, {ID: "r2"}}, nil)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(b links.Batch) bool {
+		return b.Status == links.BatchStatusCompleted && b.SuccessCount == 2 && b.FailureCount == 1 && b.UpdatedAt.After(createdAt)
+	})).Return(nil).Once()
+
+	// Act
+	res, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(2, res.Batch.SuccessCount)
+	s.Equal(1, res.Batch.FailureCount)
+	s.Equal(2, len(res.Results))
+	s.Equal("r1", res.Results[0].ID)
+	s.True(res.Results[0].Success)
+	s.Equal(uint(6), res.Results[0].InternalLinksNum)
+	s.Equal(createdAt, res.Results[0].CreatedAt)
+	s.True(res.Results[0].UpdatedAt.After(createdAt))
+	s.Equal([]links.Attempt{{Error: errors.New("timeout"), AttemptedAt: createdAt}}, res.Results[0].Attempts)
+	s.False(res.Results[1].Success)
+	s.Equal(1, len(res.Results[1].Attempts))
+	s.Equal(res.Results, s.mockRepo.Calls[3].Arguments.Get(0).([]links.Result))
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenResultsAreSelected_ThenOnlyTheyAreRetried() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 2, SuccessCount: 2}
+	selected := links.Result{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com", InternalLinksNum: 6, ExternalLinksNum: 13, Success: true}
+	google, _ := url.Parse("https://www.google.com")
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("GetBatchResult", "batchID", "r1").Return(selected, nil).Once()
+	s.mockRepo.On("UpdateBatchIf", mock.Anything, links.BatchStatusCompleted).Return(nil)
+	s.mockScraperClient.On("Scrape", []*url.URL{google}, scraper.DefaultOptions()).Return([]scraper.Result{
+		{PageURL: "https://www.google.com", Error: errors.New("timeout")},
+	})
+	s.mockRepo.On("UpdateResults", mock.Anything).Return(nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return([]links.Result{{ID: "r1"}, {ID: "r2", Success: true}}, nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+
+	// Act
+	res, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID", ResultIDs: []string{"r1", "r1"}})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(1, res.Batch.SuccessCount)
+	s.Equal(1, res.Batch.FailureCount)
+	s.Equal(1, len(res.Results))
+	s.False(res.Results[0].Success)
+	s.Equal([]links.Attempt{{InternalLinksNum: 6, ExternalLinksNum: 13, Success: true}}, res.Results[0].Attempts)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenNothingFailed_ThenNothingIsScraped() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 1, SuccessCount: 1}
+	noSuccess := false
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: []links.Result{}}, nil)
+
+	// Act
+	res, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.RetryBatchResponse{Batch: batch, Results: []links.Result{}}, res)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenBatchIsRunning_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{ID: "batchID", Status: links.BatchStatusRunning}, nil)
+
+	// Act
+	_, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.ErrorIs(err, links.ErrBatchInProgress)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenSelectedResultIsMissing_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{ID: "batchID", Status: links.BatchStatusCompleted}, nil)
+	s.mockRepo.On("GetBatchResult", "batchID", "unknown").Return(links.Result{}, errors.New("error"))
+
+	// Act
+	_, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID", ResultIDs: []string{"unknown"}})
+
+	// Assert
+	s.Equal("failed to get result error", err.Error())
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenUpdateResultsFails_ThenBatchIsRestored() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 1, FailureCount: 1}
+	failed := links.Result{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com"}
+	google, _ := url.Parse("https://www.google.com")
+	noSuccess := false
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: []links.Result{failed}}, nil)
+	s.mockRepo.On("UpdateBatchIf", mock.MatchedBy(func(b links.Batch) bool { return b.Status == links.BatchStatusRunning }), links.BatchStatusCompleted).Return(nil).Once()
+	s.mockScraperClient.On("Scrape", []*url.URL{google}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "https://www.google.com", Success: true}})
+	s.mockRepo.On("UpdateResults", mock.Anything).Return(errors.New("error"))
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(b links.Batch) bool {
+		return b.Status == links.BatchStatusCompleted && b.SuccessCount == 0 && b.FailureCount == 1
+	})).Return(nil).Once()
+
+	// Act
+	res, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.Equal("failed to update results error", err.Error())
+	s.Equal(links.RetryBatchResponse{}, res)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenFinalUpdateBatchFails_ThenBatchIsRestored() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 1, FailureCount: 1}
+	failed := links.Result{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com"}
+	google, _ := url.Parse("https://www.google.com")
+	noSuccess := false
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: []links.Result{failed}}, nil)
+	s.mockRepo.On("UpdateBatchIf", mock.MatchedBy(func(b links.Batch) bool { return b.Status == links.BatchStatusRunning }), links.BatchStatusCompleted).Return(nil).Once()
+	s.mockScraperClient.On("Scrape", []*url.URL{google}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "https://www.google.com", Success: true}})
+	s.mockRepo.On("UpdateResults", mock.Anything).Return(nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return([]links.Result{{ID: "r1", BatchID: "batchID", Success: true}}, nil)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(b links.Batch) bool {
+		return b.Status == links.BatchStatusCompleted && b.SuccessCount == 1 && b.FailureCount == 0
+	})).Return(errors.New("error")).Once()
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(b links.Batch) bool {
+		return b.Status == links.BatchStatusCompleted && b.SuccessCount == 0 && b.FailureCount == 1
+	})).Return(nil).Once()
+
+	// Act
+	res, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.Equal("failed to update batch error", err.Error())
+	s.Equal(links.RetryBatchResponse{}, res)
+	s.mockRepo.AssertExpectations(s.T())
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenAnotherRetryClaimedTheBatch_ThenFail() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 1, FailureCount: 1}
+	failed := links.Result{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com"}
+	noSuccess := false
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: []links.Result{failed}}, nil)
+	s.mockRepo.On("UpdateBatchIf", mock.Anything, links.BatchStatusCompleted).Return(links.ErrBatchStatusChanged)
+
+	// Act
+	_, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.ErrorIs(err, links.ErrBatchInProgress)
+	s.mockScraperClient.AssertNotCalled(s.T(), "Scrape", mock.Anything, mock.Anything)
+}
+
+func (s *linkProcessorTestSuite) TestRetryBatch_WhenBatchFailed_ThenItStaysFailedWithRecountedOutcomes() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusFailed, URLCount: 3, FailureCount: 1}
+	failed := links.Result{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com"}
+	google, _ := url.Parse("https://www.google.com")
+	noSuccess := false
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("ListBatchResults", "batchID", links.ResultQuery{Success: &noSuccess}).Return(links.ResultPage{Results: []links.Result{failed}}, nil)
+	s.mockRepo.On("UpdateBatchIf", mock.Anything, links.BatchStatusFailed).Return(nil)
+	s.mockScraperClient.On("Scrape", []*url.URL{google}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "https://www.google.com", Success: true}})
+	s.mockRepo.On("UpdateResults", mock.Anything).Return(nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return([]links.Result{{ID: "r1", Success: true}}, nil)
+	s.mockRepo.On("UpdateBatch", mock.MatchedBy(func(b links.Batch) bool {
+		return b.Status == links.BatchStatusFailed && b.SuccessCount == 1 && b.FailureCount == 0
+	})).Return(nil).Once()
+
+	// Act
+	res, err := s.linkProcessor.RetryBatch(context.Background(), links.RetryBatchRequest{BatchID: "batchID"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.BatchStatusFailed, res.Batch.Status)
+	s.Equal(1, res.Batch.SuccessCount)
+	s.Equal(0, res.Batch.FailureCount)
+}
+
+func (s *linkProcessorTestSuite) TestDiffBatches_ThenChangedPagesAreReported() {
+	// Arrange
+	collect := links.ScrapeOptions{CollectLinks: true}
+	s.mockRepo.On("GetBatch", "base").Return(links.Batch{ID: "base", Status: links.BatchStatusCompleted, Options: collect}, nil)
+	s.mockRepo.On("GetBatch", "target").Return(links.Batch{ID: "target", Status: links.BatchStatusCompleted, Options: collect}, nil)
+	s.mockRepo.On("GetBatchResults", "base").Return([]links.Result{
+		{PageURL: "https://www.google.com", InternalLinksNum: 1, ExternalLinksNum: 1, Success: true,
+			InternalLinks: []string{"https://www.google.com/maps"}, ExternalLinks: []string{"https://youtube.com/"}},
+		{PageURL: "https://www.facebook.com", InternalLinksNum: 27, ExternalLinksNum: 20, Success: true},
+		{PageURL: "https://unchanged.example.com/", Success: true},
+		{PageURL: "https://removed.example.com/", InternalLinksNum: 2, Success: true},
+	}, nil)
+	s.mockRepo.On("GetBatchResults", "target").Return([]links.Result{
+		{PageURL: "https://WWW.google.com:443/#top", InternalLinksNum: 1, ExternalLinksNum: 1, Success: true,
+			InternalLinks: []string{"https://www.google.com/maps"}, ExternalLinks: []string{"https://gmail.com/"}},
+		{PageURL: "https://www.facebook.com/"},
+		{PageURL: "https://unchanged.example.com", Success: true},
+		{PageURL: "https://added.example.com/", ExternalLinksNum: 3, Success: true},
+	}, nil)
+	yes, no := true, false
+
+	// Act
+	diff, err := s.linkProcessor.DiffBatches(context.Background(), links.DiffBatchesRequest{BaseBatchID: "base", TargetBatchID: "target"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.BatchDiff{
+		BaseBatchID:    "base",
+		TargetBatchID:  "target",
+		LinkDetails:    true,
+		AddedCount:     1,
+		RemovedCount:   1,
+		ChangedCount:   2,
+		UnchangedCount: 1,
+		Pages: []links.PageDiff{
+			{PageURL: "https://added.example.com/", Change: links.PageAdded, TargetSuccess: &yes, ExternalLinksDelta: 3},
+			{PageURL: "https://removed.example.com/", Change: links.PageRemoved, BaseSuccess: &yes, InternalLinksDelta: -2},
+			{PageURL: "https://www.facebook.com/", Change: links.PageChanged, BaseSuccess: &yes, TargetSuccess: &no, InternalLinksDelta: -27, ExternalLinksDelta: -20},
+			{PageURL: "https://www.google.com/", Change: links.PageChanged, BaseSuccess: &yes, TargetSuccess: &yes,
+				LinksAdded: []string{"https://gmail.com/"}, LinksRemoved: []string{"https://youtube.com/"}},
+		},
+	}, diff)
+}
+
+func (s *linkProcessorTestSuite) TestDiffBatches_WhenLinksWereNotCollected_ThenOnlyCountsAreCompared() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "base").Return(links.Batch{ID: "base", Status: links.BatchStatusCompleted, Options: links.ScrapeOptions{CollectLinks: true}}, nil)
+	s.mockRepo.On("GetBatch", "target").Return(links.Batch{ID: "target", Status: links.BatchStatusCompleted}, nil)
+	s.mockRepo.On("GetBatchResults", "base").Return([]links.Result{
+		{PageURL: "https://www.google.com/", ExternalLinksNum: 1, Success: true, ExternalLinks: []string{"https://youtube.com/"}},
+	}, nil)
+	s.mockRepo.On("GetBatchResults", "target").Return([]links.Result{
+		{PageURL: "https://www.google.com/", ExternalLinksNum: 1, Success: true},
+	}, nil)
+
+	// Act
+	diff, err := s.linkProcessor.DiffBatches(context.Background(), links.DiffBatchesRequest{BaseBatchID: "base", TargetBatchID: "target"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.False(diff.LinkDetails)
+	s.Equal(1, diff.UnchangedCount)
+	s.Equal([]links.PageDiff{}, diff.Pages)
+}
+
+func (s *linkProcessorTestSuite) TestDiffBatches_WhenBatchIsMissingOrRunning_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "base").Return(links.Batch{ID: "base", Status: links.BatchStatusCompleted}, nil)
+	s.mockRepo.On("GetBatchResults", "base").Return([]links.Result{}, nil)
+	s.mockRepo.On("GetBatch", "running").Return(links.Batch{ID: "running", Status: links.BatchStatusRunning}, nil)
+	s.mockRepo.On("GetBatch", "unknown").Return(links.Batch{}, errors.New("error"))
+
+	// Act
+	_, runningErr := s.linkProcessor.DiffBatches(context.Background(), links.DiffBatchesRequest{BaseBatchID: "base", TargetBatchID: "running"})
+	_, unknownErr := s.linkProcessor.DiffBatches(context.Background(), links.DiffBatchesRequest{BaseBatchID: "base", TargetBatchID: "unknown"})
+
+	// Assert
+	s.ErrorIs(runningErr, links.ErrBatchInProgress)
+	s.Equal("failed to get batch error", unknownErr.Error())
+}
+
+func (s *linkProcessorTestSuite) TestProcessBatch_WhenUpdateBatchFails_ThenFail() {
+	// Arrange
+	urlGenerated, _ := url.Parse("http://google.com")
+
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{urlGenerated}, scraper.DefaultOptions()).Return([]scraper.Result{{PageURL: "test1"}}, nil)
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(errors.New("error"))
+
+	// Act
+	res, err := s.linkProcessor.ProcessBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}})
+
+	// Assert
+	s.Equal("failed to update batch error", err.Error())
 	s.Equal([]links.Result([]links.Result(nil)), res)
 }
+
+func (s *linkProcessorTestSuite) TestListBatches_WhenNoLimit_ThenDefaultIsUsed() {
+	// Arrange
+	page := links.BatchPage{Batches: []links.Batch{{ID: "batchID"}}, NextCursor: "next"}
+
+	s.mockRepo.On("ListBatches", links.BatchQuery{Limit: 50, SortBy: links.BatchSortURLCount}).Return(page, nil)
+
+	// Act
+	res, err := s.linkProcessor.ListBatches(context.Background(), links.ListBatchesRequest{Query: links.BatchQuery{SortBy: links.BatchSortURLCount}})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.ListBatchesResponse{Batches: page.Batches, NextCursor: "next"}, res)
+}
+
+func (s *linkProcessorTestSuite) TestListBatches_WhenRepositoryFails_ThenFail() {
+	// Arrange
+	s.mockRepo.On("ListBatches", links.BatchQuery{Limit: 10}).Return(links.BatchPage{}, errors.New("error"))
+
+	// Act
+	_, err := s.linkProcessor.ListBatches(context.Background(), links.ListBatchesRequest{Query: links.BatchQuery{Limit: 10}})
+
+	// Assert
+	s.Equal("failed to list batches error", err.Error())
+}
@@ -0,0 +1,368 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// MonitorScheduler - keeps the stored monitors scheduled with cron, every run processes
+// the monitor urls as a new batch. A run is skipped while the previous run of the same monitor
+// is still in progress so slow batches never pile up.
+type MonitorScheduler struct {
+	repo      links.Repository
+	processor links.Processor
+	cron      *cron.Cron
+
+	mu      sync.Mutex
+	entries map[string]cron.EntryID // monitor id -> scheduled cron entry
+	running map[string]bool         // monitor ids with a run in progress
+
+	notifiers map[links.AlertSinkType]links.AlertNotifier
+}
+
+// NewMonitorScheduler ..
+func NewMonitorScheduler(repo links.Repository, processor links.Processor, opts ...MonitorOption) *MonitorScheduler {
+	m := &MonitorScheduler{
+		repo:      repo,
+		processor: processor,
+		cron:      cron.New(),
+		entries:   map[string]cron.EntryID{},
+		running:   map[string]bool{},
+		notifiers: map[links.AlertSinkType]links.AlertNotifier{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Start - schedules the stored monitors and starts running them
+func (m *MonitorScheduler) Start(ctx context.Context) error {
+	monitors, err := m.repo.ListMonitors(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list monitors %w", err)
+	}
+	for _, monitor := range monitors {
+		if err := m.schedule(monitor); err != nil {
+			log.Println("failed to schedule monitor", monitor.ID, err)
+		}
+	}
+
+	m.cron.Start()
+	return nil
+}
+
+// Stop - stops scheduling new runs and waits for the running ones to finish
+func (m *MonitorScheduler) Stop() {
+	<-m.cron.Stop().Done()
+}
+
+// CreateMonitor - validate, store and schedule a new monitor
+func (m *MonitorScheduler) CreateMonitor(ctx context.Context, req links.MonitorRequest) (links.Monitor, error) {
+	if err := validateMonitor(req); err != nil {
+		return links.Monitor{}, err
+	}
+
+	now := time.Now().UTC()
+	monitor := links.Monitor{
+		ID:        uuid.NewString(),
+		Name:      req.Name,
+		Schedule:  strings.TrimSpace(req.Schedule),
+		URLs:      req.URLs,
+		Options:   req.Options,
+		Paused:    req.Paused,
+		APIKeyID:  req.APIKeyID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := m.repo.CreateMonitor(ctx, monitor); err != nil {
+		return links.Monitor{}, fmt.Errorf("failed to create monitor %w", err)
+	}
+	if err := m.schedule(monitor); err != nil {
+		return links.Monitor{}, err
+	}
+
+	return m.withNextRun(monitor), nil
+}
+
+// GetMonitor - get monitor by id
+func (m *MonitorScheduler) GetMonitor(ctx context.Context, monitorID string) (links.Monitor, error) {
+	monitor, err := m.getMonitor(ctx, monitorID)
+	if err != nil {
+		return links.Monitor{}, fmt.Errorf("failed to get monitor %w", err)
+	}
+
+	return m.withNextRun(monitor), nil
+}
+
+// UpdateMonitor - replace the definition of a monitor and reschedule it, its run history is kept
+func (m *MonitorScheduler) UpdateMonitor(ctx context.Context, req links.MonitorRequest) (links.Monitor, error) {
+	if err := validateMonitor(req); err != nil {
+		return links.Monitor{}, err
+	}
+
+	monitor, err := m.getMonitor(ctx, req.MonitorID)
+	if err != nil {
+		return links.Monitor{}, fmt.Errorf("failed to get monitor %w", err)
+	}
+
+	monitor.Name = req.Name
+	monitor.Schedule = strings.TrimSpace(req.Schedule)
+	monitor.URLs = req.URLs
+	monitor.Options = req.Options
+	monitor.Paused = req.Paused
+	monitor.UpdatedAt = time.Now().UTC()
+	if err := m.repo.UpdateMonitor(ctx, monitor); err != nil {
+		return links.Monitor{}, fmt.Errorf("failed to update monitor %w", err)
+	}
+	if err := m.schedule(monitor); err != nil {
+		return links.Monitor{}, err
+	}
+
+	return m.withNextRun(monitor), nil
+}
+
+// DeleteMonitor - delete and unschedule a monitor, the batches of its runs are kept
+func (m *MonitorScheduler) DeleteMonitor(ctx context.Context, monitorID string) error {
+	if err := m.authorizeMonitor(ctx, monitorID); err != nil {
+		return fmt.Errorf("failed to delete monitor %w", err)
+	}
+	if err := m.repo.DeleteMonitor(ctx, monitorID); err != nil {
+		return fmt.Errorf("failed to delete monitor %w", err)
+	}
+	m.unschedule(monitorID)
+
+	return nil
+}
+
+// ListMonitors - all monitors of the api key scope of the context, oldest first
+func (m *MonitorScheduler) ListMonitors(ctx context.Context) (links.ListMonitorsResponse, error) {
+	monitors, err := m.scopedMonitors(ctx)
+	if err != nil {
+		return links.ListMonitorsResponse{}, err
+	}
+	for i := range monitors {
+		monitors[i] = m.withNextRun(monitors[i])
+	}
+
+	return links.ListMonitorsResponse{Monitors: monitors}, nil
+}
+
+// RunMonitor - run a monitor now regardless of its schedule,
+// links.ErrMonitorRunning is returned while another run of it is in progress
+func (m *MonitorScheduler) RunMonitor(ctx context.Context, monitorID string) ([]links.Result, error) {
+	monitor, err := m.getMonitor(ctx, monitorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitor %w", err)
+	}
+
+	return m.run(ctx, monitor)
+}
+
+// run - process the monitor urls as a new batch with the api key of the monitor, record the run
+// on the monitor and evaluate its alert rules against the previous run
+func (m *MonitorScheduler) run(ctx context.Context, monitor links.Monitor) ([]links.Result, error) {
+	if !m.acquire(monitor.ID) {
+		return nil, links.ErrMonitorRunning
+	}
+	defer m.release(monitor.ID)
+
+	urls, err := parseMonitorURLs(monitor.URLs)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := m.processor.ProcessBatch(ctx, links.ProcessBatchRequest{URLs: urls, Options: monitor.Options, MonitorID: monitor.ID, APIKeyID: monitor.APIKeyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run monitor %w", err)
+	}
+
+	// the monitor could have been changed or deleted during the run so the latest version is updated
+	latest, err := m.repo.GetMonitor(ctx, monitor.ID)
+	if err != nil {
+		log.Println("failed to record run of monitor", monitor.ID, err)
+		return results, nil
+	}
+	now := time.Now().UTC()
+	latest.LastRunAt = &now
+	if len(results) > 0 {
+		latest.LastBatchID = results[0].BatchID
+	}
+	if err := m.repo.UpdateMonitor(ctx, latest); err != nil {
+		log.Println("failed to record run of monitor", monitor.ID, err)
+	}
+
+	if err := m.evaluateAlerts(ctx, monitor.ID, monitor.LastBatchID, latest.LastBatchID); err != nil {
+		log.Println("failed to evaluate alerts of monitor", monitor.ID, err)
+	}
+
+	return results, nil
+}
+
+// scheduledRun - cron job of a monitor
+func (m *MonitorScheduler) scheduledRun(monitorID string) {
+	ctx := context.Background()
+	monitor, err := m.repo.GetMonitor(ctx, monitorID)
+	if err != nil {
+		log.Println("failed to get scheduled monitor", monitorID, err)
+		return
+	}
+
+	if _, err := m.run(ctx, monitor); err != nil {
+		if errors.Is(err, links.ErrMonitorRunning) {
+			log.Println("skipping run of monitor", monitorID, "the previous run is still in progress")
+			return
+		}
+		if errors.Is(err, links.ErrQuotaExceeded) || errors.Is(err, links.ErrServerBusy) {
+			log.Println("skipping run of monitor", monitorID, err)
+			return
+		}
+		log.Println("scheduled run of monitor", monitorID, "failed", err)
+	}
+}
+
+// getMonitor - get monitor by id, monitors of other api keys than the scope of the context aren't found
+func (m *MonitorScheduler) getMonitor(ctx context.Context, monitorID string) (links.Monitor, error) {
+	monitor, err := m.repo.GetMonitor(ctx, monitorID)
+	if err != nil {
+		return links.Monitor{}, err
+	}
+	if !links.InAPIKeyScope(ctx, monitor.APIKeyID) {
+		return links.Monitor{}, repository.ErrMonitorNotFound
+	}
+	return monitor, nil
+}
+
+// authorizeMonitor - repository.ErrMonitorNotFound when the monitor can't be looked up with the api key scope
+// of the context, the monitor is only read when the context has a scope
+func (m *MonitorScheduler) authorizeMonitor(ctx context.Context, monitorID string) error {
+	if _, ok := links.APIKeyScopeOf(ctx); !ok {
+		return nil
+	}
+	_, err := m.getMonitor(ctx, monitorID)
+	return err
+}
+
+// scopedMonitors - monitors of the api key scope of the context, oldest first
+func (m *MonitorScheduler) scopedMonitors(ctx context.Context) ([]links.Monitor, error) {
+	monitors, err := m.repo.ListMonitors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monitors %w", err)
+	}
+	scoped := make([]links.Monitor, 0, len(monitors))
+	for _, monitor := range monitors {
+		if links.InAPIKeyScope(ctx, monitor.APIKeyID) {
+			scoped = append(scoped, monitor)
+		}
+	}
+	return scoped, nil
+}
+
+// schedule - (re)schedules the monitor, paused monitors are only unscheduled
+func (m *MonitorScheduler) schedule(monitor links.Monitor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entryID, ok := m.entries[monitor.ID]; ok {
+		m.cron.Remove(entryID)
+		delete(m.entries, monitor.ID)
+	}
+	if monitor.Paused {
+		return nil
+	}
+
+	schedule, err := cron.ParseStandard(monitor.Schedule)
+	if err != nil {
+		return fmt.Errorf("%w: invalid schedule %q", links.ErrInvalidMonitor, monitor.Schedule)
+	}
+	monitorID := monitor.ID
+	m.entries[monitor.ID] = m.cron.Schedule(schedule, cron.FuncJob(func() { m.scheduledRun(monitorID) }))
+
+	return nil
+}
+
+func (m *MonitorScheduler) unschedule(monitorID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entryID, ok := m.entries[monitorID]; ok {
+		m.cron.Remove(entryID)
+		delete(m.entries, monitorID)
+	}
+}
+
+// withNextRun - sets the next scheduled run of the monitor, it is only known once the scheduler is started
+func (m *MonitorScheduler) withNextRun(monitor links.Monitor) links.Monitor {
+	m.mu.Lock()
+	entryID, ok := m.entries[monitor.ID]
+	m.mu.Unlock()
+
+	monitor.NextRunAt = nil
+	if !ok {
+		return monitor
+	}
+	if next := m.cron.Entry(entryID).Next; !next.IsZero() {
+		next = next.UTC()
+		monitor.NextRunAt = &next
+	}
+	return monitor
+}
+
+func (m *MonitorScheduler) acquire(monitorID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running[monitorID] {
+		return false
+	}
+	m.running[monitorID] = true
+	return true
+}
+
+func (m *MonitorScheduler) release(monitorID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.running, monitorID)
+}
+
+// validateMonitor - checks the schedule, urls and options of a monitor, errors wrap
+// links.ErrInvalidMonitor or scraper.ErrInvalidOptions
+func validateMonitor(req links.MonitorRequest) error {
+	if _, err := cron.ParseStandard(strings.TrimSpace(req.Schedule)); err != nil {
+		return fmt.Errorf("%w: invalid schedule %q", links.ErrInvalidMonitor, req.Schedule)
+	}
+	if len(req.URLs) == 0 {
+		return fmt.Errorf("%w: at least one url is required", links.ErrInvalidMonitor)
+	}
+	if _, err := parseMonitorURLs(req.URLs); err != nil {
+		return err
+	}
+	if _, err := toScraperOptions(req.Options); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseMonitorURLs(rawURLs []string) ([]*url.URL, error) {
+	urls := make([]*url.URL, 0, len(rawURLs))
+	for _, rawURL := range rawURLs {
+		parsedURL, err := url.Parse(rawURL)
+		if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+			return nil, fmt.Errorf("%w: invalid url %q", links.ErrInvalidMonitor, rawURL)
+		}
+		urls = append(urls, parsedURL)
+	}
+	return urls, nil
+}
@@ -0,0 +1,271 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/Lockwarr/codefi/services/links/mocks"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type monitorSchedulerTestSuite struct {
+	suite.Suite
+	mockRepo      *mocks.MockRepository
+	mockProcessor *mocks.MockLinksProcessor
+	scheduler     *domain.MonitorScheduler
+}
+
+func (s *monitorSchedulerTestSuite) SetupTest() {
+	s.mockRepo = new(mocks.MockRepository)
+	s.mockProcessor = new(mocks.MockLinksProcessor)
+	s.scheduler = domain.NewMonitorScheduler(s.mockRepo, s.mockProcessor)
+}
+
+func (s *monitorSchedulerTestSuite) AfterTest(suite string, testName string) {
+	s.scheduler.Stop()
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockProcessor.AssertExpectations(s.T())
+}
+
+func TestMonitorSchedulerTestSuite(t *testing.T) {
+	suite.Run(t, &monitorSchedulerTestSuite{})
+}
+
+func (s *monitorSchedulerTestSuite) TestCreateMonitor_ThenItIsStoredAndScheduled() {
+	// Arrange
+	s.mockRepo.On("ListMonitors").Return([]links.Monitor{}, nil)
+	s.mockRepo.On("CreateMonitor", mock.MatchedBy(func(m links.Monitor) bool {
+		return m.ID != "" && m.Name == "weekly scan" && m.Schedule == "@weekly" && !m.CreatedAt.IsZero()
+	})).Return(nil)
+	_ = s.scheduler.Start(context.Background())
+
+	// Act
+	monitor, err := s.scheduler.CreateMonitor(context.Background(), links.MonitorRequest{
+		Name:     "weekly scan",
+		Schedule: " @weekly ",
+		URLs:     []string{"https://www.google.com"},
+	})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal("@weekly", monitor.Schedule)
+	s.NotNil(monitor.NextRunAt)
+	s.True(monitor.NextRunAt.After(time.Now()))
+}
+
+func (s *monitorSchedulerTestSuite) TestCreateMonitor_WhenInvalid_ThenFail() {
+	testCases := []struct {
+		name        string
+		req         links.MonitorRequest
+		expectedErr error
+	}{
+		{
+			name:        "invalid schedule",
+			req:         links.MonitorRequest{Schedule: "every monday", URLs: []string{"https://www.google.com"}},
+			expectedErr: links.ErrInvalidMonitor,
+		},
+		{
+			name:        "no urls",
+			req:         links.MonitorRequest{Schedule: "0 9 * * 1"},
+			expectedErr: links.ErrInvalidMonitor,
+		},
+		{
+			name:        "invalid url",
+			req:         links.MonitorRequest{Schedule: "0 9 * * 1", URLs: []string{"www.google.com"}},
+			expectedErr: links.ErrInvalidMonitor,
+		},
+		{
+			name:        "invalid options",
+			req:         links.MonitorRequest{Schedule: "0 9 * * 1", URLs: []string{"https://www.google.com"}, Options: links.ScrapeOptions{RedirectPolicy: "sometimes"}},
+			expectedErr: scraper.ErrInvalidOptions,
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			_, err := s.scheduler.CreateMonitor(context.Background(), tc.req)
+
+			// Assert
+			s.ErrorIs(err, tc.expectedErr)
+		})
+	}
+}
+
+func (s *monitorSchedulerTestSuite) TestUpdateMonitor_WhenPaused_ThenItIsUnscheduled() {
+	// Arrange
+	createdAt := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	stored := links.Monitor{ID: "m0", Schedule: "@daily", URLs: []string{"https://www.google.com"}, LastBatchID: "b0", CreatedAt: createdAt}
+	s.mockRepo.On("ListMonitors").Return([]links.Monitor{stored}, nil)
+	s.mockRepo.On("GetMonitor", "m0").Return(stored, nil)
+	s.mockRepo.On("UpdateMonitor", mock.MatchedBy(func(m links.Monitor) bool {
+		return m.Paused && m.Schedule == "@hourly" && m.LastBatchID == "b0" && m.CreatedAt.Equal(createdAt)
+	})).Return(nil)
+	_ = s.scheduler.Start(context.Background())
+	scheduled, _ := s.scheduler.GetMonitor(context.Background(), "m0")
+
+	// Act
+	monitor, err := s.scheduler.UpdateMonitor(context.Background(), links.MonitorRequest{
+		MonitorID: "m0",
+		Schedule:  "@hourly",
+		URLs:      []string{"https://www.google.com"},
+		Paused:    true,
+	})
+
+	// Assert
+	s.NotNil(scheduled.NextRunAt)
+	s.Equal(nil, err)
+	s.Nil(monitor.NextRunAt)
+}
+
+func (s *monitorSchedulerTestSuite) TestDeleteMonitor_WhenRepositoryFails_ThenFail() {
+	// Arrange
+	s.mockRepo.On("DeleteMonitor", "m0").Return(errors.New("error"))
+
+	// Act
+	err := s.scheduler.DeleteMonitor(context.Background(), "m0")
+
+	// Assert
+	s.Equal("failed to delete monitor error", err.Error())
+}
+
+func (s *monitorSchedulerTestSuite) TestRunMonitor_ThenABatchIsProcessedAndRecorded() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@daily", URLs: []string{"https://www.google.com"}, Options: links.ScrapeOptions{TimeoutMS: 1000}}
+	google, _ := url.Parse("https://www.google.com")
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", links.ProcessBatchRequest{URLs: []*url.URL{google}, Options: monitor.Options, MonitorID: "m0"}).
+		Return([]links.Result{{ID: "r0", BatchID: "b0"}}, nil)
+	s.mockRepo.On("UpdateMonitor", mock.MatchedBy(func(m links.Monitor) bool {
+		return m.ID == "m0" && m.LastBatchID == "b0" && m.LastRunAt != nil
+	})).Return(nil)
+
+	// Act
+	results, err := s.scheduler.RunMonitor(context.Background(), "m0")
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal([]links.Result{{ID: "r0", BatchID: "b0"}}, results)
+}
+
+func (s *monitorSchedulerTestSuite) TestRunMonitor_WhenMonitorHasAPIKey_ThenTheRunCountsAgainstIt() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@daily", URLs: []string{"https://www.google.com"}, APIKeyID: "k1"}
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", mock.MatchedBy(func(req links.ProcessBatchRequest) bool { return req.APIKeyID == "k1" })).
+		Return([]links.Result(nil), links.ErrQuotaExceeded)
+
+	// Act
+	_, err := s.scheduler.RunMonitor(links.WithAPIKeyScope(context.Background(), "k1"), "m0")
+
+	// Assert
+	s.ErrorIs(err, links.ErrQuotaExceeded)
+}
+
+func (s *monitorSchedulerTestSuite) TestRunMonitor_WhenMonitorOfAnotherAPIKey_ThenNotFound() {
+	// Arrange
+	s.mockRepo.On("GetMonitor", "m0").Return(links.Monitor{ID: "m0", Schedule: "@daily", APIKeyID: "k1"}, nil)
+
+	// Act
+	_, err := s.scheduler.RunMonitor(links.WithAPIKeyScope(context.Background(), "k2"), "m0")
+
+	// Assert
+	s.ErrorIs(err, repository.ErrMonitorNotFound)
+}
+
+func (s *monitorSchedulerTestSuite) TestListMonitors_WhenScopedToAPIKey_ThenOnlyItsMonitorsAreListed() {
+	// Arrange
+	s.mockRepo.On("ListMonitors").Return([]links.Monitor{{ID: "m0", APIKeyID: "k1"}, {ID: "m1", APIKeyID: "k2"}, {ID: "m2"}}, nil)
+
+	// Act
+	res, err := s.scheduler.ListMonitors(links.WithAPIKeyScope(context.Background(), "k1"))
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(1, len(res.Monitors))
+	s.Equal("m0", res.Monitors[0].ID)
+}
+
+func (s *monitorSchedulerTestSuite) TestRunMonitor_WhenPreviousRunIsInProgress_ThenFail() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@daily", URLs: []string{"https://www.google.com"}}
+	started, finish := make(chan struct{}), make(chan struct{})
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-finish
+	}).Return([]links.Result{{BatchID: "b0"}}, nil).Once()
+	s.mockRepo.On("UpdateMonitor", mock.Anything).Return(nil)
+
+	firstErr := make(chan error)
+	go func() {
+		_, err := s.scheduler.RunMonitor(context.Background(), "m0")
+		firstErr <- err
+	}()
+	<-started
+
+	// Act
+	_, err := s.scheduler.RunMonitor(context.Background(), "m0")
+	close(finish)
+
+	// Assert
+	s.ErrorIs(err, links.ErrMonitorRunning)
+	s.Equal(nil, <-firstErr)
+}
+
+func (s *monitorSchedulerTestSuite) TestStart_ThenStoredMonitorsRunOnTheirSchedule() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@every 1s", URLs: []string{"https://www.google.com"}}
+	paused := links.Monitor{ID: "m1", Schedule: "@every 1s", URLs: []string{"https://www.facebook.com"}, Paused: true}
+	ran := make(chan links.ProcessBatchRequest, 10)
+	s.mockRepo.On("ListMonitors").Return([]links.Monitor{monitor, paused}, nil)
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", mock.Anything).Run(func(args mock.Arguments) {
+		ran <- args.Get(0).(links.ProcessBatchRequest)
+	}).Return([]links.Result{{BatchID: "b0"}}, nil)
+	s.mockRepo.On("UpdateMonitor", mock.Anything).Return(nil)
+
+	// Act
+	err := s.scheduler.Start(context.Background())
+
+	// Assert
+	s.Equal(nil, err)
+	select {
+	case req := <-ran:
+		s.Equal("m0", req.MonitorID)
+	case <-time.After(3 * time.Second):
+		s.Fail("monitor wasn't run on its schedule")
+	}
+}
+
+func (s *monitorSchedulerTestSuite) TestStart_WhenQuotaOfTheMonitorIsExceeded_ThenTheRunIsSkipped() {
+	// Arrange
+	monitor := links.Monitor{ID: "m0", Schedule: "@every 1s", URLs: []string{"https://www.google.com"}, APIKeyID: "k1"}
+	ran := make(chan links.ProcessBatchRequest, 10)
+	s.mockRepo.On("ListMonitors").Return([]links.Monitor{monitor}, nil)
+	s.mockRepo.On("GetMonitor", "m0").Return(monitor, nil)
+	s.mockProcessor.On("ProcessBatch", mock.Anything).Run(func(args mock.Arguments) {
+		ran <- args.Get(0).(links.ProcessBatchRequest)
+	}).Return([]links.Result(nil), links.ErrQuotaExceeded)
+
+	// Act
+	err := s.scheduler.Start(context.Background())
+
+	// Assert
+	s.Equal(nil, err)
+	select {
+	case req := <-ran:
+		s.Equal("k1", req.APIKeyID)
+	case <-time.After(3 * time.Second):
+		s.Fail("monitor wasn't run on its schedule")
+	}
+	s.scheduler.Stop()
+	s.mockRepo.AssertNotCalled(s.T(), "UpdateMonitor", mock.Anything)
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"log"
+	"sync"
+
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// watcherBuffer - result events buffered per watcher, a watcher which falls further behind misses
+// result events but the progress summaries still catch it up
+const watcherBuffer = 100
+
+// progressHub - live progress of the batches being processed by this instance, fanned out to their watchers
+type progressHub struct {
+	mu      sync.Mutex
+	batches map[string]*batchProgress
+}
+
+type batchProgress struct {
+	progress links.BatchProgress
+	watchers map[*subscription]bool
+}
+
+// subscription - result events of one watcher, events is closed once the batch finished
+type subscription struct {
+	batchID string
+	events  chan links.BatchEvent
+	initial links.BatchProgress // progress of the batch when the watcher subscribed
+	final   links.Batch         // set before events is closed
+}
+
+func newProgressHub() *progressHub {
+	return &progressHub{batches: map[string]*batchProgress{}}
+}
+
+// start - the batch can be watched from now on
+func (h *progressHub) start(batch links.Batch) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.batches[batch.ID] = &batchProgress{
+		progress: links.BatchProgress{BatchID: batch.ID, URLCount: batch.URLCount},
+		watchers: map[*subscription]bool{},
+	}
+}
+
+// publish - counts the result and sends it to the watchers of its batch
+func (h *progressHub) publish(result links.Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	batch, ok := h.batches[result.BatchID]
+	if !ok {
+		return
+	}
+	batch.progress.Processed++
+	if result.Success {
+		batch.progress.SuccessCount++
+	} else {
+		batch.progress.FailureCount++
+	}
+
+	for watcher := range batch.watchers {
+		select {
+		case watcher.events <- links.BatchEvent{Type: links.BatchEventResult, Result: &result}:
+		default:
+			log.Println("watcher of batch", result.BatchID, "is too slow, dropping result", result.ID)
+		}
+	}
+}
+
+// finish - hands the final batch to the watchers and stops tracking the batch
+func (h *progressHub) finish(batch links.Batch) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	progress, ok := h.batches[batch.ID]
+	if !ok {
+		return
+	}
+	for watcher := range progress.watchers {
+		watcher.final = batch
+		close(watcher.events)
+	}
+	delete(h.batches, batch.ID)
+}
+
+// subscribe - false is returned when the batch isn't being processed by this instance
+func (h *progressHub) subscribe(batchID string) (*subscription, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	batch, ok := h.batches[batchID]
+	if !ok {
+		return nil, false
+	}
+	watcher := &subscription{batchID: batchID, events: make(chan links.BatchEvent, watcherBuffer), initial: batch.progress}
+	batch.watchers[watcher] = true
+	return watcher, true
+}
+
+func (h *progressHub) unsubscribe(watcher *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if batch, ok := h.batches[watcher.batchID]; ok {
+		delete(batch.watchers, watcher)
+	}
+}
+
+// progress - current progress of a batch being processed by this instance
+func (h *progressHub) progress(batchID string) (links.BatchProgress, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	batch, ok := h.batches[batchID]
+	if !ok {
+		return links.BatchProgress{}, false
+	}
+	return batch.progress, true
+}
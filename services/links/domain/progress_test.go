@@ -0,0 +1,113 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *linkProcessorTestSuite) TestStartBatch_WhenWatched_ThenResultsProgressAndCompletionAreStreamed() {
+	// Arrange
+	s.linkProcessor = domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithProgressInterval(time.Hour))
+	google, _ := url.Parse("https://www.google.com")
+	facebook, _ := url.Parse("https://www.facebook.com")
+	watching := make(chan struct{})
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{google, facebook}, scraper.DefaultOptions()).Run(func(args mock.Arguments) {
+		<-watching
+	}).Return([]scraper.Result{{PageURL: "https://www.google.com", Success: true}, {PageURL: "https://www.facebook.com"}})
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Return(nil)
+
+	// Act
+	batch, err := s.linkProcessor.StartBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{google, facebook}})
+	events, watchErr := s.linkProcessor.WatchBatch(context.Background(), batch.ID)
+	close(watching)
+	received := []links.BatchEvent{}
+	for event := range events {
+		received = append(received, event)
+	}
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.BatchStatusRunning, batch.Status)
+	s.Equal(nil, watchErr)
+	s.Len(received, 4)
+	s.Equal(links.BatchEventProgress, received[0].Type)
+	s.Equal(links.BatchProgress{BatchID: batch.ID, URLCount: 2}, *received[0].Progress)
+	s.Equal(links.BatchEventResult, received[1].Type)
+	s.Equal("https://www.google.com", received[1].Result.PageURL)
+	s.Equal(links.BatchEventResult, received[2].Type)
+	s.Equal(links.BatchEventCompleted, received[3].Type)
+	s.Equal(links.BatchStatusCompleted, received[3].Batch.Status)
+	s.Equal(1, received[3].Batch.SuccessCount)
+	s.Equal(1, received[3].Batch.FailureCount)
+}
+
+func (s *linkProcessorTestSuite) TestWatchBatch_WhenBatchFinishesBeforeEventsAreRead_ThenProgressAtSubscriptionIsStreamedFirst() {
+	// Arrange
+	s.linkProcessor = domain.NewLinksProcessor(s.mockRepo, s.mockScraperClient, domain.WithProgressInterval(time.Hour))
+	google, _ := url.Parse("https://www.google.com")
+	watching := make(chan struct{})
+	finished := make(chan struct{})
+	s.mockRepo.On("CreateBatch", mock.Anything).Return(nil)
+	s.mockScraperClient.On("ScrapeStream", []*url.URL{google}, scraper.DefaultOptions()).Run(func(args mock.Arguments) {
+		<-watching
+	}).Return([]scraper.Result{{PageURL: "https://www.google.com", Success: true}})
+	s.mockRepo.On("AppendResults", mock.Anything).Return(nil)
+	s.mockRepo.On("UpdateBatch", mock.Anything).Run(func(args mock.Arguments) {
+		close(finished)
+	}).Return(nil)
+
+	// Act
+	batch, _ := s.linkProcessor.StartBatch(context.Background(), links.ProcessBatchRequest{URLs: []*url.URL{google}})
+	events, watchErr := s.linkProcessor.WatchBatch(context.Background(), batch.ID)
+	close(watching)
+	<-finished
+	time.Sleep(10 * time.Millisecond) // the batch stops being tracked right after it's saved
+	received := []links.BatchEvent{}
+	for event := range events {
+		received = append(received, event)
+	}
+
+	// Assert
+	s.Equal(nil, watchErr)
+	s.Len(received, 3)
+	s.Equal(links.BatchEventProgress, received[0].Type)
+	s.Equal(links.BatchProgress{BatchID: batch.ID, URLCount: 1}, *received[0].Progress)
+	s.Equal(links.BatchEventResult, received[1].Type)
+	s.Equal(links.BatchEventCompleted, received[2].Type)
+}
+
+func (s *linkProcessorTestSuite) TestWatchBatch_WhenBatchIsFinished_ThenOnlyCompletionIsStreamed() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "b0").Return(links.Batch{ID: "b0", Status: links.BatchStatusCompleted}, nil)
+
+	// Act
+	events, err := s.linkProcessor.WatchBatch(context.Background(), "b0")
+
+	// Assert
+	s.Equal(nil, err)
+	event := <-events
+	s.Equal(links.BatchEventCompleted, event.Type)
+	s.Equal("b0", event.Batch.ID)
+	_, open := <-events
+	s.False(open)
+}
+
+func (s *linkProcessorTestSuite) TestWatchBatch_WhenBatchIsMissing_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "b0").Return(links.Batch{}, errors.New("error"))
+
+	// Act
+	_, err := s.linkProcessor.WatchBatch(context.Background(), "b0")
+
+	// Assert
+	s.Equal("failed to get batch error", err.Error())
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+const (
+	reportTopPages   = 25 // pages in each of the top pages tables
+	reportTopDomains = 20 // external domains in the top domains table
+)
+
+// buildReport - summary of the results of a batch, pages with the same link count are sorted by url
+func buildReport(batch links.Batch, results []links.Result) links.BatchReport {
+	report := links.BatchReport{
+		Batch:              batch,
+		LinkDetails:        batch.Options.CollectLinks,
+		Failures:           []links.FailureCount{},
+		FailedPages:        []links.Result{},
+		TopExternalDomains: []links.DomainCount{},
+		GeneratedAt:        time.Now().UTC(),
+	}
+
+	succeeded := []links.Result{}
+	failures := map[string]int{}
+	for _, result := range results {
+		if !result.Success {
+			category, _ := scraper.ClassifyError(result.Error)
+			if category == "" { // failed without an error, e.g. the links couldn't be counted
+				category = scraper.ErrorCategoryOther
+			}
+			failures[string(category)]++
+			report.FailedPages = append(report.FailedPages, result)
+			continue
+		}
+		report.InternalLinksTotal += result.InternalLinksNum
+		report.ExternalLinksTotal += result.ExternalLinksNum
+		succeeded = append(succeeded, result)
+	}
+
+	for category, count := range failures {
+		report.Failures = append(report.Failures, links.FailureCount{Category: category, Count: count})
+	}
+	sort.Slice(report.Failures, func(i, j int) bool {
+		if report.Failures[i].Count != report.Failures[j].Count {
+			return report.Failures[i].Count > report.Failures[j].Count
+		}
+		return report.Failures[i].Category < report.Failures[j].Category
+	})
+	sort.Slice(report.FailedPages, func(i, j int) bool {
+		return report.FailedPages[i].PageURL < report.FailedPages[j].PageURL
+	})
+
+	report.TopExternalPages = topPages(succeeded, func(result links.Result) uint { return result.ExternalLinksNum })
+	report.TopInternalPages = topPages(succeeded, func(result links.Result) uint { return result.InternalLinksNum })
+	if report.LinkDetails {
+		report.TopExternalDomains = topDomains(succeeded)
+	}
+
+	return report
+}
+
+// topPages - the reportTopPages pages with the highest link count
+func topPages(results []links.Result, linkCount func(links.Result) uint) []links.Result {
+	pages := append([]links.Result(nil), results...)
+	sort.Slice(pages, func(i, j int) bool {
+		if linkCount(pages[i]) != linkCount(pages[j]) {
+			return linkCount(pages[i]) > linkCount(pages[j])
+		}
+		return pages[i].PageURL < pages[j].PageURL
+	})
+	if len(pages) > reportTopPages {
+		pages = pages[:reportTopPages]
+	}
+	if pages == nil {
+		pages = []links.Result{}
+	}
+	return pages
+}
+
+// topDomains - the reportTopDomains external domains with the most links
+func topDomains(results []links.Result) []links.DomainCount {
+	counts := map[string]*links.DomainCount{}
+	for _, result := range results {
+		for _, rawLink := range result.ExternalLinks {
+			domain, ok := linkDomain(rawLink)
+			if !ok {
+				continue
+			}
+			if _, seen := counts[domain]; !seen {
+				counts[domain] = &links.DomainCount{Domain: domain}
+			}
+			counts[domain].Links++
+		}
+		for domain := range linkDomains(result.ExternalLinks) {
+			counts[domain].Pages++
+		}
+	}
+
+	domains := make([]links.DomainCount, 0, len(counts))
+	for _, count := range counts {
+		domains = append(domains, *count)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Links != domains[j].Links {
+			return domains[i].Links > domains[j].Links
+		}
+		return domains[i].Domain < domains[j].Domain
+	})
+	if len(domains) > reportTopDomains {
+		domains = domains[:reportTopDomains]
+	}
+	return domains
+}
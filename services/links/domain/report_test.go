@@ -0,0 +1,104 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+)
+
+func (s *linkProcessorTestSuite) TestReportBatch_ThenResultsAreSummarized() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 5, Options: links.ScrapeOptions{CollectLinks: true}}
+	results := []links.Result{
+		{PageURL: "https://a.example.com/", Success: true, InternalLinksNum: 1, ExternalLinksNum: 3,
+			ExternalLinks: []string{"https://gmail.com/", "https://GMAIL.com/inbox", "https://youtube.com/"}},
+		{PageURL: "https://b.example.com/", Success: true, InternalLinksNum: 5, ExternalLinksNum: 1,
+			ExternalLinks: []string{"https://gmail.com/"}},
+		{PageURL: "https://d.example.com/", Error: &scraper.StatusError{StatusCode: 404}},
+		{PageURL: "https://c.example.com/", Error: &url.Error{Op: "Get", URL: "https://c.example.com/", Err: context.DeadlineExceeded}},
+		{PageURL: "https://e.example.com/", Error: &scraper.StatusError{StatusCode: 500}},
+	}
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return(results, nil)
+
+	// Act
+	report, err := s.linkProcessor.ReportBatch(context.Background(), "batchID")
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(batch, report.Batch)
+	s.Equal(uint(6), report.InternalLinksTotal)
+	s.Equal(uint(4), report.ExternalLinksTotal)
+	s.True(report.LinkDetails)
+	s.Equal([]string{"https://a.example.com/", "https://b.example.com/"}, pageURLs(report.TopExternalPages))
+	s.Equal([]string{"https://b.example.com/", "https://a.example.com/"}, pageURLs(report.TopInternalPages))
+	s.Equal([]links.FailureCount{{Category: "status", Count: 2}, {Category: "timeout", Count: 1}}, report.Failures)
+	s.Equal([]string{"https://c.example.com/", "https://d.example.com/", "https://e.example.com/"}, pageURLs(report.FailedPages))
+	s.Equal([]links.DomainCount{{Domain: "gmail.com", Links: 3, Pages: 2}, {Domain: "youtube.com", Links: 1, Pages: 1}}, report.TopExternalDomains)
+	s.False(report.GeneratedAt.IsZero())
+}
+
+func (s *linkProcessorTestSuite) TestReportBatch_WhenLinksWerentCollected_ThenDomainsAreEmpty() {
+	// Arrange
+	batch := links.Batch{ID: "batchID", Status: links.BatchStatusCompleted}
+	results := []links.Result{{PageURL: "https://a.example.com/", Success: true, ExternalLinksNum: 3}}
+
+	s.mockRepo.On("GetBatch", "batchID").Return(batch, nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return(results, nil)
+
+	// Act
+	report, err := s.linkProcessor.ReportBatch(context.Background(), "batchID")
+
+	// Assert
+	s.Equal(nil, err)
+	s.False(report.LinkDetails)
+	s.Equal([]links.DomainCount{}, report.TopExternalDomains)
+	s.Equal([]links.FailureCount{}, report.Failures)
+}
+
+func (s *linkProcessorTestSuite) TestReportBatch_WhenBatchIsNotFound_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{}, repository.ErrBatchNotFound)
+
+	// Act
+	_, err := s.linkProcessor.ReportBatch(context.Background(), "batchID")
+
+	// Assert
+	s.ErrorIs(err, repository.ErrBatchNotFound)
+}
+
+func (s *linkProcessorTestSuite) TestReportBatch_WhenBatchIsRunning_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{ID: "batchID", Status: links.BatchStatusRunning}, nil)
+
+	// Act
+	_, err := s.linkProcessor.ReportBatch(context.Background(), "batchID")
+
+	// Assert
+	s.ErrorIs(err, links.ErrBatchInProgress)
+}
+
+func (s *linkProcessorTestSuite) TestReportBatch_WhenGetBatchResultsFails_ThenFail() {
+	// Arrange
+	s.mockRepo.On("GetBatch", "batchID").Return(links.Batch{ID: "batchID", Status: links.BatchStatusCompleted}, nil)
+	s.mockRepo.On("GetBatchResults", "batchID").Return([]links.Result{}, errors.New("error"))
+
+	// Act
+	_, err := s.linkProcessor.ReportBatch(context.Background(), "batchID")
+
+	// Assert
+	s.Equal("failed to get batch error", err.Error())
+}
+
+func pageURLs(results []links.Result) []string {
+	urls := []string{}
+	for _, result := range results {
+		urls = append(urls, result.PageURL)
+	}
+	return urls
+}
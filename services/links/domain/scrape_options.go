@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// toScraperOptions - maps the requested options to scraper options with the defaults applied.
+// The returned error wraps scraper.ErrInvalidOptions.
+func toScraperOptions(opts links.ScrapeOptions) (scraper.Options, error) {
+	scraperOpts := scraper.Options{
+		Timeout:         time.Duration(opts.TimeoutMS) * time.Millisecond,
+		UserAgent:       opts.UserAgent,
+		Headers:         opts.Headers,
+		MaxBodySize:     opts.MaxBodySize,
+		RedirectPolicy:  scraper.RedirectPolicy(opts.RedirectPolicy),
+		MaxRedirects:    opts.MaxRedirects,
+		InternalPolicy:  scraper.InternalPolicy(opts.InternalPolicy),
+		InternalDomains: opts.InternalDomains,
+		CollectLinks:    opts.CollectLinks,
+	}
+	for _, category := range opts.LinkCategories {
+		scraperOpts.LinkCategories = append(scraperOpts.LinkCategories, scraper.LinkCategory(category))
+	}
+
+	if err := scraperOpts.Validate(); err != nil {
+		return scraper.Options{}, err
+	}
+
+	return scraperOpts.WithDefaults(), nil
+}
+
+// fromScraperOptions - maps the effective scraper options back so they can be stored on the batch
+func fromScraperOptions(opts scraper.Options) links.ScrapeOptions {
+	scrapeOpts := links.ScrapeOptions{
+		TimeoutMS:       opts.Timeout.Milliseconds(),
+		UserAgent:       opts.UserAgent,
+		Headers:         opts.Headers,
+		MaxBodySize:     opts.MaxBodySize,
+		RedirectPolicy:  string(opts.RedirectPolicy),
+		MaxRedirects:    opts.MaxRedirects,
+		InternalPolicy:  string(opts.InternalPolicy),
+		InternalDomains: opts.InternalDomains,
+		CollectLinks:    opts.CollectLinks,
+	}
+	for _, category := range opts.LinkCategories {
+		scrapeOpts.LinkCategories = append(scrapeOpts.LinkCategories, string(category))
+	}
+
+	return scrapeOpts
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/Lockwarr/codefi/services/links/domain"
+
+// WithTracerProvider - trace the batches processed and retried, the results are stored with the id
+// of the trace they were scraped in
+func WithTracerProvider(provider trace.TracerProvider) ProcessorOption {
+	return func(p *linkProcessor) {
+		p.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// noopTracer - tracer of processors without a tracer provider
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// batchAttributes - attributes of the spans of a batch
+func batchAttributes(batchID string, urls int, apiKeyID string) trace.SpanStartEventOption {
+	attributes := []attribute.KeyValue{attribute.Int("links.urls", urls)}
+	if batchID != "" {
+		attributes = append(attributes, attribute.String("links.batch_id", batchID))
+	}
+	if apiKeyID != "" {
+		attributes = append(attributes, attribute.String("links.api_key_id", apiKeyID))
+	}
+	return trace.WithAttributes(attributes...)
+}
+
+// endSpan - ends the span, as failed when err isn't nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceIDOf - id of the trace of ctx, empty when it isn't traced
+func traceIDOf(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// detachContext - context which continues the trace of ctx without being cancelled with it,
+// for the work outliving the request which started it
+func detachContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
@@ -1,16 +1,33 @@
 package links
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
+	"strings"
 	"time"
 )
 
 var ErrInternalServerError = errors.New("internal server error")
+var ErrBatchInProgress = errors.New("batch is still being processed")
+var ErrBatchStatusChanged = errors.New("batch status has changed")
+var ErrInvalidMonitor = errors.New("invalid monitor")
+var ErrMonitorRunning = errors.New("monitor is already running")
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+var ErrInvalidCallback = errors.New("invalid callback")
+var ErrInvalidAPIKey = errors.New("invalid api key")
+var ErrUnknownAPIKey = errors.New("unknown or revoked api key")
+var ErrQuotaExceeded = errors.New("api key quota exceeded")
+var ErrServerBusy = errors.New("server is busy")
 
 // ProcessBatchRequest ...
 type ProcessBatchRequest struct {
-	URLs []*url.URL
+	URLs      []*url.URL
+	Options   ScrapeOptions
+	MonitorID string    // set when the batch is a scheduled run of a monitor
+	Callback  *Callback // notified once the batch finishes
+	APIKeyID  string    // set when the batch is created with an api key, its quotas apply
 }
 
 // ProcessBatchResponse ...
@@ -21,13 +38,207 @@ type ProcessBatchResponse struct {
 // GetBatchRequest ...
 type GetBatchRequest struct {
 	BatchID string `json:"batch_id"`
+	Query   ResultQuery
 }
 
 // GetBatchResponse ...
 type GetBatchResponse struct {
+	Results    []Result
+	NextCursor string `json:",omitempty"`
+}
+
+// GetResultRequest - lookup of a single result, BatchID is optional
+type GetResultRequest struct {
+	BatchID  string `json:"batch_id"`
+	ResultID string `json:"result_id"`
+}
+
+// RetryBatchRequest - rescrape results of a batch, all failed results are retried when no ids are passed
+type RetryBatchRequest struct {
+	BatchID   string   `json:"batch_id"`
+	ResultIDs []string `json:"result_ids"`
+	APIKeyID  string   `json:"-"` // the retried urls count against the quotas of this api key
+}
+
+// RetryBatchResponse - batch with its updated summary and the retried results
+type RetryBatchResponse struct {
+	Batch   Batch
 	Results []Result
 }
 
+// DiffBatchesRequest - compare the results of the target batch against the base batch
+type DiffBatchesRequest struct {
+	BaseBatchID   string `json:"base_batch_id"`
+	TargetBatchID string `json:"target_batch_id"`
+}
+
+// PageChange - how a page differs between two batches
+type PageChange string
+
+const (
+	PageAdded   PageChange = "added"   // only in the target batch
+	PageRemoved PageChange = "removed" // only in the base batch
+	PageChanged PageChange = "changed" // in both batches with a different outcome or links
+)
+
+// BatchDiff model - pages which differ between two batches, matched by normalized page url
+type BatchDiff struct {
+	BaseBatchID    string     `json:"base_batch_id"`
+	TargetBatchID  string     `json:"target_batch_id"`
+	LinkDetails    bool       `json:"link_details"` // both batches collected links so added and removed links are reported
+	AddedCount     int        `json:"added_count"`
+	RemovedCount   int        `json:"removed_count"`
+	ChangedCount   int        `json:"changed_count"`
+	UnchangedCount int        `json:"unchanged_count"`
+	Pages          []PageDiff `json:"pages"` // sorted by page url
+}
+
+// PageDiff model - difference of one page, the deltas of added and removed pages are taken against zero
+type PageDiff struct {
+	PageURL            string     `json:"page_url"`
+	Change             PageChange `json:"change"`
+	BaseSuccess        *bool      `json:"base_success"`   // nil for added pages
+	TargetSuccess      *bool      `json:"target_success"` // nil for removed pages
+	InternalLinksDelta int        `json:"internal_links_delta"`
+	ExternalLinksDelta int        `json:"external_links_delta"`
+	LinksAdded         []string   `json:"links_added,omitempty"`
+	LinksRemoved       []string   `json:"links_removed,omitempty"`
+}
+
+// BatchReport model - summary of a finished batch, rendered as the html report of the batch
+type BatchReport struct {
+	Batch              Batch          `json:"batch"`
+	InternalLinksTotal uint           `json:"internal_links_total"`
+	ExternalLinksTotal uint           `json:"external_links_total"`
+	LinkDetails        bool           `json:"link_details"`         // the batch collected links so the external domains are known
+	TopExternalPages   []Result       `json:"top_external_pages"`   // successful pages with the most external links
+	TopInternalPages   []Result       `json:"top_internal_pages"`   // successful pages with the most internal links
+	Failures           []FailureCount `json:"failures"`             // failed pages per error category, most common first
+	FailedPages        []Result       `json:"failed_pages"`         // sorted by page url
+	TopExternalDomains []DomainCount  `json:"top_external_domains"` // most linked external domains, empty without link details
+	GeneratedAt        time.Time      `json:"generated_at"`
+}
+
+// FailureCount model - number of failed pages of an error category
+type FailureCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// DomainCount model - links to an external domain and the number of pages linking to it
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Links  int    `json:"links"`
+	Pages  int    `json:"pages"`
+}
+
+// Result sort fields
+const (
+	ResultSortCreatedAt        = "created_at"
+	ResultSortPageURL          = "page_url"
+	ResultSortInternalLinksNum = "internal_links_num"
+	ResultSortExternalLinksNum = "external_links_num"
+)
+
+// ResultQuery - filters, sorting and cursor pagination for the results of a batch.
+// Nil filters and a zero limit mean everything is returned, results are in processing
+// order when no sort field is set.
+type ResultQuery struct {
+	Limit            int
+	Cursor           string // NextCursor of the previous page
+	SortBy           string // one of the result sort fields
+	Descending       bool
+	Success          *bool  // only successful (true) or only failed (false) results
+	Host             string // only results for pages on this host
+	MinInternalLinks *uint
+	MaxInternalLinks *uint
+	MinExternalLinks *uint
+	MaxExternalLinks *uint
+}
+
+// ResultPage - one page of results, NextCursor is empty on the last page
+type ResultPage struct {
+	Results    []Result
+	NextCursor string
+}
+
+// ScrapeOptions - per batch scraping options, unset fields fall back to the scraper defaults
+type ScrapeOptions struct {
+	TimeoutMS       int64             `json:"timeout_ms,omitempty"`
+	UserAgent       string            `json:"user_agent,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	MaxBodySize     int64             `json:"max_body_size,omitempty"`
+	RedirectPolicy  string            `json:"redirect_policy,omitempty"` // follow, none or same_host
+	MaxRedirects    int               `json:"max_redirects,omitempty"`
+	InternalPolicy  string            `json:"internal_policy,omitempty"` // same_host or same_domain
+	InternalDomains []string          `json:"internal_domains,omitempty"`
+	LinkCategories  []string          `json:"link_categories,omitempty"` // anchor, area and/or link
+	CollectLinks    bool              `json:"collect_links,omitempty"`   // store the links of every page, needed for link level diffs
+}
+
+// BatchStatus - processing state of a batch
+type BatchStatus string
+
+const (
+	BatchStatusRunning   BatchStatus = "running"
+	BatchStatusCompleted BatchStatus = "completed"
+	BatchStatusFailed    BatchStatus = "failed" // the results couldn't be stored
+)
+
+// Batch model - a group of urls processed at once
+type Batch struct {
+	ID           string        `json:"id"`
+	Status       BatchStatus   `json:"status"`
+	URLCount     int           `json:"url_count"`
+	SuccessCount int           `json:"success_count"`
+	FailureCount int           `json:"failure_count"`
+	Options      ScrapeOptions `json:"options"` // options with the defaults applied so the batch can be reproduced
+	MonitorID    string        `json:"monitor_id,omitempty"`
+	Callback     *Callback     `json:"callback,omitempty"`
+	APIKeyID     string        `json:"api_key_id,omitempty"` // api key which created the batch
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// Batch sort fields
+const (
+	BatchSortCreatedAt    = "created_at"
+	BatchSortURLCount     = "url_count"
+	BatchSortSuccessCount = "success_count"
+	BatchSortFailureCount = "failure_count"
+)
+
+// BatchQuery - filters, sorting and cursor pagination for listing batches.
+// Zero values mean no filter, CreatedAfter is inclusive and CreatedBefore exclusive.
+type BatchQuery struct {
+	Limit         int
+	Cursor        string // NextCursor of the previous page
+	SortBy        string // one of the batch sort fields, created_at by default
+	Descending    bool
+	Status        BatchStatus
+	MonitorID     string
+	APIKeyID      string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// BatchPage - one page of batches, NextCursor is empty on the last page
+type BatchPage struct {
+	Batches    []Batch
+	NextCursor string
+}
+
+// ListBatchesRequest ...
+type ListBatchesRequest struct {
+	Query BatchQuery
+}
+
+// ListBatchesResponse ...
+type ListBatchesResponse struct {
+	Batches    []Batch
+	NextCursor string
+}
+
 // Result model
 type Result struct {
 	ID               string    `json:"id"`
@@ -35,14 +246,396 @@ type Result struct {
 	PageURL          string    `json:"page_url"`
 	InternalLinksNum uint      `json:"internal_links_num"`
 	ExternalLinksNum uint      `json:"external_links_num"`
+	InternalLinks    []string  `json:"internal_links,omitempty"` // only stored when the batch collects links
+	ExternalLinks    []string  `json:"external_links,omitempty"` // only stored when the batch collects links
 	Success          bool      `json:"success"`
 	Error            error     `json:"error"`
+	Attempts         []Attempt `json:"attempts,omitempty"` // previous attempts, oldest first
+	TraceID          string    `json:"trace_id,omitempty"` // trace the page was scraped in, when tracing is enabled
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// Attempt model - outcome of an earlier scrape of a result which was retried since
+type Attempt struct {
+	InternalLinksNum uint      `json:"internal_links_num"`
+	ExternalLinksNum uint      `json:"external_links_num"`
+	Success          bool      `json:"success"`
+	Error            error     `json:"error"`
+	TraceID          string    `json:"trace_id,omitempty"`
+	AttemptedAt      time.Time `json:"attempted_at"`
+}
+
+// MarshalJSON - the error of the result is encoded as its message
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		Error *string `json:"error"`
+	}{result(r), errorMessage(r.Error)})
+}
+
+// UnmarshalJSON - the error message of the result is decoded as a plain error
+func (r *Result) UnmarshalJSON(data []byte) error {
+	type result Result
+	decoded := struct {
+		*result
+		Error *string `json:"error"`
+	}{result: (*result)(r)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	r.Error = messageError(decoded.Error)
+	return nil
+}
+
+// MarshalJSON - the error of the attempt is encoded as its message
+func (a Attempt) MarshalJSON() ([]byte, error) {
+	type attempt Attempt
+	return json.Marshal(struct {
+		attempt
+		Error *string `json:"error"`
+	}{attempt(a), errorMessage(a.Error)})
+}
+
+// UnmarshalJSON - the error message of the attempt is decoded as a plain error
+func (a *Attempt) UnmarshalJSON(data []byte) error {
+	type attempt Attempt
+	decoded := struct {
+		*attempt
+		Error *string `json:"error"`
+	}{attempt: (*attempt)(a)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	a.Error = messageError(decoded.Error)
+	return nil
+}
+
+func errorMessage(err error) *string {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	return &message
+}
+
+func messageError(message *string) error {
+	if message == nil {
+		return nil
+	}
+	return errors.New(*message)
+}
+
 // Response - generic http response structure
 type Response struct {
 	Errors []string    `json:"errors,omitempty"`
 	Data   interface{} `json:"data,omitempty"`
 }
+
+// Envelope - http response structure of the v2 api, Data is set on success and Errors otherwise.
+// RequestID is the X-Request-ID of the request, generated when the client didn't send one.
+type Envelope struct {
+	RequestID  string        `json:"request_id"`
+	Data       interface{}   `json:"data,omitempty"`
+	Pagination *Pagination   `json:"pagination,omitempty"` // only on paginated listings
+	Errors     []ErrorDetail `json:"errors,omitempty"`
+}
+
+// Pagination - position of a page in a cursor paginated listing
+type Pagination struct {
+	Limit      int    `json:"limit,omitempty"`       // page size of the request
+	NextCursor string `json:"next_cursor,omitempty"` // cursor query parameter of the next page
+	HasMore    bool   `json:"has_more"`
+}
+
+// ErrorDetail - machine readable error of the v2 api
+type ErrorDetail struct {
+	Code    string `json:"code"` // one of the error codes, stable across releases
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"` // query parameter or form field the error is about
+	Line    int    `json:"line,omitempty"`  // line of the url file the error is about
+}
+
+// Error codes of the v2 api
+const (
+	ErrorCodeInvalidRequest        = "invalid_request"
+	ErrorCodeInvalidQueryParameter = "invalid_query_parameter"
+	ErrorCodeInvalidRequestBody    = "invalid_request_body"
+	ErrorCodeInvalidFile           = "invalid_file"
+	ErrorCodeInvalidURL            = "invalid_url"
+	ErrorCodeNoURLs                = "no_urls"
+	ErrorCodeInvalidOptions        = "invalid_options"
+	ErrorCodeInvalidCallback       = "invalid_callback"
+	ErrorCodeInvalidCursor         = "invalid_cursor"
+	ErrorCodeInvalidSort           = "invalid_sort"
+	ErrorCodeInvalidMonitor        = "invalid_monitor"
+	ErrorCodeInvalidAlertRule      = "invalid_alert_rule"
+	ErrorCodeBatchNotFound         = "batch_not_found"
+	ErrorCodeResultNotFound        = "result_not_found"
+	ErrorCodeMonitorNotFound       = "monitor_not_found"
+	ErrorCodeAlertRuleNotFound     = "alert_rule_not_found"
+	ErrorCodeBatchInProgress       = "batch_in_progress"
+	ErrorCodeMonitorRunning        = "monitor_running"
+	ErrorCodeInvalidAPIKey         = "invalid_api_key"
+	ErrorCodeAPIKeyNotFound        = "api_key_not_found"
+	ErrorCodeAPIKeysDisabled       = "api_keys_disabled"
+	ErrorCodeUnauthorized          = "unauthorized"
+	ErrorCodeForbidden             = "forbidden"
+	ErrorCodeQuotaExceeded         = "quota_exceeded"
+	ErrorCodeServerBusy            = "server_busy"
+	ErrorCodeInternal              = "internal_error"
+)
+
+// Monitor model - list of urls which is processed again on a schedule, every run is its own batch
+type Monitor struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Schedule    string        `json:"schedule"` // cron expression with 5 fields or a descriptor like @daily or @every 1h
+	URLs        []string      `json:"urls"`
+	Options     ScrapeOptions `json:"options"`
+	Paused      bool          `json:"paused"`
+	LastBatchID string        `json:"last_batch_id,omitempty"`
+	LastRunAt   *time.Time    `json:"last_run_at,omitempty"`
+	NextRunAt   *time.Time    `json:"next_run_at,omitempty"` // not stored, set while the monitor is scheduled
+	APIKeyID    string        `json:"api_key_id,omitempty"`  // api key which created the monitor, its runs count against its quotas
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// MonitorRequest - definition of a monitor to create or to replace an existing one with
+type MonitorRequest struct {
+	MonitorID string        `json:"-"`
+	Name      string        `json:"name"`
+	Schedule  string        `json:"schedule"`
+	URLs      []string      `json:"urls"`
+	Options   ScrapeOptions `json:"options"`
+	Paused    bool          `json:"paused"`
+	APIKeyID  string        `json:"-"` // api key creating the monitor, ignored on updates
+}
+
+// ListMonitorsResponse ...
+type ListMonitorsResponse struct {
+	Monitors []Monitor
+}
+
+// AlertCondition - what an alert rule watches for between two runs of a monitor
+type AlertCondition string
+
+const (
+	AlertExternalLinksChange AlertCondition = "external_links_change" // external links count changed by more than the threshold
+	AlertInternalLinksChange AlertCondition = "internal_links_change" // internal links count changed by more than the threshold
+	AlertSuccessToFailure    AlertCondition = "success_to_failure"    // page was scraped successfully before and failed now
+	AlertNewExternalDomain   AlertCondition = "new_external_domain"   // page links to a domain it didn't link to before, needs collect_links
+)
+
+// AlertSinkType - where alerts are delivered to
+type AlertSinkType string
+
+const (
+	AlertSinkWebhook AlertSinkType = "webhook" // alert is POSTed as JSON to the sink url
+	AlertSinkLog     AlertSinkType = "log"     // alert is appended to the alerts log file of the service
+)
+
+// AlertSink - destination of the alerts of a rule
+type AlertSink struct {
+	Type AlertSinkType `json:"type"`
+	URL  string        `json:"url,omitempty"` // only for webhooks
+}
+
+// AlertRule model - condition evaluated after every run of a monitor against its previous run
+type AlertRule struct {
+	ID               string         `json:"id"`
+	MonitorID        string         `json:"monitor_id"`
+	Name             string         `json:"name"`
+	Condition        AlertCondition `json:"condition"`
+	PageURL          string         `json:"page_url,omitempty"`          // only this page is watched, all pages when empty
+	ThresholdPercent float64        `json:"threshold_percent,omitempty"` // for link count changes, negative values watch for drops
+	Sinks            []AlertSink    `json:"sinks"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// AlertRuleRequest - definition of a new alert rule of a monitor
+type AlertRuleRequest struct {
+	MonitorID        string         `json:"-"`
+	Name             string         `json:"name"`
+	Condition        AlertCondition `json:"condition"`
+	PageURL          string         `json:"page_url"`
+	ThresholdPercent float64        `json:"threshold_percent"`
+	Sinks            []AlertSink    `json:"sinks"`
+}
+
+// Alert model - a match of an alert rule
+type Alert struct {
+	ID              string          `json:"id"`
+	RuleID          string          `json:"rule_id"`
+	RuleName        string          `json:"rule_name"`
+	MonitorID       string          `json:"monitor_id"`
+	BatchID         string          `json:"batch_id"`
+	PreviousBatchID string          `json:"previous_batch_id"`
+	Condition       AlertCondition  `json:"condition"`
+	PageURL         string          `json:"page_url"`
+	Message         string          `json:"message"`
+	Previous        uint            `json:"previous,omitempty"` // link count of the previous run for link count changes
+	Current         uint            `json:"current,omitempty"`  // link count of this run for link count changes
+	Domains         []string        `json:"domains,omitempty"`  // new external domains
+	Deliveries      []AlertDelivery `json:"deliveries"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// AlertDelivery - outcome of delivering an alert to one sink
+type AlertDelivery struct {
+	Sink  AlertSink `json:"sink"`
+	Error string    `json:"error,omitempty"`
+}
+
+// AlertQuery - filters of the alert history, newest alerts first
+type AlertQuery struct {
+	MonitorID string
+	RuleID    string
+	Limit     int // all alerts when not set
+}
+
+// ListAlertRulesResponse ...
+type ListAlertRulesResponse struct {
+	Rules []AlertRule
+}
+
+// ListAlertsResponse ...
+type ListAlertsResponse struct {
+	Alerts []Alert
+}
+
+// Callback - url the summary of a batch is POSTed to once it finishes,
+// signed with HMAC-SHA256 when a secret is set. The secret is never returned by the api.
+type Callback struct {
+	URL    string `json:"url"`
+	Secret string `json:"-"`
+}
+
+// Callback event types
+const (
+	CallbackBatchCompleted = "batch.completed"
+	CallbackBatchFailed    = "batch.failed"
+)
+
+// CallbackEvent - payload POSTed to the callback url, retries of a delivery share the event id
+type CallbackEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Batch     Batch     `json:"batch"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CallbackAttempt model - one attempt to deliver a callback event
+type CallbackAttempt struct {
+	ID          string    `json:"id"`
+	BatchID     string    `json:"batch_id"`
+	EventID     string    `json:"event_id"`
+	URL         string    `json:"url"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// ListCallbackAttemptsResponse ...
+type ListCallbackAttemptsResponse struct {
+	Attempts []CallbackAttempt
+}
+
+// BatchEventType - kind of a live event of a running batch
+type BatchEventType string
+
+const (
+	BatchEventResult    BatchEventType = "result"    // a page of the batch was scraped
+	BatchEventProgress  BatchEventType = "progress"  // periodic summary of the batch progress
+	BatchEventCompleted BatchEventType = "completed" // the batch finished, always the last event
+)
+
+// BatchEvent - live event of a batch, only the field matching the type is set
+type BatchEvent struct {
+	Type     BatchEventType
+	Result   *Result
+	Progress *BatchProgress
+	Batch    *Batch
+}
+
+// BatchProgress - how far a running batch got
+type BatchProgress struct {
+	BatchID      string `json:"batch_id"`
+	URLCount     int    `json:"url_count"`
+	Processed    int    `json:"processed"`
+	SuccessCount int    `json:"success_count"`
+	FailureCount int    `json:"failure_count"`
+}
+
+// APIKeyQuota - limits of an api key, zero means unlimited
+type APIKeyQuota struct {
+	URLsPerDay        int `json:"urls_per_day,omitempty"`       // urls processed or retried per UTC day
+	ConcurrentBatches int `json:"concurrent_batches,omitempty"` // batches processed at the same time
+}
+
+// APIKey model - credentials of a client of the api. Only the SHA-256 hash of the key is stored,
+// the key itself is returned once when it's issued.
+type APIKey struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Prefix    string      `json:"prefix"` // first characters of the key, to tell keys apart
+	Hash      string      `json:"-"`      // hex encoded SHA-256 of the key
+	Admin     bool        `json:"admin"`  // can manage api keys and sees the batches of all keys
+	Quota     APIKeyQuota `json:"quota"`
+	CreatedAt time.Time   `json:"created_at"`
+	RevokedAt *time.Time  `json:"revoked_at,omitempty"`
+}
+
+// APIKeyRequest - definition of a new api key
+type APIKeyRequest struct {
+	Name  string      `json:"name"`
+	Admin bool        `json:"admin"`
+	Quota APIKeyQuota `json:"quota"`
+}
+
+// IssuedAPIKey - a new api key together with the key itself
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// ListAPIKeysResponse ...
+type ListAPIKeysResponse struct {
+	Keys []APIKey
+}
+
+// QuotaError - an api key quota which doesn't allow a batch right now, RetryAfter is
+// when it's worth trying again
+type QuotaError struct {
+	Quota      string // urls_per_day or concurrent_batches
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: limit of %d %s reached", ErrQuotaExceeded, e.Limit, strings.ReplaceAll(e.Quota, "_", " "))
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// CapacityError - a limit of the whole service which doesn't admit a batch right now, RetryAfter is
+// when it's worth trying again
+type CapacityError struct {
+	Limit      string // queued_batches
+	Max        int
+	RetryAfter time.Duration
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("%s: limit of %d %s reached", ErrServerBusy, e.Max, strings.ReplaceAll(e.Limit, "_", " "))
+}
+
+func (e *CapacityError) Unwrap() error {
+	return ErrServerBusy
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var ErrInvalidAlertRuleRequest = errors.New("invalid alert rule request")
+
+// CreateAlertRule - handler to add an alert rule to a monitor, it is evaluated after every run of the monitor.
+// Expects a JSON body with name, condition, page_url, threshold_percent and sinks.
+func (h *Handler) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req links.AlertRuleRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{ErrInvalidAlertRuleRequest.Error()}})
+		return
+	}
+	req.MonitorID = chi.URLParam(r, "monitorID")
+
+	rule, err := h.monitors.CreateAlertRule(r.Context(), req)
+	if err != nil {
+		renderAlertError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, links.Response{Data: rule})
+}
+
+// ListAlertRules - handler for listing the alert rules of a monitor
+func (h *Handler) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.monitors.ListAlertRules(r.Context(), chi.URLParam(r, "monitorID"))
+	if err != nil {
+		renderAlertError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: rules})
+}
+
+// DeleteAlertRule - handler to delete an alert rule of a monitor, its alerts are kept
+func (h *Handler) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.monitors.DeleteAlertRule(r.Context(), chi.URLParam(r, "monitorID"), chi.URLParam(r, "ruleID")); err != nil {
+		renderAlertError(w, r, err)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// ListAlerts - handler for the alert history, newest first.
+// Supports the monitor_id, rule_id and limit query parameters.
+func (h *Handler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit, err := parseLimit(params.Get("limit"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+
+	alerts, err := h.monitors.ListAlerts(r.Context(), links.AlertQuery{
+		MonitorID: params.Get("monitor_id"),
+		RuleID:    params.Get("rule_id"),
+		Limit:     limit,
+	})
+	if err != nil {
+		renderAlertError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: alerts})
+}
+
+func renderAlertError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrMonitorNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, links.Response{Errors: []string{repository.ErrMonitorNotFound.Error()}})
+	case errors.Is(err, repository.ErrAlertRuleNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, links.Response{Errors: []string{repository.ErrAlertRuleNotFound.Error()}})
+	case errors.Is(err, links.ErrInvalidAlertRule):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+	default: // generic response to not leak details for all other errors
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+	}
+}
@@ -0,0 +1,149 @@
+package handler_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+)
+
+func (s *handlerTestSuite) TestAlerts_DifferentCases_ThenItIsHandledAsExpected() {
+	rule := links.AlertRule{ID: "ar0", MonitorID: "m0", Condition: links.AlertSuccessToFailure, Sinks: []links.AlertSink{{Type: links.AlertSinkLog}}}
+	validBody := `{"name": "homepage down", "condition": "success_to_failure", "sinks": [{"type": "log"}]}`
+	validReq := links.AlertRuleRequest{MonitorID: "m0", Name: "homepage down", Condition: links.AlertSuccessToFailure, Sinks: []links.AlertSink{{Type: links.AlertSinkLog}}}
+	alert := links.Alert{ID: "a0", RuleID: "ar0", MonitorID: "m0", PageURL: "https://www.google.com/"}
+
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		mockMethod     string
+		mockArguments  []interface{}
+		mockReturns    []interface{}
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "create rule",
+			method:         "POST",
+			target:         "/api/v1/monitors/m0/rules",
+			body:           validBody,
+			mockMethod:     "CreateAlertRule",
+			mockArguments:  []interface{}{validReq},
+			mockReturns:    []interface{}{rule, nil},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"id":"ar0"`,
+		},
+		{
+			name:           "create rule with malformed body",
+			method:         "POST",
+			target:         "/api/v1/monitors/m0/rules",
+			body:           `{"when": "down"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   handler.ErrInvalidAlertRuleRequest.Error(),
+		},
+		{
+			name:           "create invalid rule",
+			method:         "POST",
+			target:         "/api/v1/monitors/m0/rules",
+			body:           validBody,
+			mockMethod:     "CreateAlertRule",
+			mockArguments:  []interface{}{validReq},
+			mockReturns:    []interface{}{links.AlertRule{}, fmt.Errorf("%w: unknown sink type %q", links.ErrInvalidAlertRule, "email")},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `invalid alert rule: unknown sink type \"email\"`,
+		},
+		{
+			name:           "create rule of unknown monitor",
+			method:         "POST",
+			target:         "/api/v1/monitors/m0/rules",
+			body:           validBody,
+			mockMethod:     "CreateAlertRule",
+			mockArguments:  []interface{}{validReq},
+			mockReturns:    []interface{}{links.AlertRule{}, fmt.Errorf("failed to create alert rule %w", repository.ErrMonitorNotFound)},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   repository.ErrMonitorNotFound.Error(),
+		},
+		{
+			name:           "list rules",
+			method:         "GET",
+			target:         "/api/v1/monitors/m0/rules",
+			mockMethod:     "ListAlertRules",
+			mockArguments:  []interface{}{"m0"},
+			mockReturns:    []interface{}{links.ListAlertRulesResponse{Rules: []links.AlertRule{rule}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Rules":[{"id":"ar0"`,
+		},
+		{
+			name:           "delete rule",
+			method:         "DELETE",
+			target:         "/api/v1/monitors/m0/rules/ar0",
+			mockMethod:     "DeleteAlertRule",
+			mockArguments:  []interface{}{"m0", "ar0"},
+			mockReturns:    []interface{}{nil},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete unknown rule",
+			method:         "DELETE",
+			target:         "/api/v1/monitors/m0/rules/unknown",
+			mockMethod:     "DeleteAlertRule",
+			mockArguments:  []interface{}{"m0", "unknown"},
+			mockReturns:    []interface{}{fmt.Errorf("failed to delete alert rule %w", repository.ErrAlertRuleNotFound)},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   repository.ErrAlertRuleNotFound.Error(),
+		},
+		{
+			name:           "list alerts",
+			method:         "GET",
+			target:         "/api/v1/alerts?monitor_id=m0&rule_id=ar0&limit=10",
+			mockMethod:     "ListAlerts",
+			mockArguments:  []interface{}{links.AlertQuery{MonitorID: "m0", RuleID: "ar0", Limit: 10}},
+			mockReturns:    []interface{}{links.ListAlertsResponse{Alerts: []links.Alert{alert}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Alerts":[{"id":"a0"`,
+		},
+		{
+			name:           "list alerts with invalid limit",
+			method:         "GET",
+			target:         "/api/v1/alerts?limit=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid query parameter limit",
+		},
+		{
+			name:           "internal error",
+			method:         "GET",
+			target:         "/api/v1/alerts",
+			mockMethod:     "ListAlerts",
+			mockArguments:  []interface{}{links.AlertQuery{}},
+			mockReturns:    []interface{}{links.ListAlertsResponse{}, errors.New("error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body))
+			if tc.mockMethod != "" {
+				s.mockMonitors.On(tc.mockMethod, tc.mockArguments...).Return(tc.mockReturns...)
+			}
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.Contains(rr.Body.String(), tc.expectedBody)
+			s.mockMonitors.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
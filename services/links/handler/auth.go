@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/go-chi/render"
+)
+
+var ErrMissingAPIKey = errors.New("missing api key")
+var ErrAdminRequired = errors.New("admin api key required")
+var ErrAPIKeysDisabled = errors.New("api keys are not enabled")
+
+// APIKeyHeader - header with the api key of the client, the alternative to an
+// `Authorization: Bearer <key>` header
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyKey struct{}
+
+// HandlerOption - optional configuration of the handler
+type HandlerOption func(*Handler)
+
+// WithAPIKeys - every request must be authenticated with an api key of the service,
+// without it the api is open and the api key endpoints respond with 404
+func WithAPIKeys(apiKeys links.APIKeyService) HandlerOption {
+	return func(h *Handler) {
+		h.apiKeys = apiKeys
+	}
+}
+
+// authenticate - middleware rejecting requests without a valid api key with the error rendered by fail,
+// the api key of the request is read back with apiKeyOf. Requests of a client key only see what the key
+// created, see links.WithAPIKeyScope. Requests are passed on when api keys are disabled.
+func (h *Handler) authenticate(fail errorRenderer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h.apiKeys == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := requestAPIKey(r)
+			if key == "" {
+				fail(w, r, ErrMissingAPIKey)
+				return
+			}
+			apiKey, err := h.apiKeys.Authenticate(r.Context(), key)
+			if err != nil {
+				fail(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), apiKeyKey{}, apiKey)
+			if !apiKey.Admin {
+				ctx = links.WithAPIKeyScope(ctx, apiKey.ID)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requireAdmin - middleware only passing on requests authenticated with an admin api key
+func (h *Handler) requireAdmin(fail errorRenderer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h.apiKeys == nil {
+				fail(w, r, ErrAPIKeysDisabled)
+				return
+			}
+			if apiKey, ok := apiKeyOf(r.Context()); !ok || !apiKey.Admin {
+				fail(w, r, ErrAdminRequired)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestAPIKey - the bearer token of the Authorization header or the X-API-Key header
+func requestAPIKey(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
+
+// apiKeyOf - api key the request was authenticated with, false when api keys are disabled
+func apiKeyOf(ctx context.Context) (links.APIKey, bool) {
+	apiKey, ok := ctx.Value(apiKeyKey{}).(links.APIKey)
+	return apiKey, ok
+}
+
+// apiKeyID - id of the api key the request was authenticated with, empty when api keys are disabled
+func apiKeyID(ctx context.Context) string {
+	apiKey, _ := apiKeyOf(ctx)
+	return apiKey.ID
+}
+
+// scopeToAPIKey - batches listed with an api key are limited to the batches it created,
+// admin keys see every batch and can filter by api key themselves
+func scopeToAPIKey(ctx context.Context, query *links.BatchQuery) {
+	if apiKey, ok := apiKeyOf(ctx); ok && !apiKey.Admin {
+		query.APIKeyID = apiKey.ID
+	}
+}
+
+// setAuthHeaders - WWW-Authenticate for unauthenticated requests and Retry-After for exceeded quotas
+// and batches the service can't admit
+func setAuthHeaders(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrMissingAPIKey) || errors.Is(err, links.ErrUnknownAPIKey) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	var quotaErr *links.QuotaError
+	if errors.As(err, &quotaErr) {
+		setRetryAfter(w, quotaErr.RetryAfter)
+	}
+	var capacityErr *links.CapacityError
+	if errors.As(err, &capacityErr) {
+		setRetryAfter(w, capacityErr.RetryAfter)
+	}
+}
+
+// setRetryAfter - Retry-After in whole seconds, at least one
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := math.Ceil(retryAfter.Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(seconds, 1))))
+}
+
+// renderAuthError - responds with the error of authenticating a v1 request, of a quota of its api key
+// or of admitting its batch while the service is busy
+func renderAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	setAuthHeaders(w, err)
+	switch {
+	case errors.Is(err, ErrMissingAPIKey):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, links.Response{Errors: []string{ErrMissingAPIKey.Error()}})
+	case errors.Is(err, links.ErrUnknownAPIKey):
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrUnknownAPIKey.Error()}})
+	case errors.Is(err, ErrAdminRequired):
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, links.Response{Errors: []string{ErrAdminRequired.Error()}})
+	case errors.Is(err, ErrAPIKeysDisabled):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, links.Response{Errors: []string{ErrAPIKeysDisabled.Error()}})
+	case errors.Is(err, links.ErrQuotaExceeded), errors.Is(err, links.ErrServerBusy):
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+	default: // generic response to not leak details for all other errors
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+	}
+}
+
+// isAuthError - errors rendered by renderAuthError rather than by the handlers
+func isAuthError(err error) bool {
+	return errors.Is(err, links.ErrQuotaExceeded) || errors.Is(err, links.ErrServerBusy) || errors.Is(err, links.ErrUnknownAPIKey)
+}
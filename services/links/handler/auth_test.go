@@ -0,0 +1,381 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	pkgmocks "github.com/Lockwarr/codefi/pkg/mocks"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/mocks"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *handlerTestSuite) TestAuthentication_DifferentCases_ThenItIsHandledAsExpected() {
+	admin := links.APIKey{ID: "k0", Name: "admin", Admin: true}
+	client := links.APIKey{ID: "k1", Name: "ci"}
+	testCases := []struct {
+		name            string
+		req             *http.Request
+		key             string // sent as a bearer token
+		header          string // sent in the X-API-Key header
+		arrange         func(apiKeys *mocks.MockAPIKeyService)
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:            "missing key",
+			req:             httptest.NewRequest("GET", "/api/v1/links", nil),
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: handler.ErrMissingAPIKey.Error(),
+		},
+		{
+			name: "unknown key",
+			req:  httptest.NewRequest("GET", "/api/v1/links", nil),
+			key:  "lk_unknown",
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("Authenticate", "lk_unknown").Return(links.APIKey{}, links.ErrUnknownAPIKey)
+			},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: links.ErrUnknownAPIKey.Error(),
+		},
+		{
+			name:   "batches of the key in the X-API-Key header",
+			req:    httptest.NewRequest("GET", "/api/v1/links", nil),
+			header: "lk_client",
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("Authenticate", "lk_client").Return(client, nil)
+				s.mockLinkProcessor.On("ListBatches", mock.MatchedBy(func(req links.ListBatchesRequest) bool { return req.Query.APIKeyID == "k1" })).
+					Return(links.ListBatchesResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "other keys can't be listed by a client key",
+			req:  httptest.NewRequest("GET", "/api/v1/links?api_key_id=k2", nil),
+			key:  "lk_client",
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("Authenticate", "lk_client").Return(client, nil)
+				s.mockLinkProcessor.On("ListBatches", mock.MatchedBy(func(req links.ListBatchesRequest) bool { return req.Query.APIKeyID == "k1" })).
+					Return(links.ListBatchesResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "batches of every key for an admin key",
+			req:  httptest.NewRequest("GET", "/api/v1/links", nil),
+			key:  "lk_admin",
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("Authenticate", "lk_admin").Return(admin, nil)
+				s.mockLinkProcessor.On("ListBatches", mock.MatchedBy(func(req links.ListBatchesRequest) bool { return req.Query.APIKeyID == "" })).
+					Return(links.ListBatchesResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "batch is created with the key",
+			req:  createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/testFile.txt`, false),
+			key:  "lk_client",
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("Authenticate", "lk_client").Return(client, nil)
+				s.mockLinkProcessor.On("ProcessBatch", mock.MatchedBy(func(req links.ProcessBatchRequest) bool { return req.APIKeyID == "k1" })).
+					Return([]links.Result{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "key management needs an admin key",
+			req:  httptest.NewRequest("GET", "/api/v1/keys", nil),
+			key:  "lk_client",
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("Authenticate", "lk_client").Return(client, nil)
+			},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: handler.ErrAdminRequired.Error(),
+		},
+		{
+			name:           "openapi document is public",
+			req:            httptest.NewRequest("GET", "/api/v1/openapi.json", nil),
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			apiKeys := new(mocks.MockAPIKeyService)
+			if tc.arrange != nil {
+				tc.arrange(apiKeys)
+			}
+			if tc.key != "" {
+				tc.req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			if tc.header != "" {
+				tc.req.Header.Set(handler.APIKeyHeader, tc.header)
+			}
+			h := handler.NewHandler(s.mockLinkProcessor, s.mockMonitors, handler.WithAPIKeys(apiKeys))
+
+			// Act
+			handler.NewRouter(h).ServeHTTP(rr, tc.req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code, rr.Body.String())
+			if tc.expectedMessage != "" {
+				var response links.Response
+				s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+				s.Equal([]string{tc.expectedMessage}, response.Errors)
+			}
+			if tc.expectedStatus == http.StatusUnauthorized {
+				s.Equal("Bearer", rr.Header().Get("WWW-Authenticate"))
+			}
+			apiKeys.AssertExpectations(s.T())
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestProcessBatch_WhenQuotaIsExceeded_ThenRetryAfterIsSent() {
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		s.Run(prefix, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := createRequestWithAttachedFile("POST", prefix+"/links", `testdata/testFile.txt`, false)
+			req.Header.Set("Authorization", "Bearer lk_client")
+			apiKeys := new(mocks.MockAPIKeyService)
+			apiKeys.On("Authenticate", "lk_client").Return(links.APIKey{ID: "k1"}, nil)
+			quotaErr := &links.QuotaError{Quota: "urls_per_day", Limit: 10, RetryAfter: 90*time.Minute + time.Millisecond}
+			s.mockLinkProcessor.On("ProcessBatch", mock.Anything).Return([]links.Result(nil), quotaErr)
+			h := handler.NewHandler(s.mockLinkProcessor, s.mockMonitors, handler.WithAPIKeys(apiKeys))
+
+			// Act
+			handler.NewRouter(h).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(http.StatusTooManyRequests, rr.Code)
+			s.Equal("5401", rr.Header().Get("Retry-After"))
+			s.Contains(rr.Body.String(), "api key quota exceeded: limit of 10 urls per day reached")
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestBatches_WhenServerIsBusy_ThenRetryAfterIsSent() {
+	busyErr := &links.CapacityError{Limit: "queued_batches", Max: 100, RetryAfter: 10 * time.Second}
+	testCases := []struct {
+		name         string
+		req          *http.Request
+		arrange      func()
+		expectedBody string
+	}{
+		{
+			name: "process a batch",
+			req:  createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/testFile.txt`, false),
+			arrange: func() {
+				s.mockLinkProcessor.On("ProcessBatch", mock.Anything).Return([]links.Result(nil), busyErr)
+			},
+			expectedBody: "server is busy: limit of 100 queued batches reached",
+		},
+		{
+			name: "start a batch",
+			req:  createRequestWithAttachedFile("POST", "/api/v1/links?async=true", `testdata/testFile.txt`, false),
+			arrange: func() {
+				s.mockLinkProcessor.On("StartBatch", mock.Anything).Return(links.Batch{}, busyErr)
+			},
+			expectedBody: "server is busy: limit of 100 queued batches reached",
+		},
+		{
+			name: "retry a batch",
+			req:  httptest.NewRequest("POST", "/api/v1/links/b0/retry", nil),
+			arrange: func() {
+				s.mockLinkProcessor.On("RetryBatch", mock.Anything).Return(links.RetryBatchResponse{}, busyErr)
+			},
+			expectedBody: "server is busy: limit of 100 queued batches reached",
+		},
+		{
+			name: "run a monitor",
+			req:  httptest.NewRequest("POST", "/api/v1/monitors/m0/run", nil),
+			arrange: func() {
+				s.mockMonitors.On("RunMonitor", "m0").Return([]links.Result(nil), busyErr)
+			},
+			expectedBody: "server is busy: limit of 100 queued batches reached",
+		},
+		{
+			name: "process a batch on v2",
+			req:  createRequestWithAttachedFile("POST", "/api/v2/links", `testdata/testFile.txt`, false),
+			arrange: func() {
+				s.mockLinkProcessor.On("ProcessBatch", mock.Anything).Return([]links.Result(nil), busyErr)
+			},
+			expectedBody: `"code":"server_busy"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			tc.arrange()
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, tc.req)
+
+			// Assert
+			s.Equal(http.StatusTooManyRequests, rr.Code)
+			s.Equal("10", rr.Header().Get("Retry-After"))
+			s.Contains(rr.Body.String(), tc.expectedBody)
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestAPIKeys_DifferentCases_ThenItIsHandledAsExpected() {
+	at := time.Date(2022, 5, 23, 10, 51, 1, 0, time.UTC)
+	issued := links.IssuedAPIKey{APIKey: links.APIKey{ID: "k1", Name: "ci", Prefix: "lk_abcdefg", Hash: "h1", CreatedAt: at}, Key: "lk_abcdefghijkl"}
+	testCases := []struct {
+		name           string
+		req            *http.Request
+		arrange        func(apiKeys *mocks.MockAPIKeyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "issue a key",
+			req:  httptest.NewRequest("POST", "/api/v1/keys", strings.NewReader(`{"name": "ci", "quota": {"urls_per_day": 100}}`)),
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("IssueAPIKey", links.APIKeyRequest{Name: "ci", Quota: links.APIKeyQuota{URLsPerDay: 100}}).Return(issued, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"key":"lk_abcdefghijkl"`,
+		},
+		{
+			name:           "issue a key with an unknown field",
+			req:            httptest.NewRequest("POST", "/api/v1/keys", strings.NewReader(`{"name": "ci", "role": "admin"}`)),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   handler.ErrInvalidAPIKeyRequest.Error(),
+		},
+		{
+			name: "list keys without their hashes",
+			req:  httptest.NewRequest("GET", "/api/v2/keys", nil),
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("ListAPIKeys").Return(links.ListAPIKeysResponse{Keys: []links.APIKey{issued.APIKey}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"data":[{"id":"k1","name":"ci","prefix":"lk_abcdefg","admin":false,"quota":{},"created_at":"2022-05-23T10:51:01Z"}]`,
+		},
+		{
+			name:           "revoke a key",
+			req:            httptest.NewRequest("DELETE", "/api/v1/keys/k1", nil),
+			arrange:        func(apiKeys *mocks.MockAPIKeyService) { apiKeys.On("RevokeAPIKey", "k1").Return(nil) },
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "revoke an unknown key",
+			req:  httptest.NewRequest("DELETE", "/api/v2/keys/k9", nil),
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("RevokeAPIKey", "k9").Return(repository.ErrAPIKeyNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"api_key_not_found"`,
+		},
+		{
+			name: "list keys fails",
+			req:  httptest.NewRequest("GET", "/api/v1/keys", nil),
+			arrange: func(apiKeys *mocks.MockAPIKeyService) {
+				apiKeys.On("ListAPIKeys").Return(links.ListAPIKeysResponse{}, errors.New("error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			apiKeys := new(mocks.MockAPIKeyService)
+			apiKeys.On("Authenticate", "lk_admin").Return(links.APIKey{ID: "k0", Admin: true}, nil)
+			if tc.arrange != nil {
+				tc.arrange(apiKeys)
+			}
+			tc.req.Header.Set("Authorization", "Bearer lk_admin")
+			h := handler.NewHandler(s.mockLinkProcessor, s.mockMonitors, handler.WithAPIKeys(apiKeys))
+
+			// Act
+			handler.NewRouter(h).ServeHTTP(rr, tc.req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code, rr.Body.String())
+			s.Contains(rr.Body.String(), tc.expectedBody)
+			s.NotContains(rr.Body.String(), `"h1"`)
+			apiKeys.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestAPIKeys_WhenAPIKeysAreDisabled_ThenNotFound() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v2/keys", nil)
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+	// Assert
+	var response links.Envelope
+	s.Equal(http.StatusNotFound, rr.Code)
+	s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	s.Equal([]links.ErrorDetail{{Code: links.ErrorCodeAPIKeysDisabled, Message: handler.ErrAPIKeysDisabled.Error()}}, response.Errors)
+}
+
+func (s *handlerTestSuite) TestBatchesOfAnotherAPIKey_DifferentRequests_ThenNotFound() {
+	admin := links.APIKey{ID: "k0", Name: "admin", Admin: true}
+	owner := links.APIKey{ID: "k1", Name: "ci"}
+	other := links.APIKey{ID: "k2", Name: "staging"}
+	testCases := []struct {
+		name           string
+		req            *http.Request
+		apiKey         links.APIKey
+		expectedStatus int
+	}{
+		{name: "read by the key", req: httptest.NewRequest("GET", "/api/v1/links/b1", nil), apiKey: owner, expectedStatus: http.StatusOK},
+		{name: "read by an admin key", req: httptest.NewRequest("GET", "/api/v1/links/b1", nil), apiKey: admin, expectedStatus: http.StatusOK},
+		{name: "read by another key", req: httptest.NewRequest("GET", "/api/v1/links/b1", nil), apiKey: other, expectedStatus: http.StatusNotFound},
+		{name: "result read by another key", req: httptest.NewRequest("GET", "/api/v1/results/r1", nil), apiKey: other, expectedStatus: http.StatusNotFound},
+		{name: "retry by another key", req: httptest.NewRequest("POST", "/api/v1/links/b1/retry", nil), apiKey: other, expectedStatus: http.StatusNotFound},
+		{name: "v2 read by another key", req: httptest.NewRequest("GET", "/api/v2/links/b1", nil), apiKey: other, expectedStatus: http.StatusNotFound},
+		{name: "v2 retry by another key", req: httptest.NewRequest("POST", "/api/v2/links/b1/retry", nil), apiKey: other, expectedStatus: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			ctx := context.Background()
+			repo := repository.NewInMemoryDB()
+			s.NoError(repo.CreateBatch(ctx, links.Batch{ID: "b1", Status: links.BatchStatusCompleted, URLCount: 1, FailureCount: 1, APIKeyID: owner.ID}))
+			s.NoError(repo.AppendResults(ctx, []links.Result{{ID: "r1", BatchID: "b1", PageURL: "https://www.google.com"}}))
+			scraperClient := new(pkgmocks.MockScraper)
+			apiKeys := new(mocks.MockAPIKeyService)
+			apiKeys.On("Authenticate", "lk_test").Return(tc.apiKey, nil)
+			tc.req.Header.Set("Authorization", "Bearer lk_test")
+			rr := httptest.NewRecorder()
+			h := handler.NewHandler(domain.NewLinksProcessor(repo, scraperClient), s.mockMonitors, handler.WithAPIKeys(apiKeys))
+
+			// Act
+			handler.NewRouter(h).ServeHTTP(rr, tc.req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code, rr.Body.String())
+			scraperClient.AssertNotCalled(s.T(), "Scrape", mock.Anything, mock.Anything)
+			batch, err := repo.GetBatch(ctx, "b1")
+			s.NoError(err)
+			s.Equal(links.BatchStatusCompleted, batch.Status)
+			s.Equal(1, batch.FailureCount)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// diffCSVHeader - columns of the CSV diff, added and removed links are separated by spaces
+var diffCSVHeader = []string{
+	"page_url", "change", "base_success", "target_success",
+	"internal_links_delta", "external_links_delta", "links_added", "links_removed",
+}
+
+// writeDiffAttachment - responds with the diff as a CSV attachment
+func writeDiffAttachment(w http.ResponseWriter, req links.DiffBatchesRequest, diff links.BatchDiff) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="diff_%s_%s.csv"`, req.BaseBatchID, req.TargetBatchID))
+	w.WriteHeader(http.StatusOK)
+	if err := writeDiffCSV(w, diff); err != nil {
+		log.Println("failed to write diff of", req.BaseBatchID, "and", req.TargetBatchID, err)
+	}
+}
+
+// writeDiffCSV - writes one row per changed page
+func writeDiffCSV(w io.Writer, diff links.BatchDiff) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(diffCSVHeader); err != nil {
+		return err
+	}
+
+	for _, page := range diff.Pages {
+		row := []string{
+			page.PageURL,
+			string(page.Change),
+			formatOptionalBool(page.BaseSuccess),
+			formatOptionalBool(page.TargetSuccess),
+			strconv.Itoa(page.InternalLinksDelta),
+			strconv.Itoa(page.ExternalLinksDelta),
+			strings.Join(page.LinksAdded, " "),
+			strings.Join(page.LinksRemoved, " "),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatOptionalBool - empty for nil
+func formatOptionalBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
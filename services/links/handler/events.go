@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var ErrStreamingUnsupported = errors.New("streaming unsupported")
+
+// WatchBatch - handler streaming the live events of a batch as Server-Sent Events. Every scraped page
+// is a `result` event, a `progress` event summarizes the batch periodically and the `completed` event
+// with the final batch ends the stream. Finished batches only get the `completed` event.
+func (h *Handler) WatchBatch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{ErrStreamingUnsupported.Error()}})
+		return
+	}
+
+	events, err := h.linksProcessor.WatchBatch(r.Context(), chi.URLParam(r, "batchID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBatchNotFound): // batch not found
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
+
+	streamEvents(w, flusher, chi.URLParam(r, "batchID"), events)
+}
+
+// streamEvents - responds with the events as they come until the channel is closed
+func streamEvents(w http.ResponseWriter, flusher http.Flusher, batchID string, events <-chan links.BatchEvent) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for event := range events {
+		if err := writeEvent(w, event); err != nil {
+			log.Println("failed to write event of batch", batchID, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent - writes the event in the text/event-stream format with its JSON payload as data
+func writeEvent(w http.ResponseWriter, event links.BatchEvent) error {
+	var data interface{}
+	switch event.Type {
+	case links.BatchEventResult:
+		data = event.Result
+	case links.BatchEventProgress:
+		data = event.Progress
+	case links.BatchEventCompleted:
+		data = event.Batch
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+	return err
+}
@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+)
+
+func (s *handlerTestSuite) TestWatchBatch_DifferentCases_ThenItIsHandledAsExpected() {
+	result := links.Result{ID: "r0", BatchID: "b0", PageURL: "https://www.google.com", Success: true}
+	progress := links.BatchProgress{BatchID: "b0", URLCount: 1, Processed: 1, SuccessCount: 1}
+	batch := links.Batch{ID: "b0", Status: links.BatchStatusCompleted, URLCount: 1, SuccessCount: 1}
+
+	testCases := []struct {
+		name                string
+		mockReturns         []interface{}
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        []string
+	}{
+		{
+			name: "running batch",
+			mockReturns: []interface{}{[]links.BatchEvent{
+				{Type: links.BatchEventResult, Result: &result},
+				{Type: links.BatchEventProgress, Progress: &progress},
+				{Type: links.BatchEventCompleted, Batch: &batch},
+			}, nil},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/event-stream",
+			expectedBody: []string{
+				"event: result\ndata: {\"id\":\"r0\",\"batch_id\":\"b0\"",
+				"event: progress\ndata: {\"batch_id\":\"b0\",\"url_count\":1,\"processed\":1,\"success_count\":1,\"failure_count\":0}\n\n",
+				"event: completed\ndata: {\"id\":\"b0\",\"status\":\"completed\"",
+			},
+		},
+		{
+			name:                "unknown batch",
+			mockReturns:         []interface{}{[]links.BatchEvent(nil), fmt.Errorf("failed to get batch %w", repository.ErrBatchNotFound)},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/json",
+			expectedBody:        []string{repository.ErrBatchNotFound.Error()},
+		},
+		{
+			name:                "internal error",
+			mockReturns:         []interface{}{[]links.BatchEvent(nil), errors.New("error")},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/json",
+			expectedBody:        []string{links.ErrInternalServerError.Error()},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/links/b0/events", nil)
+			s.mockLinkProcessor.On("WatchBatch", "b0").Return(tc.mockReturns...)
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.Contains(rr.Header().Get("Content-Type"), tc.expectedContentType)
+			for _, expected := range tc.expectedBody {
+				s.Contains(rr.Body.String(), expected)
+			}
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestProcessBatch_WhenAsync_ThenRunningBatchIsReturned() {
+	testCases := []struct {
+		name           string
+		mockReturns    []interface{}
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "started",
+			mockReturns:    []interface{}{links.Batch{ID: "b0", Status: links.BatchStatusRunning}, nil},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `"data":{"id":"b0","status":"running"`,
+		},
+		{
+			name:           "invalid callback",
+			mockReturns:    []interface{}{links.Batch{}, fmt.Errorf("%w: invalid callback url %q", links.ErrInvalidCallback, "")},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid callback",
+		},
+		{
+			name:           "internal error",
+			mockReturns:    []interface{}{links.Batch{}, errors.New("error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := createRequestWithAttachedFile("POST", "/api/v1/links?async=true", `testdata/testFile.txt`, false)
+			urlGenerated, _ := url.Parse("https://www.google.com")
+			s.mockLinkProcessor.On("StartBatch", links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}}).Return(tc.mockReturns...)
+
+			// Act
+			s.handler.ProcessBatch(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.Contains(rr.Body.String(), tc.expectedBody)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
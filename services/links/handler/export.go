@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// resultColumns - columns of exported results, the links of the pages are only exported when selected
+var resultColumns = []string{
+	"id", "batch_id", "page_url", "success", "internal_links_num", "external_links_num",
+	"error", "error_category", "status_code", "created_at", "updated_at",
+	"internal_links", "external_links",
+}
+
+// defaultResultColumns - exported when no columns are selected
+var defaultResultColumns = resultColumns[:11]
+
+// resultExporter - writes results as CSV rows or NDJSON lines with the selected columns
+type resultExporter struct {
+	format  string
+	columns []string
+	buf     *bufio.Writer
+	csv     *csv.Writer
+}
+
+func newResultExporter(w io.Writer, format string, columns []string) *resultExporter {
+	buf := bufio.NewWriter(w)
+	return &resultExporter{format: format, columns: columns, buf: buf, csv: csv.NewWriter(buf)}
+}
+
+// contentType - media type of the exported results
+func (e *resultExporter) contentType() string {
+	if e.format == formatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// writeHeader - the column names for CSV, nothing for NDJSON
+func (e *resultExporter) writeHeader() error {
+	if e.format != formatCSV {
+		return nil
+	}
+	return e.csv.Write(e.columns)
+}
+
+// write - one CSV row or one JSON object per line, the NDJSON keys are in the order of the columns
+func (e *resultExporter) write(result links.Result) error {
+	if e.format == formatCSV {
+		row := make([]string, len(e.columns))
+		for i, column := range e.columns {
+			row[i] = formatField(resultField(result, column))
+		}
+		return e.csv.Write(row)
+	}
+
+	line := []byte{'{'}
+	for i, column := range e.columns {
+		value, err := json.Marshal(resultField(result, column))
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = append(line, strconv.Quote(column)...)
+		line = append(line, ':')
+		line = append(line, value...)
+	}
+	line = append(line, '}', '\n')
+	_, err := e.buf.Write(line)
+	return err
+}
+
+// flush - writes out the buffered rows
+func (e *resultExporter) flush() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
+
+// resultField - value of a column of a result, the error is flattened to its message,
+// category and the status code of the page. nil is an empty CSV field and a JSON null.
+func resultField(result links.Result, column string) interface{} {
+	switch column {
+	case "id":
+		return result.ID
+	case "batch_id":
+		return result.BatchID
+	case "page_url":
+		return result.PageURL
+	case "success":
+		return result.Success
+	case "internal_links_num":
+		return result.InternalLinksNum
+	case "external_links_num":
+		return result.ExternalLinksNum
+	case "error":
+		if result.Error == nil {
+			return nil
+		}
+		return result.Error.Error()
+	case "error_category":
+		if category, _ := scraper.ClassifyError(result.Error); category != "" {
+			return string(category)
+		}
+		return nil
+	case "status_code":
+		if _, statusCode := scraper.ClassifyError(result.Error); statusCode != 0 {
+			return statusCode
+		}
+		return nil
+	case "created_at":
+		return result.CreatedAt
+	case "updated_at":
+		return result.UpdatedAt
+	case "internal_links":
+		return result.InternalLinks
+	case "external_links":
+		return result.ExternalLinks
+	}
+	return nil
+}
+
+// formatField - CSV field of a column value, links are separated by spaces
+func formatField(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []string:
+		return strings.Join(v, " ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,155 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+)
+
+func (s *handlerTestSuite) TestGetBatch_WhenExportIsRequested_ThenItIsHandledAsExpected() {
+	createdAt := time.Date(2022, 5, 23, 10, 51, 1, 0, time.UTC)
+	results := []links.Result{
+		{ID: "r1", BatchID: "batchID", PageURL: "https://www.google.com/", InternalLinksNum: 6, ExternalLinksNum: 13, Success: true,
+			ExternalLinks: []string{"https://gmail.com/", "https://youtube.com/"}, CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "r2", BatchID: "batchID", PageURL: "https://www.google.com/missing", Error: &scraper.StatusError{StatusCode: 404}, CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "r3", BatchID: "batchID", PageURL: "https://slow.example.com/",
+			Error: &url.Error{Op: "Get", URL: "https://slow.example.com/", Err: context.DeadlineExceeded}, CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+	success := false
+
+	testCases := []struct {
+		name                string
+		target              string
+		accept              string
+		expectedQuery       links.ResultQuery
+		results             []links.Result
+		processorErr        error
+		callsProcessor      bool
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "csv with the default columns",
+			target:              "/api/v1/links/batchID?format=csv",
+			expectedQuery:       links.ResultQuery{Limit: 50},
+			results:             results[:2],
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody: "id,batch_id,page_url,success,internal_links_num,external_links_num,error,error_category,status_code,created_at,updated_at\n" +
+				"r1,batchID,https://www.google.com/,true,6,13,,,,2022-05-23T10:51:01Z,2022-05-23T10:51:01Z\n" +
+				"r2,batchID,https://www.google.com/missing,false,0,0,bad status code,status,404,2022-05-23T10:51:01Z,2022-05-23T10:51:01Z\n",
+		},
+		{
+			name:                "csv with the links",
+			target:              "/api/v1/links/batchID?format=csv&columns=page_url,external_links",
+			expectedQuery:       links.ResultQuery{Limit: 50},
+			results:             results[:1],
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody:        "page_url,external_links\nhttps://www.google.com/,https://gmail.com/ https://youtube.com/\n",
+		},
+		{
+			name:                "ndjson from the accept header with the filters of the query",
+			target:              "/api/v1/links/batchID?success=false&columns=page_url,error_category,status_code&limit=1&cursor=abc",
+			accept:              "application/x-ndjson",
+			expectedQuery:       links.ResultQuery{Success: &success, Limit: 1, Cursor: "abc"},
+			results:             results[1:],
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"page_url":"https://www.google.com/missing","error_category":"status","status_code":404}` + "\n" +
+				`{"page_url":"https://slow.example.com/","error_category":"timeout","status_code":null}` + "\n",
+		},
+		{
+			name:                "csv without matching results",
+			target:              "/api/v1/links/batchID?format=csv&columns=id,error",
+			expectedQuery:       links.ResultQuery{Limit: 50},
+			results:             []links.Result{},
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody:        "id,error\n",
+		},
+		{
+			name:           "unknown column",
+			target:         "/api/v1/links/batchID?format=csv&columns=page_url,body",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `invalid query parameter columns, unknown column \"body\"`,
+		},
+		{
+			name:           "unsupported format",
+			target:         "/api/v1/links/batchID?format=xml",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid query parameter format, expected one of json, csv, ndjson",
+		},
+		{
+			name:           "batch not found",
+			target:         "/api/v1/links/batchID?format=ndjson",
+			expectedQuery:  links.ResultQuery{Limit: 50},
+			results:        []links.Result{},
+			processorErr:   fmt.Errorf("failed to get batch %w", repository.ErrBatchNotFound),
+			callsProcessor: true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   repository.ErrBatchNotFound.Error(),
+		},
+		{
+			name:           "invalid sort",
+			target:         "/api/v1/links/batchID?format=csv&sort=size",
+			expectedQuery:  links.ResultQuery{Limit: 50, SortBy: "size"},
+			results:        []links.Result{},
+			processorErr:   fmt.Errorf("failed to get batch %w", repository.ErrInvalidSort),
+			callsProcessor: true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   repository.ErrInvalidSort.Error(),
+		},
+		{
+			name:           "internal error",
+			target:         "/api/v1/links/batchID?format=csv",
+			expectedQuery:  links.ResultQuery{Limit: 50},
+			results:        []links.Result{},
+			processorErr:   errors.New("error"),
+			callsProcessor: true,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.target, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			if tc.callsProcessor {
+				s.mockLinkProcessor.On("ExportBatch", links.GetBatchRequest{BatchID: "batchID", Query: tc.expectedQuery}).Return(tc.results, tc.processorErr)
+			}
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			if tc.expectedContentType != "" {
+				s.Contains(rr.Header().Get("Content-Type"), tc.expectedContentType)
+				s.Equal(tc.expectedBody, rr.Body.String())
+			} else {
+				s.Contains(rr.Body.String(), tc.expectedBody)
+			}
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
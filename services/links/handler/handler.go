@@ -2,70 +2,404 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/Lockwarr/codefi/pkg/helpers"
+	"github.com/Lockwarr/codefi/pkg/scraper"
 	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/metrics"
 	"github.com/Lockwarr/codefi/services/links/repository"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ErrNoUrlsForProcessing = errors.New("no urls for processing")
 var ErrRetrievingFile = errors.New("bad file")
+var ErrInvalidOptions = errors.New("invalid options")
+var ErrInvalidRetryRequest = errors.New("invalid retry request")
 
 type Handler struct {
 	linksProcessor links.Processor
+	monitors       links.MonitorService
+	apiKeys        links.APIKeyService
+	metrics        *metrics.Metrics
+	tracer         trace.Tracer
 }
 
 // NewHandler ..
-func NewHandler(linksProcessor links.Processor) *Handler {
-	return &Handler{linksProcessor: linksProcessor}
+func NewHandler(linksProcessor links.Processor, monitors links.MonitorService, opts ...HandlerOption) *Handler {
+	h := &Handler{linksProcessor: linksProcessor, monitors: monitors}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // StartBatchProcessing - handler to start processing of batch of urls
 // passed in a file with multi-line text with valid url on each line.
+// Scrape options can be passed as JSON in the optional `options` form field, the optional
+// `callback_url` and `callback_secret` form fields register a callback for when the batch finishes.
+// With the `async=true` query parameter the running batch is returned right away with 202,
+// its progress can be followed on the events endpoint.
 func (h *Handler) ProcessBatch(w http.ResponseWriter, r *http.Request) {
+	req, err := readBatchRequest(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+	req.APIKeyID = apiKeyID(r.Context())
+
+	if r.URL.Query().Get("async") == "true" {
+		h.startBatch(w, r, req)
+		return
+	}
+
+	results, err := h.linksProcessor.ProcessBatch(r.Context(), req)
+	if errors.Is(err, scraper.ErrInvalidOptions) || errors.Is(err, links.ErrInvalidCallback) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+	if isAuthError(err) {
+		renderAuthError(w, r, err)
+		return
+	}
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: links.ProcessBatchResponse{Results: results}})
+}
+
+// readBatchRequest - reads the url file, the scrape options and the callback of the multipart form.
+// Errors are ErrRetrievingFile, a *helpers.URLError, ErrNoUrlsForProcessing or ErrInvalidOptions.
+func readBatchRequest(r *http.Request) (links.ProcessBatchRequest, error) {
 	// FormFile returns the first file for the given key `urlsFile`
 	file, _, err := r.FormFile("urlsFile")
+	if err != nil {
+		return links.ProcessBatchRequest{}, ErrRetrievingFile
+	}
+	defer file.Close()
 
+	urls, err := helpers.GatherUrls(file)
 	if err != nil {
+		return links.ProcessBatchRequest{}, err
+	}
+
+	if len(urls) == 0 {
+		return links.ProcessBatchRequest{}, ErrNoUrlsForProcessing
+	}
+
+	var opts links.ScrapeOptions
+	if rawOpts := r.FormValue("options"); strings.TrimSpace(rawOpts) != "" {
+		decoder := json.NewDecoder(strings.NewReader(rawOpts))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&opts); err != nil {
+			return links.ProcessBatchRequest{}, ErrInvalidOptions
+		}
+	}
+
+	var callback *links.Callback
+	if callbackURL, secret := strings.TrimSpace(r.FormValue("callback_url")), r.FormValue("callback_secret"); callbackURL != "" || secret != "" {
+		callback = &links.Callback{URL: callbackURL, Secret: secret}
+	}
+
+	return links.ProcessBatchRequest{URLs: urls, Options: opts, Callback: callback}, nil
+}
+
+// startBatch - starts processing the batch in the background and responds with the running batch
+func (h *Handler) startBatch(w http.ResponseWriter, r *http.Request, req links.ProcessBatchRequest) {
+	batch, err := h.linksProcessor.StartBatch(r.Context(), req)
+	if errors.Is(err, scraper.ErrInvalidOptions) || errors.Is(err, links.ErrInvalidCallback) {
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, links.Response{Errors: []string{ErrRetrievingFile.Error()}})
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+	if isAuthError(err) {
+		renderAuthError(w, r, err)
+		return
+	}
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
 		return
 	}
-	defer file.Close()
 
-	urls, err := helpers.GatherUrls(file)
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, links.Response{Data: batch})
+}
+
+// GetBatch - handler for getting processed links by batch ID.
+// The results are paginated, see parseResultQuery for the query parameters.
+// With format=csv or format=ndjson, or the matching Accept header, every result matching the query
+// is exported instead, see exportBatch.
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	batchID := chi.URLParam(r, "batchID")
+
+	query, err := parseResultQuery(r)
 	if err != nil {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
 		return
 	}
 
-	if len(urls) == 0 {
+	format, err := parseFormat(r, formatJSON, formatCSV, formatNDJSON)
+	if err != nil {
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, links.Response{Errors: []string{ErrNoUrlsForProcessing.Error()}})
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+	if format != formatJSON {
+		h.exportBatch(w, r, batchID, query, format, renderExportError)
 		return
 	}
 
-	results, err := h.linksProcessor.ProcessBatch(r.Context(), links.ProcessBatchRequest{URLs: urls})
+	batch, err := h.linksProcessor.GetBatch(r.Context(), links.GetBatchRequest{BatchID: batchID, Query: query})
 	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBatchNotFound): // batch not found
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+			return
+		case errors.Is(err, repository.ErrInvalidCursor): // cursor is malformed or from another sort order
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrInvalidCursor.Error()}})
+			return
+		case errors.Is(err, repository.ErrInvalidSort):
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrInvalidSort.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: batch})
+}
+
+// errorRenderer - responds with an error in the format of an api version
+type errorRenderer func(w http.ResponseWriter, r *http.Request, err error)
+
+// exportBatch - streams the results of the batch as CSV or NDJSON with the columns from the
+// columns query parameter. The limit and cursor are ignored. The response only starts with the
+// first result, so errors before it are still answered with a JSON error rendered by fail.
+func (h *Handler) exportBatch(w http.ResponseWriter, r *http.Request, batchID string, query links.ResultQuery, format string, fail errorRenderer) {
+	columns, err := parseColumns(r, resultColumns, defaultResultColumns)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+
+	exporter := newResultExporter(w, format, columns)
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", exporter.contentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="batch_%s.%s"`, batchID, format))
+		w.WriteHeader(http.StatusOK)
+		return exporter.writeHeader()
+	}
+
+	err = h.linksProcessor.ExportBatch(r.Context(), links.GetBatchRequest{BatchID: batchID, Query: query}, func(result links.Result) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return exporter.write(result)
+	})
+	if err != nil && !started {
+		fail(w, r, err)
+		return
+	}
+
+	if err == nil && !started { // no result matched the query
+		err = start()
+	}
+	if err == nil {
+		err = exporter.flush()
+	}
+	if err != nil {
+		log.Println("failed to export batch", batchID, err)
+	}
+}
+
+func renderExportError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidQueryParam):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+	case errors.Is(err, repository.ErrBatchNotFound): // batch not found
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+	case errors.Is(err, repository.ErrInvalidSort):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{repository.ErrInvalidSort.Error()}})
+	default: // generic response to not leak details for all other errors
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+	}
+}
+
+// GetResult - handler for getting a single result by ID.
+// Under /links/{batchID} the result must belong to the batch, under /results it is looked up in every batch.
+func (h *Handler) GetResult(w http.ResponseWriter, r *http.Request) {
+	req := links.GetResultRequest{BatchID: chi.URLParam(r, "batchID"), ResultID: chi.URLParam(r, "resultID")}
+
+	result, err := h.linksProcessor.GetResult(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBatchNotFound): // batch not found
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+			return
+		case errors.Is(err, repository.ErrResultNotFound): // result not found or belongs to another batch
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrResultNotFound.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: result})
+}
+
+// RetryBatch - handler to rescrape results of a batch in place.
+// The optional JSON body {"result_ids": [...]} selects the results, all failed results are retried otherwise.
+func (h *Handler) RetryBatch(w http.ResponseWriter, r *http.Request) {
+	req := links.RetryBatchRequest{BatchID: chi.URLParam(r, "batchID")}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{ErrInvalidRetryRequest.Error()}})
 		return
 	}
+	req.BatchID = chi.URLParam(r, "batchID") // the path wins over a batch id in the body
+	req.APIKeyID = apiKeyID(r.Context())
+
+	retried, err := h.linksProcessor.RetryBatch(r.Context(), req)
+	if err != nil {
+		switch {
+		case isAuthError(err):
+			renderAuthError(w, r, err)
+			return
+		case errors.Is(err, repository.ErrBatchNotFound): // batch not found
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+			return
+		case errors.Is(err, repository.ErrResultNotFound): // selected result not found in the batch
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrResultNotFound.Error()}})
+			return
+		case errors.Is(err, links.ErrBatchInProgress):
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrBatchInProgress.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
 
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, links.Response{Data: links.ProcessBatchResponse{Results: results}})
+	render.JSON(w, r, links.Response{Data: retried})
 }
 
-// GetBatch - handler for getting processed links by batch ID
-func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
-	batchID := chi.URLParam(r, "batchID")
+// DiffBatches - handler for the pages which changed from one batch to another.
+// The diff is rendered as JSON or as CSV when format=csv is passed or text/csv is accepted.
+func (h *Handler) DiffBatches(w http.ResponseWriter, r *http.Request) {
+	format, err := parseFormat(r, formatJSON, formatCSV)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+
+	req := links.DiffBatchesRequest{BaseBatchID: chi.URLParam(r, "batchID"), TargetBatchID: chi.URLParam(r, "targetBatchID")}
+	diff, err := h.linksProcessor.DiffBatches(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBatchNotFound): // one of the batches not found
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+			return
+		case errors.Is(err, links.ErrBatchInProgress):
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrBatchInProgress.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
+
+	if format == formatCSV {
+		writeDiffAttachment(w, req, diff)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: diff})
+}
+
+// ListBatches - handler for listing batches with their summary, page by page.
+// Requests authenticated with an api key only list the batches created with it, see scopeToAPIKey.
+func (h *Handler) ListBatches(w http.ResponseWriter, r *http.Request) {
+	query, err := parseBatchQuery(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+		return
+	}
+	scopeToAPIKey(r.Context(), &query)
+
+	batches, err := h.linksProcessor.ListBatches(r.Context(), links.ListBatchesRequest{Query: query})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidCursor): // cursor is malformed or from another sort order
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrInvalidCursor.Error()}})
+			return
+		case errors.Is(err, repository.ErrInvalidSort):
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrInvalidSort.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: batches})
+}
 
-	results, err := h.linksProcessor.GetBatch(r.Context(), links.GetBatchRequest{BatchID: batchID})
+// ListCallbackAttempts - handler for the callback delivery attempts of a batch, oldest first
+func (h *Handler) ListCallbackAttempts(w http.ResponseWriter, r *http.Request) {
+	attempts, err := h.linksProcessor.ListCallbackAttempts(r.Context(), chi.URLParam(r, "batchID"))
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrBatchNotFound): // batch not found
@@ -80,5 +414,5 @@ func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, links.Response{Data: links.GetBatchResponse{Results: results}})
+	render.JSON(w, r, links.Response{Data: attempts})
 }
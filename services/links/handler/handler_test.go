@@ -2,7 +2,9 @@ package handler_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -10,7 +12,9 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/Lockwarr/codefi/pkg/scraper"
 	"github.com/Lockwarr/codefi/services/links"
 	"github.com/Lockwarr/codefi/services/links/handler"
 	"github.com/Lockwarr/codefi/services/links/mocks"
@@ -21,16 +25,19 @@ import (
 type handlerTestSuite struct {
 	suite.Suite
 	mockLinkProcessor *mocks.MockLinksProcessor
+	mockMonitors      *mocks.MockMonitorService
 	handler           *handler.Handler
 }
 
 func (s *handlerTestSuite) SetupTest() {
 	s.mockLinkProcessor = new(mocks.MockLinksProcessor)
-	s.handler = handler.NewHandler(s.mockLinkProcessor)
+	s.mockMonitors = new(mocks.MockMonitorService)
+	s.handler = handler.NewHandler(s.mockLinkProcessor, s.mockMonitors)
 }
 
 func (s *handlerTestSuite) AfterTest(suite string, testName string) {
 	s.mockLinkProcessor.AssertExpectations(s.T())
+	s.mockMonitors.AssertExpectations(s.T())
 }
 
 func TestHandlerTestSuite(t *testing.T) {
@@ -46,31 +53,31 @@ func (s *handlerTestSuite) TestProcessBatch_DifferentCases_ThenItIsHandledAsExpe
 	}{
 		{
 			name:           "successful results",
-			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata\testFile.txt`, false),
+			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/testFile.txt`, false),
 			rr:             httptest.NewRecorder(),
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "fail on formFile",
-			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata\testFile.txt`, true),
+			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/testFile.txt`, true),
 			rr:             httptest.NewRecorder(),
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			name:           "fail on gatherUrls",
-			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata\badFile.txt`, false),
+			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/badFile.txt`, false),
 			rr:             httptest.NewRecorder(),
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			name:           "pass emtpy file",
-			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata\emptyFile.txt`, false),
+			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/emptyFile.txt`, false),
 			rr:             httptest.NewRecorder(),
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			name:           "fail on processor processBatch",
-			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata\testFile.txt`, false),
+			req:            createRequestWithAttachedFile("POST", "/api/v1/links", `testdata/testFile.txt`, false),
 			rr:             httptest.NewRecorder(),
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -101,7 +108,8 @@ func (s *handlerTestSuite) TestProcessBatch_DifferentCases_ThenItIsHandledAsExpe
 
 func (s *handlerTestSuite) ResetMocks() {
 	s.mockLinkProcessor = new(mocks.MockLinksProcessor)
-	s.handler = handler.NewHandler(s.mockLinkProcessor)
+	s.mockMonitors = new(mocks.MockMonitorService)
+	s.handler = handler.NewHandler(s.mockLinkProcessor, s.mockMonitors)
 }
 
 func (s *handlerTestSuite) TestGetBatch_WhenRequestIsCorrect_ThenItIsHandled() {
@@ -109,7 +117,7 @@ func (s *handlerTestSuite) TestGetBatch_WhenRequestIsCorrect_ThenItIsHandled() {
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/", nil)
 
-	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{BatchID: ""}).Return([]links.Result{}, nil)
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{Query: links.ResultQuery{Limit: 50}}).Return(links.GetBatchResponse{}, nil)
 
 	// Act
 	s.handler.GetBatch(rr, req)
@@ -118,12 +126,44 @@ func (s *handlerTestSuite) TestGetBatch_WhenRequestIsCorrect_ThenItIsHandled() {
 	s.Equal(http.StatusOK, rr.Code)
 }
 
+func (s *handlerTestSuite) TestGetBatch_WhenResultsFailed_ThenErrorsAreTheirMessages() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	results := []links.Result{
+		{ID: "r0", Success: true},
+		{ID: "r1", Error: &scraper.StatusError{StatusCode: 404}, Attempts: []links.Attempt{{Error: errors.New("timeout")}}},
+	}
+
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{Query: links.ResultQuery{Limit: 50}}).Return(links.GetBatchResponse{Results: results}, nil)
+
+	// Act
+	s.handler.GetBatch(rr, req)
+
+	// Assert
+	var response struct {
+		Data struct {
+			Results []map[string]interface{}
+		} `json:"data"`
+	}
+	s.Equal(http.StatusOK, rr.Code)
+	s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	s.Require().Len(response.Data.Results, 2)
+	s.Nil(response.Data.Results[0]["error"])
+	s.Contains(response.Data.Results[0], "error")
+	s.Equal("bad status code", response.Data.Results[1]["error"])
+	s.Equal([]interface{}{map[string]interface{}{
+		"internal_links_num": float64(0), "external_links_num": float64(0), "success": false,
+		"error": "timeout", "attempted_at": "0001-01-01T00:00:00Z",
+	}}, response.Data.Results[1]["attempts"])
+}
+
 func (s *handlerTestSuite) TestGetBatch_WhenProcessorGetBatchFailsWithInternal_ThenItIsHandled() {
 	// Arrange
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/", nil)
 
-	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{BatchID: ""}).Return([]links.Result{}, links.ErrInternalServerError)
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{Query: links.ResultQuery{Limit: 50}}).Return(links.GetBatchResponse{}, links.ErrInternalServerError)
 
 	// Act
 	s.handler.GetBatch(rr, req)
@@ -142,7 +182,7 @@ func (s *handlerTestSuite) TestGetBatch_WhenProcessorGetBatchFails_ThenItIsHandl
 		BatchID: "testID",
 	}
 
-	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{BatchID: ""}).Return([]links.Result{res}, repository.ErrBatchNotFound)
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{Query: links.ResultQuery{Limit: 50}}).Return(links.GetBatchResponse{Results: []links.Result{res}}, repository.ErrBatchNotFound)
 
 	// Act
 	s.handler.GetBatch(rr, req)
@@ -151,13 +191,596 @@ func (s *handlerTestSuite) TestGetBatch_WhenProcessorGetBatchFails_ThenItIsHandl
 	s.Equal(http.StatusNotFound, rr.Code)
 }
 
+func (s *handlerTestSuite) TestListBatches_DifferentCases_ThenItIsHandledAsExpected() {
+	testCases := []struct {
+		name           string
+		target         string
+		expectedQuery  *links.BatchQuery
+		processorErr   error
+		expectedStatus int
+	}{
+		{
+			name:           "newest first by default",
+			target:         "/api/v1/links",
+			expectedQuery:  &links.BatchQuery{Limit: 50, SortBy: links.BatchSortCreatedAt, Descending: true},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "all parameters",
+			target: "/api/v1/links?limit=10&cursor=abc&sort=-failure_count&status=completed&created_after=2022-05-23T00:00:00Z&created_before=2022-05-24T00:00:00Z",
+			expectedQuery: &links.BatchQuery{
+				Limit:         10,
+				Cursor:        "abc",
+				SortBy:        links.BatchSortFailureCount,
+				Descending:    true,
+				Status:        links.BatchStatusCompleted,
+				CreatedAfter:  time.Date(2022, 5, 23, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2022, 5, 24, 0, 0, 0, 0, time.UTC),
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "invalid limit", target: "/api/v1/links?limit=1000", expectedStatus: http.StatusBadRequest},
+		{name: "invalid status", target: "/api/v1/links?status=done", expectedStatus: http.StatusBadRequest},
+		{name: "invalid date", target: "/api/v1/links?created_after=yesterday", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "invalid cursor",
+			target:         "/api/v1/links?cursor=abc",
+			expectedQuery:  &links.BatchQuery{Limit: 50, Cursor: "abc", SortBy: links.BatchSortCreatedAt, Descending: true},
+			processorErr:   fmt.Errorf("failed to list batches %w", repository.ErrInvalidCursor),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid sort",
+			target:         "/api/v1/links?sort=name",
+			expectedQuery:  &links.BatchQuery{Limit: 50, SortBy: "name"},
+			processorErr:   fmt.Errorf("failed to list batches %w", repository.ErrInvalidSort),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "processor fails",
+			target:         "/api/v1/links",
+			expectedQuery:  &links.BatchQuery{Limit: 50, SortBy: links.BatchSortCreatedAt, Descending: true},
+			processorErr:   errors.New("error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.target, nil)
+			if tc.expectedQuery != nil {
+				s.mockLinkProcessor.On("ListBatches", links.ListBatchesRequest{Query: *tc.expectedQuery}).
+					Return(links.ListBatchesResponse{Batches: []links.Batch{{ID: "batchID"}}}, tc.processorErr)
+			}
+
+			// Act
+			s.handler.ListBatches(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestGetBatch_WhenQueryIsPassed_ThenItIsHandledAsExpected() {
+	success := false
+	one, ten, hundred := uint(1), uint(10), uint(100)
+	testCases := []struct {
+		name           string
+		target         string
+		expectedQuery  *links.ResultQuery
+		processorErr   error
+		expectedStatus int
+	}{
+		{
+			name:   "all parameters",
+			target: "/?limit=20&cursor=abc&sort=-internal_links_num&success=false&host=www.google.com&min_internal=1&max_internal=10&min_external=10&max_external=100",
+			expectedQuery: &links.ResultQuery{
+				Limit:            20,
+				Cursor:           "abc",
+				SortBy:           links.ResultSortInternalLinksNum,
+				Descending:       true,
+				Success:          &success,
+				Host:             "www.google.com",
+				MinInternalLinks: &one,
+				MaxInternalLinks: &ten,
+				MinExternalLinks: &ten,
+				MaxExternalLinks: &hundred,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "default page size",
+			target:         "/",
+			expectedQuery:  &links.ResultQuery{Limit: 50},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "invalid limit", target: "/?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "invalid success", target: "/?success=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "invalid bound", target: "/?min_external=-1", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "invalid cursor",
+			target:         "/?cursor=abc",
+			expectedQuery:  &links.ResultQuery{Limit: 50, Cursor: "abc"},
+			processorErr:   fmt.Errorf("failed to get batch %w", repository.ErrInvalidCursor),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid sort",
+			target:         "/?sort=success",
+			expectedQuery:  &links.ResultQuery{Limit: 50, SortBy: "success"},
+			processorErr:   fmt.Errorf("failed to get batch %w", repository.ErrInvalidSort),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.target, nil)
+			if tc.expectedQuery != nil {
+				s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{Query: *tc.expectedQuery}).Return(links.GetBatchResponse{}, tc.processorErr)
+			}
+
+			// Act
+			s.handler.GetBatch(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestGetResult_DifferentCases_ThenItIsHandledAsExpected() {
+	result := links.Result{ID: "resultID", BatchID: "batchID", PageURL: "https://www.google.com", Success: true}
+	testCases := []struct {
+		name           string
+		target         string
+		expectedReq    links.GetResultRequest
+		result         links.Result
+		processorErr   error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "result of a batch",
+			target:         "/api/v1/links/batchID/results/resultID",
+			expectedReq:    links.GetResultRequest{BatchID: "batchID", ResultID: "resultID"},
+			result:         result,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "result of any batch",
+			target:         "/api/v1/results/resultID",
+			expectedReq:    links.GetResultRequest{ResultID: "resultID"},
+			result:         result,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "batch not found",
+			target:         "/api/v1/links/unknown/results/resultID",
+			expectedReq:    links.GetResultRequest{BatchID: "unknown", ResultID: "resultID"},
+			processorErr:   fmt.Errorf("failed to get result %w", repository.ErrBatchNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  repository.ErrBatchNotFound.Error(),
+		},
+		{
+			name:           "result not found",
+			target:         "/api/v1/results/unknown",
+			expectedReq:    links.GetResultRequest{ResultID: "unknown"},
+			processorErr:   fmt.Errorf("failed to get result %w", repository.ErrResultNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  repository.ErrResultNotFound.Error(),
+		},
+		{
+			name:           "internal error",
+			target:         "/api/v1/results/resultID",
+			expectedReq:    links.GetResultRequest{ResultID: "resultID"},
+			processorErr:   errors.New("error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.target, nil)
+			s.mockLinkProcessor.On("GetResult", tc.expectedReq).Return(tc.result, tc.processorErr)
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			if tc.expectedError != "" {
+				s.Contains(rr.Body.String(), tc.expectedError)
+			} else {
+				s.Contains(rr.Body.String(), `"id":"resultID"`)
+			}
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestRetryBatch_DifferentCases_ThenItIsHandledAsExpected() {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedReq    *links.RetryBatchRequest
+		processorErr   error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "all failed results",
+			expectedReq:    &links.RetryBatchRequest{BatchID: "batchID"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "selected results",
+			body:           `{"result_ids": ["r1", "r2"], "batch_id": "other"}`,
+			expectedReq:    &links.RetryBatchRequest{BatchID: "batchID", ResultIDs: []string{"r1", "r2"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "malformed body",
+			body:           `{"results": ["r1"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  handler.ErrInvalidRetryRequest.Error(),
+		},
+		{
+			name:           "batch not found",
+			expectedReq:    &links.RetryBatchRequest{BatchID: "batchID"},
+			processorErr:   fmt.Errorf("failed to get batch %w", repository.ErrBatchNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  repository.ErrBatchNotFound.Error(),
+		},
+		{
+			name:           "result not found",
+			body:           `{"result_ids": ["unknown"]}`,
+			expectedReq:    &links.RetryBatchRequest{BatchID: "batchID", ResultIDs: []string{"unknown"}},
+			processorErr:   fmt.Errorf("failed to get result %w", repository.ErrResultNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  repository.ErrResultNotFound.Error(),
+		},
+		{
+			name:           "batch is running",
+			expectedReq:    &links.RetryBatchRequest{BatchID: "batchID"},
+			processorErr:   links.ErrBatchInProgress,
+			expectedStatus: http.StatusConflict,
+			expectedError:  links.ErrBatchInProgress.Error(),
+		},
+		{
+			name:           "internal error",
+			expectedReq:    &links.RetryBatchRequest{BatchID: "batchID"},
+			processorErr:   errors.New("error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/links/batchID/retry", bytes.NewBufferString(tc.body))
+			if tc.expectedReq != nil {
+				s.mockLinkProcessor.On("RetryBatch", *tc.expectedReq).Return(links.RetryBatchResponse{}, tc.processorErr)
+			}
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			if tc.expectedError != "" {
+				s.Contains(rr.Body.String(), tc.expectedError)
+			}
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestDiffBatches_DifferentCases_ThenItIsHandledAsExpected() {
+	yes := true
+	diff := links.BatchDiff{
+		BaseBatchID:   "base",
+		TargetBatchID: "target",
+		LinkDetails:   true,
+		AddedCount:    1,
+		ChangedCount:  1,
+		Pages: []links.PageDiff{
+			{PageURL: "https://added.example.com/", Change: links.PageAdded, TargetSuccess: &yes, ExternalLinksDelta: 3},
+			{PageURL: "https://www.google.com/", Change: links.PageChanged, BaseSuccess: &yes, TargetSuccess: &yes,
+				LinksAdded: []string{"https://gmail.com/", "https://maps.google.com/"}, LinksRemoved: []string{"https://youtube.com/"}},
+		},
+	}
+	expectedCSV := "page_url,change,base_success,target_success,internal_links_delta,external_links_delta,links_added,links_removed\n" +
+		"https://added.example.com/,added,,true,0,3,,\n" +
+		"https://www.google.com/,changed,true,true,0,0,https://gmail.com/ https://maps.google.com/,https://youtube.com/\n"
+
+	testCases := []struct {
+		name                string
+		target              string
+		accept              string
+		processorErr        error
+		callsProcessor      bool
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "json",
+			target:              "/api/v1/links/base/diff/target",
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `"links_added":["https://gmail.com/","https://maps.google.com/"]`,
+		},
+		{
+			name:                "csv from the query",
+			target:              "/api/v1/links/base/diff/target?format=csv",
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody:        expectedCSV,
+		},
+		{
+			name:                "csv from the accept header",
+			target:              "/api/v1/links/base/diff/target",
+			accept:              "text/csv",
+			callsProcessor:      true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody:        expectedCSV,
+		},
+		{
+			name:           "unsupported format",
+			target:         "/api/v1/links/base/diff/target?format=xml",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid query parameter format, expected one of json, csv",
+		},
+		{
+			name:           "batch not found",
+			target:         "/api/v1/links/base/diff/target",
+			processorErr:   fmt.Errorf("failed to get batch %w", repository.ErrBatchNotFound),
+			callsProcessor: true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   repository.ErrBatchNotFound.Error(),
+		},
+		{
+			name:           "batch is running",
+			target:         "/api/v1/links/base/diff/target?format=csv",
+			processorErr:   links.ErrBatchInProgress,
+			callsProcessor: true,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   links.ErrBatchInProgress.Error(),
+		},
+		{
+			name:           "internal error",
+			target:         "/api/v1/links/base/diff/target",
+			processorErr:   errors.New("error"),
+			callsProcessor: true,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.target, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			if tc.callsProcessor {
+				result := diff
+				if tc.processorErr != nil {
+					result = links.BatchDiff{}
+				}
+				s.mockLinkProcessor.On("DiffBatches", links.DiffBatchesRequest{BaseBatchID: "base", TargetBatchID: "target"}).Return(result, tc.processorErr)
+			}
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.Contains(rr.Header().Get("Content-Type"), tc.expectedContentType)
+			if tc.expectedContentType == "text/csv" {
+				s.Equal(tc.expectedBody, rr.Body.String())
+			} else {
+				s.Contains(rr.Body.String(), tc.expectedBody)
+			}
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestProcessBatch_WhenOptionsArePassed_ThenTheyAreHandled() {
+	testCases := []struct {
+		name           string
+		options        string
+		processorErr   error
+		expectedStatus int
+		expectedOpts   links.ScrapeOptions
+	}{
+		{
+			name:           "options are passed to the processor",
+			options:        `{"timeout_ms": 1500, "user_agent": "links-bot", "headers": {"Accept-Language": "en"}, "link_categories": ["anchor", "area"]}`,
+			expectedStatus: http.StatusOK,
+			expectedOpts: links.ScrapeOptions{
+				TimeoutMS:      1500,
+				UserAgent:      "links-bot",
+				Headers:        map[string]string{"Accept-Language": "en"},
+				LinkCategories: []string{"anchor", "area"},
+			},
+		},
+		{
+			name:           "malformed options",
+			options:        `{"timeout_ms": "soon"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown option",
+			options:        `{"retries": 3}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "options rejected by validation",
+			options:        `{"redirect_policy": "sometimes"}`,
+			processorErr:   scraper.ErrInvalidOptions,
+			expectedStatus: http.StatusBadRequest,
+			expectedOpts:   links.ScrapeOptions{RedirectPolicy: "sometimes"},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := createRequestWithAttachedFileAndOptions("POST", "/api/v1/links", `testdata/testFile.txt`, tc.options)
+			urlGenerated, _ := url.Parse("https://www.google.com")
+			if tc.expectedStatus == http.StatusOK || tc.processorErr != nil {
+				s.mockLinkProcessor.On("ProcessBatch", links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}, Options: tc.expectedOpts}).
+					Return([]links.Result{}, tc.processorErr)
+			}
+
+			// Act
+			s.handler.ProcessBatch(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestProcessBatch_WhenCallbackIsPassed_ThenItIsHandled() {
+	testCases := []struct {
+		name             string
+		fields           map[string]string
+		processorErr     error
+		expectedStatus   int
+		expectedCallback *links.Callback
+	}{
+		{
+			name:             "callback with secret",
+			fields:           map[string]string{"callback_url": " https://hooks.example.com/batches ", "callback_secret": "s3cret"},
+			expectedStatus:   http.StatusOK,
+			expectedCallback: &links.Callback{URL: "https://hooks.example.com/batches", Secret: "s3cret"},
+		},
+		{
+			name:             "callback without secret",
+			fields:           map[string]string{"callback_url": "https://hooks.example.com/batches"},
+			expectedStatus:   http.StatusOK,
+			expectedCallback: &links.Callback{URL: "https://hooks.example.com/batches"},
+		},
+		{
+			name:             "secret without callback",
+			fields:           map[string]string{"callback_secret": "s3cret"},
+			processorErr:     fmt.Errorf("%w: invalid callback url %q", links.ErrInvalidCallback, ""),
+			expectedStatus:   http.StatusBadRequest,
+			expectedCallback: &links.Callback{Secret: "s3cret"},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := createRequestWithAttachedFileAndFields("POST", "/api/v1/links", `testdata/testFile.txt`, tc.fields)
+			urlGenerated, _ := url.Parse("https://www.google.com")
+			s.mockLinkProcessor.On("ProcessBatch", links.ProcessBatchRequest{URLs: []*url.URL{urlGenerated}, Callback: tc.expectedCallback}).
+				Return([]links.Result{}, tc.processorErr)
+
+			// Act
+			s.handler.ProcessBatch(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestListCallbackAttempts_DifferentCases_ThenItIsHandledAsExpected() {
+	testCases := []struct {
+		name           string
+		mockReturns    []interface{}
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "attempts",
+			mockReturns:    []interface{}{links.ListCallbackAttemptsResponse{Attempts: []links.CallbackAttempt{{ID: "c0", BatchID: "b0", Attempt: 1, StatusCode: 503}}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Attempts":[{"id":"c0","batch_id":"b0"`,
+		},
+		{
+			name:           "unknown batch",
+			mockReturns:    []interface{}{links.ListCallbackAttemptsResponse{}, fmt.Errorf("failed to list callback attempts %w", repository.ErrBatchNotFound)},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   repository.ErrBatchNotFound.Error(),
+		},
+		{
+			name:           "internal error",
+			mockReturns:    []interface{}{links.ListCallbackAttemptsResponse{}, errors.New("error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/links/b0/callbacks", nil)
+			s.mockLinkProcessor.On("ListCallbackAttempts", "b0").Return(tc.mockReturns...)
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.Contains(rr.Body.String(), tc.expectedBody)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
+
 //
 func createRequestWithAttachedFile(method, urlPath, filename string, emptyBody bool) *http.Request {
 	if emptyBody {
 		return httptest.NewRequest(method, urlPath, nil)
 	}
+	return createRequestWithAttachedFileAndOptions(method, urlPath, filename, "")
+}
+
+func createRequestWithAttachedFileAndOptions(method, urlPath, filename, options string) *http.Request {
+	fields := map[string]string{}
+	if options != "" {
+		fields["options"] = options
+	}
+	return createRequestWithAttachedFileAndFields(method, urlPath, filename, fields)
+}
+
+func createRequestWithAttachedFileAndFields(method, urlPath, filename string, fields map[string]string) *http.Request {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil
+		}
+	}
 	fw, err := writer.CreateFormFile("urlsFile", "testFile.txt")
 	if err != nil {
 		return nil
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+
+// IssueAPIKey - handler to issue a new api key, only for admin keys.
+// Expects a JSON body with name, admin and quota, the key is only returned in this response.
+func (h *Handler) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req links.APIKeyRequest
+	if err := decodeStrict(r, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{ErrInvalidAPIKeyRequest.Error()}})
+		return
+	}
+
+	issued, err := h.apiKeys.IssueAPIKey(r.Context(), req)
+	if err != nil {
+		renderAPIKeyError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, links.Response{Data: issued})
+}
+
+// ListAPIKeys - handler for listing all api keys including the revoked ones, only for admin keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeys.ListAPIKeys(r.Context())
+	if err != nil {
+		renderAPIKeyError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: keys})
+}
+
+// RevokeAPIKey - handler to revoke an api key, only for admin keys. Responds with 204,
+// the batches created with the key are kept.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.apiKeys.RevokeAPIKey(r.Context(), chi.URLParam(r, "keyID")); err != nil {
+		renderAPIKeyError(w, r, err)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+func renderAPIKeyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrAPIKeyNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, links.Response{Errors: []string{repository.ErrAPIKeyNotFound.Error()}})
+	case errors.Is(err, links.ErrInvalidAPIKey):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+	default: // generic response to not leak details for all other errors
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+	}
+}
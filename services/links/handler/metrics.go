@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute - route label of the requests which didn't match any route
+const unmatchedRoute = "unmatched"
+
+// WithMetrics - record the latency of every request and serve the metrics at /metrics,
+// the metrics endpoint doesn't need an api key
+func WithMetrics(m *metrics.Metrics) HandlerOption {
+	return func(h *Handler) {
+		h.metrics = m
+	}
+}
+
+// instrument - middleware recording the latency of the requests by the route pattern they were served by,
+// so the ids in the path don't create a series per request
+func (h *Handler) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := ww.Status()
+		if status == 0 { // nothing was written
+			status = http.StatusOK
+		}
+		h.metrics.ObserveHTTPRequest(route, r.Method, status, time.Since(start))
+	})
+}
@@ -0,0 +1,39 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/metrics"
+)
+
+func (s *handlerTestSuite) TestMetrics_WhenEnabled_ThenRequestsAreRecordedByRoute() {
+	// Arrange
+	s.mockMonitors.On("ListMonitors").Return(links.ListMonitorsResponse{}, nil)
+	router := handler.NewRouter(handler.NewHandler(s.mockLinkProcessor, s.mockMonitors, handler.WithMetrics(metrics.New())))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/monitors", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown", nil))
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	// Assert
+	s.Equal(http.StatusOK, rr.Code)
+	s.Contains(rr.Body.String(), `links_http_request_duration_seconds_count{method="GET",route="/api/v1/monitors",status="200"} 1`)
+	s.Contains(rr.Body.String(), `links_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	s.Contains(rr.Body.String(), "go_goroutines")
+}
+
+func (s *handlerTestSuite) TestMetrics_WhenDisabled_ThenNotFound() {
+	// Arrange
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	// Assert
+	s.Equal(http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var ErrInvalidMonitorRequest = errors.New("invalid monitor request")
+
+// CreateMonitor - handler to register a list of urls which is processed again on a cron schedule.
+// Expects a JSON body with name, schedule, urls, options and paused.
+func (h *Handler) CreateMonitor(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMonitorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	req.APIKeyID = apiKeyID(r.Context())
+
+	monitor, err := h.monitors.CreateMonitor(r.Context(), req)
+	if err != nil {
+		renderMonitorError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, links.Response{Data: monitor})
+}
+
+// GetMonitor - handler for getting a monitor by ID
+func (h *Handler) GetMonitor(w http.ResponseWriter, r *http.Request) {
+	monitor, err := h.monitors.GetMonitor(r.Context(), chi.URLParam(r, "monitorID"))
+	if err != nil {
+		renderMonitorError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: monitor})
+}
+
+// UpdateMonitor - handler to replace the definition of a monitor, expects the same body as CreateMonitor
+func (h *Handler) UpdateMonitor(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMonitorRequest(w, r)
+	if !ok {
+		return
+	}
+	req.MonitorID = chi.URLParam(r, "monitorID")
+
+	monitor, err := h.monitors.UpdateMonitor(r.Context(), req)
+	if err != nil {
+		renderMonitorError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: monitor})
+}
+
+// DeleteMonitor - handler to delete a monitor, the batches of its runs are kept
+func (h *Handler) DeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	if err := h.monitors.DeleteMonitor(r.Context(), chi.URLParam(r, "monitorID")); err != nil {
+		renderMonitorError(w, r, err)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// ListMonitors - handler for listing all monitors
+func (h *Handler) ListMonitors(w http.ResponseWriter, r *http.Request) {
+	monitors, err := h.monitors.ListMonitors(r.Context())
+	if err != nil {
+		renderMonitorError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: monitors})
+}
+
+// RunMonitor - handler to run a monitor now regardless of its schedule
+func (h *Handler) RunMonitor(w http.ResponseWriter, r *http.Request) {
+	results, err := h.monitors.RunMonitor(r.Context(), chi.URLParam(r, "monitorID"))
+	if err != nil {
+		renderMonitorError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, links.Response{Data: links.ProcessBatchResponse{Results: results}})
+}
+
+func decodeMonitorRequest(w http.ResponseWriter, r *http.Request) (links.MonitorRequest, bool) {
+	var req links.MonitorRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{ErrInvalidMonitorRequest.Error()}})
+		return links.MonitorRequest{}, false
+	}
+	return req, true
+}
+
+func renderMonitorError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrMonitorNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, links.Response{Errors: []string{repository.ErrMonitorNotFound.Error()}})
+	case errors.Is(err, links.ErrInvalidMonitor), errors.Is(err, scraper.ErrInvalidOptions):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{err.Error()}})
+	case errors.Is(err, links.ErrMonitorRunning):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrMonitorRunning.Error()}})
+	case errors.Is(err, links.ErrQuotaExceeded), errors.Is(err, links.ErrServerBusy):
+		renderAuthError(w, r, err)
+	default: // generic response to not leak details for all other errors
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+	}
+}
@@ -0,0 +1,177 @@
+package handler_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+)
+
+func (s *handlerTestSuite) TestMonitors_DifferentCases_ThenItIsHandledAsExpected() {
+	monitor := links.Monitor{ID: "m0", Name: "weekly scan", Schedule: "@weekly", URLs: []string{"https://www.google.com"}}
+	validBody := `{"name": "weekly scan", "schedule": "@weekly", "urls": ["https://www.google.com"]}`
+	validReq := links.MonitorRequest{Name: "weekly scan", Schedule: "@weekly", URLs: []string{"https://www.google.com"}}
+	updateReq := validReq
+	updateReq.MonitorID = "m0"
+
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		mockMethod     string
+		mockArgument   interface{}
+		mockReturns    []interface{}
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "create",
+			method:         "POST",
+			target:         "/api/v1/monitors",
+			body:           validBody,
+			mockMethod:     "CreateMonitor",
+			mockArgument:   validReq,
+			mockReturns:    []interface{}{monitor, nil},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"id":"m0"`,
+		},
+		{
+			name:           "create with malformed body",
+			method:         "POST",
+			target:         "/api/v1/monitors",
+			body:           `{"cron": "@weekly"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   handler.ErrInvalidMonitorRequest.Error(),
+		},
+		{
+			name:           "create with invalid schedule",
+			method:         "POST",
+			target:         "/api/v1/monitors",
+			body:           validBody,
+			mockMethod:     "CreateMonitor",
+			mockArgument:   validReq,
+			mockReturns:    []interface{}{links.Monitor{}, fmt.Errorf("%w: invalid schedule %q", links.ErrInvalidMonitor, "@weekly")},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `invalid monitor: invalid schedule \"@weekly\"`,
+		},
+		{
+			name:           "create with invalid options",
+			method:         "POST",
+			target:         "/api/v1/monitors",
+			body:           validBody,
+			mockMethod:     "CreateMonitor",
+			mockArgument:   validReq,
+			mockReturns:    []interface{}{links.Monitor{}, fmt.Errorf("%w: invalid user agent", scraper.ErrInvalidOptions)},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid scrape options: invalid user agent",
+		},
+		{
+			name:           "get",
+			method:         "GET",
+			target:         "/api/v1/monitors/m0",
+			mockMethod:     "GetMonitor",
+			mockArgument:   "m0",
+			mockReturns:    []interface{}{monitor, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"schedule":"@weekly"`,
+		},
+		{
+			name:           "get unknown",
+			method:         "GET",
+			target:         "/api/v1/monitors/unknown",
+			mockMethod:     "GetMonitor",
+			mockArgument:   "unknown",
+			mockReturns:    []interface{}{links.Monitor{}, fmt.Errorf("failed to get monitor %w", repository.ErrMonitorNotFound)},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   repository.ErrMonitorNotFound.Error(),
+		},
+		{
+			name:           "update",
+			method:         "PUT",
+			target:         "/api/v1/monitors/m0",
+			body:           validBody,
+			mockMethod:     "UpdateMonitor",
+			mockArgument:   updateReq,
+			mockReturns:    []interface{}{monitor, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":"m0"`,
+		},
+		{
+			name:           "delete",
+			method:         "DELETE",
+			target:         "/api/v1/monitors/m0",
+			mockMethod:     "DeleteMonitor",
+			mockArgument:   "m0",
+			mockReturns:    []interface{}{nil},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "list",
+			method:         "GET",
+			target:         "/api/v1/monitors",
+			mockMethod:     "ListMonitors",
+			mockReturns:    []interface{}{links.ListMonitorsResponse{Monitors: []links.Monitor{monitor}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Monitors":[{"id":"m0"`,
+		},
+		{
+			name:           "run",
+			method:         "POST",
+			target:         "/api/v1/monitors/m0/run",
+			mockMethod:     "RunMonitor",
+			mockArgument:   "m0",
+			mockReturns:    []interface{}{[]links.Result{{ID: "r0", BatchID: "b0"}}, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"batch_id":"b0"`,
+		},
+		{
+			name:           "run while running",
+			method:         "POST",
+			target:         "/api/v1/monitors/m0/run",
+			mockMethod:     "RunMonitor",
+			mockArgument:   "m0",
+			mockReturns:    []interface{}{[]links.Result(nil), links.ErrMonitorRunning},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   links.ErrMonitorRunning.Error(),
+		},
+		{
+			name:           "internal error",
+			method:         "GET",
+			target:         "/api/v1/monitors",
+			mockMethod:     "ListMonitors",
+			mockReturns:    []interface{}{links.ListMonitorsResponse{}, errors.New("error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   links.ErrInternalServerError.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body))
+			if tc.mockMethod != "" {
+				arguments := []interface{}{}
+				if tc.mockArgument != nil {
+					arguments = append(arguments, tc.mockArgument)
+				}
+				s.mockMonitors.On(tc.mockMethod, arguments...).Return(tc.mockReturns...)
+			}
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.Contains(rr.Body.String(), tc.expectedBody)
+			s.mockMonitors.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/render"
+)
+
+var ErrInvalidRequest = errors.New("invalid request")
+var ErrInvalidRequestBody = errors.New("invalid request body")
+
+// openAPIDocument - OpenAPI 3 document of every endpoint of the api, served as it is
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openAPIDocumentV2 - OpenAPI 3 document of the v2 api
+//
+//go:embed openapi_v2.json
+var openAPIDocumentV2 []byte
+
+// openAPIRouter - finds the operation of a request in the OpenAPI document
+var openAPIRouter = mustLoadOpenAPI(openAPIDocument)
+
+// openAPIRouterV2 - finds the operation of a request in the OpenAPI document of the v2 api
+var openAPIRouterV2 = mustLoadOpenAPI(openAPIDocumentV2)
+
+// bodyErrors - error of an invalid body per operation, the same the handler of the operation responds with
+var bodyErrors = map[string]error{
+	"processBatch":    ErrRetrievingFile,
+	"retryBatch":      ErrInvalidRetryRequest,
+	"createMonitor":   ErrInvalidMonitorRequest,
+	"updateMonitor":   ErrInvalidMonitorRequest,
+	"createAlertRule": ErrInvalidAlertRuleRequest,
+	"issueAPIKey":     ErrInvalidAPIKeyRequest,
+}
+
+// LoadOpenAPI - parses and validates the OpenAPI document of the api
+func LoadOpenAPI() (*openapi3.T, error) {
+	return loadOpenAPI(openAPIDocument)
+}
+
+// LoadOpenAPIV2 - parses and validates the OpenAPI document of the v2 api
+func LoadOpenAPIV2() (*openapi3.T, error) {
+	return loadOpenAPI(openAPIDocumentV2)
+}
+
+func loadOpenAPI(document []byte) (*openapi3.T, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi document %w", err)
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi document %w", err)
+	}
+	return spec, nil
+}
+
+func mustLoadOpenAPI(document []byte) routers.Router {
+	spec, err := loadOpenAPI(document)
+	if err != nil {
+		panic(err)
+	}
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		panic(err)
+	}
+	return router
+}
+
+// OpenAPI - handler serving the OpenAPI document of the api
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocument)
+}
+
+// OpenAPI - handler serving the OpenAPI document of the v2 api
+func (v apiV2) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocumentV2)
+}
+
+// ValidateRequests - middleware rejecting requests which don't match the OpenAPI document with 400.
+// The errors name the query parameter and bad bodies get the error the handler of the operation
+// responds with, so clients see the same messages as without the middleware.
+// Requests of paths or methods missing from the document are passed on.
+func ValidateRequests(next http.Handler) http.Handler {
+	return validateRequests(openAPIRouter, next, func(w http.ResponseWriter, r *http.Request, route *routers.Route, err error) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, links.Response{Errors: []string{requestErrorMessage(route, err)}})
+	})
+}
+
+// ValidateRequestsV2 - middleware rejecting requests which don't match the OpenAPI document of the
+// v2 api with the typed error the handler of the operation would respond with
+func ValidateRequestsV2(next http.Handler) http.Handler {
+	return validateRequests(openAPIRouterV2, next, func(w http.ResponseWriter, r *http.Request, route *routers.Route, err error) {
+		renderErrorV2(w, r, requestError(route, err))
+	})
+}
+
+func validateRequests(router routers.Router, next http.Handler, reject func(http.ResponseWriter, *http.Request, *routers.Route, error)) http.Handler {
+	options := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = withJSONContentType(r, route)
+		input := &openapi3filter.RequestValidationInput{Request: r, PathParams: pathParams, Route: route, Options: options}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			reject(w, r, route, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withJSONContentType - the handlers decode JSON bodies whatever the Content-Type of the request,
+// so bodies of operations which only take JSON are validated as JSON as well
+func withJSONContentType(r *http.Request, route *routers.Route) *http.Request {
+	body := route.Operation.RequestBody
+	if body == nil || body.Value == nil || len(body.Value.Content) != 1 || body.Value.Content.Get("application/json") == nil {
+		return r
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return r
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// requestErrorMessage - message of a validation error in the style of the handlers,
+// e.g. "invalid query parameter limit, expected a number between 1 and 500"
+func requestErrorMessage(route *routers.Route, err error) string {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return err.Error()
+	}
+	if invalid := invalidParamOrBody(route, requestErr); invalid != nil {
+		return invalid.Error()
+	}
+	return requestErr.Error()
+}
+
+// requestError - validation error as the error the handlers return, a *queryParamError for query parameters
+func requestError(route *routers.Route, err error) error {
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) {
+		if invalid := invalidParamOrBody(route, requestErr); invalid != nil {
+			return invalid
+		}
+	}
+	return fmt.Errorf("%w, %v", ErrInvalidRequest, err)
+}
+
+// invalidParamOrBody - error of an invalid query parameter or body, nil for other validation errors
+func invalidParamOrBody(route *routers.Route, requestErr *openapi3filter.RequestError) error {
+	switch {
+	case requestErr.Parameter != nil && requestErr.Parameter.In == openapi3.ParameterInQuery:
+		return invalidQueryParam(requestErr.Parameter.Name, expectation(requestErr.Parameter.Schema.Value, requestErr))
+	case requestErr.RequestBody != nil:
+		if bodyErr, ok := bodyErrors[route.Operation.OperationID]; ok {
+			return bodyErr
+		}
+		return fmt.Errorf("%w, %s", ErrInvalidRequestBody, bodyReason(requestErr))
+	}
+	return nil
+}
+
+// expectation - what the schema of a query parameter expects, worded like the handlers word it
+func expectation(schema *openapi3.Schema, requestErr *openapi3filter.RequestError) string {
+	switch {
+	case len(schema.Enum) > 0:
+		values := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			values = append(values, fmt.Sprint(value))
+		}
+		return "expected one of " + strings.Join(values, ", ")
+	case schema.Type == openapi3.TypeInteger && schema.Min != nil && schema.Max != nil:
+		return fmt.Sprintf("expected a number between %v and %v", *schema.Min, *schema.Max)
+	case schema.Type == openapi3.TypeInteger && schema.Min != nil && *schema.Min == 0:
+		return "expected a non-negative number"
+	case schema.Type == openapi3.TypeInteger:
+		return "expected a number"
+	case schema.Type == openapi3.TypeBoolean:
+		return "expected true or false"
+	case schema.Format == "date-time":
+		return "expected RFC 3339 time"
+	}
+	return bodyReason(requestErr)
+}
+
+// bodyReason - reason of the validation error with the location of the invalid value
+func bodyReason(requestErr *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(requestErr.Err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			return fmt.Sprintf("%s at /%s", schemaErr.Reason, strings.Join(pointer, "/"))
+		}
+		return schemaErr.Reason
+	}
+	if requestErr.Err != nil {
+		return requestErr.Err.Error()
+	}
+	return requestErr.Reason
+}
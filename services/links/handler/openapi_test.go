@@ -0,0 +1,456 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *handlerTestSuite) TestOpenAPI_ThenDocumentIsServed() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/openapi.json", nil)
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+	// Assert
+	s.Equal(http.StatusOK, rr.Code)
+	s.Equal("application/json", rr.Header().Get("Content-Type"))
+	var spec openapi3.T
+	s.NoError(json.Unmarshal(rr.Body.Bytes(), &spec))
+	s.Equal("3.0.3", spec.OpenAPI)
+}
+
+func (s *handlerTestSuite) TestOpenAPI_ThenEveryRouteIsDocumentedAndEveryOperationIsRouted() {
+	// Arrange
+	documented := map[string]bool{}
+	for prefix, load := range map[string]func() (*openapi3.T, error){"/api/v1": handler.LoadOpenAPI, "/api/v2": handler.LoadOpenAPIV2} {
+		spec, err := load()
+		s.Require().NoError(err)
+		for path, item := range spec.Paths {
+			for method := range item.Operations() {
+				documented[method+" "+prefix+path] = true
+			}
+		}
+	}
+
+	// Act
+	routed := map[string]bool{}
+	err := chi.Walk(handler.NewRouter(s.handler), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.Replace(route, "/api/v1//", "/api/v1/", 1)
+		routed[method+" "+strings.Replace(route, "/api/v2//", "/api/v2/", 1)] = true
+		return nil
+	})
+
+	// Assert
+	s.NoError(err)
+	for route := range routed {
+		s.True(documented[route], "route %s is missing from the openapi document", route)
+	}
+	for operation := range documented {
+		s.True(routed[operation], "operation %s of the openapi document isn't routed", operation)
+	}
+}
+
+func (s *handlerTestSuite) TestOpenAPI_DifferentResponses_ThenTheyMatchTheDocument() {
+	for _, contentType := range []string{"text/csv", "application/x-ndjson", "text/event-stream", "text/html"} {
+		if openapi3filter.RegisteredBodyDecoder(contentType) == nil {
+			openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.FileBodyDecoder)
+		}
+	}
+
+	at := time.Date(2022, 5, 23, 10, 51, 1, 0, time.UTC)
+	success := true
+	batch := links.Batch{ID: "b0", Status: links.BatchStatusCompleted, URLCount: 2, SuccessCount: 1, FailureCount: 1,
+		Options:  links.ScrapeOptions{TimeoutMS: 5000, Headers: map[string]string{"Accept-Language": "en"}, CollectLinks: true},
+		Callback: &links.Callback{URL: "https://hooks.example.com", Secret: "s3cret"}, CreatedAt: at, UpdatedAt: at}
+	results := []links.Result{
+		{ID: "r0", BatchID: "b0", PageURL: "https://www.google.com", InternalLinksNum: 1, ExternalLinksNum: 1, Success: true,
+			InternalLinks: []string{"https://www.google.com/a"}, ExternalLinks: []string{"https://gmail.com"}, CreatedAt: at, UpdatedAt: at,
+			Attempts: []links.Attempt{{Error: &scraper.StatusError{StatusCode: 503}, AttemptedAt: at}}},
+		{ID: "r1", BatchID: "b0", PageURL: "https://www.google.com/missing", Error: &scraper.StatusError{StatusCode: 404}, CreatedAt: at, UpdatedAt: at},
+	}
+	monitor := links.Monitor{ID: "m0", Name: "weekly scan", Schedule: "@weekly", URLs: []string{"https://www.google.com"},
+		LastBatchID: "b0", LastRunAt: &at, NextRunAt: &at, CreatedAt: at, UpdatedAt: at}
+	rule := links.AlertRule{ID: "ar0", MonitorID: "m0", Name: "drops", Condition: links.AlertExternalLinksChange, ThresholdPercent: -10,
+		Sinks: []links.AlertSink{{Type: links.AlertSinkWebhook, URL: "https://hooks.example.com"}}, CreatedAt: at}
+	monitorBody := `{"name": "weekly scan", "schedule": "@weekly", "urls": ["https://www.google.com"], "options": {"collect_links": true}}`
+
+	type openAPICase struct {
+		name           string
+		req            *http.Request
+		arrange        func()
+		expectedStatus int
+	}
+	testCases := func(prefix string) []openAPICase {
+		return []openAPICase{
+			{
+				name:           "process batch",
+				req:            createRequestWithAttachedFile("POST", prefix+"/links", `testdata/testFile.txt`, false),
+				arrange:        func() { s.mockLinkProcessor.On("ProcessBatch", mock.Anything).Return(results, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "start batch",
+				req:            createRequestWithAttachedFile("POST", prefix+"/links?async=true", `testdata/testFile.txt`, false),
+				arrange:        func() { s.mockLinkProcessor.On("StartBatch", mock.Anything).Return(batch, nil) },
+				expectedStatus: http.StatusAccepted,
+			},
+			{
+				name:           "process batch without urls",
+				req:            createRequestWithAttachedFile("POST", prefix+"/links", `testdata/emptyFile.txt`, false),
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name: "list batches",
+				req:  httptest.NewRequest("GET", prefix+"/links?limit=1&status=completed", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("ListBatches", mock.Anything).Return(links.ListBatchesResponse{Batches: []links.Batch{batch}, NextCursor: "c1"}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "list no batches",
+				req:            httptest.NewRequest("GET", prefix+"/links", nil),
+				arrange:        func() { s.mockLinkProcessor.On("ListBatches", mock.Anything).Return(links.ListBatchesResponse{}, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "get batch",
+				req:  httptest.NewRequest("GET", prefix+"/links/b0?limit=2&sort=-page_url", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("GetBatch", mock.Anything).Return(links.GetBatchResponse{Results: results, NextCursor: "c1"}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "get batch as csv",
+				req:            httptest.NewRequest("GET", prefix+"/links/b0?format=csv", nil),
+				arrange:        func() { s.mockLinkProcessor.On("ExportBatch", mock.Anything).Return(results, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "get batch as ndjson",
+				req:            httptest.NewRequest("GET", prefix+"/links/b0?format=ndjson", nil),
+				arrange:        func() { s.mockLinkProcessor.On("ExportBatch", mock.Anything).Return(results, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "get missing batch",
+				req:  httptest.NewRequest("GET", prefix+"/links/b0", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("GetBatch", mock.Anything).Return(links.GetBatchResponse{}, repository.ErrBatchNotFound)
+				},
+				expectedStatus: http.StatusNotFound,
+			},
+			{
+				name:           "get batch with an invalid limit",
+				req:            httptest.NewRequest("GET", prefix+"/links/b0?limit=1000", nil),
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "get result of a batch",
+				req:            httptest.NewRequest("GET", prefix+"/links/b0/results/r1", nil),
+				arrange:        func() { s.mockLinkProcessor.On("GetResult", mock.Anything).Return(results[1], nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "get result",
+				req:            httptest.NewRequest("GET", prefix+"/results/r0", nil),
+				arrange:        func() { s.mockLinkProcessor.On("GetResult", mock.Anything).Return(results[0], nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "retry batch",
+				req:  httptest.NewRequest("POST", prefix+"/links/b0/retry", strings.NewReader(`{"result_ids": ["r1"]}`)),
+				arrange: func() {
+					s.mockLinkProcessor.On("RetryBatch", mock.Anything).Return(links.RetryBatchResponse{Batch: batch, Results: results[1:]}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "retry running batch",
+				req:  httptest.NewRequest("POST", prefix+"/links/b0/retry", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("RetryBatch", mock.Anything).Return(links.RetryBatchResponse{}, links.ErrBatchInProgress)
+				},
+				expectedStatus: http.StatusConflict,
+			},
+			{
+				name: "diff batches",
+				req:  httptest.NewRequest("GET", prefix+"/links/b0/diff/b1", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("DiffBatches", mock.Anything).Return(links.BatchDiff{BaseBatchID: "b0", TargetBatchID: "b1", AddedCount: 1,
+						Pages: []links.PageDiff{{PageURL: "https://www.google.com", Change: links.PageAdded, TargetSuccess: &success, InternalLinksDelta: 1}}}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "diff batches as csv",
+				req:            httptest.NewRequest("GET", prefix+"/links/b0/diff/b1?format=csv", nil),
+				arrange:        func() { s.mockLinkProcessor.On("DiffBatches", mock.Anything).Return(links.BatchDiff{}, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "list callback attempts",
+				req:  httptest.NewRequest("GET", prefix+"/links/b0/callbacks", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("ListCallbackAttempts", "b0").Return(links.ListCallbackAttemptsResponse{Attempts: []links.CallbackAttempt{
+						{ID: "c0", BatchID: "b0", EventID: "e0", URL: "https://hooks.example.com", Attempt: 1, StatusCode: 503, Error: "callback responded with status 503", AttemptedAt: at},
+					}}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "watch batch",
+				req:  httptest.NewRequest("GET", prefix+"/links/b0/events", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("WatchBatch", "b0").Return([]links.BatchEvent{{Type: links.BatchEventCompleted, Batch: &batch}}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "report batch",
+				req:            httptest.NewRequest("GET", prefix+"/links/b0/report", nil),
+				arrange:        func() { s.mockLinkProcessor.On("ReportBatch", "b0").Return(links.BatchReport{Batch: batch}, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "report running batch",
+				req:  httptest.NewRequest("GET", prefix+"/links/b0/report", nil),
+				arrange: func() {
+					s.mockLinkProcessor.On("ReportBatch", "b0").Return(links.BatchReport{}, links.ErrBatchInProgress)
+				},
+				expectedStatus: http.StatusConflict,
+			},
+			{
+				name:           "create monitor",
+				req:            httptest.NewRequest("POST", prefix+"/monitors", strings.NewReader(monitorBody)),
+				arrange:        func() { s.mockMonitors.On("CreateMonitor", mock.Anything).Return(monitor, nil) },
+				expectedStatus: http.StatusCreated,
+			},
+			{
+				name:           "create monitor with an unknown field",
+				req:            httptest.NewRequest("POST", prefix+"/monitors", strings.NewReader(`{"name": "weekly scan", "cron": "@weekly"}`)),
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name: "list monitors",
+				req:  httptest.NewRequest("GET", prefix+"/monitors", nil),
+				arrange: func() {
+					s.mockMonitors.On("ListMonitors").Return(links.ListMonitorsResponse{Monitors: []links.Monitor{monitor}}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "get monitor",
+				req:            httptest.NewRequest("GET", prefix+"/monitors/m0", nil),
+				arrange:        func() { s.mockMonitors.On("GetMonitor", "m0").Return(monitor, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "update monitor",
+				req:            httptest.NewRequest("PUT", prefix+"/monitors/m0", strings.NewReader(monitorBody)),
+				arrange:        func() { s.mockMonitors.On("UpdateMonitor", mock.Anything).Return(monitor, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "delete monitor",
+				req:            httptest.NewRequest("DELETE", prefix+"/monitors/m0", nil),
+				arrange:        func() { s.mockMonitors.On("DeleteMonitor", "m0").Return(nil) },
+				expectedStatus: http.StatusNoContent,
+			},
+			{
+				name:           "delete missing monitor",
+				req:            httptest.NewRequest("DELETE", prefix+"/monitors/m0", nil),
+				arrange:        func() { s.mockMonitors.On("DeleteMonitor", "m0").Return(repository.ErrMonitorNotFound) },
+				expectedStatus: http.StatusNotFound,
+			},
+			{
+				name:           "run monitor",
+				req:            httptest.NewRequest("POST", prefix+"/monitors/m0/run", nil),
+				arrange:        func() { s.mockMonitors.On("RunMonitor", "m0").Return(results, nil) },
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name: "create alert rule",
+				req: httptest.NewRequest("POST", prefix+"/monitors/m0/rules", strings.NewReader(
+					`{"name": "drops", "condition": "external_links_change", "threshold_percent": -10, "sinks": [{"type": "webhook", "url": "https://hooks.example.com"}]}`)),
+				arrange:        func() { s.mockMonitors.On("CreateAlertRule", mock.Anything).Return(rule, nil) },
+				expectedStatus: http.StatusCreated,
+			},
+			{
+				name: "list alert rules",
+				req:  httptest.NewRequest("GET", prefix+"/monitors/m0/rules", nil),
+				arrange: func() {
+					s.mockMonitors.On("ListAlertRules", "m0").Return(links.ListAlertRulesResponse{Rules: []links.AlertRule{rule}}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "delete alert rule",
+				req:            httptest.NewRequest("DELETE", prefix+"/monitors/m0/rules/ar0", nil),
+				arrange:        func() { s.mockMonitors.On("DeleteAlertRule", "m0", "ar0").Return(nil) },
+				expectedStatus: http.StatusNoContent,
+			},
+			{
+				name: "list alerts",
+				req:  httptest.NewRequest("GET", prefix+"/alerts?monitor_id=m0&limit=10", nil),
+				arrange: func() {
+					s.mockMonitors.On("ListAlerts", mock.Anything).Return(links.ListAlertsResponse{Alerts: []links.Alert{{ID: "a0", RuleID: "ar0", RuleName: "drops",
+						MonitorID: "m0", BatchID: "b1", PreviousBatchID: "b0", Condition: links.AlertExternalLinksChange, PageURL: "https://www.google.com",
+						Message: "external links dropped by 50%", Previous: 2, Current: 1,
+						Deliveries: []links.AlertDelivery{{Sink: rule.Sinks[0], Error: "timeout"}}, CreatedAt: at}}}, nil)
+				},
+				expectedStatus: http.StatusOK,
+			},
+			{
+				name:           "internal error",
+				req:            httptest.NewRequest("GET", prefix+"/monitors", nil),
+				arrange:        func() { s.mockMonitors.On("ListMonitors").Return(links.ListMonitorsResponse{}, errors.New("error")) },
+				expectedStatus: http.StatusInternalServerError,
+			},
+			{
+				name:           "api keys not enabled",
+				req:            httptest.NewRequest("GET", prefix+"/keys", nil),
+				expectedStatus: http.StatusNotFound,
+			},
+		}
+	}
+	versions := []struct {
+		prefix string
+		load   func() (*openapi3.T, error)
+	}{
+		{"/api/v1", handler.LoadOpenAPI},
+		{"/api/v2", handler.LoadOpenAPIV2},
+	}
+	for _, version := range versions {
+		spec, err := version.load()
+		s.Require().NoError(err)
+		openAPIRouter, err := gorillamux.NewRouter(spec)
+		s.Require().NoError(err)
+
+		for _, tc := range testCases(version.prefix) {
+			s.Run(version.prefix+" "+tc.name, func() {
+				// Arrange
+				rr := httptest.NewRecorder()
+				if tc.arrange != nil {
+					tc.arrange()
+				}
+				route, pathParams, err := openAPIRouter.FindRoute(tc.req)
+				s.Require().NoError(err)
+
+				// Act
+				handler.NewRouter(s.handler).ServeHTTP(rr, tc.req)
+
+				// Assert
+				s.Equal(tc.expectedStatus, rr.Code, rr.Body.String())
+				s.NoError(validateResponse(route, pathParams, tc.req, rr))
+				s.mockLinkProcessor.AssertExpectations(s.T())
+				s.mockMonitors.AssertExpectations(s.T())
+				s.ResetMocks()
+			})
+		}
+	}
+}
+
+func (s *handlerTestSuite) TestValidateRequests_DifferentCases_ThenInvalidRequestsAreRejected() {
+	testCases := []struct {
+		name          string
+		req           *http.Request
+		expectedError string
+	}{
+		{
+			name:          "limit out of range",
+			req:           httptest.NewRequest("GET", "/api/v1/links?limit=0", nil),
+			expectedError: "invalid query parameter limit, expected a number between 1 and 500",
+		},
+		{
+			name:          "unknown status",
+			req:           httptest.NewRequest("GET", "/api/v1/links?status=done", nil),
+			expectedError: "invalid query parameter status, expected one of running, completed, failed",
+		},
+		{
+			name:          "created after isn't a time",
+			req:           httptest.NewRequest("GET", "/api/v1/links?created_after=yesterday", nil),
+			expectedError: "invalid query parameter created_after, expected RFC 3339 time",
+		},
+		{
+			name:          "negative link count",
+			req:           httptest.NewRequest("GET", "/api/v1/links/b0?min_external=-1", nil),
+			expectedError: "invalid query parameter min_external, expected a non-negative number",
+		},
+		{
+			name:          "success isn't a boolean",
+			req:           httptest.NewRequest("GET", "/api/v1/links/b0?success=maybe", nil),
+			expectedError: "invalid query parameter success, expected true or false",
+		},
+		{
+			name:          "missing file",
+			req:           httptest.NewRequest("POST", "/api/v1/links", nil),
+			expectedError: handler.ErrRetrievingFile.Error(),
+		},
+		{
+			name:          "result ids aren't a list",
+			req:           httptest.NewRequest("POST", "/api/v1/links/b0/retry", strings.NewReader(`{"result_ids": "r0"}`)),
+			expectedError: handler.ErrInvalidRetryRequest.Error(),
+		},
+		{
+			name:          "unknown field of a monitor",
+			req:           httptest.NewRequest("PUT", "/api/v1/monitors/m0", strings.NewReader(`{"name": "weekly scan", "cron": "@weekly"}`)),
+			expectedError: handler.ErrInvalidMonitorRequest.Error(),
+		},
+		{
+			name:          "unknown field of an alert sink",
+			req:           httptest.NewRequest("POST", "/api/v1/monitors/m0/rules", strings.NewReader(`{"sinks": [{"type": "email", "to": "ops@example.com"}]}`)),
+			expectedError: handler.ErrInvalidAlertRuleRequest.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, tc.req)
+
+			// Assert
+			var response links.Response
+			s.Equal(http.StatusBadRequest, rr.Code)
+			s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+			s.Equal([]string{tc.expectedError}, response.Errors)
+			s.ResetMocks()
+		})
+	}
+}
+
+// validateResponse - checks the recorded response against the operation of the request in the OpenAPI document,
+// undocumented status codes and content types are errors
+func validateResponse(route *routers.Route, pathParams map[string]string, req *http.Request, rr *httptest.ResponseRecorder) error {
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, PathParams: pathParams, Route: route},
+		Status:                 rr.Code,
+		Header:                 rr.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rr.Body.Bytes())),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	return openapi3filter.ValidateResponse(context.Background(), input)
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+var ErrInvalidQueryParam = errors.New("invalid query parameter")
+
+const maxPageSize = 500
+
+// defaultPageSize - page size of batch and result listings without a limit
+const defaultPageSize = 50
+
+// queryParamError - invalid query parameter with what was expected of it, matches ErrInvalidQueryParam
+type queryParamError struct {
+	name   string
+	reason string
+}
+
+func invalidQueryParam(name, reason string) error {
+	return &queryParamError{name: name, reason: reason}
+}
+
+func (e *queryParamError) Error() string {
+	if e.reason == "" {
+		return fmt.Sprintf("%s %s", ErrInvalidQueryParam, e.name)
+	}
+	return fmt.Sprintf("%s %s, %s", ErrInvalidQueryParam, e.name, e.reason)
+}
+
+func (e *queryParamError) Unwrap() error {
+	return ErrInvalidQueryParam
+}
+
+// Response formats
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// formatMediaTypes - media types in the Accept header which select a response format
+var formatMediaTypes = map[string][]string{
+	formatCSV:    {"text/csv"},
+	formatNDJSON: {"application/x-ndjson", "application/ndjson"},
+}
+
+// parseBatchQuery - reads the batch listing query parameters:
+// limit, cursor, sort (field name, prefixed with - for descending order),
+// status, monitor_id, created_after and created_before (RFC 3339).
+// Batches are listed newest first when no sort is requested, defaultPageSize at a time when no limit is set.
+func parseBatchQuery(r *http.Request) (links.BatchQuery, error) {
+	params := r.URL.Query()
+	query := links.BatchQuery{
+		Cursor:    params.Get("cursor"),
+		Status:    links.BatchStatus(params.Get("status")),
+		MonitorID: params.Get("monitor_id"),
+		APIKeyID:  params.Get("api_key_id"),
+	}
+
+	var err error
+	if query.Limit, err = parseLimit(params.Get("limit")); err != nil {
+		return links.BatchQuery{}, err
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+
+	query.SortBy, query.Descending = parseSort(params.Get("sort"))
+	if query.SortBy == "" {
+		query.SortBy, query.Descending = links.BatchSortCreatedAt, true
+	}
+
+	switch query.Status {
+	case "", links.BatchStatusRunning, links.BatchStatusCompleted, links.BatchStatusFailed:
+	default:
+		return links.BatchQuery{}, invalidQueryParam("status", "")
+	}
+
+	if query.CreatedAfter, err = parseTime(params.Get("created_after"), "created_after"); err != nil {
+		return links.BatchQuery{}, err
+	}
+	if query.CreatedBefore, err = parseTime(params.Get("created_before"), "created_before"); err != nil {
+		return links.BatchQuery{}, err
+	}
+
+	return query, nil
+}
+
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, invalidQueryParam("limit", fmt.Sprintf("expected a number between 1 and %d", maxPageSize))
+	}
+	return limit, nil
+}
+
+// parseSort - "-field" sorts by field in descending order
+func parseSort(value string) (field string, descending bool) {
+	if strings.HasPrefix(value, "-") {
+		return strings.TrimPrefix(value, "-"), true
+	}
+	return value, false
+}
+
+func parseTime(value, name string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, invalidQueryParam(name, "expected RFC 3339 time")
+	}
+	return t, nil
+}
+
+// parseResultQuery - reads the batch results query parameters:
+// limit, cursor, sort (field name, prefixed with - for descending order),
+// success (true or false), host, min_internal, max_internal, min_external and max_external.
+// Results are returned defaultPageSize at a time when no limit is set.
+func parseResultQuery(r *http.Request) (links.ResultQuery, error) {
+	params := r.URL.Query()
+	query := links.ResultQuery{Cursor: params.Get("cursor"), Host: params.Get("host")}
+
+	var err error
+	if query.Limit, err = parseLimit(params.Get("limit")); err != nil {
+		return links.ResultQuery{}, err
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+
+	query.SortBy, query.Descending = parseSort(params.Get("sort"))
+
+	if value := params.Get("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			return links.ResultQuery{}, invalidQueryParam("success", "expected true or false")
+		}
+		query.Success = &success
+	}
+
+	bounds := []struct {
+		name  string
+		value **uint
+	}{
+		{"min_internal", &query.MinInternalLinks},
+		{"max_internal", &query.MaxInternalLinks},
+		{"min_external", &query.MinExternalLinks},
+		{"max_external", &query.MaxExternalLinks},
+	}
+	for _, bound := range bounds {
+		if *bound.value, err = parseUint(params.Get(bound.name), bound.name); err != nil {
+			return links.ResultQuery{}, err
+		}
+	}
+
+	return query, nil
+}
+
+func parseUint(value, name string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, invalidQueryParam(name, "expected a non-negative number")
+	}
+	result := uint(number)
+	return &result, nil
+}
+
+// parseFormat - response format from the format query parameter, falls back to the Accept header
+// and then to JSON. Only the formats listed in supported are accepted.
+func parseFormat(r *http.Request, supported ...string) (string, error) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = acceptedFormat(r.Header.Get("Accept"), supported)
+	}
+
+	for _, s := range supported {
+		if format == s {
+			return format, nil
+		}
+	}
+	return "", invalidQueryParam("format", "expected one of "+strings.Join(supported, ", "))
+}
+
+// acceptedFormat - first supported format whose media type is accepted, JSON otherwise
+func acceptedFormat(accept string, supported []string) string {
+	for _, format := range supported {
+		for _, mediaType := range formatMediaTypes[format] {
+			if strings.Contains(accept, mediaType) {
+				return format
+			}
+		}
+	}
+	return formatJSON
+}
+
+// parseColumns - comma separated column names from the columns query parameter,
+// the default columns when it isn't set. Every column must be one of available.
+func parseColumns(r *http.Request, available, defaults []string) ([]string, error) {
+	value := r.URL.Query().Get("columns")
+	if strings.TrimSpace(value) == "" {
+		return defaults, nil
+	}
+
+	known := make(map[string]bool, len(available))
+	for _, column := range available {
+		known[column] = true
+	}
+	columns := []string{}
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if !known[column] {
+			return nil, invalidQueryParam("columns", fmt.Sprintf("unknown column %q", column))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+//go:embed templates/report.html
+var templates embed.FS
+
+// reportTemplate - standalone page, the styles are inlined so the report can be emailed or archived
+var reportTemplate = template.Must(template.New("report.html").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+	"percent":    percent,
+	"classify":   classify,
+}).ParseFS(templates, "templates/report.html"))
+
+// ReportBatch - handler for the html report of a finished batch
+func (h *Handler) ReportBatch(w http.ResponseWriter, r *http.Request) {
+	batchID := chi.URLParam(r, "batchID")
+
+	report, err := h.linksProcessor.ReportBatch(r.Context(), batchID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBatchNotFound): // batch not found
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, links.Response{Errors: []string{repository.ErrBatchNotFound.Error()}})
+			return
+		case errors.Is(err, links.ErrBatchInProgress):
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrBatchInProgress.Error()}})
+			return
+		default: // generic response to not leak details for all other errors
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+			return
+		}
+	}
+
+	// rendered before responding so a template error is still answered with a JSON error
+	page := &bytes.Buffer{}
+	if err := reportTemplate.Execute(page, report); err != nil {
+		log.Println("failed to render report of batch", batchID, err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, links.Response{Errors: []string{links.ErrInternalServerError.Error()}})
+		return
+	}
+
+	writeReport(w, batchID, page)
+}
+
+// writeReport - responds with the rendered report as an inline html page
+func writeReport(w http.ResponseWriter, batchID string, page *bytes.Buffer) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="report_%s.html"`, batchID))
+	w.WriteHeader(http.StatusOK)
+	if _, err := page.WriteTo(w); err != nil {
+		log.Println("failed to write report of batch", batchID, err)
+	}
+}
+
+// failure - flattened error of a failed page
+type failure struct {
+	Category   scraper.ErrorCategory
+	StatusCode int
+}
+
+func classify(err error) failure {
+	category, statusCode := scraper.ClassifyError(err)
+	if category == "" {
+		category = scraper.ErrorCategoryOther
+	}
+	return failure{Category: category, StatusCode: statusCode}
+}
+
+// percent - share of part in total between 0 and 100, 0 for an empty total
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
@@ -0,0 +1,106 @@
+package handler_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+)
+
+func (s *handlerTestSuite) TestReportBatch_DifferentCases_ThenItIsHandledAsExpected() {
+	createdAt := time.Date(2022, 5, 23, 10, 51, 1, 0, time.UTC)
+	report := links.BatchReport{
+		Batch: links.Batch{ID: "batchID", Status: links.BatchStatusCompleted, URLCount: 4, SuccessCount: 2, FailureCount: 2,
+			Options: links.ScrapeOptions{CollectLinks: true}, CreatedAt: createdAt},
+		InternalLinksTotal: 9,
+		ExternalLinksTotal: 16,
+		LinkDetails:        true,
+		TopExternalPages:   []links.Result{{PageURL: "https://www.google.com/", ExternalLinksNum: 13, InternalLinksNum: 6}},
+		TopInternalPages:   []links.Result{{PageURL: "https://www.google.com/", ExternalLinksNum: 13, InternalLinksNum: 6}},
+		Failures:           []links.FailureCount{{Category: "status", Count: 2}},
+		FailedPages: []links.Result{
+			{PageURL: "https://example.com/<script>alert(1)</script>", Error: &scraper.StatusError{StatusCode: 404}},
+		},
+		TopExternalDomains: []links.DomainCount{{Domain: "gmail.com", Links: 4, Pages: 1}, {Domain: "youtube.com", Links: 1, Pages: 1}},
+		GeneratedAt:        createdAt,
+	}
+
+	testCases := []struct {
+		name             string
+		report           links.BatchReport
+		processorErr     error
+		expectedStatus   int
+		expectedContains []string
+	}{
+		{
+			name:           "report",
+			report:         report,
+			expectedStatus: http.StatusOK,
+			expectedContains: []string{
+				"<title>Links report batchID</title>",
+				`<div class="value">50.0%</div><div class="label">Success rate</div>`,
+				`<td class="url">https://www.google.com/</td><td class="number">13</td><td class="number">6</td>`,
+				`<td>status</td><td class="number">2</td><td class="number">100.0%</td>`,
+				"https://example.com/&lt;script&gt;alert(1)&lt;/script&gt;",
+				`<td class="number">404</td><td>bad status code</td>`,
+				`<td>gmail.com</td><td class="number">4</td><td class="number">1</td><td><div class="bar" style="width: 100.0%">`,
+				`<div class="bar" style="width: 25.0%">`,
+			},
+		},
+		{
+			name:             "batch without link details",
+			report:           links.BatchReport{Batch: links.Batch{ID: "batchID", Status: links.BatchStatusCompleted}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: []string{"No page was scraped successfully.", "Every page was scraped successfully.", "process it with the <code>collect_links</code> option"},
+		},
+		{
+			name:             "batch not found",
+			processorErr:     fmt.Errorf("failed to get batch %w", repository.ErrBatchNotFound),
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: []string{repository.ErrBatchNotFound.Error()},
+		},
+		{
+			name:             "batch is running",
+			processorErr:     links.ErrBatchInProgress,
+			expectedStatus:   http.StatusConflict,
+			expectedContains: []string{links.ErrBatchInProgress.Error()},
+		},
+		{
+			name:             "internal error",
+			processorErr:     errors.New("error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: []string{links.ErrInternalServerError.Error()},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/links/batchID/report", nil)
+			s.mockLinkProcessor.On("ReportBatch", "batchID").Return(tc.report, tc.processorErr)
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tc.expectedStatus, rr.Code)
+			for _, expected := range tc.expectedContains {
+				s.Contains(rr.Body.String(), expected)
+			}
+			if tc.expectedStatus == http.StatusOK {
+				s.Equal("text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+				s.NotContains(rr.Body.String(), "<script")
+				s.NotContains(rr.Body.String(), "<link")
+				s.NotContains(rr.Body.String(), "src=")
+			}
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
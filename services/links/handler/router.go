@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NewRouter - router with all routes of the links service. Everything but the OpenAPI documents
+// needs an api key when the handler has api keys, the api key endpoints need an admin key.
+// The metrics are served at /metrics when the handler has metrics, and requests are traced when it has a tracer.
+func NewRouter(h *Handler) *chi.Mux {
+	router := chi.NewRouter()
+	if h.tracer != nil {
+		router.Use(h.traceRequests)
+	}
+	if h.metrics != nil {
+		router.Use(h.instrument)
+		router.Method(http.MethodGet, "/metrics", h.metrics.Handler())
+	}
+
+	router.Route("/api/v1/", func(r chi.Router) {
+		r.With(ValidateRequests).Get("/openapi.json", h.OpenAPI)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.authenticate(renderAuthError), ValidateRequests)
+
+			r.Post("/links", h.ProcessBatch)
+			r.Get("/links", h.ListBatches)
+			r.Get("/links/{batchID}", h.GetBatch)
+			r.Get("/links/{batchID}/results/{resultID}", h.GetResult)
+			r.Post("/links/{batchID}/retry", h.RetryBatch)
+			r.Get("/links/{batchID}/diff/{targetBatchID}", h.DiffBatches)
+			r.Get("/links/{batchID}/callbacks", h.ListCallbackAttempts)
+			r.Get("/links/{batchID}/events", h.WatchBatch)
+			r.Get("/links/{batchID}/report", h.ReportBatch)
+
+			r.Post("/monitors", h.CreateMonitor)
+			r.Get("/monitors", h.ListMonitors)
+			r.Get("/monitors/{monitorID}", h.GetMonitor)
+			r.Put("/monitors/{monitorID}", h.UpdateMonitor)
+			r.Delete("/monitors/{monitorID}", h.DeleteMonitor)
+			r.Post("/monitors/{monitorID}/run", h.RunMonitor)
+			r.Post("/monitors/{monitorID}/rules", h.CreateAlertRule)
+			r.Get("/monitors/{monitorID}/rules", h.ListAlertRules)
+			r.Delete("/monitors/{monitorID}/rules/{ruleID}", h.DeleteAlertRule)
+			r.Get("/alerts", h.ListAlerts)
+			r.Get("/results/{resultID}", h.GetResult)
+
+			r.Group(func(r chi.Router) {
+				r.Use(h.requireAdmin(renderAuthError))
+
+				r.Post("/keys", h.IssueAPIKey)
+				r.Get("/keys", h.ListAPIKeys)
+				r.Delete("/keys/{keyID}", h.RevokeAPIKey)
+			})
+		})
+	})
+
+	v2 := apiV2{h}
+	router.Route("/api/v2/", func(r chi.Router) {
+		r.Use(RequestID)
+		r.With(ValidateRequestsV2).Get("/openapi.json", v2.OpenAPI)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.authenticate(renderErrorV2), ValidateRequestsV2)
+
+			r.Post("/links", v2.ProcessBatch)
+			r.Get("/links", v2.ListBatches)
+			r.Get("/links/{batchID}", v2.GetBatch)
+			r.Get("/links/{batchID}/results/{resultID}", v2.GetResult)
+			r.Post("/links/{batchID}/retry", v2.RetryBatch)
+			r.Get("/links/{batchID}/diff/{targetBatchID}", v2.DiffBatches)
+			r.Get("/links/{batchID}/callbacks", v2.ListCallbackAttempts)
+			r.Get("/links/{batchID}/events", v2.WatchBatch)
+			r.Get("/links/{batchID}/report", v2.ReportBatch)
+
+			r.Post("/monitors", v2.CreateMonitor)
+			r.Get("/monitors", v2.ListMonitors)
+			r.Get("/monitors/{monitorID}", v2.GetMonitor)
+			r.Put("/monitors/{monitorID}", v2.UpdateMonitor)
+			r.Delete("/monitors/{monitorID}", v2.DeleteMonitor)
+			r.Post("/monitors/{monitorID}/run", v2.RunMonitor)
+			r.Post("/monitors/{monitorID}/rules", v2.CreateAlertRule)
+			r.Get("/monitors/{monitorID}/rules", v2.ListAlertRules)
+			r.Delete("/monitors/{monitorID}/rules/{ruleID}", v2.DeleteAlertRule)
+			r.Get("/alerts", v2.ListAlerts)
+			r.Get("/results/{resultID}", v2.GetResult)
+
+			r.Group(func(r chi.Router) {
+				r.Use(h.requireAdmin(renderErrorV2))
+
+				r.Post("/keys", v2.IssueAPIKey)
+				r.Get("/keys", v2.ListAPIKeys)
+				r.Delete("/keys/{keyID}", v2.RevokeAPIKey)
+			})
+		})
+	})
+
+	return router
+}
@@ -0,0 +1,61 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (s *handlerTestSuite) TestTracing_WhenEnabled_ThenRequestsAreTracedByRoute() {
+	tests := []struct {
+		name         string
+		path         string
+		traceparent  string
+		expectedName string
+		expectedCode int
+	}{
+		{"new trace", "/api/v1/monitors", "", "GET /api/v1/monitors", http.StatusOK},
+		{"trace of the client", "/api/v1/monitors", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "GET /api/v1/monitors", http.StatusOK},
+		{"unmatched route", "/unknown", "", "GET", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			// Arrange
+			s.ResetMocks()
+			if tt.expectedCode == http.StatusOK {
+				s.mockMonitors.On("ListMonitors").Return(links.ListMonitorsResponse{}, nil)
+			}
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			router := handler.NewRouter(handler.NewHandler(s.mockLinkProcessor, s.mockMonitors, handler.WithTracerProvider(provider)))
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			s.Equal(tt.expectedCode, rr.Code)
+			spans := recorder.Ended()
+			s.Equal(1, len(spans))
+			s.Equal(tt.expectedName, spans[0].Name())
+			s.Equal(trace.SpanKindServer, spans[0].SpanKind())
+			s.Contains(spans[0].Attributes(), attribute.Int("http.response.status_code", tt.expectedCode))
+			if tt.traceparent != "" {
+				s.Equal("4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+				s.Equal("00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+			} else {
+				s.False(spans[0].Parent().IsValid())
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/pkg/helpers"
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader - header with the id of a request, echoed on every v2 response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - longer ids sent by clients are replaced by a generated one
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// apiV2 - handlers of the v2 api. They share the services of the v1 handlers but respond with
+// a links.Envelope: snake_case data, typed errors, pagination metadata and the request id.
+type apiV2 struct {
+	*Handler
+}
+
+// errorCodes - status, code and field of the errors of the v2 api, the first match wins.
+// Bad requests, exceeded quotas and a busy server keep the details of their message, other errors get the message of the matched error.
+var errorCodes = []struct {
+	err    error
+	status int
+	code   string
+	field  string
+}{
+	{ErrRetrievingFile, http.StatusBadRequest, links.ErrorCodeInvalidFile, "urlsFile"},
+	{ErrNoUrlsForProcessing, http.StatusBadRequest, links.ErrorCodeNoURLs, "urlsFile"},
+	{ErrInvalidOptions, http.StatusBadRequest, links.ErrorCodeInvalidOptions, "options"},
+	{scraper.ErrInvalidOptions, http.StatusBadRequest, links.ErrorCodeInvalidOptions, "options"},
+	{links.ErrInvalidCallback, http.StatusBadRequest, links.ErrorCodeInvalidCallback, "callback_url"},
+	{ErrInvalidRetryRequest, http.StatusBadRequest, links.ErrorCodeInvalidRequestBody, ""},
+	{ErrInvalidMonitorRequest, http.StatusBadRequest, links.ErrorCodeInvalidRequestBody, ""},
+	{ErrInvalidAlertRuleRequest, http.StatusBadRequest, links.ErrorCodeInvalidRequestBody, ""},
+	{ErrInvalidRequestBody, http.StatusBadRequest, links.ErrorCodeInvalidRequestBody, ""},
+	{ErrInvalidRequest, http.StatusBadRequest, links.ErrorCodeInvalidRequest, ""},
+	{links.ErrInvalidMonitor, http.StatusBadRequest, links.ErrorCodeInvalidMonitor, ""},
+	{links.ErrInvalidAlertRule, http.StatusBadRequest, links.ErrorCodeInvalidAlertRule, ""},
+	{repository.ErrInvalidCursor, http.StatusBadRequest, links.ErrorCodeInvalidCursor, "cursor"},
+	{repository.ErrInvalidSort, http.StatusBadRequest, links.ErrorCodeInvalidSort, "sort"},
+	{repository.ErrBatchNotFound, http.StatusNotFound, links.ErrorCodeBatchNotFound, ""},
+	{repository.ErrResultNotFound, http.StatusNotFound, links.ErrorCodeResultNotFound, ""},
+	{repository.ErrMonitorNotFound, http.StatusNotFound, links.ErrorCodeMonitorNotFound, ""},
+	{repository.ErrAlertRuleNotFound, http.StatusNotFound, links.ErrorCodeAlertRuleNotFound, ""},
+	{links.ErrBatchInProgress, http.StatusConflict, links.ErrorCodeBatchInProgress, ""},
+	{links.ErrMonitorRunning, http.StatusConflict, links.ErrorCodeMonitorRunning, ""},
+	{ErrInvalidAPIKeyRequest, http.StatusBadRequest, links.ErrorCodeInvalidRequestBody, ""},
+	{links.ErrInvalidAPIKey, http.StatusBadRequest, links.ErrorCodeInvalidAPIKey, ""},
+	{ErrMissingAPIKey, http.StatusUnauthorized, links.ErrorCodeUnauthorized, ""},
+	{links.ErrUnknownAPIKey, http.StatusUnauthorized, links.ErrorCodeUnauthorized, ""},
+	{ErrAdminRequired, http.StatusForbidden, links.ErrorCodeForbidden, ""},
+	{ErrAPIKeysDisabled, http.StatusNotFound, links.ErrorCodeAPIKeysDisabled, ""},
+	{repository.ErrAPIKeyNotFound, http.StatusNotFound, links.ErrorCodeAPIKeyNotFound, ""},
+	{links.ErrQuotaExceeded, http.StatusTooManyRequests, links.ErrorCodeQuotaExceeded, ""},
+	{links.ErrServerBusy, http.StatusTooManyRequests, links.ErrorCodeServerBusy, ""},
+}
+
+// RequestID - middleware reading the X-Request-ID header of the request, or generating one when
+// it's missing or too long, and echoing it on the response. See requestID for reading it back.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID - id of the request set by the RequestID middleware, empty without it
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// respondV2 - responds with the data in the v2 envelope, pagination is only set for paginated listings
+func respondV2(w http.ResponseWriter, r *http.Request, status int, data interface{}, pagination *links.Pagination) {
+	render.Status(r, status)
+	render.JSON(w, r, links.Envelope{RequestID: requestID(r.Context()), Data: data, Pagination: pagination})
+}
+
+// renderErrorV2 - responds with the typed error of err in the v2 envelope, with the
+// WWW-Authenticate or Retry-After header for authentication, quota and capacity errors.
+// Unexpected errors are logged with the request id and answered with a generic internal error.
+func renderErrorV2(w http.ResponseWriter, r *http.Request, err error) {
+	status, detail := errorDetail(err)
+	if status == http.StatusInternalServerError {
+		log.Println("request", requestID(r.Context()), "failed", err)
+	}
+	setAuthHeaders(w, err)
+
+	render.Status(r, status)
+	render.JSON(w, r, links.Envelope{RequestID: requestID(r.Context()), Errors: []links.ErrorDetail{detail}})
+}
+
+// errorDetail - status and typed error of an error of parsing the request or of the services
+func errorDetail(err error) (int, links.ErrorDetail) {
+	var paramErr *queryParamError
+	if errors.As(err, &paramErr) {
+		return http.StatusBadRequest, links.ErrorDetail{Code: links.ErrorCodeInvalidQueryParameter, Message: err.Error(), Field: paramErr.name}
+	}
+	var urlErr *helpers.URLError
+	if errors.As(err, &urlErr) {
+		return http.StatusBadRequest, links.ErrorDetail{Code: links.ErrorCodeInvalidURL, Message: err.Error(), Field: "urlsFile", Line: urlErr.Line}
+	}
+
+	for _, c := range errorCodes {
+		if !errors.Is(err, c.err) {
+			continue
+		}
+		message := c.err.Error()
+		if c.status == http.StatusBadRequest || c.status == http.StatusTooManyRequests {
+			message = err.Error()
+		}
+		return c.status, links.ErrorDetail{Code: c.code, Message: message, Field: c.field}
+	}
+
+	// generic response to not leak details for all other errors
+	return http.StatusInternalServerError, links.ErrorDetail{Code: links.ErrorCodeInternal, Message: links.ErrInternalServerError.Error()}
+}
+
+// pagination - metadata of a page of a cursor paginated listing
+func pagination(limit int, nextCursor string) *links.Pagination {
+	return &links.Pagination{Limit: limit, NextCursor: nextCursor, HasMore: nextCursor != ""}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// IssueAPIKey - same body as the v1 handler, the data is the issued key
+func (v apiV2) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req links.APIKeyRequest
+	if err := decodeStrict(r, &req); err != nil {
+		renderErrorV2(w, r, ErrInvalidAPIKeyRequest)
+		return
+	}
+
+	issued, err := v.apiKeys.IssueAPIKey(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	respondV2(w, r, http.StatusCreated, issued, nil)
+}
+
+// ListAPIKeys - the data is the list of all api keys
+func (v apiV2) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := v.apiKeys.ListAPIKeys(r.Context())
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if keys.Keys == nil {
+		keys.Keys = []links.APIKey{}
+	}
+	respondV2(w, r, http.StatusOK, keys.Keys, nil)
+}
+
+// RevokeAPIKey - responds with 204, the batches created with the key are kept
+func (v apiV2) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := v.apiKeys.RevokeAPIKey(r.Context(), chi.URLParam(r, "keyID")); err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
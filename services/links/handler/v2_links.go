@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/go-chi/chi/v5"
+)
+
+// retriedBatch - data of a retry, the batch with its updated summary and the retried results
+type retriedBatch struct {
+	Batch   links.Batch    `json:"batch"`
+	Results []links.Result `json:"results"`
+}
+
+// ProcessBatch - same form as the v1 handler, the data is the list of results
+// or the running batch with `async=true`
+func (v apiV2) ProcessBatch(w http.ResponseWriter, r *http.Request) {
+	req, err := readBatchRequest(r)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	req.APIKeyID = apiKeyID(r.Context())
+
+	if r.URL.Query().Get("async") == "true" {
+		batch, err := v.linksProcessor.StartBatch(r.Context(), req)
+		if err != nil {
+			renderErrorV2(w, r, err)
+			return
+		}
+		respondV2(w, r, http.StatusAccepted, batch, nil)
+		return
+	}
+
+	results, err := v.linksProcessor.ProcessBatch(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if results == nil {
+		results = []links.Result{}
+	}
+	respondV2(w, r, http.StatusOK, results, nil)
+}
+
+// ListBatches - the data is one page of batches, see parseBatchQuery for the query parameters.
+// Batches are scoped to the api key of the request as on v1.
+func (v apiV2) ListBatches(w http.ResponseWriter, r *http.Request) {
+	query, err := parseBatchQuery(r)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	scopeToAPIKey(r.Context(), &query)
+
+	page, err := v.linksProcessor.ListBatches(r.Context(), links.ListBatchesRequest{Query: query})
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if page.Batches == nil {
+		page.Batches = []links.Batch{}
+	}
+	respondV2(w, r, http.StatusOK, page.Batches, pagination(query.Limit, page.NextCursor))
+}
+
+// GetBatch - the data is one page of results of the batch, see parseResultQuery for the query parameters.
+// CSV and NDJSON are exported as on v1.
+func (v apiV2) GetBatch(w http.ResponseWriter, r *http.Request) {
+	batchID := chi.URLParam(r, "batchID")
+
+	query, err := parseResultQuery(r)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+
+	format, err := parseFormat(r, formatJSON, formatCSV, formatNDJSON)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if format != formatJSON {
+		v.exportBatch(w, r, batchID, query, format, renderErrorV2)
+		return
+	}
+
+	page, err := v.linksProcessor.GetBatch(r.Context(), links.GetBatchRequest{BatchID: batchID, Query: query})
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if page.Results == nil {
+		page.Results = []links.Result{}
+	}
+	respondV2(w, r, http.StatusOK, page.Results, pagination(query.Limit, page.NextCursor))
+}
+
+// GetResult - the data is the result, under /links/{batchID} it must belong to the batch
+func (v apiV2) GetResult(w http.ResponseWriter, r *http.Request) {
+	req := links.GetResultRequest{BatchID: chi.URLParam(r, "batchID"), ResultID: chi.URLParam(r, "resultID")}
+
+	result, err := v.linksProcessor.GetResult(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	respondV2(w, r, http.StatusOK, result, nil)
+}
+
+// RetryBatch - same body as the v1 handler, the data is the batch and the retried results
+func (v apiV2) RetryBatch(w http.ResponseWriter, r *http.Request) {
+	var req links.RetryBatchRequest
+	if err := decodeStrict(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		renderErrorV2(w, r, ErrInvalidRetryRequest)
+		return
+	}
+	req.BatchID = chi.URLParam(r, "batchID") // the path wins over a batch id in the body
+	req.APIKeyID = apiKeyID(r.Context())
+
+	retried, err := v.linksProcessor.RetryBatch(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if retried.Results == nil {
+		retried.Results = []links.Result{}
+	}
+	respondV2(w, r, http.StatusOK, retriedBatch{Batch: retried.Batch, Results: retried.Results}, nil)
+}
+
+// DiffBatches - the data is the diff of the batches, CSV is exported as on v1
+func (v apiV2) DiffBatches(w http.ResponseWriter, r *http.Request) {
+	format, err := parseFormat(r, formatJSON, formatCSV)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+
+	req := links.DiffBatchesRequest{BaseBatchID: chi.URLParam(r, "batchID"), TargetBatchID: chi.URLParam(r, "targetBatchID")}
+	diff, err := v.linksProcessor.DiffBatches(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+
+	if format == formatCSV {
+		writeDiffAttachment(w, req, diff)
+		return
+	}
+	respondV2(w, r, http.StatusOK, diff, nil)
+}
+
+// ListCallbackAttempts - the data is the list of callback delivery attempts of the batch, oldest first
+func (v apiV2) ListCallbackAttempts(w http.ResponseWriter, r *http.Request) {
+	attempts, err := v.linksProcessor.ListCallbackAttempts(r.Context(), chi.URLParam(r, "batchID"))
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if attempts.Attempts == nil {
+		attempts.Attempts = []links.CallbackAttempt{}
+	}
+	respondV2(w, r, http.StatusOK, attempts.Attempts, nil)
+}
+
+// WatchBatch - the same Server-Sent Events as on v1, errors before the stream starts are v2 errors
+func (v apiV2) WatchBatch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		renderErrorV2(w, r, ErrStreamingUnsupported)
+		return
+	}
+
+	events, err := v.linksProcessor.WatchBatch(r.Context(), chi.URLParam(r, "batchID"))
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	streamEvents(w, flusher, chi.URLParam(r, "batchID"), events)
+}
+
+// ReportBatch - the same html report as on v1, errors are v2 errors
+func (v apiV2) ReportBatch(w http.ResponseWriter, r *http.Request) {
+	batchID := chi.URLParam(r, "batchID")
+
+	report, err := v.linksProcessor.ReportBatch(r.Context(), batchID)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+
+	page := &bytes.Buffer{}
+	if err := reportTemplate.Execute(page, report); err != nil {
+		renderErrorV2(w, r, fmt.Errorf("failed to render report of batch %s %w", batchID, err))
+		return
+	}
+	writeReport(w, batchID, page)
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CreateMonitor - same body as the v1 handler, the data is the created monitor
+func (v apiV2) CreateMonitor(w http.ResponseWriter, r *http.Request) {
+	var req links.MonitorRequest
+	if err := decodeStrict(r, &req); err != nil {
+		renderErrorV2(w, r, ErrInvalidMonitorRequest)
+		return
+	}
+
+	req.APIKeyID = apiKeyID(r.Context())
+
+	monitor, err := v.monitors.CreateMonitor(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	respondV2(w, r, http.StatusCreated, monitor, nil)
+}
+
+// ListMonitors - the data is the list of all monitors
+func (v apiV2) ListMonitors(w http.ResponseWriter, r *http.Request) {
+	monitors, err := v.monitors.ListMonitors(r.Context())
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if monitors.Monitors == nil {
+		monitors.Monitors = []links.Monitor{}
+	}
+	respondV2(w, r, http.StatusOK, monitors.Monitors, nil)
+}
+
+// GetMonitor - the data is the monitor
+func (v apiV2) GetMonitor(w http.ResponseWriter, r *http.Request) {
+	monitor, err := v.monitors.GetMonitor(r.Context(), chi.URLParam(r, "monitorID"))
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	respondV2(w, r, http.StatusOK, monitor, nil)
+}
+
+// UpdateMonitor - same body as CreateMonitor, the data is the updated monitor
+func (v apiV2) UpdateMonitor(w http.ResponseWriter, r *http.Request) {
+	var req links.MonitorRequest
+	if err := decodeStrict(r, &req); err != nil {
+		renderErrorV2(w, r, ErrInvalidMonitorRequest)
+		return
+	}
+	req.MonitorID = chi.URLParam(r, "monitorID")
+
+	monitor, err := v.monitors.UpdateMonitor(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	respondV2(w, r, http.StatusOK, monitor, nil)
+}
+
+// DeleteMonitor - responds with 204, the batches of the runs of the monitor are kept
+func (v apiV2) DeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	if err := v.monitors.DeleteMonitor(r.Context(), chi.URLParam(r, "monitorID")); err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// RunMonitor - the data is the list of results of the run
+func (v apiV2) RunMonitor(w http.ResponseWriter, r *http.Request) {
+	results, err := v.monitors.RunMonitor(r.Context(), chi.URLParam(r, "monitorID"))
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if results == nil {
+		results = []links.Result{}
+	}
+	respondV2(w, r, http.StatusOK, results, nil)
+}
+
+// CreateAlertRule - same body as the v1 handler, the data is the created rule
+func (v apiV2) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req links.AlertRuleRequest
+	if err := decodeStrict(r, &req); err != nil {
+		renderErrorV2(w, r, ErrInvalidAlertRuleRequest)
+		return
+	}
+	req.MonitorID = chi.URLParam(r, "monitorID")
+
+	rule, err := v.monitors.CreateAlertRule(r.Context(), req)
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	respondV2(w, r, http.StatusCreated, rule, nil)
+}
+
+// ListAlertRules - the data is the list of alert rules of the monitor
+func (v apiV2) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := v.monitors.ListAlertRules(r.Context(), chi.URLParam(r, "monitorID"))
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if rules.Rules == nil {
+		rules.Rules = []links.AlertRule{}
+	}
+	respondV2(w, r, http.StatusOK, rules.Rules, nil)
+}
+
+// DeleteAlertRule - responds with 204, the alerts of the rule are kept
+func (v apiV2) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	if err := v.monitors.DeleteAlertRule(r.Context(), chi.URLParam(r, "monitorID"), chi.URLParam(r, "ruleID")); err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// ListAlerts - the data is the alert history, newest first.
+// Supports the monitor_id, rule_id and limit query parameters.
+func (v apiV2) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit, err := parseLimit(params.Get("limit"))
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+
+	alerts, err := v.monitors.ListAlerts(r.Context(), links.AlertQuery{
+		MonitorID: params.Get("monitor_id"),
+		RuleID:    params.Get("rule_id"),
+		Limit:     limit,
+	})
+	if err != nil {
+		renderErrorV2(w, r, err)
+		return
+	}
+	if alerts.Alerts == nil {
+		alerts.Alerts = []links.Alert{}
+	}
+	respondV2(w, r, http.StatusOK, alerts.Alerts, nil)
+}
+
+// decodeStrict - decodes the JSON body into v, unknown fields are an error
+func decodeStrict(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
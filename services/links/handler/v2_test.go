@@ -0,0 +1,266 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/handler"
+	"github.com/Lockwarr/codefi/services/links/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *handlerTestSuite) TestV2RequestID_DifferentCases_ThenItIsEchoed() {
+	testCases := []struct {
+		name       string
+		requestID  string
+		expectSent bool
+	}{
+		{name: "sent by the client", requestID: "req-42", expectSent: true},
+		{name: "missing", requestID: ""},
+		{name: "too long", requestID: strings.Repeat("x", 129)},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v2/monitors", nil)
+			if tc.requestID != "" {
+				req.Header.Set(handler.RequestIDHeader, tc.requestID)
+			}
+			s.mockMonitors.On("ListMonitors").Return(links.ListMonitorsResponse{}, nil)
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+			// Assert
+			var response links.Envelope
+			s.Equal(http.StatusOK, rr.Code)
+			s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+			s.NotEmpty(response.RequestID)
+			s.Equal(response.RequestID, rr.Header().Get(handler.RequestIDHeader))
+			if tc.expectSent {
+				s.Equal(tc.requestID, response.RequestID)
+			} else {
+				s.NotEqual(tc.requestID, response.RequestID)
+			}
+			s.ResetMocks()
+		})
+	}
+}
+
+func (s *handlerTestSuite) TestV2GetBatch_WhenThereAreMoreResults_ThenPaginationIsReturned() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v2/links/b0?limit=1", nil)
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{BatchID: "b0", Query: links.ResultQuery{Limit: 1}}).
+		Return(links.GetBatchResponse{Results: []links.Result{{ID: "r0", BatchID: "b0", Success: true}}, NextCursor: "c1"}, nil)
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+	// Assert
+	var response struct {
+		RequestID  string            `json:"request_id"`
+		Data       []links.Result    `json:"data"`
+		Pagination *links.Pagination `json:"pagination"`
+	}
+	s.Equal(http.StatusOK, rr.Code)
+	s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	s.Len(response.Data, 1)
+	s.Equal("r0", response.Data[0].ID)
+	s.Equal(&links.Pagination{Limit: 1, NextCursor: "c1", HasMore: true}, response.Pagination)
+	s.Contains(rr.Body.String(), `"pagination":{"limit":1,"next_cursor":"c1","has_more":true}`)
+}
+
+func (s *handlerTestSuite) TestV2GetBatch_WhenNoLimitIsPassed_ThenDefaultPageIsReturned() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v2/links/b0", nil)
+	s.mockLinkProcessor.On("GetBatch", links.GetBatchRequest{BatchID: "b0", Query: links.ResultQuery{Limit: 50}}).
+		Return(links.GetBatchResponse{Results: []links.Result{{ID: "r0", BatchID: "b0", Success: true}}, NextCursor: "c1"}, nil)
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+	// Assert
+	s.Equal(http.StatusOK, rr.Code)
+	s.Contains(rr.Body.String(), `"pagination":{"limit":50,"next_cursor":"c1","has_more":true}`)
+	s.mockLinkProcessor.AssertExpectations(s.T())
+}
+
+func (s *handlerTestSuite) TestV2ListBatches_WhenThereAreNoBatches_ThenDataIsAnEmptyList() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v2/links", nil)
+	s.mockLinkProcessor.On("ListBatches", mock.Anything).Return(links.ListBatchesResponse{}, nil)
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+	// Assert
+	s.Equal(http.StatusOK, rr.Code)
+	s.Contains(rr.Body.String(), `"data":[],"pagination":{"limit":50,"has_more":false}`)
+}
+
+func (s *handlerTestSuite) TestV2RetryBatch_WhenResultsAreRetried_ThenKeysAreSnakeCase() {
+	// Arrange
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v2/links/b0/retry", nil)
+	s.mockLinkProcessor.On("RetryBatch", links.RetryBatchRequest{BatchID: "b0"}).
+		Return(links.RetryBatchResponse{Batch: links.Batch{ID: "b0"}}, nil)
+
+	// Act
+	handler.NewRouter(s.handler).ServeHTTP(rr, req)
+
+	// Assert
+	var response struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	s.Equal(http.StatusOK, rr.Code)
+	s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	s.Contains(response.Data, "batch")
+	s.Equal(`[]`, string(response.Data["results"]))
+}
+
+func (s *handlerTestSuite) TestV2_DifferentErrors_ThenTypedErrorsAreReturned() {
+	testCases := []struct {
+		name           string
+		req            *http.Request
+		arrange        func()
+		expectedStatus int
+		expectedError  links.ErrorDetail
+	}{
+		{
+			name:           "bad url in the file",
+			req:            createRequestWithAttachedFile("POST", "/api/v2/links", `testdata/badFile.txt`, false),
+			expectedStatus: http.StatusBadRequest,
+			expectedError: links.ErrorDetail{Code: links.ErrorCodeInvalidURL, Message: "bad url at line 1 just%20not%20an%20url invalid url",
+				Field: "urlsFile", Line: 1},
+		},
+		{
+			name:           "missing file",
+			req:            httptest.NewRequest("POST", "/api/v2/links", nil),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeInvalidFile, Message: handler.ErrRetrievingFile.Error(), Field: "urlsFile"},
+		},
+		{
+			name:           "empty file",
+			req:            createRequestWithAttachedFile("POST", "/api/v2/links", `testdata/emptyFile.txt`, false),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeNoURLs, Message: handler.ErrNoUrlsForProcessing.Error(), Field: "urlsFile"},
+		},
+		{
+			name: "invalid scrape options",
+			req:  createRequestWithAttachedFileAndOptions("POST", "/api/v2/links", `testdata/testFile.txt`, `{"timeout_ms": -1}`),
+			arrange: func() {
+				s.mockLinkProcessor.On("ProcessBatch", mock.Anything).
+					Return([]links.Result(nil), fmt.Errorf("%w timeout_ms must be positive", scraper.ErrInvalidOptions))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError: links.ErrorDetail{Code: links.ErrorCodeInvalidOptions,
+				Message: fmt.Sprintf("%s timeout_ms must be positive", scraper.ErrInvalidOptions), Field: "options"},
+		},
+		{
+			name:           "limit out of range",
+			req:            httptest.NewRequest("GET", "/api/v2/links?limit=0", nil),
+			expectedStatus: http.StatusBadRequest,
+			expectedError: links.ErrorDetail{Code: links.ErrorCodeInvalidQueryParameter,
+				Message: "invalid query parameter limit, expected a number between 1 and 500", Field: "limit"},
+		},
+		{
+			name:           "unknown export column",
+			req:            httptest.NewRequest("GET", "/api/v2/links/b0?format=csv&columns=id,size", nil),
+			expectedStatus: http.StatusBadRequest,
+			expectedError: links.ErrorDetail{Code: links.ErrorCodeInvalidQueryParameter,
+				Message: `invalid query parameter columns, unknown column "size"`, Field: "columns"},
+		},
+		{
+			name: "invalid cursor",
+			req:  httptest.NewRequest("GET", "/api/v2/links/b0?cursor=nope", nil),
+			arrange: func() {
+				s.mockLinkProcessor.On("GetBatch", mock.Anything).Return(links.GetBatchResponse{}, repository.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeInvalidCursor, Message: repository.ErrInvalidCursor.Error(), Field: "cursor"},
+		},
+		{
+			name: "batch not found",
+			req:  httptest.NewRequest("GET", "/api/v2/links/b0/callbacks", nil),
+			arrange: func() {
+				s.mockLinkProcessor.On("ListCallbackAttempts", "b0").Return(links.ListCallbackAttemptsResponse{}, repository.ErrBatchNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeBatchNotFound, Message: repository.ErrBatchNotFound.Error()},
+		},
+		{
+			name: "batch in progress",
+			req:  httptest.NewRequest("GET", "/api/v2/links/b0/report", nil),
+			arrange: func() {
+				s.mockLinkProcessor.On("ReportBatch", "b0").Return(links.BatchReport{}, links.ErrBatchInProgress)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeBatchInProgress, Message: links.ErrBatchInProgress.Error()},
+		},
+		{
+			name:           "unknown field of a monitor",
+			req:            httptest.NewRequest("POST", "/api/v2/monitors", strings.NewReader(`{"name": "weekly scan", "cron": "@weekly"}`)),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeInvalidRequestBody, Message: handler.ErrInvalidMonitorRequest.Error()},
+		},
+		{
+			name:           "monitor not found",
+			req:            httptest.NewRequest("DELETE", "/api/v2/monitors/m0", nil),
+			arrange:        func() { s.mockMonitors.On("DeleteMonitor", "m0").Return(repository.ErrMonitorNotFound) },
+			expectedStatus: http.StatusNotFound,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeMonitorNotFound, Message: repository.ErrMonitorNotFound.Error()},
+		},
+		{
+			name: "invalid alert rule",
+			req:  httptest.NewRequest("POST", "/api/v2/monitors/m0/rules", strings.NewReader(`{"name": "drops"}`)),
+			arrange: func() {
+				s.mockMonitors.On("CreateAlertRule", mock.Anything).Return(links.AlertRule{}, fmt.Errorf("%w, condition is required", links.ErrInvalidAlertRule))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError: links.ErrorDetail{Code: links.ErrorCodeInvalidAlertRule,
+				Message: fmt.Sprintf("%s, condition is required", links.ErrInvalidAlertRule)},
+		},
+		{
+			name: "internal error",
+			req:  httptest.NewRequest("GET", "/api/v2/alerts", nil),
+			arrange: func() {
+				s.mockMonitors.On("ListAlerts", mock.Anything).Return(links.ListAlertsResponse{}, errors.New("disk full"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  links.ErrorDetail{Code: links.ErrorCodeInternal, Message: links.ErrInternalServerError.Error()},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Arrange
+			rr := httptest.NewRecorder()
+			if tc.arrange != nil {
+				tc.arrange()
+			}
+
+			// Act
+			handler.NewRouter(s.handler).ServeHTTP(rr, tc.req)
+
+			// Assert
+			var response links.Envelope
+			s.Equal(tc.expectedStatus, rr.Code)
+			s.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+			s.Equal([]links.ErrorDetail{tc.expectedError}, response.Errors)
+			s.Nil(response.Data)
+			s.NotEmpty(response.RequestID)
+			s.mockLinkProcessor.AssertExpectations(s.T())
+			s.mockMonitors.AssertExpectations(s.T())
+			s.ResetMocks()
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "links"
+
+// MaxFetchHosts - hosts with their own fetch duration series, the fetches of any further host are
+// observed under otherHost so scraping many distinct hosts doesn't grow the series without bound
+const MaxFetchHosts = 100
+
+// otherHost - host label of the fetches of the hosts over MaxFetchHosts
+const otherHost = "other"
+
+// Metrics - prometheus metrics of the links service. It observes the batches of the processor,
+// the fetches of the scraper and the operations of the repository, see Handler for exposing them.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpDuration       *prometheus.HistogramVec
+	batches            *prometheus.CounterVec
+	batchDuration      *prometheus.HistogramVec
+	rejectedBatches    *prometheus.CounterVec
+	scrapes            *prometheus.CounterVec
+	fetchDuration      *prometheus.HistogramVec
+	fetchesInFlight    prometheus.Gauge
+	repositoryDuration *prometheus.HistogramVec
+
+	hostsMu    sync.Mutex
+	fetchHosts map[string]bool // hosts with their own fetch duration series
+}
+
+// New - metrics registered in their own registry together with the go and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry:   prometheus.NewRegistry(),
+		fetchHosts: map[string]bool{},
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the http requests by route pattern, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		batches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "batches_total",
+			Help:      "Batches processed by their final status.",
+		}, []string{"status"}),
+		batchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "batch_duration_seconds",
+			Help:      "Time to process a batch by its final status.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+		}, []string{"status"}),
+		rejectedBatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "batches_rejected_total",
+			Help:      "Batches rejected by an api key quota (quota_exceeded) or because the service is busy (server_busy).",
+		}, []string{"reason"}),
+		scrapes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scrapes_total",
+			Help:      "Pages scraped, including retries, by outcome and error category.",
+		}, []string{"outcome", "error_category"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "Time to fetch and parse a page by host and outcome, the hosts after the first " + strconv.Itoa(MaxFetchHosts) + " are labelled other.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"host", "outcome"}),
+		fetchesInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "fetches_in_flight",
+			Help:      "Pages being fetched right now.",
+		}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_duration_seconds",
+			Help:      "Latency of the repository operations by operation and outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}, []string{"operation", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.batches,
+		m.batchDuration,
+		m.rejectedBatches,
+		m.scrapes,
+		m.fetchDuration,
+		m.fetchesInFlight,
+		m.repositoryDuration,
+	)
+	return m
+}
+
+// Handler - serves the metrics in the prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// WatchAdmission - exports the queue of the admission controller, read whenever the metrics are scraped
+func (m *Metrics) WatchAdmission(admission *domain.AdmissionController) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queued_batches",
+			Help:      "Batches admitted and not finished yet.",
+		}, func() float64 { return float64(admission.Stats().QueuedBatches) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queued_urls",
+			Help:      "Pages of admitted batches waiting for a fetch slot.",
+		}, func() float64 { return float64(admission.Stats().WaitingURLs) }),
+	)
+}
+
+// ObserveHTTPRequest - a served request, route is the pattern it was routed by
+func (m *Metrics) ObserveHTTPRequest(route, method string, status int, duration time.Duration) {
+	m.httpDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// BatchRejected - see links.BatchObserver
+func (m *Metrics) BatchRejected(err error) {
+	reason := links.ErrorCodeServerBusy
+	if errors.Is(err, links.ErrQuotaExceeded) {
+		reason = links.ErrorCodeQuotaExceeded
+	}
+	m.rejectedBatches.WithLabelValues(reason).Inc()
+}
+
+// BatchFinished - see links.BatchObserver
+func (m *Metrics) BatchFinished(batch links.Batch, duration time.Duration) {
+	m.batches.WithLabelValues(string(batch.Status)).Inc()
+	m.batchDuration.WithLabelValues(string(batch.Status)).Observe(duration.Seconds())
+}
+
+// PageScraped - see links.BatchObserver
+func (m *Metrics) PageScraped(result links.Result) {
+	if result.Success {
+		m.scrapes.WithLabelValues("success", "").Inc()
+		return
+	}
+	category, _ := scraper.ClassifyError(result.Error)
+	if category == "" { // failed without an error, e.g. a result read back from the repository
+		category = scraper.ErrorCategoryOther
+	}
+	m.scrapes.WithLabelValues("failure", string(category)).Inc()
+}
+
+// FetchStarted - see scraper.FetchObserver
+func (m *Metrics) FetchStarted(pageURL *url.URL) {
+	m.fetchesInFlight.Inc()
+}
+
+// FetchFinished - see scraper.FetchObserver
+func (m *Metrics) FetchFinished(pageURL *url.URL, duration time.Duration, err error) {
+	m.fetchesInFlight.Dec()
+	m.fetchDuration.WithLabelValues(m.fetchHost(pageURL), outcomeOf(err)).Observe(duration.Seconds())
+}
+
+// fetchHost - host label of a fetch, the first MaxFetchHosts distinct hosts keep their own label
+func (m *Metrics) fetchHost(pageURL *url.URL) string {
+	host := strings.ToLower(pageURL.Hostname())
+
+	m.hostsMu.Lock()
+	defer m.hostsMu.Unlock()
+	if m.fetchHosts[host] {
+		return host
+	}
+	if len(m.fetchHosts) >= MaxFetchHosts {
+		return otherHost
+	}
+	m.fetchHosts[host] = true
+	return host
+}
+
+// ObserveRepository - a repository operation, see repository.NewInstrumentedRepository
+func (m *Metrics) ObserveRepository(operation string, duration time.Duration, err error) {
+	m.repositoryDuration.WithLabelValues(operation, outcomeOf(err)).Observe(duration.Seconds())
+}
+
+// outcomeOf - outcome label of an operation
+func outcomeOf(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
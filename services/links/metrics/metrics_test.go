@@ -0,0 +1,102 @@
+package metrics_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lockwarr/codefi/pkg/scraper"
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestBatchRejected(t *testing.T) {
+	// Arrange
+	m := metrics.New()
+
+	// Act
+	m.BatchRejected(&links.QuotaError{Quota: "urls_per_day", Limit: 10})
+	m.BatchRejected(links.ErrServerBusy)
+	m.BatchRejected(&links.CapacityError{Limit: "queued_batches", Max: 5})
+
+	// Assert
+	body := scrape(t, m)
+	assert.Contains(t, body, `links_batches_rejected_total{reason="quota_exceeded"} 1`)
+	assert.Contains(t, body, `links_batches_rejected_total{reason="server_busy"} 2`)
+}
+
+func TestPageScraped(t *testing.T) {
+	// Arrange
+	m := metrics.New()
+
+	// Act
+	m.PageScraped(links.Result{Success: true})
+	m.PageScraped(links.Result{Error: &scraper.StatusError{StatusCode: 404}})
+	m.PageScraped(links.Result{})
+
+	// Assert
+	body := scrape(t, m)
+	assert.Contains(t, body, `links_scrapes_total{error_category="",outcome="success"} 1`)
+	assert.Contains(t, body, `links_scrapes_total{error_category="status",outcome="failure"} 1`)
+	assert.Contains(t, body, `links_scrapes_total{error_category="other",outcome="failure"} 1`)
+}
+
+func TestFetchFinished(t *testing.T) {
+	// Arrange
+	m := metrics.New()
+	google, _ := url.Parse("https://www.google.com/")
+	facebook, _ := url.Parse("https://www.facebook.com/")
+
+	// Act
+	m.FetchStarted(google)
+	m.FetchStarted(facebook)
+	m.FetchStarted(google)
+	m.FetchFinished(google, 200*time.Millisecond, nil)
+	m.FetchFinished(facebook, time.Second, errors.New("timeout"))
+
+	// Assert
+	body := scrape(t, m)
+	assert.Contains(t, body, `links_fetch_duration_seconds_count{host="www.google.com",outcome="success"} 1`)
+	assert.Contains(t, body, `links_fetch_duration_seconds_count{host="www.facebook.com",outcome="error"} 1`)
+	assert.Contains(t, body, `links_fetch_duration_seconds_bucket{host="www.google.com",outcome="success",le="0.25"} 1`)
+	assert.Contains(t, body, "links_fetches_in_flight 1")
+}
+
+func TestFetchFinished_WhenThereAreMoreHostsThanTracked_ThenTheRestAreOther(t *testing.T) {
+	// Arrange
+	m := metrics.New()
+	first, _ := url.Parse("https://host0.example.com/")
+	overflow, _ := url.Parse("https://overflow.example.com/")
+
+	// Act
+	for i := 0; i < metrics.MaxFetchHosts; i++ {
+		pageURL, _ := url.Parse(fmt.Sprintf("https://host%d.example.com/", i))
+		m.FetchStarted(pageURL)
+		m.FetchFinished(pageURL, time.Second, nil)
+	}
+	m.FetchStarted(overflow)
+	m.FetchFinished(overflow, time.Second, nil)
+	m.FetchStarted(first)
+	m.FetchFinished(first, time.Second, nil)
+
+	// Assert
+	body := scrape(t, m)
+	assert.Contains(t, body, `links_fetch_duration_seconds_count{host="host0.example.com",outcome="success"} 2`)
+	assert.Contains(t, body, fmt.Sprintf(`links_fetch_duration_seconds_count{host="host%d.example.com",outcome="success"} 1`, metrics.MaxFetchHosts-1))
+	assert.Contains(t, body, `links_fetch_duration_seconds_count{host="other",outcome="success"} 1`)
+	assert.NotContains(t, body, "overflow.example.com")
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) IssueAPIKey(ctx context.Context, req links.APIKeyRequest) (links.IssuedAPIKey, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.IssuedAPIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context) (links.ListAPIKeysResponse, error) {
+	args := m.Called()
+	return args.Get(0).(links.ListAPIKeysResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, keyID string) error {
+	args := m.Called(keyID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, key string) (links.APIKey, error) {
+	args := m.Called(key)
+	return args.Get(0).(links.APIKey), args.Error(1)
+}
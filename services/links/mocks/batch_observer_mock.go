@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBatchObserver struct {
+	mock.Mock
+}
+
+func (m *MockBatchObserver) BatchRejected(err error) {
+	m.Called(err)
+}
+
+func (m *MockBatchObserver) BatchFinished(batch links.Batch, duration time.Duration) {
+	m.Called(batch, duration)
+}
+
+func (m *MockBatchObserver) PageScraped(result links.Result) {
+	m.Called(result)
+}
@@ -16,7 +16,65 @@ func (m *MockLinksProcessor) ProcessBatch(ctx context.Context, req links.Process
 	return args.Get(0).([]links.Result), args.Error(1)
 }
 
-func (m *MockLinksProcessor) GetBatch(ctx context.Context, req links.GetBatchRequest) ([]links.Result, error) {
+func (m *MockLinksProcessor) GetBatch(ctx context.Context, req links.GetBatchRequest) (links.GetBatchResponse, error) {
 	args := m.Called(req)
-	return args.Get(0).([]links.Result), args.Error(1)
+	return args.Get(0).(links.GetBatchResponse), args.Error(1)
+}
+
+// ExportBatch - writes the mocked results, the mocked error is returned after them
+func (m *MockLinksProcessor) ExportBatch(ctx context.Context, req links.GetBatchRequest, write func(links.Result) error) error {
+	args := m.Called(req)
+	for _, result := range args.Get(0).([]links.Result) {
+		if err := write(result); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockLinksProcessor) ListBatches(ctx context.Context, req links.ListBatchesRequest) (links.ListBatchesResponse, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.ListBatchesResponse), args.Error(1)
+}
+
+func (m *MockLinksProcessor) GetResult(ctx context.Context, req links.GetResultRequest) (links.Result, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.Result), args.Error(1)
+}
+
+func (m *MockLinksProcessor) RetryBatch(ctx context.Context, req links.RetryBatchRequest) (links.RetryBatchResponse, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.RetryBatchResponse), args.Error(1)
+}
+
+func (m *MockLinksProcessor) DiffBatches(ctx context.Context, req links.DiffBatchesRequest) (links.BatchDiff, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.BatchDiff), args.Error(1)
+}
+
+func (m *MockLinksProcessor) ReportBatch(ctx context.Context, batchID string) (links.BatchReport, error) {
+	args := m.Called(batchID)
+	return args.Get(0).(links.BatchReport), args.Error(1)
+}
+
+func (m *MockLinksProcessor) ListCallbackAttempts(ctx context.Context, batchID string) (links.ListCallbackAttemptsResponse, error) {
+	args := m.Called(batchID)
+	return args.Get(0).(links.ListCallbackAttemptsResponse), args.Error(1)
+}
+
+func (m *MockLinksProcessor) StartBatch(ctx context.Context, req links.ProcessBatchRequest) (links.Batch, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.Batch), args.Error(1)
+}
+
+// WatchBatch - streams the mocked events, the channel is closed after the last one
+func (m *MockLinksProcessor) WatchBatch(ctx context.Context, batchID string) (<-chan links.BatchEvent, error) {
+	args := m.Called(batchID)
+	events := args.Get(0).([]links.BatchEvent)
+	stream := make(chan links.BatchEvent, len(events))
+	for _, event := range events {
+		stream <- event
+	}
+	close(stream)
+	return stream, args.Error(1)
 }
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMonitorService struct {
+	mock.Mock
+}
+
+func (m *MockMonitorService) CreateMonitor(ctx context.Context, req links.MonitorRequest) (links.Monitor, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.Monitor), args.Error(1)
+}
+
+func (m *MockMonitorService) GetMonitor(ctx context.Context, monitorID string) (links.Monitor, error) {
+	args := m.Called(monitorID)
+	return args.Get(0).(links.Monitor), args.Error(1)
+}
+
+func (m *MockMonitorService) UpdateMonitor(ctx context.Context, req links.MonitorRequest) (links.Monitor, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.Monitor), args.Error(1)
+}
+
+func (m *MockMonitorService) DeleteMonitor(ctx context.Context, monitorID string) error {
+	args := m.Called(monitorID)
+	return args.Error(0)
+}
+
+func (m *MockMonitorService) ListMonitors(ctx context.Context) (links.ListMonitorsResponse, error) {
+	args := m.Called()
+	return args.Get(0).(links.ListMonitorsResponse), args.Error(1)
+}
+
+func (m *MockMonitorService) RunMonitor(ctx context.Context, monitorID string) ([]links.Result, error) {
+	args := m.Called(monitorID)
+	return args.Get(0).([]links.Result), args.Error(1)
+}
+
+func (m *MockMonitorService) CreateAlertRule(ctx context.Context, req links.AlertRuleRequest) (links.AlertRule, error) {
+	args := m.Called(req)
+	return args.Get(0).(links.AlertRule), args.Error(1)
+}
+
+func (m *MockMonitorService) ListAlertRules(ctx context.Context, monitorID string) (links.ListAlertRulesResponse, error) {
+	args := m.Called(monitorID)
+	return args.Get(0).(links.ListAlertRulesResponse), args.Error(1)
+}
+
+func (m *MockMonitorService) DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error {
+	args := m.Called(monitorID, ruleID)
+	return args.Error(0)
+}
+
+func (m *MockMonitorService) ListAlerts(ctx context.Context, query links.AlertQuery) (links.ListAlertsResponse, error) {
+	args := m.Called(query)
+	return args.Get(0).(links.ListAlertsResponse), args.Error(1)
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAlertNotifier struct {
+	mock.Mock
+}
+
+func (m *MockAlertNotifier) Notify(ctx context.Context, sink links.AlertSink, alert links.Alert) error {
+	args := m.Called(sink, alert)
+	return args.Error(0)
+}
+
+type MockCallbackSender struct {
+	mock.Mock
+}
+
+func (m *MockCallbackSender) Send(ctx context.Context, callback links.Callback, event links.CallbackEvent) (int, error) {
+	args := m.Called(callback, event)
+	return args.Int(0), args.Error(1)
+}
@@ -11,17 +11,163 @@ type MockRepository struct {
 	mock.Mock
 }
 
+func (m *MockRepository) CreateBatch(ctx context.Context, batch links.Batch) error {
+	args := m.Called(batch)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetBatch(ctx context.Context, batchID string) (links.Batch, error) {
+	args := m.Called(batchID)
+	return args.Get(0).(links.Batch), args.Error(1)
+}
+
+func (m *MockRepository) UpdateBatch(ctx context.Context, batch links.Batch) error {
+	args := m.Called(batch)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateBatchIf(ctx context.Context, batch links.Batch, status links.BatchStatus) error {
+	args := m.Called(batch, status)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListBatches(ctx context.Context, query links.BatchQuery) (links.BatchPage, error) {
+	args := m.Called(query)
+	return args.Get(0).(links.BatchPage), args.Error(1)
+}
+
 func (m *MockRepository) CreateResults(ctx context.Context, results []links.Result) error {
 	args := m.Called(results)
 	return args.Error(0)
 }
 
+func (m *MockRepository) AppendResults(ctx context.Context, results []links.Result) error {
+	args := m.Called(results)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateResults(ctx context.Context, results []links.Result) error {
+	args := m.Called(results)
+	return args.Error(0)
+}
+
 func (m *MockRepository) GetBatchResults(ctx context.Context, batchID string) ([]links.Result, error) {
 	args := m.Called(batchID)
 	return args.Get(0).([]links.Result), args.Error(1)
 }
 
+func (m *MockRepository) ListBatchResults(ctx context.Context, batchID string, query links.ResultQuery) (links.ResultPage, error) {
+	args := m.Called(batchID, query)
+	return args.Get(0).(links.ResultPage), args.Error(1)
+}
+
+// EachBatchResult - calls fn with the mocked results, the mocked error is returned after them
+func (m *MockRepository) EachBatchResult(ctx context.Context, batchID string, query links.ResultQuery, fn func(links.Result) error) error {
+	args := m.Called(batchID, query)
+	for _, result := range args.Get(0).([]links.Result) {
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockRepository) GetResult(ctx context.Context, resultID string) (links.Result, error) {
+	args := m.Called(resultID)
+	return args.Get(0).(links.Result), args.Error(1)
+}
+
+func (m *MockRepository) GetBatchResult(ctx context.Context, batchID, resultID string) (links.Result, error) {
+	args := m.Called(batchID, resultID)
+	return args.Get(0).(links.Result), args.Error(1)
+}
+
 func (m *MockRepository) ListResults(ctx context.Context) map[string][]links.Result {
 	args := m.Called()
 	return args.Get(0).(map[string][]links.Result)
 }
+
+func (m *MockRepository) CreateMonitor(ctx context.Context, monitor links.Monitor) error {
+	args := m.Called(monitor)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetMonitor(ctx context.Context, monitorID string) (links.Monitor, error) {
+	args := m.Called(monitorID)
+	return args.Get(0).(links.Monitor), args.Error(1)
+}
+
+func (m *MockRepository) UpdateMonitor(ctx context.Context, monitor links.Monitor) error {
+	args := m.Called(monitor)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteMonitor(ctx context.Context, monitorID string) error {
+	args := m.Called(monitorID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListMonitors(ctx context.Context) ([]links.Monitor, error) {
+	args := m.Called()
+	return args.Get(0).([]links.Monitor), args.Error(1)
+}
+
+func (m *MockRepository) CreateAlertRule(ctx context.Context, rule links.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListAlertRules(ctx context.Context, monitorID string) ([]links.AlertRule, error) {
+	args := m.Called(monitorID)
+	return args.Get(0).([]links.AlertRule), args.Error(1)
+}
+
+func (m *MockRepository) DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error {
+	args := m.Called(monitorID, ruleID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateAlerts(ctx context.Context, alerts []links.Alert) error {
+	args := m.Called(alerts)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListAlerts(ctx context.Context, query links.AlertQuery) ([]links.Alert, error) {
+	args := m.Called(query)
+	return args.Get(0).([]links.Alert), args.Error(1)
+}
+
+func (m *MockRepository) CreateCallbackAttempt(ctx context.Context, attempt links.CallbackAttempt) error {
+	args := m.Called(attempt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListCallbackAttempts(ctx context.Context, batchID string) ([]links.CallbackAttempt, error) {
+	args := m.Called(batchID)
+	return args.Get(0).([]links.CallbackAttempt), args.Error(1)
+}
+
+func (m *MockRepository) CreateAPIKey(ctx context.Context, key links.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetAPIKey(ctx context.Context, keyID string) (links.APIKey, error) {
+	args := m.Called(keyID)
+	return args.Get(0).(links.APIKey), args.Error(1)
+}
+
+func (m *MockRepository) GetAPIKeyByHash(ctx context.Context, hash string) (links.APIKey, error) {
+	args := m.Called(hash)
+	return args.Get(0).(links.APIKey), args.Error(1)
+}
+
+func (m *MockRepository) UpdateAPIKey(ctx context.Context, key links.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListAPIKeys(ctx context.Context) ([]links.APIKey, error) {
+	args := m.Called()
+	return args.Get(0).([]links.APIKey), args.Error(1)
+}
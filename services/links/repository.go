@@ -4,8 +4,35 @@ import "context"
 
 // Repository
 type Repository interface {
+	CreateBatch(ctx context.Context, batch Batch) error
+	GetBatch(ctx context.Context, batchID string) (Batch, error)
+	UpdateBatch(ctx context.Context, batch Batch) error
+	UpdateBatchIf(ctx context.Context, batch Batch, status BatchStatus) error
+	ListBatches(ctx context.Context, query BatchQuery) (BatchPage, error)
 	CreateResults(ctx context.Context, results []Result) error
+	AppendResults(ctx context.Context, results []Result) error
+	UpdateResults(ctx context.Context, results []Result) error
 	GetBatchResults(ctx context.Context, batchID string) ([]Result, error)
+	ListBatchResults(ctx context.Context, batchID string, query ResultQuery) (ResultPage, error)
+	EachBatchResult(ctx context.Context, batchID string, query ResultQuery, fn func(Result) error) error
+	GetResult(ctx context.Context, resultID string) (Result, error)
+	GetBatchResult(ctx context.Context, batchID, resultID string) (Result, error)
 	ListResults(ctx context.Context) map[string][]Result
-	// GetResult(ctx context.Context, resultID string) (Result, error) TODO with real db
+	CreateMonitor(ctx context.Context, monitor Monitor) error
+	GetMonitor(ctx context.Context, monitorID string) (Monitor, error)
+	UpdateMonitor(ctx context.Context, monitor Monitor) error
+	DeleteMonitor(ctx context.Context, monitorID string) error
+	ListMonitors(ctx context.Context) ([]Monitor, error)
+	CreateAlertRule(ctx context.Context, rule AlertRule) error
+	ListAlertRules(ctx context.Context, monitorID string) ([]AlertRule, error)
+	DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error
+	CreateAlerts(ctx context.Context, alerts []Alert) error
+	ListAlerts(ctx context.Context, query AlertQuery) ([]Alert, error)
+	CreateCallbackAttempt(ctx context.Context, attempt CallbackAttempt) error
+	ListCallbackAttempts(ctx context.Context, batchID string) ([]CallbackAttempt, error)
+	CreateAPIKey(ctx context.Context, key APIKey) error
+	GetAPIKey(ctx context.Context, keyID string) (APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	UpdateAPIKey(ctx context.Context, key APIKey) error
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
 }
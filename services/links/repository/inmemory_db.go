@@ -3,28 +3,138 @@ package repository
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/Lockwarr/codefi/services/links"
 )
 
 var (
-	ErrBatchNotFound = errors.New("batch of results not found")
+	ErrBatchNotFound     = errors.New("batch of results not found")
+	ErrResultNotFound    = errors.New("result not found")
+	ErrMonitorNotFound   = errors.New("monitor not found")
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
 )
 
 type inMemoryDB struct {
-	results map[string][]links.Result
-	rw      *sync.RWMutex
+	batches     map[string]links.Batch
+	results     map[string][]links.Result
+	resultIndex map[string]resultLocation // result id -> where the result is stored
+	monitors    map[string]links.Monitor
+	alertRules  map[string][]links.AlertRule       // monitor id -> rules
+	alerts      []links.Alert                      // oldest first
+	callbacks   map[string][]links.CallbackAttempt // batch id -> delivery attempts, oldest first
+	apiKeys     map[string]links.APIKey            // key id -> api key
+	apiKeyIndex map[string]string                  // key hash -> key id
+	rw          *sync.RWMutex
+}
+
+// resultLocation - batch and position of a stored result
+type resultLocation struct {
+	batchID  string
+	position int
 }
 
 // NewInMemoryDB ..
 func NewInMemoryDB() links.Repository {
-	return &inMemoryDB{results: map[string][]links.Result{}, rw: &sync.RWMutex{}}
+	return &inMemoryDB{
+		batches:     map[string]links.Batch{},
+		results:     map[string][]links.Result{},
+		resultIndex: map[string]resultLocation{},
+		monitors:    map[string]links.Monitor{},
+		alertRules:  map[string][]links.AlertRule{},
+		callbacks:   map[string][]links.CallbackAttempt{},
+		apiKeys:     map[string]links.APIKey{},
+		apiKeyIndex: map[string]string{},
+		rw:          &sync.RWMutex{},
+	}
+}
+
+// CreateBatch - save the batch together with the options it was processed with
+func (mem *inMemoryDB) CreateBatch(ctx context.Context, batch links.Batch) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if batch.ID == "" {
+		return errors.New("batch id is required")
+	}
+
+	mem.batches[batch.ID] = batch
+
+	return nil
+}
+
+// GetBatch - get batch by id, if it doesn't exists an error is returned
+func (mem *inMemoryDB) GetBatch(ctx context.Context, batchID string) (links.Batch, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	batch, ok := mem.batches[batchID]
+	if !ok {
+		return links.Batch{}, ErrBatchNotFound
+	}
+
+	return batch, nil
 }
 
-// ListResults - lists all results that we have so far
+// ListResults - lists all results that we have so far.
+// A copy is returned so callers can't race with writes to the database.
 func (mem *inMemoryDB) ListResults(ctx context.Context) map[string][]links.Result {
-	return mem.results
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	results := make(map[string][]links.Result, len(mem.results))
+	for batchID, batchResults := range mem.results {
+		results[batchID] = append([]links.Result(nil), batchResults...)
+	}
+
+	return results
+}
+
+// UpdateBatch - replace a stored batch, if it doesn't exists an error is returned
+func (mem *inMemoryDB) UpdateBatch(ctx context.Context, batch links.Batch) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if _, ok := mem.batches[batch.ID]; !ok {
+		return ErrBatchNotFound
+	}
+
+	mem.batches[batch.ID] = batch
+
+	return nil
+}
+
+// UpdateBatchIf - replace a stored batch only while it still has the given status,
+// otherwise links.ErrBatchStatusChanged is returned
+func (mem *inMemoryDB) UpdateBatchIf(ctx context.Context, batch links.Batch, status links.BatchStatus) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	stored, ok := mem.batches[batch.ID]
+	if !ok {
+		return ErrBatchNotFound
+	}
+	if stored.Status != status {
+		return links.ErrBatchStatusChanged
+	}
+
+	mem.batches[batch.ID] = batch
+
+	return nil
+}
+
+// ListBatches - one page of the batches matching the query
+func (mem *inMemoryDB) ListBatches(ctx context.Context, query links.BatchQuery) (links.BatchPage, error) {
+	mem.rw.RLock()
+	batches := make([]links.Batch, 0, len(mem.batches))
+	for _, batch := range mem.batches {
+		batches = append(batches, batch)
+	}
+	mem.rw.RUnlock()
+
+	return pageBatches(batches, query)
 }
 
 // CreateResults - assign batch id to the processed urls and save results
@@ -37,21 +147,433 @@ func (mem *inMemoryDB) CreateResults(ctx context.Context, results []links.Result
 	}
 
 	// we only call this function with urls processed in the same batch
-	mem.results[results[0].BatchID] = results
+	batchID := results[0].BatchID
+	for _, previous := range mem.results[batchID] {
+		delete(mem.resultIndex, previous.ID)
+	}
+	mem.results[batchID] = results
+	for i, result := range results {
+		mem.resultIndex[result.ID] = resultLocation{batchID: batchID, position: i}
+	}
+
+	return nil
+}
+
+// AppendResults - add results to the ones already saved for their batch, so a batch can be saved
+// in chunks while it is processed. Readers keep seeing the results saved before the append.
+func (mem *inMemoryDB) AppendResults(ctx context.Context, results []links.Result) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if len(results) == 0 {
+		return errors.New("no results were passed")
+	}
+
+	// we only call this function with urls processed in the same batch
+	batchID := results[0].BatchID
+	offset := len(mem.results[batchID])
+	mem.results[batchID] = append(mem.results[batchID], results...)
+	for i, result := range results {
+		mem.resultIndex[result.ID] = resultLocation{batchID: batchID, position: offset + i}
+	}
 
 	return nil
 }
 
+// UpdateResults - replace stored results with the same ids, if any of them doesn't exists nothing is updated.
+// The stored slices are copied before the update because readers page through them without holding the lock.
+func (mem *inMemoryDB) UpdateResults(ctx context.Context, results []links.Result) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	for _, result := range results {
+		location, ok := mem.resultIndex[result.ID]
+		if !ok || location.batchID != result.BatchID {
+			return ErrResultNotFound
+		}
+	}
+
+	updated := map[string][]links.Result{}
+	for _, result := range results {
+		location := mem.resultIndex[result.ID]
+		batchResults, ok := updated[location.batchID]
+		if !ok {
+			batchResults = append([]links.Result(nil), mem.results[location.batchID]...)
+			updated[location.batchID] = batchResults
+		}
+		batchResults[location.position] = result
+	}
+	for batchID, batchResults := range updated {
+		mem.results[batchID] = batchResults
+	}
+
+	return nil
+}
+
+// GetResult - get a single result by id regardless of its batch,
+// if it doesn't exists an error is returned
+func (r *inMemoryDB) GetResult(ctx context.Context, resultID string) (links.Result, error) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	location, ok := r.resultIndex[resultID]
+	if !ok {
+		return links.Result{}, ErrResultNotFound
+	}
+
+	return r.results[location.batchID][location.position], nil
+}
+
+// GetBatchResult - get a single result of the batch by id,
+// if the batch or the result in it doesn't exists an error is returned
+func (r *inMemoryDB) GetBatchResult(ctx context.Context, batchID, resultID string) (links.Result, error) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	if !r.hasBatch(batchID) {
+		return links.Result{}, ErrBatchNotFound
+	}
+
+	location, ok := r.resultIndex[resultID]
+	if !ok || location.batchID != batchID {
+		return links.Result{}, ErrResultNotFound
+	}
+
+	return r.results[batchID][location.position], nil
+}
+
 // GetBatchResults - get batch of processed urls by batch id
 // if it doesn't exists an error is returned
 func (r *inMemoryDB) GetBatchResults(ctx context.Context, batchID string) ([]links.Result, error) {
 	r.rw.RLock()
 	defer r.rw.RUnlock()
 
+	if !r.hasBatch(batchID) {
+		return nil, ErrBatchNotFound
+	}
 	results, ok := r.results[batchID]
 	if !ok {
-		return nil, ErrBatchNotFound
+		return []links.Result{}, nil
 	}
 
 	return results, nil
 }
+
+// ListBatchResults - one page of the batch results matching the query,
+// if the batch doesn't exists an error is returned
+func (r *inMemoryDB) ListBatchResults(ctx context.Context, batchID string, query links.ResultQuery) (links.ResultPage, error) {
+	r.rw.RLock()
+	results, ok := r.results[batchID], r.hasBatch(batchID)
+	r.rw.RUnlock()
+
+	if !ok {
+		return links.ResultPage{}, ErrBatchNotFound
+	}
+
+	return pageResults(results, query)
+}
+
+// EachBatchResult - calls fn for every result of the batch matching the query, in the order of the query.
+// The results are filtered and sorted once, the limit and cursor of the query are ignored.
+// Iterating stops at the first error of fn, which is returned.
+func (r *inMemoryDB) EachBatchResult(ctx context.Context, batchID string, query links.ResultQuery, fn func(links.Result) error) error {
+	r.rw.RLock()
+	results, ok := r.results[batchID], r.hasBatch(batchID)
+	r.rw.RUnlock()
+
+	if !ok {
+		return ErrBatchNotFound
+	}
+
+	sorted, err := sortResults(results, query)
+	if err != nil {
+		return err
+	}
+	for _, res := range sorted {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(res.result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasBatch - whether the batch was created or has results, a running batch may have none saved yet.
+// Must be called with the lock held.
+func (r *inMemoryDB) hasBatch(batchID string) bool {
+	if _, ok := r.batches[batchID]; ok {
+		return true
+	}
+	_, ok := r.results[batchID]
+	return ok
+}
+
+// CreateMonitor - save the monitor definition
+func (mem *inMemoryDB) CreateMonitor(ctx context.Context, monitor links.Monitor) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if monitor.ID == "" {
+		return errors.New("monitor id is required")
+	}
+
+	mem.monitors[monitor.ID] = monitor
+
+	return nil
+}
+
+// GetMonitor - get monitor by id, if it doesn't exists an error is returned
+func (mem *inMemoryDB) GetMonitor(ctx context.Context, monitorID string) (links.Monitor, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	monitor, ok := mem.monitors[monitorID]
+	if !ok {
+		return links.Monitor{}, ErrMonitorNotFound
+	}
+
+	return monitor, nil
+}
+
+// UpdateMonitor - replace a stored monitor, if it doesn't exists an error is returned
+func (mem *inMemoryDB) UpdateMonitor(ctx context.Context, monitor links.Monitor) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if _, ok := mem.monitors[monitor.ID]; !ok {
+		return ErrMonitorNotFound
+	}
+
+	mem.monitors[monitor.ID] = monitor
+
+	return nil
+}
+
+// DeleteMonitor - delete monitor by id together with its alert rules, the batches of its runs
+// and its alerts are kept. If it doesn't exists an error is returned
+func (mem *inMemoryDB) DeleteMonitor(ctx context.Context, monitorID string) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if _, ok := mem.monitors[monitorID]; !ok {
+		return ErrMonitorNotFound
+	}
+
+	delete(mem.monitors, monitorID)
+	delete(mem.alertRules, monitorID)
+
+	return nil
+}
+
+// ListMonitors - all monitors, oldest first
+func (mem *inMemoryDB) ListMonitors(ctx context.Context) ([]links.Monitor, error) {
+	mem.rw.RLock()
+	monitors := make([]links.Monitor, 0, len(mem.monitors))
+	for _, monitor := range mem.monitors {
+		monitors = append(monitors, monitor)
+	}
+	mem.rw.RUnlock()
+
+	sort.Slice(monitors, func(i, j int) bool {
+		if !monitors[i].CreatedAt.Equal(monitors[j].CreatedAt) {
+			return monitors[i].CreatedAt.Before(monitors[j].CreatedAt)
+		}
+		return monitors[i].ID < monitors[j].ID
+	})
+
+	return monitors, nil
+}
+
+// CreateAlertRule - save an alert rule of an existing monitor
+func (mem *inMemoryDB) CreateAlertRule(ctx context.Context, rule links.AlertRule) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if rule.ID == "" {
+		return errors.New("alert rule id is required")
+	}
+	if _, ok := mem.monitors[rule.MonitorID]; !ok {
+		return ErrMonitorNotFound
+	}
+
+	// copy on write, the rules are read without holding the lock
+	rules := append([]links.AlertRule(nil), mem.alertRules[rule.MonitorID]...)
+	mem.alertRules[rule.MonitorID] = append(rules, rule)
+
+	return nil
+}
+
+// ListAlertRules - alert rules of a monitor, oldest first
+func (mem *inMemoryDB) ListAlertRules(ctx context.Context, monitorID string) ([]links.AlertRule, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	if _, ok := mem.monitors[monitorID]; !ok {
+		return nil, ErrMonitorNotFound
+	}
+
+	return append([]links.AlertRule{}, mem.alertRules[monitorID]...), nil
+}
+
+// DeleteAlertRule - delete an alert rule of a monitor, if it doesn't exists an error is returned
+func (mem *inMemoryDB) DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if _, ok := mem.monitors[monitorID]; !ok {
+		return ErrMonitorNotFound
+	}
+
+	rules := make([]links.AlertRule, 0, len(mem.alertRules[monitorID]))
+	for _, rule := range mem.alertRules[monitorID] {
+		if rule.ID != ruleID {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == len(mem.alertRules[monitorID]) {
+		return ErrAlertRuleNotFound
+	}
+	mem.alertRules[monitorID] = rules
+
+	return nil
+}
+
+// CreateAlerts - append alerts to the alert history
+func (mem *inMemoryDB) CreateAlerts(ctx context.Context, alerts []links.Alert) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	mem.alerts = append(mem.alerts, alerts...)
+
+	return nil
+}
+
+// ListAlerts - alert history matching the query, newest first
+func (mem *inMemoryDB) ListAlerts(ctx context.Context, query links.AlertQuery) ([]links.Alert, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	alerts := []links.Alert{}
+	for i := len(mem.alerts) - 1; i >= 0; i-- {
+		alert := mem.alerts[i]
+		if query.MonitorID != "" && alert.MonitorID != query.MonitorID {
+			continue
+		}
+		if query.RuleID != "" && alert.RuleID != query.RuleID {
+			continue
+		}
+		alerts = append(alerts, alert)
+		if query.Limit > 0 && len(alerts) == query.Limit {
+			break
+		}
+	}
+
+	return alerts, nil
+}
+
+// CreateCallbackAttempt - record a callback delivery attempt of an existing batch
+func (mem *inMemoryDB) CreateCallbackAttempt(ctx context.Context, attempt links.CallbackAttempt) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if _, ok := mem.batches[attempt.BatchID]; !ok {
+		return ErrBatchNotFound
+	}
+
+	mem.callbacks[attempt.BatchID] = append(mem.callbacks[attempt.BatchID], attempt)
+
+	return nil
+}
+
+// ListCallbackAttempts - callback delivery attempts of a batch, oldest first
+func (mem *inMemoryDB) ListCallbackAttempts(ctx context.Context, batchID string) ([]links.CallbackAttempt, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	if _, ok := mem.batches[batchID]; !ok {
+		return nil, ErrBatchNotFound
+	}
+
+	return append([]links.CallbackAttempt{}, mem.callbacks[batchID]...), nil
+}
+
+// CreateAPIKey - save an api key, the hash of the key must be unique
+func (mem *inMemoryDB) CreateAPIKey(ctx context.Context, key links.APIKey) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	if key.ID == "" || key.Hash == "" {
+		return errors.New("api key id and hash are required")
+	}
+	if _, ok := mem.apiKeyIndex[key.Hash]; ok {
+		return errors.New("api key hash already exists")
+	}
+
+	mem.apiKeys[key.ID] = key
+	mem.apiKeyIndex[key.Hash] = key.ID
+
+	return nil
+}
+
+// GetAPIKey - get api key by id, if it doesn't exists an error is returned
+func (mem *inMemoryDB) GetAPIKey(ctx context.Context, keyID string) (links.APIKey, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	key, ok := mem.apiKeys[keyID]
+	if !ok {
+		return links.APIKey{}, ErrAPIKeyNotFound
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash - get api key by the hash of the key, if it doesn't exists an error is returned
+func (mem *inMemoryDB) GetAPIKeyByHash(ctx context.Context, hash string) (links.APIKey, error) {
+	mem.rw.RLock()
+	defer mem.rw.RUnlock()
+
+	keyID, ok := mem.apiKeyIndex[hash]
+	if !ok {
+		return links.APIKey{}, ErrAPIKeyNotFound
+	}
+
+	return mem.apiKeys[keyID], nil
+}
+
+// UpdateAPIKey - replace a stored api key, its hash can't change. If it doesn't exists an error is returned
+func (mem *inMemoryDB) UpdateAPIKey(ctx context.Context, key links.APIKey) error {
+	mem.rw.Lock()
+	defer mem.rw.Unlock()
+
+	stored, ok := mem.apiKeys[key.ID]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+
+	key.Hash = stored.Hash
+	mem.apiKeys[key.ID] = key
+
+	return nil
+}
+
+// ListAPIKeys - all api keys including the revoked ones, oldest first
+func (mem *inMemoryDB) ListAPIKeys(ctx context.Context) ([]links.APIKey, error) {
+	mem.rw.RLock()
+	keys := make([]links.APIKey, 0, len(mem.apiKeys))
+	for _, key := range mem.apiKeys {
+		keys = append(keys, key)
+	}
+	mem.rw.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys, nil
+}
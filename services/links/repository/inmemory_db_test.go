@@ -2,7 +2,9 @@ package repository_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Lockwarr/codefi/services/links"
 	"github.com/Lockwarr/codefi/services/links/repository"
@@ -53,6 +55,36 @@ func (s *inmemoryDBTestSuite) TestCreateResults_WhenZeroResults_ThenFail() {
 	s.Equal("no results were passed", err.Error())
 }
 
+func (s *inmemoryDBTestSuite) TestAppendResults_ThenResultsOfTheBatchAreKept() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.AppendResults(ctx, []links.Result{{ID: "r0", BatchID: "b"}, {ID: "r1", BatchID: "b"}})
+	before, _ := s.inMemoryDB.GetBatchResults(ctx, "b")
+
+	// Act
+	err := s.inMemoryDB.AppendResults(ctx, []links.Result{{ID: "r2", BatchID: "b"}})
+	after, _ := s.inMemoryDB.GetBatchResults(ctx, "b")
+	appended, getErr := s.inMemoryDB.GetBatchResult(ctx, "b", "r2")
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(2, len(before))
+	s.Equal(3, len(after))
+	s.Equal(nil, getErr)
+	s.Equal("r2", appended.ID)
+}
+
+func (s *inmemoryDBTestSuite) TestAppendResults_WhenZeroResults_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	err := s.inMemoryDB.AppendResults(ctx, []links.Result{})
+
+	// Assert
+	s.Equal("no results were passed", err.Error())
+}
+
 func (s *inmemoryDBTestSuite) TestGetBatchResults_ThenSuccess() {
 	// Arrange
 	batchID := "testBatchID"
@@ -84,3 +116,710 @@ func (s *inmemoryDBTestSuite) TestGetBatchResults_WhenNotExistingBatchIDPassed_T
 	s.Equal(repository.ErrBatchNotFound, err)
 	s.Equal(0, len(actualResults))
 }
+
+func (s *inmemoryDBTestSuite) TestGetBatchResults_WhenBatchHasNoResultsYet_ThenEmpty() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "testBatchID", Status: links.BatchStatusRunning})
+
+	// Act
+	results, resultsErr := s.inMemoryDB.GetBatchResults(ctx, "testBatchID")
+	page, pageErr := s.inMemoryDB.ListBatchResults(ctx, "testBatchID", links.ResultQuery{})
+	_, resultErr := s.inMemoryDB.GetBatchResult(ctx, "testBatchID", "testID")
+
+	// Assert
+	s.Equal(nil, resultsErr)
+	s.Equal([]links.Result{}, results)
+	s.Equal(nil, pageErr)
+	s.Equal(0, len(page.Results))
+	s.NotNil(page.Results)
+	s.Equal(repository.ErrResultNotFound, resultErr)
+}
+
+func (s *inmemoryDBTestSuite) TestCreateBatch_ThenSuccess() {
+	// Arrange
+	ctx := context.Background()
+	batch := links.Batch{
+		ID:      "testBatchID",
+		Options: links.ScrapeOptions{TimeoutMS: 1000, InternalPolicy: "same_domain"},
+	}
+
+	// Act
+	err := s.inMemoryDB.CreateBatch(ctx, batch)
+	actualBatch, getErr := s.inMemoryDB.GetBatch(ctx, batch.ID)
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(nil, getErr)
+	s.Equal(batch, actualBatch)
+}
+
+func (s *inmemoryDBTestSuite) TestCreateBatch_WhenIDIsMissing_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	err := s.inMemoryDB.CreateBatch(ctx, links.Batch{})
+
+	// Assert
+	s.Equal("batch id is required", err.Error())
+}
+
+func (s *inmemoryDBTestSuite) TestGetBatch_WhenNotExistingBatchIDPassed_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	_, err := s.inMemoryDB.GetBatch(ctx, "testBatchID")
+
+	// Assert
+	s.Equal(repository.ErrBatchNotFound, err)
+}
+
+func (s *inmemoryDBTestSuite) TestListResults_ThenACopyIsReturned() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "testID", BatchID: "testBatchID"}})
+
+	// Act
+	listed := s.inMemoryDB.ListResults(ctx)
+	listed["testBatchID"][0].ID = "changed"
+	delete(listed, "testBatchID")
+
+	// Assert
+	actualResults, err := s.inMemoryDB.GetBatchResults(ctx, "testBatchID")
+	s.Equal(nil, err)
+	s.Equal("testID", actualResults[0].ID)
+}
+
+func (s *inmemoryDBTestSuite) TestUpdateBatch_ThenSuccess() {
+	// Arrange
+	ctx := context.Background()
+	batch := links.Batch{ID: "testBatchID", Status: links.BatchStatusRunning}
+	_ = s.inMemoryDB.CreateBatch(ctx, batch)
+	batch.Status = links.BatchStatusCompleted
+	batch.SuccessCount = 2
+
+	// Act
+	err := s.inMemoryDB.UpdateBatch(ctx, batch)
+	actualBatch, _ := s.inMemoryDB.GetBatch(ctx, batch.ID)
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(batch, actualBatch)
+}
+
+func (s *inmemoryDBTestSuite) TestUpdateBatch_WhenNotExistingBatchIDPassed_ThenFail() {
+	// Act
+	err := s.inMemoryDB.UpdateBatch(context.Background(), links.Batch{ID: "testBatchID"})
+
+	// Assert
+	s.Equal(repository.ErrBatchNotFound, err)
+}
+
+func (s *inmemoryDBTestSuite) TestUpdateBatchIf_WhenStatusMatches_ThenOnlyFirstUpdateWins() {
+	// Arrange
+	ctx := context.Background()
+	batch := links.Batch{ID: "testBatchID", Status: links.BatchStatusCompleted}
+	_ = s.inMemoryDB.CreateBatch(ctx, batch)
+	batch.Status = links.BatchStatusRunning
+
+	// Act
+	first := s.inMemoryDB.UpdateBatchIf(ctx, batch, links.BatchStatusCompleted)
+	second := s.inMemoryDB.UpdateBatchIf(ctx, batch, links.BatchStatusCompleted)
+	actualBatch, _ := s.inMemoryDB.GetBatch(ctx, batch.ID)
+
+	// Assert
+	s.Equal(nil, first)
+	s.Equal(links.ErrBatchStatusChanged, second)
+	s.Equal(batch, actualBatch)
+}
+
+func (s *inmemoryDBTestSuite) TestUpdateBatchIf_WhenNotExistingBatchIDPassed_ThenFail() {
+	// Act
+	err := s.inMemoryDB.UpdateBatchIf(context.Background(), links.Batch{ID: "testBatchID"}, links.BatchStatusCompleted)
+
+	// Assert
+	s.Equal(repository.ErrBatchNotFound, err)
+}
+
+func (s *inmemoryDBTestSuite) TestListBatches_DifferentQueries_ThenPagesAreReturned() {
+	// Arrange
+	ctx := context.Background()
+	start := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{
+			ID:           fmt.Sprintf("batch%d", i),
+			Status:       links.BatchStatusCompleted,
+			URLCount:     10 - i,
+			FailureCount: i % 2,
+			CreatedAt:    start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "running", Status: links.BatchStatusRunning, CreatedAt: start.Add(-time.Hour)})
+
+	testCases := []struct {
+		name          string
+		query         links.BatchQuery
+		expectedPages [][]string
+	}{
+		{
+			name:          "oldest first by default",
+			query:         links.BatchQuery{Limit: 4},
+			expectedPages: [][]string{{"running", "batch0", "batch1", "batch2"}, {"batch3", "batch4"}},
+		},
+		{
+			name:          "newest first",
+			query:         links.BatchQuery{Limit: 2, Descending: true, Status: links.BatchStatusCompleted},
+			expectedPages: [][]string{{"batch4", "batch3"}, {"batch2", "batch1"}, {"batch0"}},
+		},
+		{
+			name:          "by url count",
+			query:         links.BatchQuery{Limit: 3, SortBy: links.BatchSortURLCount, Status: links.BatchStatusCompleted},
+			expectedPages: [][]string{{"batch4", "batch3", "batch2"}, {"batch1", "batch0"}},
+		},
+		{
+			name:          "by failure count with ties broken by id",
+			query:         links.BatchQuery{Limit: 2, SortBy: links.BatchSortFailureCount, Descending: true, Status: links.BatchStatusCompleted},
+			expectedPages: [][]string{{"batch3", "batch1"}, {"batch4", "batch2"}, {"batch0"}},
+		},
+		{
+			name:          "created date range",
+			query:         links.BatchQuery{CreatedAfter: start.Add(time.Hour), CreatedBefore: start.Add(3 * time.Hour)},
+			expectedPages: [][]string{{"batch1", "batch2"}},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			query := tc.query
+			actualPages := [][]string{}
+			for {
+				page, err := s.inMemoryDB.ListBatches(ctx, query)
+				s.Require().Equal(nil, err)
+				ids := []string{}
+				for _, batch := range page.Batches {
+					ids = append(ids, batch.ID)
+				}
+				actualPages = append(actualPages, ids)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			// Assert
+			s.Equal(tc.expectedPages, actualPages)
+		})
+	}
+}
+
+func (s *inmemoryDBTestSuite) TestListBatches_WhenCursorIsInvalid_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: fmt.Sprintf("batch%d", i)})
+	}
+	page, _ := s.inMemoryDB.ListBatches(ctx, links.BatchQuery{Limit: 1})
+
+	// Act
+	_, malformedErr := s.inMemoryDB.ListBatches(ctx, links.BatchQuery{Limit: 1, Cursor: "not a cursor"})
+	_, otherSortErr := s.inMemoryDB.ListBatches(ctx, links.BatchQuery{Limit: 1, Cursor: page.NextCursor, SortBy: links.BatchSortURLCount})
+	_, sortErr := s.inMemoryDB.ListBatches(ctx, links.BatchQuery{SortBy: "name"})
+
+	// Assert
+	s.Equal(repository.ErrInvalidCursor, malformedErr)
+	s.Equal(repository.ErrInvalidCursor, otherSortErr)
+	s.Equal(repository.ErrInvalidSort, sortErr)
+}
+
+func (s *inmemoryDBTestSuite) TestListBatchResults_DifferentQueries_ThenPagesAreReturned() {
+	// Arrange
+	ctx := context.Background()
+	createdAt := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{
+		{ID: "r0", BatchID: "b", PageURL: "https://www.google.com", InternalLinksNum: 6, ExternalLinksNum: 13, Success: true, CreatedAt: createdAt},
+		{ID: "r1", BatchID: "b", PageURL: "https://www.facebook.com", InternalLinksNum: 27, ExternalLinksNum: 20, Success: true, CreatedAt: createdAt},
+		{ID: "r2", BatchID: "b", PageURL: "https://broken.example.com", CreatedAt: createdAt},
+		{ID: "r3", BatchID: "b", PageURL: "https://www.google.com/maps", InternalLinksNum: 6, ExternalLinksNum: 2, Success: true, CreatedAt: createdAt},
+	})
+	success, failure := true, false
+	five, ten := uint(5), uint(10)
+
+	testCases := []struct {
+		name          string
+		query         links.ResultQuery
+		expectedPages [][]string
+	}{
+		{
+			name:          "processing order by default",
+			query:         links.ResultQuery{},
+			expectedPages: [][]string{{"r0", "r1", "r2", "r3"}},
+		},
+		{
+			name:          "paginated",
+			query:         links.ResultQuery{Limit: 3},
+			expectedPages: [][]string{{"r0", "r1", "r2"}, {"r3"}},
+		},
+		{
+			name:          "most internal links first with ties in processing order",
+			query:         links.ResultQuery{Limit: 1, SortBy: links.ResultSortInternalLinksNum, Descending: true},
+			expectedPages: [][]string{{"r1"}, {"r3"}, {"r0"}, {"r2"}},
+		},
+		{
+			name:          "by page url",
+			query:         links.ResultQuery{Limit: 2, SortBy: links.ResultSortPageURL},
+			expectedPages: [][]string{{"r2", "r1"}, {"r0", "r3"}},
+		},
+		{
+			name:          "successful only",
+			query:         links.ResultQuery{Success: &success, SortBy: links.ResultSortExternalLinksNum},
+			expectedPages: [][]string{{"r3", "r0", "r1"}},
+		},
+		{
+			name:          "failed only",
+			query:         links.ResultQuery{Success: &failure},
+			expectedPages: [][]string{{"r2"}},
+		},
+		{
+			name:          "host",
+			query:         links.ResultQuery{Host: "WWW.google.com"},
+			expectedPages: [][]string{{"r0", "r3"}},
+		},
+		{
+			name:          "link count ranges",
+			query:         links.ResultQuery{MinInternalLinks: &five, MaxInternalLinks: &ten, MinExternalLinks: &five},
+			expectedPages: [][]string{{"r0"}},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			query := tc.query
+			actualPages := [][]string{}
+			for {
+				page, err := s.inMemoryDB.ListBatchResults(ctx, "b", query)
+				s.Require().Equal(nil, err)
+				ids := []string{}
+				for _, result := range page.Results {
+					ids = append(ids, result.ID)
+				}
+				actualPages = append(actualPages, ids)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			// Assert
+			s.Equal(tc.expectedPages, actualPages)
+		})
+	}
+}
+
+func (s *inmemoryDBTestSuite) TestListBatchResults_WhenInvalidQuery_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r0", BatchID: "b"}, {ID: "r1", BatchID: "b"}})
+	page, _ := s.inMemoryDB.ListBatchResults(ctx, "b", links.ResultQuery{Limit: 1})
+
+	// Act
+	_, notFoundErr := s.inMemoryDB.ListBatchResults(ctx, "unknown", links.ResultQuery{})
+	_, malformedErr := s.inMemoryDB.ListBatchResults(ctx, "b", links.ResultQuery{Cursor: "not a cursor"})
+	_, otherSortErr := s.inMemoryDB.ListBatchResults(ctx, "b", links.ResultQuery{Cursor: page.NextCursor, SortBy: links.ResultSortPageURL})
+	_, sortErr := s.inMemoryDB.ListBatchResults(ctx, "b", links.ResultQuery{SortBy: "id"})
+
+	// Assert
+	s.Equal(repository.ErrBatchNotFound, notFoundErr)
+	s.Equal(repository.ErrInvalidCursor, malformedErr)
+	s.Equal(repository.ErrInvalidCursor, otherSortErr)
+	s.Equal(repository.ErrInvalidSort, sortErr)
+}
+
+func (s *inmemoryDBTestSuite) TestEachBatchResult_ThenMatchingResultsAreIteratedInQueryOrder() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{
+		{ID: "r0", BatchID: "b", PageURL: "https://www.google.com", InternalLinksNum: 6, Success: true},
+		{ID: "r1", BatchID: "b", PageURL: "https://www.facebook.com", InternalLinksNum: 27, Success: true},
+		{ID: "r2", BatchID: "b", PageURL: "https://broken.example.com"},
+		{ID: "r3", BatchID: "b", PageURL: "https://www.google.com/maps", InternalLinksNum: 9, Success: true},
+	})
+	success := true
+	ids := []string{}
+
+	// Act
+	err := s.inMemoryDB.EachBatchResult(ctx, "b", links.ResultQuery{
+		Success: &success, SortBy: links.ResultSortInternalLinksNum, Descending: true, Limit: 1, Cursor: "ignored",
+	}, func(result links.Result) error {
+		ids = append(ids, result.ID)
+		return nil
+	})
+
+	// Assert
+	s.NoError(err)
+	s.Equal([]string{"r1", "r3", "r0"}, ids)
+}
+
+func (s *inmemoryDBTestSuite) TestEachBatchResult_WhenInvalidQueryOrFnFails_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r0", BatchID: "b"}, {ID: "r1", BatchID: "b"}})
+	fnErr := fmt.Errorf("broken pipe")
+	calls := 0
+	none := func(result links.Result) error { return nil }
+
+	// Act
+	notFoundErr := s.inMemoryDB.EachBatchResult(ctx, "unknown", links.ResultQuery{}, none)
+	sortErr := s.inMemoryDB.EachBatchResult(ctx, "b", links.ResultQuery{SortBy: "id"}, none)
+	err := s.inMemoryDB.EachBatchResult(ctx, "b", links.ResultQuery{}, func(result links.Result) error {
+		calls++
+		return fnErr
+	})
+
+	// Assert
+	s.Equal(repository.ErrBatchNotFound, notFoundErr)
+	s.Equal(repository.ErrInvalidSort, sortErr)
+	s.Equal(fnErr, err)
+	s.Equal(1, calls)
+}
+
+func (s *inmemoryDBTestSuite) TestGetResult_ThenSuccess() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r0", BatchID: "b0"}, {ID: "r1", BatchID: "b0"}})
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r2", BatchID: "b1", PageURL: "https://www.google.com"}})
+
+	// Act
+	result, err := s.inMemoryDB.GetResult(ctx, "r2")
+	batchResult, batchErr := s.inMemoryDB.GetBatchResult(ctx, "b0", "r1")
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(links.Result{ID: "r2", BatchID: "b1", PageURL: "https://www.google.com"}, result)
+	s.Equal(nil, batchErr)
+	s.Equal(links.Result{ID: "r1", BatchID: "b0"}, batchResult)
+}
+
+func (s *inmemoryDBTestSuite) TestGetResult_WhenNotFound_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r0", BatchID: "b0"}})
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r1", BatchID: "b1"}})
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r2", BatchID: "b1"}}) // replaces r1
+
+	// Act
+	_, unknownErr := s.inMemoryDB.GetResult(ctx, "unknown")
+	_, replacedErr := s.inMemoryDB.GetResult(ctx, "r1")
+	_, otherBatchErr := s.inMemoryDB.GetBatchResult(ctx, "b1", "r0")
+	_, unknownBatchErr := s.inMemoryDB.GetBatchResult(ctx, "unknown", "r0")
+
+	// Assert
+	s.Equal(repository.ErrResultNotFound, unknownErr)
+	s.Equal(repository.ErrResultNotFound, replacedErr)
+	s.Equal(repository.ErrResultNotFound, otherBatchErr)
+	s.Equal(repository.ErrBatchNotFound, unknownBatchErr)
+}
+
+func (s *inmemoryDBTestSuite) TestUpdateResults_ThenStoredResultsAreReplaced() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r0", BatchID: "b"}, {ID: "r1", BatchID: "b"}})
+	before, _ := s.inMemoryDB.ListBatchResults(ctx, "b", links.ResultQuery{})
+	updated := links.Result{ID: "r1", BatchID: "b", Success: true, Attempts: []links.Attempt{{Success: false}}}
+
+	// Act
+	err := s.inMemoryDB.UpdateResults(ctx, []links.Result{updated})
+
+	// Assert
+	s.Equal(nil, err)
+	after, _ := s.inMemoryDB.GetBatchResults(ctx, "b")
+	s.Equal([]links.Result{{ID: "r0", BatchID: "b"}, updated}, after)
+	s.Equal([]links.Result{{ID: "r0", BatchID: "b"}, {ID: "r1", BatchID: "b"}}, before.Results) // earlier reads are untouched
+}
+
+func (s *inmemoryDBTestSuite) TestUpdateResults_WhenResultIsMissing_ThenNothingIsUpdated() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateResults(ctx, []links.Result{{ID: "r0", BatchID: "b"}})
+
+	// Act
+	err := s.inMemoryDB.UpdateResults(ctx, []links.Result{{ID: "r0", BatchID: "b", Success: true}, {ID: "r1", BatchID: "b"}})
+	otherBatchErr := s.inMemoryDB.UpdateResults(ctx, []links.Result{{ID: "r0", BatchID: "other"}})
+
+	// Assert
+	s.Equal(repository.ErrResultNotFound, err)
+	s.Equal(repository.ErrResultNotFound, otherBatchErr)
+	result, _ := s.inMemoryDB.GetResult(ctx, "r0")
+	s.Equal(links.Result{ID: "r0", BatchID: "b"}, result)
+}
+
+func (s *inmemoryDBTestSuite) TestListBatches_WhenMonitorIDIsSet_ThenOnlyItsRunsAreListed() {
+	// Arrange
+	ctx := context.Background()
+	start := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b0", MonitorID: "m0", CreatedAt: start})
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b1", CreatedAt: start.Add(time.Minute)})
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b2", MonitorID: "m0", CreatedAt: start.Add(2 * time.Minute)})
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b3", MonitorID: "m1", CreatedAt: start.Add(3 * time.Minute)})
+
+	// Act
+	page, err := s.inMemoryDB.ListBatches(ctx, links.BatchQuery{SortBy: links.BatchSortCreatedAt, MonitorID: "m0"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(2, len(page.Batches))
+	s.Equal("b0", page.Batches[0].ID)
+	s.Equal("b2", page.Batches[1].ID)
+}
+
+func (s *inmemoryDBTestSuite) TestMonitors_ThenTheyAreCreatedUpdatedListedAndDeleted() {
+	// Arrange
+	ctx := context.Background()
+	start := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	first := links.Monitor{ID: "m1", Name: "weekly", Schedule: "@weekly", URLs: []string{"https://www.google.com"}, CreatedAt: start}
+	second := links.Monitor{ID: "m0", Name: "daily", Schedule: "@daily", URLs: []string{"https://www.facebook.com"}, CreatedAt: start.Add(time.Hour)}
+
+	// Act
+	createErr := s.inMemoryDB.CreateMonitor(ctx, first)
+	_ = s.inMemoryDB.CreateMonitor(ctx, second)
+	first.Paused = true
+	updateErr := s.inMemoryDB.UpdateMonitor(ctx, first)
+	updated, getErr := s.inMemoryDB.GetMonitor(ctx, "m1")
+	listed, listErr := s.inMemoryDB.ListMonitors(ctx)
+	deleteErr := s.inMemoryDB.DeleteMonitor(ctx, "m1")
+	_, deletedErr := s.inMemoryDB.GetMonitor(ctx, "m1")
+
+	// Assert
+	s.Equal(nil, createErr)
+	s.Equal(nil, updateErr)
+	s.Equal(nil, getErr)
+	s.Equal(first, updated)
+	s.Equal(nil, listErr)
+	s.Equal([]links.Monitor{first, second}, listed)
+	s.Equal(nil, deleteErr)
+	s.Equal(repository.ErrMonitorNotFound, deletedErr)
+}
+
+func (s *inmemoryDBTestSuite) TestMonitors_WhenNotFoundOrInvalid_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	createErr := s.inMemoryDB.CreateMonitor(ctx, links.Monitor{})
+	_, getErr := s.inMemoryDB.GetMonitor(ctx, "unknown")
+	updateErr := s.inMemoryDB.UpdateMonitor(ctx, links.Monitor{ID: "unknown"})
+	deleteErr := s.inMemoryDB.DeleteMonitor(ctx, "unknown")
+
+	// Assert
+	s.Equal("monitor id is required", createErr.Error())
+	s.Equal(repository.ErrMonitorNotFound, getErr)
+	s.Equal(repository.ErrMonitorNotFound, updateErr)
+	s.Equal(repository.ErrMonitorNotFound, deleteErr)
+}
+
+func (s *inmemoryDBTestSuite) TestAlertRules_ThenTheyAreCreatedListedAndDeletedWithTheirMonitor() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateMonitor(ctx, links.Monitor{ID: "m0"})
+	first := links.AlertRule{ID: "ar0", MonitorID: "m0", Condition: links.AlertSuccessToFailure}
+	second := links.AlertRule{ID: "ar1", MonitorID: "m0", Condition: links.AlertNewExternalDomain}
+
+	// Act
+	createErr := s.inMemoryDB.CreateAlertRule(ctx, first)
+	_ = s.inMemoryDB.CreateAlertRule(ctx, second)
+	listed, listErr := s.inMemoryDB.ListAlertRules(ctx, "m0")
+	deleteErr := s.inMemoryDB.DeleteAlertRule(ctx, "m0", "ar0")
+	deletedErr := s.inMemoryDB.DeleteAlertRule(ctx, "m0", "ar0")
+	remaining, _ := s.inMemoryDB.ListAlertRules(ctx, "m0")
+	_ = s.inMemoryDB.DeleteMonitor(ctx, "m0")
+	_ = s.inMemoryDB.CreateMonitor(ctx, links.Monitor{ID: "m0"})
+	recreated, _ := s.inMemoryDB.ListAlertRules(ctx, "m0")
+
+	// Assert
+	s.Equal(nil, createErr)
+	s.Equal(nil, listErr)
+	s.Equal([]links.AlertRule{first, second}, listed)
+	s.Equal(nil, deleteErr)
+	s.Equal(repository.ErrAlertRuleNotFound, deletedErr)
+	s.Equal([]links.AlertRule{second}, remaining)
+	s.Empty(recreated)
+}
+
+func (s *inmemoryDBTestSuite) TestAlertRules_WhenMonitorIsMissing_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	createErr := s.inMemoryDB.CreateAlertRule(ctx, links.AlertRule{ID: "ar0", MonitorID: "unknown"})
+	_, listErr := s.inMemoryDB.ListAlertRules(ctx, "unknown")
+	deleteErr := s.inMemoryDB.DeleteAlertRule(ctx, "unknown", "ar0")
+
+	// Assert
+	s.Equal(repository.ErrMonitorNotFound, createErr)
+	s.Equal(repository.ErrMonitorNotFound, listErr)
+	s.Equal(repository.ErrMonitorNotFound, deleteErr)
+}
+
+func (s *inmemoryDBTestSuite) TestListAlerts_DifferentQueries_ThenNewestAlertsAreReturned() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateAlerts(ctx, []links.Alert{
+		{ID: "a0", RuleID: "ar0", MonitorID: "m0"},
+		{ID: "a1", RuleID: "ar1", MonitorID: "m0"},
+	})
+	_ = s.inMemoryDB.CreateAlerts(ctx, []links.Alert{
+		{ID: "a2", RuleID: "ar0", MonitorID: "m0"},
+		{ID: "a3", RuleID: "ar2", MonitorID: "m1"},
+	})
+
+	testCases := []struct {
+		name        string
+		query       links.AlertQuery
+		expectedIDs []string
+	}{
+		{name: "all", query: links.AlertQuery{}, expectedIDs: []string{"a3", "a2", "a1", "a0"}},
+		{name: "by monitor", query: links.AlertQuery{MonitorID: "m0"}, expectedIDs: []string{"a2", "a1", "a0"}},
+		{name: "by rule", query: links.AlertQuery{RuleID: "ar0"}, expectedIDs: []string{"a2", "a0"}},
+		{name: "limited", query: links.AlertQuery{MonitorID: "m0", Limit: 2}, expectedIDs: []string{"a2", "a1"}},
+		{name: "no match", query: links.AlertQuery{MonitorID: "m2"}, expectedIDs: []string{}},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			// Act
+			alerts, err := s.inMemoryDB.ListAlerts(ctx, tc.query)
+
+			// Assert
+			s.Equal(nil, err)
+			ids := []string{}
+			for _, alert := range alerts {
+				ids = append(ids, alert.ID)
+			}
+			s.Equal(tc.expectedIDs, ids)
+		})
+	}
+}
+
+func (s *inmemoryDBTestSuite) TestCallbackAttempts_ThenTheyAreListedPerBatch() {
+	// Arrange
+	ctx := context.Background()
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b0"})
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b1"})
+	first := links.CallbackAttempt{ID: "c0", BatchID: "b0", Attempt: 1, StatusCode: 503}
+	second := links.CallbackAttempt{ID: "c1", BatchID: "b0", Attempt: 2, StatusCode: 204, Success: true}
+
+	// Act
+	createErr := s.inMemoryDB.CreateCallbackAttempt(ctx, first)
+	_ = s.inMemoryDB.CreateCallbackAttempt(ctx, second)
+	attempts, listErr := s.inMemoryDB.ListCallbackAttempts(ctx, "b0")
+	none, _ := s.inMemoryDB.ListCallbackAttempts(ctx, "b1")
+
+	// Assert
+	s.Equal(nil, createErr)
+	s.Equal(nil, listErr)
+	s.Equal([]links.CallbackAttempt{first, second}, attempts)
+	s.Equal([]links.CallbackAttempt{}, none)
+}
+
+func (s *inmemoryDBTestSuite) TestCallbackAttempts_WhenBatchIsMissing_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	createErr := s.inMemoryDB.CreateCallbackAttempt(ctx, links.CallbackAttempt{ID: "c0", BatchID: "unknown"})
+	_, listErr := s.inMemoryDB.ListCallbackAttempts(ctx, "unknown")
+
+	// Assert
+	s.Equal(repository.ErrBatchNotFound, createErr)
+	s.Equal(repository.ErrBatchNotFound, listErr)
+}
+
+func (s *inmemoryDBTestSuite) TestListBatches_WhenAPIKeyIDIsSet_ThenOnlyItsBatchesAreListed() {
+	// Arrange
+	ctx := context.Background()
+	start := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b0", APIKeyID: "k0", CreatedAt: start})
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b1", CreatedAt: start.Add(time.Minute)})
+	_ = s.inMemoryDB.CreateBatch(ctx, links.Batch{ID: "b2", APIKeyID: "k1", CreatedAt: start.Add(2 * time.Minute)})
+
+	// Act
+	page, err := s.inMemoryDB.ListBatches(ctx, links.BatchQuery{SortBy: links.BatchSortCreatedAt, APIKeyID: "k0"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(1, len(page.Batches))
+	s.Equal("b0", page.Batches[0].ID)
+}
+
+func (s *inmemoryDBTestSuite) TestAPIKeys_ThenTheyAreCreatedFoundUpdatedAndListed() {
+	// Arrange
+	ctx := context.Background()
+	start := time.Date(2022, 5, 23, 10, 0, 0, 0, time.UTC)
+	first := links.APIKey{ID: "k1", Name: "ci", Prefix: "lk_abcdefg", Hash: "h1", CreatedAt: start}
+	second := links.APIKey{ID: "k0", Name: "admin", Prefix: "lk_hijklmn", Hash: "h0", Admin: true, CreatedAt: start.Add(time.Hour)}
+
+	// Act
+	createErr := s.inMemoryDB.CreateAPIKey(ctx, first)
+	_ = s.inMemoryDB.CreateAPIKey(ctx, second)
+	duplicateErr := s.inMemoryDB.CreateAPIKey(ctx, links.APIKey{ID: "k2", Hash: "h1"})
+	revokedAt := start.Add(2 * time.Hour)
+	first.RevokedAt = &revokedAt
+	updateErr := s.inMemoryDB.UpdateAPIKey(ctx, links.APIKey{ID: "k1", Name: "ci", Prefix: "lk_abcdefg", CreatedAt: start, RevokedAt: &revokedAt})
+	byID, getErr := s.inMemoryDB.GetAPIKey(ctx, "k1")
+	byHash, hashErr := s.inMemoryDB.GetAPIKeyByHash(ctx, "h1")
+	listed, listErr := s.inMemoryDB.ListAPIKeys(ctx)
+
+	// Assert
+	s.Equal(nil, createErr)
+	s.Equal("api key hash already exists", duplicateErr.Error())
+	s.Equal(nil, updateErr)
+	s.Equal(nil, getErr)
+	s.Equal(first, byID)
+	s.Equal(nil, hashErr)
+	s.Equal(first, byHash)
+	s.Equal(nil, listErr)
+	s.Equal([]links.APIKey{first, second}, listed)
+}
+
+func (s *inmemoryDBTestSuite) TestAPIKeys_WhenNotFoundOrInvalid_ThenFail() {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	createErr := s.inMemoryDB.CreateAPIKey(ctx, links.APIKey{ID: "k0"})
+	_, getErr := s.inMemoryDB.GetAPIKey(ctx, "unknown")
+	_, hashErr := s.inMemoryDB.GetAPIKeyByHash(ctx, "unknown")
+	updateErr := s.inMemoryDB.UpdateAPIKey(ctx, links.APIKey{ID: "unknown"})
+
+	// Assert
+	s.Equal("api key id and hash are required", createErr.Error())
+	s.Equal(repository.ErrAPIKeyNotFound, getErr)
+	s.Equal(repository.ErrAPIKeyNotFound, hashErr)
+	s.Equal(repository.ErrAPIKeyNotFound, updateErr)
+}
+
+func (s *inmemoryDBTestSuite) TestInstrumentedRepository_ThenEveryOperationIsObserved() {
+	// Arrange
+	ctx := context.Background()
+	observed := []string{}
+	errs := []error{}
+	repo := repository.NewInstrumentedRepository(s.inMemoryDB, func(operation string, duration time.Duration, err error) {
+		observed = append(observed, operation)
+		errs = append(errs, err)
+	})
+
+	// Act
+	createErr := repo.CreateBatch(ctx, links.Batch{ID: "b0", Status: links.BatchStatusRunning})
+	batch, getErr := repo.GetBatch(ctx, "b0")
+	_, missingErr := repo.GetBatch(ctx, "b1")
+	results := repo.ListResults(ctx)
+
+	// Assert
+	s.NoError(createErr)
+	s.NoError(getErr)
+	s.Equal("b0", batch.ID)
+	s.ErrorIs(missingErr, repository.ErrBatchNotFound)
+	s.Empty(results)
+	s.Equal([]string{"create_batch", "get_batch", "get_batch", "list_results"}, observed)
+	s.Equal([]error{nil, nil, missingErr, nil}, errs)
+}
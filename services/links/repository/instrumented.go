@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+// ObserveFunc - called after every operation of an instrumented repository with the name of the
+// operation, how long it took and its error
+type ObserveFunc func(operation string, duration time.Duration, err error)
+
+type instrumentedRepository struct {
+	repo    links.Repository
+	observe ObserveFunc
+}
+
+// NewInstrumentedRepository - repository reporting every operation of repo to observe, e.g. to export its latencies
+func NewInstrumentedRepository(repo links.Repository, observe ObserveFunc) links.Repository {
+	return &instrumentedRepository{repo: repo, observe: observe}
+}
+
+func (r *instrumentedRepository) CreateBatch(ctx context.Context, batch links.Batch) error {
+	start := time.Now()
+	err := r.repo.CreateBatch(ctx, batch)
+	r.observe("create_batch", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetBatch(ctx context.Context, batchID string) (links.Batch, error) {
+	start := time.Now()
+	value, err := r.repo.GetBatch(ctx, batchID)
+	r.observe("get_batch", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) UpdateBatch(ctx context.Context, batch links.Batch) error {
+	start := time.Now()
+	err := r.repo.UpdateBatch(ctx, batch)
+	r.observe("update_batch", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) UpdateBatchIf(ctx context.Context, batch links.Batch, status links.BatchStatus) error {
+	start := time.Now()
+	err := r.repo.UpdateBatchIf(ctx, batch, status)
+	r.observe("update_batch_if", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) ListBatches(ctx context.Context, query links.BatchQuery) (links.BatchPage, error) {
+	start := time.Now()
+	value, err := r.repo.ListBatches(ctx, query)
+	r.observe("list_batches", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) CreateResults(ctx context.Context, results []links.Result) error {
+	start := time.Now()
+	err := r.repo.CreateResults(ctx, results)
+	r.observe("create_results", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) AppendResults(ctx context.Context, results []links.Result) error {
+	start := time.Now()
+	err := r.repo.AppendResults(ctx, results)
+	r.observe("append_results", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) UpdateResults(ctx context.Context, results []links.Result) error {
+	start := time.Now()
+	err := r.repo.UpdateResults(ctx, results)
+	r.observe("update_results", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetBatchResults(ctx context.Context, batchID string) ([]links.Result, error) {
+	start := time.Now()
+	value, err := r.repo.GetBatchResults(ctx, batchID)
+	r.observe("get_batch_results", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) ListBatchResults(ctx context.Context, batchID string, query links.ResultQuery) (links.ResultPage, error) {
+	start := time.Now()
+	value, err := r.repo.ListBatchResults(ctx, batchID, query)
+	r.observe("list_batch_results", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) EachBatchResult(ctx context.Context, batchID string, query links.ResultQuery, fn func(links.Result) error) error {
+	start := time.Now()
+	err := r.repo.EachBatchResult(ctx, batchID, query, fn)
+	r.observe("each_batch_result", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetResult(ctx context.Context, resultID string) (links.Result, error) {
+	start := time.Now()
+	value, err := r.repo.GetResult(ctx, resultID)
+	r.observe("get_result", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) GetBatchResult(ctx context.Context, batchID, resultID string) (links.Result, error) {
+	start := time.Now()
+	value, err := r.repo.GetBatchResult(ctx, batchID, resultID)
+	r.observe("get_batch_result", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) ListResults(ctx context.Context) map[string][]links.Result {
+	start := time.Now()
+	value := r.repo.ListResults(ctx)
+	r.observe("list_results", time.Since(start), nil)
+	return value
+}
+
+func (r *instrumentedRepository) CreateMonitor(ctx context.Context, monitor links.Monitor) error {
+	start := time.Now()
+	err := r.repo.CreateMonitor(ctx, monitor)
+	r.observe("create_monitor", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetMonitor(ctx context.Context, monitorID string) (links.Monitor, error) {
+	start := time.Now()
+	value, err := r.repo.GetMonitor(ctx, monitorID)
+	r.observe("get_monitor", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) UpdateMonitor(ctx context.Context, monitor links.Monitor) error {
+	start := time.Now()
+	err := r.repo.UpdateMonitor(ctx, monitor)
+	r.observe("update_monitor", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) DeleteMonitor(ctx context.Context, monitorID string) error {
+	start := time.Now()
+	err := r.repo.DeleteMonitor(ctx, monitorID)
+	r.observe("delete_monitor", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) ListMonitors(ctx context.Context) ([]links.Monitor, error) {
+	start := time.Now()
+	value, err := r.repo.ListMonitors(ctx)
+	r.observe("list_monitors", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) CreateAlertRule(ctx context.Context, rule links.AlertRule) error {
+	start := time.Now()
+	err := r.repo.CreateAlertRule(ctx, rule)
+	r.observe("create_alert_rule", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) ListAlertRules(ctx context.Context, monitorID string) ([]links.AlertRule, error) {
+	start := time.Now()
+	value, err := r.repo.ListAlertRules(ctx, monitorID)
+	r.observe("list_alert_rules", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) DeleteAlertRule(ctx context.Context, monitorID, ruleID string) error {
+	start := time.Now()
+	err := r.repo.DeleteAlertRule(ctx, monitorID, ruleID)
+	r.observe("delete_alert_rule", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) CreateAlerts(ctx context.Context, alerts []links.Alert) error {
+	start := time.Now()
+	err := r.repo.CreateAlerts(ctx, alerts)
+	r.observe("create_alerts", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) ListAlerts(ctx context.Context, query links.AlertQuery) ([]links.Alert, error) {
+	start := time.Now()
+	value, err := r.repo.ListAlerts(ctx, query)
+	r.observe("list_alerts", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) CreateCallbackAttempt(ctx context.Context, attempt links.CallbackAttempt) error {
+	start := time.Now()
+	err := r.repo.CreateCallbackAttempt(ctx, attempt)
+	r.observe("create_callback_attempt", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) ListCallbackAttempts(ctx context.Context, batchID string) ([]links.CallbackAttempt, error) {
+	start := time.Now()
+	value, err := r.repo.ListCallbackAttempts(ctx, batchID)
+	r.observe("list_callback_attempts", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) CreateAPIKey(ctx context.Context, key links.APIKey) error {
+	start := time.Now()
+	err := r.repo.CreateAPIKey(ctx, key)
+	r.observe("create_api_key", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetAPIKey(ctx context.Context, keyID string) (links.APIKey, error) {
+	start := time.Now()
+	value, err := r.repo.GetAPIKey(ctx, keyID)
+	r.observe("get_api_key", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) GetAPIKeyByHash(ctx context.Context, hash string) (links.APIKey, error) {
+	start := time.Now()
+	value, err := r.repo.GetAPIKeyByHash(ctx, hash)
+	r.observe("get_api_key_by_hash", time.Since(start), err)
+	return value, err
+}
+
+func (r *instrumentedRepository) UpdateAPIKey(ctx context.Context, key links.APIKey) error {
+	start := time.Now()
+	err := r.repo.UpdateAPIKey(ctx, key)
+	r.observe("update_api_key", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) ListAPIKeys(ctx context.Context) ([]links.APIKey, error) {
+	start := time.Now()
+	value, err := r.repo.ListAPIKeys(ctx)
+	r.observe("list_api_keys", time.Since(start), err)
+	return value, err
+}
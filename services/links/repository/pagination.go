@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+// batchCursor - position after the last batch of a page. The sort values of that batch are
+// kept in the cursor (keyset pagination) so pages stay stable while batches are added or updated.
+type batchCursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d"`
+	CreatedAt  time.Time `json:"t"`
+	Count      int       `json:"c"`
+	ID         string    `json:"id"`
+}
+
+func newBatchCursor(batch links.Batch, query links.BatchQuery) batchCursor {
+	return batchCursor{
+		SortBy:     query.SortBy,
+		Descending: query.Descending,
+		CreatedAt:  batch.CreatedAt,
+		Count:      batchCount(batch, query.SortBy),
+		ID:         batch.ID,
+	}
+}
+
+func (c batchCursor) encode() string {
+	return encodeCursor(c)
+}
+
+// decodeBatchCursor - the cursor must have been created for the same sorting
+func decodeBatchCursor(value string, query links.BatchQuery) (batchCursor, error) {
+	c := batchCursor{}
+	if err := decodeCursor(value, &c); err != nil || c.ID == "" {
+		return batchCursor{}, ErrInvalidCursor
+	}
+	if c.SortBy != query.SortBy || c.Descending != query.Descending {
+		return batchCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// encodeCursor - cursors are opaque to the clients, base64 encoded json
+func encodeCursor(c interface{}) string {
+	raw, _ := json.Marshal(c) // can't fail for the cursor structs
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string, c interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// pageBatches - filters, sorts and cuts one page out of all batches
+func pageBatches(batches []links.Batch, query links.BatchQuery) (links.BatchPage, error) {
+	if query.SortBy == "" {
+		query.SortBy = links.BatchSortCreatedAt
+	}
+	switch query.SortBy {
+	case links.BatchSortCreatedAt, links.BatchSortURLCount, links.BatchSortSuccessCount, links.BatchSortFailureCount:
+	default:
+		return links.BatchPage{}, ErrInvalidSort
+	}
+
+	var after *batchCursor
+	if query.Cursor != "" {
+		c, err := decodeBatchCursor(query.Cursor, query)
+		if err != nil {
+			return links.BatchPage{}, err
+		}
+		after = &c
+	}
+
+	filtered := make([]links.Batch, 0, len(batches))
+	for _, batch := range batches {
+		if matchesBatchQuery(batch, query) {
+			filtered = append(filtered, batch)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return compareToCursor(filtered[i], newBatchCursor(filtered[j], query), query) < 0
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return compareToCursor(filtered[i], *after, query) > 0
+		})
+	}
+
+	end := len(filtered)
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+
+	page := links.BatchPage{Batches: filtered[start:end]}
+	if end < len(filtered) {
+		page.NextCursor = newBatchCursor(filtered[end-1], query).encode()
+	}
+	return page, nil
+}
+
+func matchesBatchQuery(batch links.Batch, query links.BatchQuery) bool {
+	if query.Status != "" && batch.Status != query.Status {
+		return false
+	}
+	if query.MonitorID != "" && batch.MonitorID != query.MonitorID {
+		return false
+	}
+	if query.APIKeyID != "" && batch.APIKeyID != query.APIKeyID {
+		return false
+	}
+	if !query.CreatedAfter.IsZero() && batch.CreatedAt.Before(query.CreatedAfter) {
+		return false
+	}
+	if !query.CreatedBefore.IsZero() && !batch.CreatedAt.Before(query.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// compareToCursor - orders the batch relative to the cursor position in the requested sort order,
+// ties are broken by id so the order is total
+func compareToCursor(batch links.Batch, c batchCursor, query links.BatchQuery) int {
+	result := 0
+	if query.SortBy == links.BatchSortCreatedAt {
+		result = compareTimes(batch.CreatedAt, c.CreatedAt)
+	} else {
+		result = compareInts(batchCount(batch, query.SortBy), c.Count)
+	}
+	if result == 0 {
+		result = strings.Compare(batch.ID, c.ID)
+	}
+	if query.Descending {
+		return -result
+	}
+	return result
+}
+
+func batchCount(batch links.Batch, sortBy string) int {
+	switch sortBy {
+	case links.BatchSortURLCount:
+		return batch.URLCount
+	case links.BatchSortSuccessCount:
+		return batch.SuccessCount
+	case links.BatchSortFailureCount:
+		return batch.FailureCount
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// resultCursor - position after the last result of a page. Results are keyed by their
+// position in the batch, which never changes, and the sort value of the last result.
+type resultCursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d"`
+	CreatedAt  time.Time `json:"t"`
+	PageURL    string    `json:"u,omitempty"`
+	Count      uint      `json:"c"`
+	Position   int       `json:"p"`
+}
+
+// positionedResult - result together with its position in the batch
+type positionedResult struct {
+	result   links.Result
+	position int
+}
+
+func newResultCursor(res positionedResult, query links.ResultQuery) resultCursor {
+	c := resultCursor{SortBy: query.SortBy, Descending: query.Descending, CreatedAt: res.result.CreatedAt, Position: res.position}
+	switch query.SortBy {
+	case links.ResultSortPageURL:
+		c.PageURL = res.result.PageURL
+	case links.ResultSortInternalLinksNum:
+		c.Count = res.result.InternalLinksNum
+	case links.ResultSortExternalLinksNum:
+		c.Count = res.result.ExternalLinksNum
+	}
+	return c
+}
+
+// decodeResultCursor - the cursor must have been created for the same sorting
+func decodeResultCursor(value string, query links.ResultQuery) (resultCursor, error) {
+	c := resultCursor{Position: -1}
+	if err := decodeCursor(value, &c); err != nil || c.Position < 0 {
+		return resultCursor{}, ErrInvalidCursor
+	}
+	if c.SortBy != query.SortBy || c.Descending != query.Descending {
+		return resultCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// pageResults - filters, sorts and cuts one page out of the results of a batch
+func pageResults(results []links.Result, query links.ResultQuery) (links.ResultPage, error) {
+	var after *resultCursor
+	if query.Cursor != "" {
+		c, err := decodeResultCursor(query.Cursor, query)
+		if err != nil {
+			return links.ResultPage{}, err
+		}
+		after = &c
+	}
+
+	filtered, err := sortResults(results, query)
+	if err != nil {
+		return links.ResultPage{}, err
+	}
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return compareResultToCursor(filtered[i], *after, query) > 0
+		})
+	}
+
+	end := len(filtered)
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+
+	page := links.ResultPage{Results: make([]links.Result, 0, end-start)}
+	for _, res := range filtered[start:end] {
+		page.Results = append(page.Results, res.result)
+	}
+	if end < len(filtered) {
+		page.NextCursor = encodeCursor(newResultCursor(filtered[end-1], query))
+	}
+	return page, nil
+}
+
+// sortResults - the results of a batch matching the query in the order of the query,
+// the limit and cursor are left to the caller
+func sortResults(results []links.Result, query links.ResultQuery) ([]positionedResult, error) {
+	switch query.SortBy {
+	case "", links.ResultSortCreatedAt, links.ResultSortPageURL, links.ResultSortInternalLinksNum, links.ResultSortExternalLinksNum:
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	filtered := make([]positionedResult, 0, len(results))
+	for position, result := range results {
+		if matchesResultQuery(result, query) {
+			filtered = append(filtered, positionedResult{result: result, position: position})
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return compareResultToCursor(filtered[i], newResultCursor(filtered[j], query), query) < 0
+	})
+	return filtered, nil
+}
+
+func matchesResultQuery(result links.Result, query links.ResultQuery) bool {
+	if query.Success != nil && result.Success != *query.Success {
+		return false
+	}
+	if query.Host != "" {
+		pageURL, err := url.Parse(result.PageURL)
+		if err != nil || !strings.EqualFold(pageURL.Hostname(), query.Host) {
+			return false
+		}
+	}
+	return inRange(result.InternalLinksNum, query.MinInternalLinks, query.MaxInternalLinks) &&
+		inRange(result.ExternalLinksNum, query.MinExternalLinks, query.MaxExternalLinks)
+}
+
+func inRange(value uint, min, max *uint) bool {
+	return (min == nil || value >= *min) && (max == nil || value <= *max)
+}
+
+// compareResultToCursor - orders the result relative to the cursor position in the requested sort order,
+// ties are broken by the position in the batch so the order is total
+func compareResultToCursor(res positionedResult, c resultCursor, query links.ResultQuery) int {
+	result := 0
+	switch query.SortBy {
+	case links.ResultSortCreatedAt:
+		result = compareTimes(res.result.CreatedAt, c.CreatedAt)
+	case links.ResultSortPageURL:
+		result = strings.Compare(res.result.PageURL, c.PageURL)
+	case links.ResultSortInternalLinksNum:
+		result = compareInts(int(res.result.InternalLinksNum), int(c.Count))
+	case links.ResultSortExternalLinksNum:
+		result = compareInts(int(res.result.ExternalLinksNum), int(c.Count))
+	}
+	if result == 0 {
+		result = compareInts(res.position, c.Position)
+	}
+	if query.Descending {
+		return -result
+	}
+	return result
+}
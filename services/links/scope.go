@@ -0,0 +1,23 @@
+package links
+
+import "context"
+
+type apiKeyScopeKey struct{}
+
+// WithAPIKeyScope - batches, results, monitors and alerts looked up with the returned context are limited
+// to the ones created with the api key, the others are reported as not found
+func WithAPIKeyScope(ctx context.Context, apiKeyID string) context.Context {
+	return context.WithValue(ctx, apiKeyScopeKey{}, apiKeyID)
+}
+
+// APIKeyScopeOf - api key the lookups of the context are limited to, false when everything can be looked up
+func APIKeyScopeOf(ctx context.Context) (string, bool) {
+	apiKeyID, ok := ctx.Value(apiKeyScopeKey{}).(string)
+	return apiKeyID, ok
+}
+
+// InAPIKeyScope - whether something created with the api key can be looked up with the context
+func InAPIKeyScope(ctx context.Context, apiKeyID string) bool {
+	scope, ok := APIKeyScopeOf(ctx)
+	return !ok || scope == apiKeyID
+}
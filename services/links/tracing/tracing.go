@@ -0,0 +1,87 @@
+// Package tracing sets up the OpenTelemetry tracer provider of the links service
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var ErrUnknownExporter = errors.New("unknown traces exporter")
+
+// Exporters of the spans
+const (
+	ExporterNone   = "none"   // spans aren't recorded
+	ExporterStdout = "stdout" // spans are written to stdout as indented json
+	ExporterFile   = "file"   // spans are appended to Config.File as a json object per line
+	ExporterOTLP   = "otlp"   // spans are sent over otlp/http, configured with the OTEL_EXPORTER_OTLP_* environment variables
+)
+
+// Config - where the spans are exported to
+type Config struct {
+	Exporter    string // one of the exporters, ExporterNone when empty
+	File        string // file of ExporterFile
+	ServiceName string
+}
+
+// NewTracerProvider - tracer provider exporting the spans with the exporter of the config, shutdown
+// flushes the spans not exported yet. The stdout and file exporters write every span as soon as it ends
+// so none is lost when the service is stopped, otlp exports them in batches.
+func NewTracerProvider(ctx context.Context, cfg Config) (trace.TracerProvider, func(context.Context) error, error) {
+	var export sdktrace.TracerProviderOption
+	var closer io.Closer
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter %w", err)
+		}
+		export = sdktrace.WithSyncer(exporter)
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open traces file %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter %w", err)
+		}
+		export = sdktrace.WithSyncer(exporter)
+		closer = file
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create otlp exporter %w", err)
+		}
+		export = sdktrace.WithBatcher(exporter)
+	default:
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownExporter, cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create resource %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(export, sdktrace.WithResource(res))
+	shutdown := func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}
+	return provider, shutdown, nil
+}
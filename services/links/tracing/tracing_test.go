@@ -0,0 +1,68 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lockwarr/codefi/services/links/tracing"
+	"github.com/stretchr/testify/suite"
+)
+
+type tracingTestSuite struct {
+	suite.Suite
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, &tracingTestSuite{})
+}
+
+func (s *tracingTestSuite) TestNewTracerProvider_WhenExporterIsFile_ThenSpansAreAppendedAsJSONLines() {
+	// Arrange
+	path := filepath.Join(s.T().TempDir(), "traces.jsonl")
+	provider, shutdown, err := tracing.NewTracerProvider(context.Background(), tracing.Config{Exporter: tracing.ExporterFile, File: path, ServiceName: "links"})
+	s.Require().NoError(err)
+
+	// Act
+	_, span := provider.Tracer("test").Start(context.Background(), "first")
+	span.End()
+	_, span = provider.Tracer("test").Start(context.Background(), "second")
+	span.End()
+	shutdownErr := shutdown(context.Background())
+
+	// Assert
+	s.NoError(shutdownErr)
+	content, err := os.ReadFile(path)
+	s.Require().NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	s.Equal(2, len(lines))
+	var exported struct{ Name string }
+	s.NoError(json.Unmarshal([]byte(lines[0]), &exported))
+	s.Equal("first", exported.Name)
+	s.Contains(lines[0], `{"Key":"service.name","Value":{"Type":"STRING","Value":"links"}}`)
+}
+
+func (s *tracingTestSuite) TestNewTracerProvider_WhenExporterIsNone_ThenSpansAreNotRecorded() {
+	// Arrange
+	provider, shutdown, err := tracing.NewTracerProvider(context.Background(), tracing.Config{})
+	s.Require().NoError(err)
+
+	// Act
+	_, span := provider.Tracer("test").Start(context.Background(), "span")
+
+	// Assert
+	s.False(span.IsRecording())
+	s.NoError(shutdown(context.Background()))
+}
+
+func (s *tracingTestSuite) TestNewTracerProvider_WhenExporterIsUnknown_ThenFail() {
+	// Act
+	_, _, err := tracing.NewTracerProvider(context.Background(), tracing.Config{Exporter: "jaeger"})
+
+	// Assert
+	s.ErrorIs(err, tracing.ErrUnknownExporter)
+	s.EqualError(err, `unknown traces exporter "jaeger"`)
+}
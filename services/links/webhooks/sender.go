@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// Headers sent with every callback
+const (
+	EventHeader     = "X-Callback-Event"
+	DeliveryHeader  = "X-Callback-Delivery" // event id, the same for every retry of a delivery
+	SignatureHeader = "X-Callback-Signature"
+)
+
+var sendTimeout = 10 * time.Second
+
+// Sender - POSTs callback events as JSON, signed when the callback has a secret
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender - when client is nil a cleanhttp client with a 10s timeout is used
+func NewSender(client *http.Client) *Sender {
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+		client.Timeout = sendTimeout
+	}
+	return &Sender{client: client}
+}
+
+// Send - any response other than 2xx is an error, the status code is returned whenever there was a response
+func (s *Sender) Send(ctx context.Context, callback links.Callback, event links.CallbackEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callback.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID)
+	if callback.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(callback.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign - "sha256=" followed by the hex encoded HMAC-SHA256 of the body,
+// receivers verify a callback by computing the same value with their secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package webhooks_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lockwarr/codefi/services/links"
+	"github.com/Lockwarr/codefi/services/links/webhooks"
+	"github.com/stretchr/testify/suite"
+)
+
+type senderTestSuite struct {
+	suite.Suite
+}
+
+func TestSenderTestSuite(t *testing.T) {
+	suite.Run(t, &senderTestSuite{})
+}
+
+func (s *senderTestSuite) TestSend_WhenSecretIsSet_ThenBodyIsSigned() {
+	// Arrange
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+	}))
+	defer server.Close()
+	sender := webhooks.NewSender(nil)
+	event := links.CallbackEvent{ID: "e0", Type: links.CallbackBatchCompleted, Batch: links.Batch{ID: "b0"}}
+
+	// Act
+	statusCode, err := sender.Send(context.Background(), links.Callback{URL: server.URL, Secret: "s3cret"}, event)
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal(http.StatusOK, statusCode)
+	req := <-requests
+	s.Equal("application/json", req.header.Get("Content-Type"))
+	s.Equal(links.CallbackBatchCompleted, req.header.Get(webhooks.EventHeader))
+	s.Equal("e0", req.header.Get(webhooks.DeliveryHeader))
+	s.Equal(webhooks.Sign("s3cret", req.body), req.header.Get(webhooks.SignatureHeader))
+	s.Contains(string(req.body), `"batch":{"id":"b0"`)
+}
+
+func (s *senderTestSuite) TestSend_WhenSecretIsMissing_ThenBodyIsNotSigned() {
+	// Arrange
+	signatures := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures <- r.Header.Get(webhooks.SignatureHeader)
+	}))
+	defer server.Close()
+	sender := webhooks.NewSender(server.Client())
+
+	// Act
+	_, err := sender.Send(context.Background(), links.Callback{URL: server.URL}, links.CallbackEvent{ID: "e0"})
+
+	// Assert
+	s.Equal(nil, err)
+	s.Equal("", <-signatures)
+}
+
+func (s *senderTestSuite) TestSend_WhenStatusIsNotSuccessful_ThenFail() {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	sender := webhooks.NewSender(server.Client())
+
+	// Act
+	statusCode, err := sender.Send(context.Background(), links.Callback{URL: server.URL}, links.CallbackEvent{ID: "e0"})
+
+	// Assert
+	s.Equal(http.StatusServiceUnavailable, statusCode)
+	s.Equal("callback responded with status 503", err.Error())
+}
+
+func (s *senderTestSuite) TestSign_ThenKnownDigestIsReturned() {
+	// Act
+	signature := webhooks.Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+
+	// Assert
+	s.Equal("sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", signature)
+}